@@ -254,3 +254,47 @@ func gitRevParse(t *testing.T, dir string, rev string) string {
 	}
 	return strings.TrimSpace(string(out))
 }
+
+func TestAPIScanHandlerはcontextパラメータで前後の行を返す(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.name", "Tester")
+	runGit(t, repoDir, "config", "user.email", "tester@example.com")
+	source := "package main\n\nfunc main() {\n\t// TODO: wire flags\n\trun()\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte(source), 0o644); err != nil {
+		t.Fatalf("ファイルの作成に失敗しました: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial todo")
+
+	handler := apiScanHandler(repoDir)
+	req := httptest.NewRequest(http.MethodGet, "/api/scan?context=1", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ステータスコードが一致しません: got=%d want=%d body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var res engine.Result
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatalf("レスポンスのデコードに失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 {
+		t.Fatalf("TODO が1件ではありません: %+v", res.Items)
+	}
+	it := res.Items[0]
+	if len(it.ContextBefore) != 1 || it.ContextBefore[0] != "func main() {" {
+		t.Fatalf("context_before が期待通りではありません: %q", it.ContextBefore)
+	}
+	if len(it.ContextAfter) != 1 || it.ContextAfter[0] != "\trun()" {
+		t.Fatalf("context_after が期待通りではありません: %q", it.ContextAfter)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/scan?context=51", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("ステータスコードが一致しません: got=%d want=%d", rr.Code, http.StatusBadRequest)
+	}
+}
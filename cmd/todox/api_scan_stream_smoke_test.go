@@ -18,16 +18,17 @@ import (
 
 func TestAPIScanStreamHandlerEmitsProgressAndResult(t *testing.T) {
 	repoDir := prepareStreamRepo(t)
+	runGit(t, repoDir, "remote", "add", "origin", "https://github.com/example/repo.git")
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/scan/stream", apiScanStreamHandler(repoDir))
+	mux.HandleFunc("/api/scan/stream", apiScanStreamHandler(repoDir, 0))
 	srv := httptest.NewServer(mux)
 	t.Cleanup(srv.Close)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/scan/stream?with_pr_links=0", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/scan/stream?with_pr_links=0&with_commit_link=1&group_by=author", nil)
 	if err != nil {
 		t.Fatalf("failed to build request: %v", err)
 	}
@@ -61,6 +62,7 @@ func TestAPIScanStreamHandlerEmitsProgressAndResult(t *testing.T) {
 		dataLines    []string
 		progressSeen int
 		stages       []string
+		items        []engine.Item
 		gotResult    bool
 	)
 
@@ -77,13 +79,29 @@ func TestAPIScanStreamHandlerEmitsProgressAndResult(t *testing.T) {
 			}
 			progressSeen++
 			stages = append(stages, evt.Stage)
+		case "item":
+			var it engine.Item
+			if err := json.Unmarshal([]byte(payload), &it); err != nil {
+				t.Fatalf("failed to decode item payload: %v (raw=%s)", err, payload)
+			}
+			if !strings.HasPrefix(it.URL, "https://github.com/example/repo/blob/") {
+				t.Fatalf("item events should carry the URL, got %q", it.URL)
+			}
+			items = append(items, it)
 		case "result":
 			var res engine.Result
 			if err := json.Unmarshal([]byte(payload), &res); err != nil {
 				t.Fatalf("failed to decode result payload: %v (raw=%s)", err, payload)
 			}
-			if len(res.Items) == 0 {
-				t.Fatalf("expected result items, got none: %+v", res)
+			// 項目は item イベントで送り終えているので、result は集計だけ
+			if len(items) == 0 || len(res.Items) != 0 || res.Total != len(items) {
+				t.Fatalf("expected a summary-only result after %d items, got %+v", len(items), res)
+			}
+			if res.GroupBy != "author" || len(res.Groups) != 1 || res.Groups[0].Key != "Tester" || res.Groups[0].Count != len(items) {
+				t.Fatalf("unexpected groups in the summary: %+v", res.Groups)
+			}
+			if !res.HasURL {
+				t.Fatalf("the summary should still report the URL column")
 			}
 			if res.HasPRs {
 				t.Fatalf("with_pr_links=0 should disable PR enrichment")
@@ -143,7 +161,7 @@ func TestAPIScanStreamHandlerEmitsProgressAndResult(t *testing.T) {
 
 func TestAPIScanStreamHandlerStopsOnClientClose(t *testing.T) {
 	repoDir := prepareStreamRepo(t)
-	handler := apiScanStreamHandler(repoDir)
+	handler := apiScanStreamHandler(repoDir, 0)
 
 	pr, pw := io.Pipe()
 	recorder := newSSERecorder(pw)
@@ -194,6 +212,85 @@ func TestAPIScanStreamHandlerStopsOnClientClose(t *testing.T) {
 	}
 }
 
+func TestAPIScanStreamHandlerPushesUpdatesWhenWatching(t *testing.T) {
+	repoDir := prepareStreamRepo(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/scan/stream", apiScanStreamHandler(repoDir, 50*time.Millisecond))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/scan/stream?with_pr_links=0", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to call stream endpoint: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	// nextEvent は次の watch / result イベントを返します（progress や item は読み飛ばす）。
+	nextEvent := func() (string, string) {
+		t.Helper()
+		var event string
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event == "watch" || event == "result" {
+					return event, strings.Join(data, "\n")
+				}
+				if event == "error" {
+					t.Fatalf("stream returned error event: %s", strings.Join(data, "\n"))
+				}
+				event, data = "", nil
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(line[6:])
+			case strings.HasPrefix(line, "data:"):
+				data = append(data, strings.TrimSpace(line[5:]))
+			}
+		}
+		t.Fatalf("stream ended early: %v", scanner.Err())
+		return "", ""
+	}
+	decode := func(payload string) engine.Result {
+		t.Helper()
+		var res engine.Result
+		if err := json.Unmarshal([]byte(payload), &res); err != nil {
+			t.Fatalf("failed to decode result payload: %v (raw=%s)", err, payload)
+		}
+		return res
+	}
+
+	if event, _ := nextEvent(); event != "watch" {
+		t.Fatalf("expected the watch event first, got %q", event)
+	}
+	if event, payload := nextEvent(); event != "result" || len(decode(payload).Items) != 1 {
+		t.Fatalf("unexpected first result: %s %s", event, payload)
+	}
+
+	source := "package main\n\nfunc main() {\n  // TODO: stream check\n  // FIXME: added later\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte(source), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	event, payload := nextEvent()
+	if event != "result" {
+		t.Fatalf("expected an updated result, got %q", event)
+	}
+	res := decode(payload)
+	if len(res.Items) != 2 || res.Items[0].Author != "Tester" || res.Items[1].Author != "(working tree)" {
+		t.Fatalf("unexpected updated result: %+v", res.Items)
+	}
+}
+
 func prepareStreamRepo(t *testing.T) string {
 	t.Helper()
 	repoDir := t.TempDir()
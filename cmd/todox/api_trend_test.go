@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+func TestAPITrendHandlerは件数推移を返す(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.name", "Tester")
+	runGit(t, repoDir, "config", "user.email", "tester@example.com")
+	if err := os.MkdirAll(filepath.Join(repoDir, "src"), 0o755); err != nil {
+		t.Fatalf("ディレクトリの作成に失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "src", "main.go"), []byte("package main\n// TODO one\n// FIXME two\n"), 0o644); err != nil {
+		t.Fatalf("ファイルの作成に失敗しました: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	handler := apiTrendHandler(repoDir)
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/trend?every=1d&since=2d&group_by=dir&until="+until, nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ステータスコードが一致しません: got=%d want=%d body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var res engine.TrendResult
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatalf("レスポンスのデコードに失敗しました: %v", err)
+	}
+	if len(res.Points) == 0 {
+		t.Fatalf("サンプルが返っていません: %+v", res)
+	}
+	last := res.Points[len(res.Points)-1]
+	if last.Total != 2 || last.Tags["TODO"] != 1 || last.Tags["FIXME"] != 1 {
+		t.Fatalf("件数が期待と異なります: %+v", last)
+	}
+	if len(last.Groups) != 1 || last.Groups[0].Key != "src" {
+		t.Fatalf("グループが期待と異なります: %+v", last.Groups)
+	}
+}
+
+func TestAPITrendHandlerは不正なeveryで400を返す(t *testing.T) {
+	t.Parallel()
+
+	handler := apiTrendHandler(".")
+	req := httptest.NewRequest(http.MethodGet, "/api/trend?every=soon", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("ステータスコードが一致しません: got=%d want=%d", rr.Code, http.StatusBadRequest)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "invalid --every") {
+		t.Fatalf("エラーメッセージが期待通りではありません: %q", body)
+	}
+}
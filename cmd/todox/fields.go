@@ -37,25 +37,33 @@ type fieldMeta struct {
 }
 
 var fieldRegistry = map[string]fieldMeta{
-	"type":       {header: "TYPE"},
-	"lang":       {header: "LANG"},
-	"kind":       {header: "KIND"},
-	"tag":        {header: "TAG"},
-	"author":     {header: "AUTHOR"},
-	"email":      {header: "EMAIL"},
-	"date":       {header: "DATE"},
-	"age":        {header: "AGE", isAge: true},
-	"commit":     {header: "COMMIT"},
-	"location":   {header: "LOCATION"},
-	"text":       {header: "TEXT"},
-	"span":       {header: "SPAN"},
-	"comment":    {header: "COMMENT", isComment: true},
-	"message":    {header: "MESSAGE", isMessage: true},
-	"url":        {header: "URL", isURL: true},
-	"commit_url": {header: "COMMIT_URL", isURL: true},
-	"pr":         {header: "PR", isPR: true},
-	"prs":        {header: "PRS", isPR: true},
-	"pr_urls":    {header: "PR_URLS", isPR: true},
+	"type":            {header: "TYPE"},
+	"lang":            {header: "LANG"},
+	"kind":            {header: "KIND"},
+	"tag":             {header: "TAG"},
+	"author":          {header: "AUTHOR"},
+	"email":           {header: "EMAIL"},
+	"committer":       {header: "COMMITTER"},
+	"committer_email": {header: "COMMITTER_EMAIL"},
+	"date":            {header: "DATE"},
+	"age":             {header: "AGE", isAge: true},
+	"commit":          {header: "COMMIT"},
+	"location":        {header: "LOCATION"},
+	"text":            {header: "TEXT"},
+	"span":            {header: "SPAN"},
+	"cell":            {header: "CELL"},
+	"comment":         {header: "COMMENT", isComment: true},
+	"body":            {header: "BODY", isComment: true},
+	"message":         {header: "MESSAGE", isMessage: true},
+	"url":             {header: "URL", isURL: true},
+	"commit_url":      {header: "COMMIT_URL", isURL: true},
+	"pr":              {header: "PR", isPR: true},
+	"prs":             {header: "PRS", isPR: true},
+	"pr_urls":         {header: "PR_URLS", isPR: true},
+	"owner":           {header: "OWNER"},
+	"owners":          {header: "OWNERS"},
+	"skipped":         {header: "SKIPPED"},
+	"suppressed":      {header: "SUPPRESSED"},
 }
 
 func ResolveFields(raw string, withComment, withMessage, withAge, withURL, withPRs bool) (FieldSelection, error) {
@@ -146,6 +154,10 @@ func formatFieldValue(it engine.Item, key string) string {
 		return it.Author
 	case "email":
 		return it.Email
+	case "committer":
+		return it.Committer
+	case "committer_email":
+		return it.CommitterEmail
 	case "date":
 		return it.Date
 	case "age":
@@ -158,14 +170,29 @@ func formatFieldValue(it engine.Item, key string) string {
 		return it.Text
 	case "span":
 		return formatSpan(it.Span)
+	case "cell":
+		return formatCell(it.Cell)
 	case "comment":
 		return it.Comment
+	case "body":
+		return it.Body
 	case "message":
 		return it.Message
 	case "url":
 		return it.URL
 	case "commit_url":
 		return it.URL
+	case "owner":
+		if len(it.Owners) == 0 {
+			return ""
+		}
+		return it.Owners[0]
+	case "owners":
+		return strings.Join(it.Owners, " ")
+	case "skipped":
+		return formatSkipped(it.Skipped)
+	case "suppressed":
+		return it.Suppressed
 	case "pr":
 		if len(it.PRs) == 0 {
 			return ""
@@ -234,3 +261,22 @@ func formatSpan(span model.Span) string {
 	}
 	return fmt.Sprintf("%d:%d-%d:%d", span.StartLine, startCol, endLine, endCol)
 }
+
+func formatSkipped(skipped []engine.SkippedCommit) string {
+	parts := make([]string, 0, len(skipped))
+	for _, sc := range skipped {
+		commit := sc.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		parts = append(parts, fmt.Sprintf("%s(%s)", commit, sc.Author))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatCell(cell *model.CellRef) string {
+	if cell == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", cell.Index, cell.Line)
+}
@@ -167,6 +167,8 @@ func TestParseScanArgsAcceptsNewOutputs(t *testing.T) {
 		"ndjson":         "ndjson",
 		"md":             "md",
 		"markdown-table": "md",
+		"QuickFix":       "quickfix",
+		"fzf":            "fzf",
 	}
 	for input, want := range cases {
 		cfg, err := parseScanArgs([]string{"--output", input}, "en")
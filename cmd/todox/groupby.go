@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+const unownedGroupKey = "(unowned)"
+
+// ParseGroupBy は --group-by の値を正規化します。空文字列はグループ化なしを表します。
+func ParseGroupBy(raw string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	switch key {
+	case "", "none":
+		return "", nil
+	case "owner", "owners":
+		return "owners", nil
+	case "author", "type", "file":
+		return key, nil
+	default:
+		return "", fmt.Errorf("invalid --group-by: %s (allowed: owners, author, type, file)", raw)
+	}
+}
+
+func groupKey(it engine.Item, groupBy string) string {
+	switch groupBy {
+	case "owners":
+		if len(it.Owners) == 0 {
+			return unownedGroupKey
+		}
+		return strings.Join(it.Owners, " ")
+	case "author":
+		return it.Author
+	case "type":
+		return it.Kind
+	case "file":
+		return it.File
+	default:
+		return ""
+	}
+}
+
+// ApplyGroup は items をグループ単位にまとめ直し、グループごとの件数を返します。
+// グループは件数の多い順（同数ならキー順）に並び、グループ内では既存の並び順を保ちます。
+// 担当者なしのグループは常に最後に置きます。
+func ApplyGroup(items []engine.Item, groupBy string) []engine.Group {
+	if groupBy == "" {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, it := range items {
+		counts[groupKey(it, groupBy)]++
+	}
+	groups := groupsFromCounts(counts)
+	rank := make(map[string]int, len(groups))
+	for i, g := range groups {
+		rank[g.Key] = i
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return rank[groupKey(items[i], groupBy)] < rank[groupKey(items[j], groupBy)]
+	})
+	return groups
+}
+
+// groupsFromCounts はキーごとの件数を ApplyGroup と同じ順に並べたグループにします。
+// 項目を保持せずに件数だけを数えたとき（SSE の逐次送信など）に使います。
+func groupsFromCounts(counts map[string]int) []engine.Group {
+	groups := make([]engine.Group, 0, len(counts))
+	for key, n := range counts {
+		groups = append(groups, engine.Group{Key: key, Count: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if (groups[i].Key == unownedGroupKey) != (groups[j].Key == unownedGroupKey) {
+			return groups[j].Key == unownedGroupKey
+		}
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/output"
+)
+
+func TestParseGroupByは別名を正規化する(t *testing.T) {
+	cases := map[string]string{"": "", "none": "", "Owner": "owners", "owners": "owners", "author": "author", "FILE": "file"}
+	for in, want := range cases {
+		got, err := ParseGroupBy(in)
+		if err != nil {
+			t.Fatalf("ParseGroupBy(%q) でエラー: %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseGroupBy(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := ParseGroupBy("dir"); err == nil {
+		t.Fatal("未知のキーでエラーになっていません")
+	}
+}
+
+func TestApplyGroupは件数順に並べ担当者なしを最後に置く(t *testing.T) {
+	items := []engine.Item{
+		{File: "a.go", Line: 1},
+		{File: "b.go", Line: 1, Owners: []string{"@org/web"}},
+		{File: "c.go", Line: 1, Owners: []string{"@org/api"}},
+		{File: "c.go", Line: 2, Owners: []string{"@org/api"}},
+		{File: "d.go", Line: 1},
+		{File: "e.go", Line: 1},
+	}
+	groups := ApplyGroup(items, "owners")
+	want := []engine.Group{{Key: "@org/api", Count: 2}, {Key: "@org/web", Count: 1}, {Key: "(unowned)", Count: 3}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("グループが期待と異なります: got=%+v want=%+v", groups, want)
+	}
+	var order []string
+	for _, it := range items {
+		order = append(order, it.File)
+	}
+	if got := strings.Join(order, ","); got != "c.go,c.go,b.go,a.go,d.go,e.go" {
+		t.Fatalf("並び順が期待と異なります: %s", got)
+	}
+}
+
+func TestSortSpecはownerキーで並べ替える(t *testing.T) {
+	spec, err := ParseSortSpec("-owners")
+	if err != nil {
+		t.Fatalf("ParseSortSpec に失敗しました: %v", err)
+	}
+	items := []engine.Item{
+		{File: "a.go", Owners: []string{"@alice"}},
+		{File: "b.go", Owners: []string{"@bob"}},
+		{File: "c.go"},
+	}
+	ApplySort(items, spec)
+	if items[0].File != "b.go" || items[2].File != "c.go" {
+		t.Fatalf("owner 降順の並びが期待と異なります: %+v", items)
+	}
+}
+
+func TestPrintTableはグループ見出しを挟む(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("パイプの作成に失敗しました: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = oldStdout })
+
+	res := &engine.Result{
+		Items: []engine.Item{
+			{Kind: "TODO", File: "a.go", Line: 1},
+			{Kind: "TODO", File: "b.go", Line: 2, Owners: []string{"@org/api"}},
+		},
+	}
+	res.GroupBy = "owners"
+	res.Groups = ApplyGroup(res.Items, res.GroupBy)
+
+	sel, err := output.ResolveFields("owner,location", false, false, false, false, false)
+	if err != nil {
+		t.Fatalf("ResolveFields failed: %v", err)
+	}
+	printTable(res, sel, tableColorConfig{})
+	_ = w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("出力の読み込みに失敗しました: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	want := []string{"== @org/api (1) ==", "== (unowned) (1) =="}
+	if len(lines) != 5 || lines[1] != want[0] || lines[3] != want[1] {
+		t.Fatalf("グループ見出しが期待と異なります: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "OWNER") || !strings.Contains(lines[2], "@org/api  b.go:2") {
+		t.Fatalf("表の内容が期待と異なります: %q", lines)
+	}
+}
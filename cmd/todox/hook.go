@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/execx"
+)
+
+// hookMarker はインストールしたフックスクリプトの 2 行目です。自分で書いたフックかの判定に使います。
+const hookMarker = "# todox hook:"
+
+// hookKinds は todox hook install が書けるフックです。
+var hookKinds = []string{"pre-commit", "pre-push"}
+
+// hookRule は新しい項目に求める情報です。
+type hookRule uint8
+
+const (
+	requireOwner hookRule = 1 << iota
+	requireIssue
+)
+
+var (
+	// defaultIssuePattern は #123、owner/repo#123、PROJ-123、課題や PR の URL に一致します。
+	defaultIssuePattern = regexp.MustCompile(`(?:^|[^\w&])#\d+\b|\b[A-Z][A-Z0-9_]+-\d+\b|https?://\S+/(?:issues|pull|pulls|merge_requests|browse)/\S+`)
+	mentionPattern      = regexp.MustCompile(`(?:^|[^\w.])@[A-Za-z0-9][\w.-]*(?:/[\w.-]+)?`)
+	simpleShellWord     = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+)
+
+// hookPolicy は新しい項目に求める情報をタグごとに持ちます。byTag にないタグには all を使います。
+type hookPolicy struct {
+	all   hookRule
+	byTag map[string]hookRule
+	issue *regexp.Regexp
+}
+
+// hookCheckConfig は todox hook pre-commit / pre-push の設定です。
+type hookCheckConfig struct {
+	repo   string
+	policy hookPolicy
+	args   []string // pre-push に git が渡すリモート名と URL
+}
+
+// hookViolation は方針を満たさない新しい項目と、足りない情報です。
+type hookViolation struct {
+	item    engine.Item
+	missing hookRule
+}
+
+func hookCmd(args []string) {
+	err := runHook(args, os.Stdin, os.Stderr)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printHookHelp()
+		return
+	}
+	var verr *hookViolationError
+	if errors.As(err, &verr) {
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "todox hook: %v\n", err)
+	var uerr *usageError
+	if errors.As(err, &uerr) {
+		printHookHelp()
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+func printHookHelp() {
+	fmt.Print("Usage: todox hook pre-commit [check options]\n" +
+		"       todox hook pre-push [check options] [REMOTE [URL]]\n" +
+		"       todox hook install [pre-commit|pre-push] [--force] [--repo DIR] [-- check options]\n" +
+		"       todox hook uninstall [pre-commit|pre-push] [--repo DIR]\n\n" +
+		"Check TODO/FIXME comments on newly added lines before they leave your machine.\n" +
+		"pre-commit scans the staged changes (git diff --cached); pre-push reads the\n" +
+		"pushed refs from stdin and scans the commits the remote does not have yet.\n" +
+		"Moved or re-indented lines do not count as new. Items that break the policy\n" +
+		"are listed on stderr and the command exits with status 1.\n\n" +
+		"Check options:\n" +
+		"  --require RULES        Information each new item must carry (repeatable):\n" +
+		"                         owner  FIXME(alice): ... or an @mention\n" +
+		"                         issue  #123, PROJ-123 or an issue/PR URL\n" +
+		"                         RULES is RULE[,RULE] for every tag, TAG[,TAG]=RULE[,RULE]\n" +
+		"                         for some tags, or TAG=none to exempt a tag\n" +
+		"                         (default: owner)\n" +
+		"  --issue-pattern REGEX  What counts as an issue reference (default: see above)\n" +
+		"  --repo DIR             Repository to check (default: .)\n\n" +
+		"Tags, detection, paths, .todoxignore and todox:ignore markers follow\n" +
+		".todox.yaml and TODOX_* variables, like a normal scan.\n\n" +
+		"install writes .git/hooks/pre-commit (or pre-push, honouring core.hooksPath)\n" +
+		"that runs the check with the options after --. It refuses to replace a hook it\n" +
+		"did not write unless --force is given. Skip a check once with --no-verify.\n\n" +
+		"Examples:\n" +
+		"  todox hook install\n" +
+		"  todox hook install pre-push -- --require FIXME=owner,issue --require TODO=issue\n" +
+		"  git diff --cached --stat && todox hook pre-commit --require owner,issue\n")
+}
+
+// hookViolationError は方針違反を表します。一覧は出力済みなので、メッセージは付けずに終了コードだけを決めます。
+type hookViolationError struct {
+	count int
+}
+
+func (e *hookViolationError) Error() string {
+	return fmt.Sprintf("%d item(s) break the policy", e.count)
+}
+
+func runHook(args []string, stdin io.Reader, stderr io.Writer) error {
+	if len(args) == 0 {
+		return &usageError{err: errors.New("missing subcommand (pre-commit, pre-push, install or uninstall)")}
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "-h", "--help", "help":
+		return flag.ErrHelp
+	case "pre-commit", "pre-push":
+		cfg, err := parseHookCheckArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		var items []engine.Item
+		if args[0] == "pre-commit" {
+			items, err = stagedItems(ctx, cfg)
+		} else {
+			items, err = pushedItems(ctx, cfg, stdin)
+		}
+		if err != nil {
+			return err
+		}
+		violations := cfg.policy.check(items)
+		if len(violations) == 0 {
+			return nil
+		}
+		reportViolations(stderr, violations, args[0])
+		return &hookViolationError{count: len(violations)}
+	case "install":
+		path, err := installHook(args[1:])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "todox hook: installed %s\n", path)
+		return nil
+	case "uninstall":
+		path, err := uninstallHook(args[1:])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "todox hook: removed %s\n", path)
+		return nil
+	}
+	return &usageError{err: fmt.Errorf("unknown subcommand %q (want pre-commit, pre-push, install or uninstall)", args[0])}
+}
+
+func parseHookCheckArgs(args []string) (hookCheckConfig, error) {
+	cfg := hookCheckConfig{repo: "."}
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	require := multiFlag{noSplit: true}
+	fs.Var(&require, "require", "RULES")
+	issuePattern := fs.String("issue-pattern", "", "issue reference regexp")
+	repo := fs.String("repo", ".", "repository")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cfg, err
+		}
+		return cfg, &usageError{err: err}
+	}
+	rules := require.Slice()
+	if !require.WasSet() {
+		rules = []string{"owner"}
+	}
+	policy, err := parseHookPolicy(rules, *issuePattern)
+	if err != nil {
+		return cfg, &usageError{err: err}
+	}
+	cfg.policy = policy
+	cfg.repo = mustAbs(*repo)
+	cfg.args = fs.Args()
+	return cfg, nil
+}
+
+// parseHookPolicy は --require の値を解釈します。TAG= のある指定は、そのタグについて全タグ向けの指定を置き換えます。
+func parseHookPolicy(entries []string, issuePattern string) (hookPolicy, error) {
+	p := hookPolicy{byTag: make(map[string]hookRule), issue: defaultIssuePattern}
+	if strings.TrimSpace(issuePattern) != "" {
+		rx, err := regexp.Compile(issuePattern)
+		if err != nil {
+			return p, fmt.Errorf("invalid --issue-pattern: %w", err)
+		}
+		p.issue = rx
+	}
+	for _, entry := range entries {
+		tagsPart, rulesPart, scoped := strings.Cut(entry, "=")
+		if !scoped {
+			rulesPart = tagsPart
+		}
+		var rule hookRule
+		for _, name := range strings.Split(rulesPart, ",") {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "owner":
+				rule |= requireOwner
+			case "issue":
+				rule |= requireIssue
+			case "none":
+			default:
+				return p, fmt.Errorf("invalid --require %q (want owner, issue or none, optionally prefixed with TAG=)", entry)
+			}
+		}
+		if !scoped {
+			p.all |= rule
+			continue
+		}
+		for _, tag := range strings.Split(tagsPart, ",") {
+			tag = strings.ToUpper(strings.TrimSpace(tag))
+			if tag == "" {
+				return p, fmt.Errorf("invalid --require %q (empty tag)", entry)
+			}
+			p.byTag[tag] |= rule
+		}
+	}
+	return p, nil
+}
+
+func (p hookPolicy) rulesFor(tag string) hookRule {
+	if rule, ok := p.byTag[strings.ToUpper(tag)]; ok {
+		return rule
+	}
+	return p.all
+}
+
+// check は items のうち、求める情報が欠けている項目を返します。
+func (p hookPolicy) check(items []engine.Item) []hookViolation {
+	var out []hookViolation
+	for _, it := range items {
+		rule := p.rulesFor(it.Tag)
+		var missing hookRule
+		if rule&requireOwner != 0 && !p.hasOwner(it) {
+			missing |= requireOwner
+		}
+		if rule&requireIssue != 0 && !p.hasIssue(it) {
+			missing |= requireIssue
+		}
+		if missing != 0 {
+			out = append(out, hookViolation{item: it, missing: missing})
+		}
+	}
+	return out
+}
+
+// hasOwner は TAG(alice) のようにタグの直後の括弧で、または @alice のように担当者を書いているかを返します。
+// TODO(#123) のように括弧の中が課題番号だけなら担当者とはみなしません。
+func (p hookPolicy) hasOwner(it engine.Item) bool {
+	text := it.Text + "\n" + it.Body
+	if mentionPattern.MatchString(text) {
+		return true
+	}
+	if it.Tag == "" {
+		return false
+	}
+	rx, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(it.Tag) + `\s*\(([^)]*)\)`)
+	if err != nil {
+		return false
+	}
+	m := rx.FindStringSubmatch(it.Text)
+	if m == nil {
+		return false
+	}
+	for _, token := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if strings.IndexFunc(token, isLetter) >= 0 && !p.issue.MatchString(token) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p hookPolicy) hasIssue(it engine.Item) bool {
+	return p.issue.MatchString(it.Text + "\n" + it.Body)
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f
+}
+
+// reportViolations は方針違反の項目を 1 行ずつ、最後に直し方を添えて書き出します。
+func reportViolations(w io.Writer, violations []hookViolation, kind string) {
+	var all hookRule
+	fmt.Fprintf(w, "todox: %d new TODO/FIXME item(s) break the policy:\n", len(violations))
+	for _, v := range violations {
+		all |= v.missing
+		var needs []string
+		if v.missing&requireOwner != 0 {
+			needs = append(needs, "an owner")
+		}
+		if v.missing&requireIssue != 0 {
+			needs = append(needs, "an issue reference")
+		}
+		text := v.item.Text
+		if v.item.Comment != "" {
+			text = v.item.Comment
+		}
+		fmt.Fprintf(w, "  %s:%d: %s (needs %s)\n", v.item.File, v.item.Line, text, strings.Join(needs, " and "))
+	}
+	var hints []string
+	if all&requireOwner != 0 {
+		hints = append(hints, "name an owner as FIXME(alice): or @alice")
+	}
+	if all&requireIssue != 0 {
+		hints = append(hints, "reference an issue as #123 or PROJ-123")
+	}
+	git := "git commit"
+	if kind == "pre-push" {
+		git = "git push"
+	}
+	fmt.Fprintf(w, "To fix, %s; to skip this check once, use %s --no-verify.\n", strings.Join(hints, " and "), git)
+}
+
+// hookScanOptions は設定ファイルと TODOX_* 環境変数を反映した、フック用の走査オプションです。
+func hookScanOptions(repo string) (engine.Options, error) {
+	opts, err := layeredEngineOptions(repo)
+	if err != nil {
+		return opts, err
+	}
+	opts.RepoDir = repo
+	opts.WithComment = true
+	opts.Progress = false
+	return opts, nil
+}
+
+// stagedItems はステージ済みの変更で追加された項目を返します。
+func stagedItems(ctx context.Context, cfg hookCheckConfig) ([]engine.Item, error) {
+	opts, err := hookScanOptions(cfg.repo)
+	if err != nil {
+		return nil, err
+	}
+	return engine.ScanAdded(ctx, opts, "", "")
+}
+
+// pushedItems は pre-push の標準入力（<local ref> <local sha> <remote ref> <remote sha> の行）を読み、
+// リモートにまだ無いコミットで追加された項目を返します。ブランチの削除は対象外です。
+func pushedItems(ctx context.Context, cfg hookCheckConfig, stdin io.Reader) ([]engine.Item, error) {
+	opts, err := hookScanOptions(cfg.repo)
+	if err != nil {
+		return nil, err
+	}
+	remote := ""
+	if len(cfg.args) > 0 {
+		remote = cfg.args[0]
+	}
+	runner := execx.DefaultRunner()
+	var items []engine.Item
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(stdin)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 4 || isZeroSHA(fields[1]) {
+			continue
+		}
+		local, remoteSHA := fields[1], fields[3]
+		base, err := pushBase(ctx, runner, cfg.repo, remote, local, remoteSHA)
+		if err != nil {
+			return nil, err
+		}
+		if base == "" {
+			continue
+		}
+		found, err := engine.ScanAdded(ctx, opts, base, local)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range found {
+			key := fmt.Sprintf("%s:%d:%s", it.File, it.Line, it.Text)
+			if !seen[key] {
+				seen[key] = true
+				items = append(items, it)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// pushBase は local を push するときの比較元を返します。リモートの先端が手元にあればそれを、
+// 新しいブランチなどでは remote にまだ無い最も古いコミットの親（なければ空のツリー）を使います。
+// 送るコミットが無ければ空文字列を返します。
+func pushBase(ctx context.Context, runner execx.Runner, repo, remote, local, remoteSHA string) (string, error) {
+	if !isZeroSHA(remoteSHA) {
+		if _, _, err := runner.Run(ctx, repo, "git", "cat-file", "-e", remoteSHA+"^{commit}"); err == nil {
+			return remoteSHA, nil
+		}
+	}
+	args := []string{"rev-list", "--reverse", local, "--not"}
+	if remote != "" {
+		args = append(args, "--remotes="+remote)
+	} else {
+		args = append(args, "--remotes")
+	}
+	out, stderr, err := runner.Run(ctx, repo, "git", args...)
+	if err != nil {
+		return "", fmt.Errorf("git rev-list failed: %s", strings.TrimSpace(string(stderr)))
+	}
+	commits := strings.Fields(string(out))
+	if len(commits) == 0 {
+		return "", nil
+	}
+	parent, _, err := runner.Run(ctx, repo, "git", "rev-parse", "-q", "--verify", commits[0]+"^")
+	if err != nil {
+		return engine.EmptyTree, nil
+	}
+	return strings.TrimSpace(string(parent)), nil
+}
+
+func isZeroSHA(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// parseHookInstallArgs は install / uninstall の引数を、フックの種類・リポジトリ・-- の後の検査オプションに分けます。
+func parseHookInstallArgs(args []string, withForce bool) (kind, repo string, force bool, checkArgs []string, err error) {
+	kind = "pre-commit"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		kind = args[0]
+		args = args[1:]
+	}
+	valid := false
+	for _, k := range hookKinds {
+		valid = valid || k == kind
+	}
+	if !valid {
+		return "", "", false, nil, &usageError{err: fmt.Errorf("unknown hook %q (want %s)", kind, strings.Join(hookKinds, " or "))}
+	}
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	repoFlag := fs.String("repo", ".", "repository")
+	var forceFlag *bool
+	if withForce {
+		forceFlag = fs.Bool("force", false, "replace an existing hook")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", "", false, nil, err
+		}
+		return "", "", false, nil, &usageError{err: err}
+	}
+	checkArgs = fs.Args()
+	if !withForce && len(checkArgs) > 0 {
+		return "", "", false, nil, &usageError{err: fmt.Errorf("unexpected arguments: %s", strings.Join(checkArgs, " "))}
+	}
+	if forceFlag != nil {
+		force = *forceFlag
+	}
+	return kind, mustAbs(*repoFlag), force, checkArgs, nil
+}
+
+// hookPath は git rev-parse --git-path で core.hooksPath を反映したフックのパスを返します。
+func hookPath(repo, kind string) (string, error) {
+	out, stderr, err := execx.DefaultRunner().Run(context.Background(), repo, "git", "rev-parse", "--git-path", "hooks/"+kind)
+	if err != nil {
+		return "", fmt.Errorf("not a git repository (%s): %s", repo, strings.TrimSpace(string(stderr)))
+	}
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repo, path)
+	}
+	return path, nil
+}
+
+// isTodoxHook は path が todox hook install で書いたスクリプトかを返します。
+func isTodoxHook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), "\n"+hookMarker)
+}
+
+func installHook(args []string) (string, error) {
+	kind, repo, force, checkArgs, err := parseHookInstallArgs(args, true)
+	if err != nil {
+		return "", err
+	}
+	// 書き込む前に検査オプションを確かめ、コミットのたびに失敗するフックを作らない
+	if _, err := parseHookCheckArgs(checkArgs); err != nil {
+		return "", err
+	}
+	path, err := hookPath(repo, kind)
+	if err != nil {
+		return "", err
+	}
+	if _, statErr := os.Stat(path); statErr == nil && !force && !isTodoxHook(path) {
+		return "", fmt.Errorf("%s already exists; use --force to replace it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(hookScript(todoxCommand(), kind, checkArgs)), 0o755); err != nil {
+		return "", err
+	}
+	// 既存のファイルを上書きしたときは WriteFile がパーミッションを変えないため、実行権を付け直す
+	return path, os.Chmod(path, 0o755)
+}
+
+func uninstallHook(args []string) (string, error) {
+	kind, repo, _, _, err := parseHookInstallArgs(args, false)
+	if err != nil {
+		return "", err
+	}
+	path, err := hookPath(repo, kind)
+	if err != nil {
+		return "", err
+	}
+	if _, statErr := os.Stat(path); statErr != nil {
+		return "", fmt.Errorf("%s does not exist", path)
+	}
+	if !isTodoxHook(path) {
+		return "", fmt.Errorf("%s was not installed by todox; remove it by hand", path)
+	}
+	return path, os.Remove(path)
+}
+
+// todoxCommand はフックから呼ぶ todox です。PATH にあれば名前だけを、なければ実行中のバイナリの絶対パスを使います。
+func todoxCommand() string {
+	if _, err := exec.LookPath("todox"); err == nil {
+		return "todox"
+	}
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
+	return "todox"
+}
+
+// hookScript は kind のフックとして checkArgs 付きで todox hook を呼ぶシェルスクリプトです。
+// pre-push では git が渡すリモート名と URL を "$@" でそのまま渡します。
+func hookScript(todox, kind string, checkArgs []string) string {
+	words := []string{"exec", shellQuote(todox), "hook", kind}
+	for _, arg := range checkArgs {
+		words = append(words, shellQuote(arg))
+	}
+	words = append(words, `"$@"`)
+	return "#!/bin/sh\n" +
+		hookMarker + " installed by `todox hook install`; run `todox hook uninstall " + kind + "` to remove it.\n" +
+		strings.Join(words, " ") + "\n"
+}
+
+func shellQuote(s string) string {
+	if simpleShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
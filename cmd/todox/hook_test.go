@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+func TestParseHookPolicy(t *testing.T) {
+	p, err := parseHookPolicy([]string{"owner", "FIXME,bug=owner,issue", "TODO=none"}, "")
+	if err != nil {
+		t.Fatalf("parseHookPolicy failed: %v", err)
+	}
+	if got := p.rulesFor("NOTE"); got != requireOwner {
+		t.Fatalf("untagged rules should apply to other tags, got %b", got)
+	}
+	if got := p.rulesFor("fixme"); got != requireOwner|requireIssue {
+		t.Fatalf("FIXME rules mismatch: %b", got)
+	}
+	if got := p.rulesFor("BUG"); got != requireOwner|requireIssue {
+		t.Fatalf("BUG rules mismatch: %b", got)
+	}
+	if got := p.rulesFor("TODO"); got != 0 {
+		t.Fatalf("TODO=none should exempt TODO, got %b", got)
+	}
+	for _, bad := range []string{"owners", "=owner", "FIXME=sometimes"} {
+		if _, err := parseHookPolicy([]string{bad}, ""); err == nil {
+			t.Errorf("parseHookPolicy(%q) should fail", bad)
+		}
+	}
+	if _, err := parseHookPolicy(nil, "("); err == nil {
+		t.Error("an invalid --issue-pattern should fail")
+	}
+}
+
+func TestHookPolicyCheck(t *testing.T) {
+	p, err := parseHookPolicy([]string{"owner,issue"}, "")
+	if err != nil {
+		t.Fatalf("parseHookPolicy failed: %v", err)
+	}
+	cases := []struct {
+		text, body string
+		missing    hookRule
+	}{
+		{"FIXME(alice): nil check #12", "", 0},
+		{"FIXME: ask @org/backend, see PROJ-7", "", 0},
+		{"TODO: later", "owned by @bob\nhttps://github.com/o/r/issues/3", 0},
+		{"FIXME(#12): nil check", "", requireOwner},
+		{"FIXME(JIRA-9): nil check", "", requireOwner},
+		{"TODO: mail me at bob@example.com", "", requireOwner | requireIssue},
+		{"TODO(bob): use UTF-16LE &#35;", "", requireIssue},
+	}
+	for _, tc := range cases {
+		tag := strings.SplitN(tc.text, ":", 2)[0]
+		tag = strings.SplitN(tag, "(", 2)[0]
+		got := p.check([]engine.Item{{Tag: tag, Text: tc.text, Body: tc.body}})
+		var missing hookRule
+		if len(got) == 1 {
+			missing = got[0].missing
+		}
+		if missing != tc.missing {
+			t.Errorf("check(%q) missing=%b, want %b", tc.text, missing, tc.missing)
+		}
+	}
+}
+
+func TestRunHookは新しい項目だけを方針で検査する(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.name", "alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, "a.go"), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	write("package a\n// FIXME: old and ownerless\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--no-verify", "-m", "init")
+
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		var stderr bytes.Buffer
+		err := runHook(args, strings.NewReader(stdin), &stderr)
+		return stderr.String(), err
+	}
+
+	// 既存の項目は問わず、ステージした新しい項目だけを検査する
+	write("package a\n// FIXME: old and ownerless\n// FIXME: new and ownerless\n// TODO(bob): fine\n")
+	runGit(t, repo, "add", ".")
+	out, err := run("", "pre-commit", "--repo", repo)
+	var verr *hookViolationError
+	if !errors.As(err, &verr) || verr.count != 1 {
+		t.Fatalf("expected one violation, got %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "a.go:3: FIXME: new and ownerless (needs an owner)") || strings.Contains(out, "old") {
+		t.Fatalf("unexpected report:\n%s", out)
+	}
+	if _, err := run("", "pre-commit", "--repo", repo, "--require", "FIXME=none"); err != nil {
+		t.Fatalf("FIXME=none should pass: %v", err)
+	}
+
+	// pre-push は remote にまだ無いコミットを検査する
+	runGit(t, repo, "commit", "--no-verify", "-m", "second")
+	head := strings.TrimSpace(gitRevParse(t, repo, "HEAD"))
+	stdin := "refs/heads/main " + head + " refs/heads/main " + strings.Repeat("0", 40) + "\n"
+	out, err = run(stdin, "pre-push", "--repo", repo, "--require", "owner", "--require", "TODO=issue", "origin", "https://example.com/r.git")
+	if !errors.As(err, &verr) || verr.count != 3 {
+		t.Fatalf("a new branch should be checked from its first commit, got %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "a.go:4: TODO(bob): fine (needs an issue reference)") || !strings.Contains(out, "git push --no-verify") {
+		t.Fatalf("unexpected pre-push report:\n%s", out)
+	}
+
+	// install はスクリプトを書き、自分で書いていないフックは --force なしに置き換えない
+	if _, err := run("", "install", "--repo", repo, "--", "--require", "FIXME=owner,issue"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	hook := filepath.Join(repo, ".git", "hooks", "pre-commit")
+	data, err := os.ReadFile(hook)
+	if err != nil {
+		t.Fatalf("hook was not written: %v", err)
+	}
+	if !strings.Contains(string(data), "hook pre-commit --require FIXME=owner,issue \"$@\"") {
+		t.Fatalf("unexpected hook script:\n%s", data)
+	}
+	if info, err := os.Stat(hook); err != nil || info.Mode()&0o111 == 0 {
+		t.Fatalf("hook should be executable: %v %v", info, err)
+	}
+	if _, err := run("", "install", "--repo", repo, "--", "--require", "sometimes"); err == nil {
+		t.Fatal("install should reject invalid check options")
+	}
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nmake lint\n"), 0o755); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := run("", "install", "--repo", repo); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("install should refuse to replace a foreign hook, got %v", err)
+	}
+	if _, err := run("", "uninstall", "--repo", repo); err == nil {
+		t.Fatal("uninstall should keep a foreign hook")
+	}
+	if _, err := run("", "install", "--repo", repo, "--force"); err != nil {
+		t.Fatalf("install --force failed: %v", err)
+	}
+	if _, err := run("", "uninstall", "pre-commit", "--repo", repo); err != nil {
+		t.Fatalf("uninstall failed: %v", err)
+	}
+	if _, err := os.Stat(hook); !os.IsNotExist(err) {
+		t.Fatalf("hook should be removed: %v", err)
+	}
+}
+
+func TestHookScriptQuotesArguments(t *testing.T) {
+	got := hookScript("/opt/my tools/todox", "pre-push", []string{"--issue-pattern", `[A-Z]+-\d+`, "--require", "owner"})
+	want := `exec '/opt/my tools/todox' hook pre-push --issue-pattern '[A-Z]+-\d+' --require owner "$@"`
+	if !strings.HasPrefix(got, "#!/bin/sh\n"+hookMarker) || !strings.HasSuffix(got, want+"\n") {
+		t.Fatalf("unexpected script:\n%s", got)
+	}
+}
@@ -0,0 +1,810 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/execx"
+	ghclient "github.com/phyten/todox/internal/host/github"
+	"github.com/phyten/todox/internal/link"
+	"github.com/phyten/todox/internal/lsp"
+)
+
+// lspOpenURLCommand はコードアクションから呼ばれ、クライアントに URL を開かせるコマンドです。
+const lspOpenURLCommand = "todox.openURL"
+
+type lspConfig struct {
+	severity map[string]lsp.DiagnosticSeverity
+	withPRs  bool
+}
+
+func lspCmd(args []string) {
+	cfg, err := parseLSPArgs(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printLSPHelp()
+			return
+		}
+		fmt.Fprintf(os.Stderr, "todox lsp: %v\n", err)
+		printLSPHelp()
+		os.Exit(2)
+	}
+	srv := newLSPServer(os.Stdin, os.Stdout, cfg)
+	if err := srv.serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "todox lsp: %v\n", err)
+		os.Exit(1)
+	}
+	if !srv.shutdownRequested() {
+		os.Exit(1)
+	}
+}
+
+func printLSPHelp() {
+	fmt.Print("Usage: todox lsp [options]\n\n" +
+		"Run a Language Server Protocol server over stdio.\n" +
+		"Open buffers are scanned as you type; the workspace is scanned and blamed\n" +
+		"after initialization and again for each saved file. Detection settings come\n" +
+		"from the workspace's .todox.yaml and TODOX_* environment variables.\n\n" +
+		"Options:\n" +
+		"      --severity TAG=LEVEL   Diagnostic severity per tag: error, warning, info, hint\n" +
+		"                             (repeatable / CSV; default: FIXME=warning, others info)\n" +
+		"      --with-pr-links        Look up pull requests for hovers and code actions\n" +
+		"      --stdio                Accepted for editor compatibility (stdio is always used)\n")
+}
+
+func parseLSPArgs(args []string) (lspConfig, error) {
+	cfg := lspConfig{severity: map[string]lsp.DiagnosticSeverity{"FIXME": lsp.SeverityWarning}}
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var severity multiFlag
+	fs.Var(&severity, "severity", "TAG=LEVEL")
+	withPRs := fs.Bool("with-pr-links", false, "look up pull requests")
+	fs.Bool("stdio", true, "use stdio")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	for _, entry := range severity.Slice() {
+		tag, level, ok := strings.Cut(entry, "=")
+		sev, valid := lsp.ParseSeverity(level)
+		if !ok || strings.TrimSpace(tag) == "" || !valid {
+			return cfg, fmt.Errorf("invalid --severity: %s (want TAG=error|warning|info|hint)", entry)
+		}
+		cfg.severity[strings.ToUpper(strings.TrimSpace(tag))] = sev
+	}
+	cfg.withPRs = *withPRs
+	return cfg, nil
+}
+
+// lspDocument はエディタで開かれているファイルです。
+type lspDocument struct {
+	uri     string
+	file    string // リポジトリ相対（リポジトリ外なら絶対パス）
+	version int
+	text    string
+	items   []engine.Item
+}
+
+// lspServer は開いているバッファを engine.ScanContent で、ワークスペース全体を engine で走査します。
+// 保存済みの内容を blame した結果を、同じ本文のバッファ上の項目に引き継ぎます。
+type lspServer struct {
+	conn    *lsp.Conn
+	cfg     lspConfig
+	runner  execx.Runner
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	remotes remoteInfoCache
+
+	mu        sync.Mutex
+	root      string
+	opts      engine.Options
+	docs      map[string]*lspDocument
+	blamed    map[string][]engine.Item // 保存済みの内容を blame した項目（URI ごと）
+	scanned   bool
+	published map[string]bool
+	prs       map[string][]engine.PullRequestRef // コミットごと
+	shutdown  bool
+}
+
+func newLSPServer(r io.Reader, w io.Writer, cfg lspConfig) *lspServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lspServer{
+		conn:      lsp.NewConn(r, w),
+		cfg:       cfg,
+		runner:    execx.DefaultRunner(),
+		ctx:       ctx,
+		cancel:    cancel,
+		docs:      make(map[string]*lspDocument),
+		blamed:    make(map[string][]engine.Item),
+		published: make(map[string]bool),
+		prs:       make(map[string][]engine.PullRequestRef),
+	}
+}
+
+func (s *lspServer) shutdownRequested() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdown
+}
+
+// serve は exit 通知を受け取るか入力が閉じられるまでメッセージを処理します。
+func (s *lspServer) serve() error {
+	defer func() {
+		s.cancel()
+		s.wg.Wait()
+	}()
+	for {
+		msg, err := s.conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rerr *lsp.ResponseError
+			if errors.As(err, &rerr) {
+				_ = s.conn.Reply(nil, nil, rerr)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if msg.Method == "" {
+			continue // window/showDocument などへの応答
+		}
+		if lspAsyncMethods[msg.Method] && msg.IsRequest() {
+			// リモートや PR の問い合わせを待つ間も、後続のメッセージを読めるようにする
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				result, rerr := s.handle(msg)
+				_ = s.conn.Reply(msg.ID, result, rerr)
+			}()
+			continue
+		}
+		result, rerr := s.handle(msg)
+		if msg.IsRequest() {
+			if err := s.conn.Reply(msg.ID, result, rerr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// lspAsyncMethods は git や gh を呼ぶことがあり、読み取りのループの外で応答するリクエストです。
+// LSP では応答の順序は問われません。
+var lspAsyncMethods = map[string]bool{
+	"textDocument/hover":      true,
+	"textDocument/codeAction": true,
+}
+
+func (s *lspServer) handle(msg *lsp.Message) (any, *lsp.ResponseError) {
+	switch msg.Method {
+	case "initialize":
+		var params lsp.InitializeParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		return s.initialize(params)
+	case "initialized":
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.scanWorkspace()
+		}()
+		return nil, nil
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		s.cancel()
+		return nil, nil
+	case "textDocument/didOpen":
+		var params lsp.DidOpenTextDocumentParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		s.updateDocument(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params lsp.DidChangeTextDocumentParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.updateDocument(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		var params lsp.DidSaveTextDocumentParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.rescanSaved(params.TextDocument.URI)
+		}()
+		return nil, nil
+	case "textDocument/didClose":
+		var params lsp.DidCloseTextDocumentParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		s.closeDocument(params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/hover":
+		var params lsp.TextDocumentPositionParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		return s.hover(params), nil
+	case "workspace/symbol":
+		var params lsp.WorkspaceSymbolParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		return s.symbols(params.Query), nil
+	case "textDocument/codeAction":
+		var params lsp.CodeActionParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		return s.codeActions(params), nil
+	case "workspace/executeCommand":
+		var params lsp.ExecuteCommandParams
+		if rerr := decodeParams(msg, &params); rerr != nil {
+			return nil, rerr
+		}
+		return nil, s.executeCommand(params)
+	}
+	if msg.IsRequest() {
+		return nil, &lsp.ResponseError{Code: lsp.CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	return nil, nil
+}
+
+func decodeParams(msg *lsp.Message, dst any) *lsp.ResponseError {
+	if len(msg.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Params, dst); err != nil {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *lspServer) initialize(params lsp.InitializeParams) (any, *lsp.ResponseError) {
+	root := params.RootPath
+	if p, ok := lsp.URIToPath(params.RootURI); ok {
+		root = p
+	}
+	if len(params.WorkspaceFolders) > 0 {
+		if p, ok := lsp.URIToPath(params.WorkspaceFolders[0].URI); ok {
+			root = p
+		}
+	}
+	if root == "" {
+		root = "."
+	}
+	root = mustAbs(root)
+	opts, err := layeredEngineOptions(root)
+	if err != nil {
+		return nil, &lsp.ResponseError{Code: lsp.CodeInternalError, Message: err.Error()}
+	}
+	opts.RepoDir = root
+	opts.WithComment = true
+	opts.WithMessage = true
+	opts.Progress = false
+	s.mu.Lock()
+	s.root = root
+	s.opts = opts
+	s.mu.Unlock()
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    lsp.TextDocumentSyncFull,
+				"save":      map[string]any{"includeText": false},
+			},
+			"hoverProvider":           true,
+			"workspaceSymbolProvider": true,
+			"codeActionProvider":      true,
+			"executeCommandProvider":  map[string]any{"commands": []string{lspOpenURLCommand}},
+		},
+		"serverInfo": map[string]any{"name": "todox"},
+	}, nil
+}
+
+// scanWorkspace はワークスペース全体を blame し、開いていないファイルの診断も送ります。
+func (s *lspServer) scanWorkspace() {
+	s.mu.Lock()
+	opts := s.opts
+	s.mu.Unlock()
+	res, err := engine.RunContext(s.ctx, opts)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			_ = s.conn.Notify("window/logMessage", map[string]any{"type": 1, "message": "todox: " + err.Error()})
+		}
+		return
+	}
+	s.mu.Lock()
+	byURI := make(map[string][]engine.Item)
+	for _, it := range res.Items {
+		uri := s.uriFor(it.File)
+		byURI[uri] = append(byURI[uri], it)
+	}
+	s.blamed = byURI
+	s.scanned = true
+	s.mu.Unlock()
+	s.publishAll()
+}
+
+// rescanSaved は保存されたファイルだけを blame し直します。
+func (s *lspServer) rescanSaved(uri string) {
+	s.mu.Lock()
+	opts := s.opts
+	doc := s.docs[uri]
+	s.mu.Unlock()
+	file, ok := s.relPath(uri)
+	if doc != nil {
+		file = doc.file
+	}
+	if !ok || filepath.IsAbs(file) {
+		return
+	}
+	opts.Paths = []string{":(literal)" + file}
+	res, err := engine.RunContext(s.ctx, opts)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if len(res.Items) > 0 {
+		s.blamed[s.uriFor(file)] = res.Items
+	} else {
+		delete(s.blamed, s.uriFor(file))
+	}
+	if doc := s.docs[uri]; doc != nil {
+		s.attribute(doc)
+	}
+	s.mu.Unlock()
+	s.publish(uri)
+}
+
+func (s *lspServer) updateDocument(uri string, version int, text string) {
+	file, ok := s.relPath(uri)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	opts := s.opts
+	s.mu.Unlock()
+	scanOpts := opts
+	if filepath.IsAbs(file) {
+		scanOpts.RepoDir = ""
+	}
+	items, err := engine.ScanContent(file, []byte(text), scanOpts)
+	if err != nil {
+		items = nil
+	}
+	s.mu.Lock()
+	doc := &lspDocument{uri: uri, file: file, version: version, text: text, items: items}
+	s.attribute(doc)
+	s.docs[uri] = doc
+	s.mu.Unlock()
+	s.publish(uri)
+}
+
+func (s *lspServer) closeDocument(uri string) {
+	s.mu.Lock()
+	delete(s.docs, uri)
+	s.mu.Unlock()
+	s.publish(uri)
+}
+
+// attribute はバッファ上の項目に、保存済みの内容を blame した結果を本文の一致で引き継ぎます。
+// 同じ本文が複数あれば行の近いものを使います。s.mu を保持して呼びます。
+func (s *lspServer) attribute(doc *lspDocument) {
+	saved := s.blamed[s.uriFor(doc.file)]
+	used := make([]bool, len(saved))
+	for i := range doc.items {
+		it := &doc.items[i]
+		best := -1
+		for j, b := range saved {
+			if used[j] || b.Tag != it.Tag || strings.TrimSpace(b.Text) != strings.TrimSpace(it.Text) {
+				continue
+			}
+			if best < 0 || absInt(b.Line-it.Line) < absInt(saved[best].Line-it.Line) {
+				best = j
+			}
+		}
+		if best < 0 {
+			if s.scanned {
+				it.Author = "(working tree)"
+			}
+			continue
+		}
+		used[best] = true
+		b := saved[best]
+		it.Author, it.Email, it.Date, it.AgeDays = b.Author, b.Email, b.Date, b.AgeDays
+		it.Committer, it.CommitterEmail = b.Committer, b.CommitterEmail
+		it.Commit, it.Message, it.Skipped, it.Submodule = b.Commit, b.Message, b.Skipped, b.Submodule
+	}
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// relPath は URI をリポジトリ相対のパスに変換します。リポジトリ外のファイルは絶対パスのまま返します。
+func (s *lspServer) relPath(uri string) (string, bool) {
+	p, ok := lsp.URIToPath(uri)
+	if !ok {
+		return "", false
+	}
+	s.mu.Lock()
+	root := s.root
+	s.mu.Unlock()
+	if root != "" {
+		if rel, err := filepath.Rel(root, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return p, true
+}
+
+func (s *lspServer) uriFor(file string) string {
+	if filepath.IsAbs(file) {
+		return lsp.PathToURI(file)
+	}
+	return lsp.PathToURI(filepath.Join(s.root, filepath.FromSlash(file)))
+}
+
+// itemsFor は uri の現在の項目と各行の内容を返します。開いていなければ保存済みの内容を使います。
+func (s *lspServer) itemsFor(uri string) ([]engine.Item, []string) {
+	s.mu.Lock()
+	if doc := s.docs[uri]; doc != nil {
+		// attribute が doc.items を書き換えるため、写しを返す
+		items := append([]engine.Item(nil), doc.items...)
+		s.mu.Unlock()
+		return items, strings.Split(doc.text, "\n")
+	}
+	items, root := s.blamed[uri], s.root
+	s.mu.Unlock()
+	if len(items) == 0 {
+		return nil, nil
+	}
+	data, _ := os.ReadFile(filepath.Join(root, filepath.FromSlash(items[0].File)))
+	return items, strings.Split(string(data), "\n")
+}
+
+func (s *lspServer) publishAll() {
+	s.mu.Lock()
+	uris := make(map[string]bool)
+	for uri := range s.blamed {
+		uris[uri] = true
+	}
+	for uri, doc := range s.docs {
+		s.attribute(doc)
+		uris[uri] = true
+	}
+	for uri := range s.published {
+		uris[uri] = true
+	}
+	s.mu.Unlock()
+	sorted := make([]string, 0, len(uris))
+	for uri := range uris {
+		sorted = append(sorted, uri)
+	}
+	sort.Strings(sorted)
+	for _, uri := range sorted {
+		s.publish(uri)
+	}
+}
+
+func (s *lspServer) publish(uri string) {
+	items, lines := s.itemsFor(uri)
+	diags := make([]lsp.Diagnostic, 0, len(items))
+	for _, it := range items {
+		diags = append(diags, lsp.Diagnostic{
+			Range:    itemRange(it, lines),
+			Severity: s.severityFor(it.Tag),
+			Code:     it.Tag,
+			Source:   "todox",
+			Message:  diagnosticMessage(it),
+		})
+	}
+	s.mu.Lock()
+	var version *int
+	if doc := s.docs[uri]; doc != nil {
+		v := doc.version
+		version = &v
+	}
+	if len(diags) == 0 && !s.published[uri] {
+		s.mu.Unlock()
+		return
+	}
+	s.published[uri] = len(diags) > 0
+	s.mu.Unlock()
+	_ = s.conn.Notify("textDocument/publishDiagnostics", lsp.PublishDiagnosticsParams{URI: uri, Version: version, Diagnostics: diags})
+}
+
+func (s *lspServer) severityFor(tag string) lsp.DiagnosticSeverity {
+	if sev, ok := s.cfg.severity[strings.ToUpper(tag)]; ok {
+		return sev
+	}
+	return lsp.SeverityInformation
+}
+
+// itemRange はタグの位置を LSP の範囲（0 始まり、UTF-16 単位）で返します。
+func itemRange(it engine.Item, lines []string) lsp.Range {
+	line := it.Line - 1
+	if line < 0 {
+		line = 0
+	}
+	text := ""
+	if line < len(lines) {
+		text = strings.TrimSuffix(lines[line], "\r")
+	}
+	start := it.Span.StartCol - 1
+	if it.Span.StartLine != it.Line || start < 0 {
+		start = 0
+	}
+	end := start + len(it.Tag)
+	return lsp.Range{
+		Start: lsp.Position{Line: line, Character: lsp.UTF16Column(text, start)},
+		End:   lsp.Position{Line: line, Character: lsp.UTF16Column(text, end)},
+	}
+}
+
+func diagnosticMessage(it engine.Item) string {
+	msg := strings.TrimSpace(it.Comment)
+	if msg == "" {
+		msg = strings.TrimSpace(it.Text)
+	}
+	if who := attributionText(it); who != "" {
+		msg += " — " + who
+	}
+	return msg
+}
+
+func attributionText(it engine.Item) string {
+	switch {
+	case it.Commit != "":
+		return fmt.Sprintf("%s, %s (%s)", it.Author, ageText(it.AgeDays), shortSHA(it.Commit))
+	case it.Author == "(working tree)":
+		return "not committed yet"
+	}
+	return ""
+}
+
+func ageText(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day ago"
+	}
+	return fmt.Sprintf("%d days ago", days)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// itemAt は pos の行にある項目を返します。同じ行に複数あればカーソルより前で最も近いものです。
+func (s *lspServer) itemAt(uri string, pos lsp.Position) (engine.Item, []string, bool) {
+	items, lines := s.itemsFor(uri)
+	var found *engine.Item
+	for i := range items {
+		it := &items[i]
+		if it.Line-1 != pos.Line {
+			continue
+		}
+		if found == nil {
+			found = it
+			continue
+		}
+		col := 0
+		if pos.Line < len(lines) {
+			col = lsp.ByteColumn(lines[pos.Line], pos.Character)
+		}
+		if it.Span.StartCol-1 <= col {
+			found = it
+		}
+	}
+	if found == nil {
+		return engine.Item{}, nil, false
+	}
+	return *found, lines, true
+}
+
+func (s *lspServer) hover(params lsp.TextDocumentPositionParams) *lsp.Hover {
+	it, lines, ok := s.itemAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", it.Tag)
+	switch {
+	case it.Commit != "":
+		fmt.Fprintf(&b, " · %s <%s> · %s (%s)", it.Author, it.Email, ageText(it.AgeDays), it.Date)
+	case it.Author == "(working tree)":
+		b.WriteString(" · not committed yet")
+	}
+	if comment := strings.TrimSpace(it.Comment); comment != "" {
+		fmt.Fprintf(&b, "\n\n%s", comment)
+	}
+	if it.Commit != "" {
+		commit := "`" + shortSHA(it.Commit) + "`"
+		if info, err := s.remotes.Get(s.ctx, s.runner, s.root); err == nil {
+			if u := link.Commit(info, it.Commit); u != "" {
+				commit = fmt.Sprintf("[%s](%s)", shortSHA(it.Commit), u)
+			}
+		}
+		fmt.Fprintf(&b, "\n\n%s %s", commit, it.Message)
+	}
+	for _, pr := range s.pullRequests(it.Commit) {
+		fmt.Fprintf(&b, "\n\n[#%d %s](%s) (%s)", pr.Number, pr.Title, pr.URL, strings.ToLower(pr.State))
+	}
+	r := itemRange(it, lines)
+	return &lsp.Hover{Contents: lsp.MarkupContent{Kind: "markdown", Value: b.String()}, Range: &r}
+}
+
+// pullRequests は --with-pr-links のときにコミットを含む PR を調べます。結果はコミットごとに保持します。
+func (s *lspServer) pullRequests(sha string) []engine.PullRequestRef {
+	if !s.cfg.withPRs || sha == "" {
+		return nil
+	}
+	s.mu.Lock()
+	cached, ok := s.prs[sha]
+	s.mu.Unlock()
+	if ok {
+		return cached
+	}
+	info, err := s.remotes.Get(s.ctx, s.runner, s.root)
+	if err != nil {
+		return nil
+	}
+	found, err := ghclient.NewClient(info, s.root, s.runner).FindPullRequestsByCommit(s.ctx, sha)
+	if err != nil {
+		return nil
+	}
+	refs := make([]engine.PullRequestRef, 0, len(found))
+	for _, pr := range found {
+		refs = append(refs, engine.PullRequestRef{Number: pr.Number, State: pr.State, URL: pr.URL, Title: pr.Title})
+	}
+	s.mu.Lock()
+	s.prs[sha] = refs
+	s.mu.Unlock()
+	return refs
+}
+
+// symbols はワークスペースの全項目（開いているファイルは編集中の内容）を query で絞り込んで返します。
+func (s *lspServer) symbols(query string) []lsp.SymbolInformation {
+	s.mu.Lock()
+	byURI := make(map[string][]engine.Item)
+	for uri, items := range s.blamed {
+		byURI[uri] = items
+	}
+	for uri, doc := range s.docs {
+		byURI[uri] = doc.items
+	}
+	s.mu.Unlock()
+	uris := make([]string, 0, len(byURI))
+	for uri := range byURI {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	q := strings.ToLower(strings.TrimSpace(query))
+	out := []lsp.SymbolInformation{}
+	for _, uri := range uris {
+		for _, it := range byURI[uri] {
+			name := strings.TrimSpace(it.Comment)
+			if name == "" {
+				name = strings.TrimSpace(it.Text)
+			}
+			if q != "" && !strings.Contains(strings.ToLower(name), q) && !strings.Contains(strings.ToLower(it.File), q) {
+				continue
+			}
+			line := it.Line - 1
+			if line < 0 {
+				line = 0
+			}
+			out = append(out, lsp.SymbolInformation{
+				Name:          name,
+				Kind:          lsp.SymbolKindEvent,
+				Location:      lsp.Location{URI: uri, Range: lsp.Range{Start: lsp.Position{Line: line}, End: lsp.Position{Line: line}}},
+				ContainerName: it.File,
+			})
+		}
+	}
+	return out
+}
+
+// codeActions は範囲内の項目ごとに、Issue の作成とコミット・PR を開く操作を返します。
+func (s *lspServer) codeActions(params lsp.CodeActionParams) []lsp.CodeAction {
+	items, _ := s.itemsFor(params.TextDocument.URI)
+	info, remoteErr := s.remotes.Get(s.ctx, s.runner, s.root)
+	out := []lsp.CodeAction{}
+	for _, it := range items {
+		line := it.Line - 1
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+		if remoteErr != nil {
+			continue
+		}
+		if u := newIssueURL(info.WebURL(), it, link.Blob(info, it.Commit, it.File, it.Line)); u != "" {
+			out = append(out, openURLAction(fmt.Sprintf("Create issue for this %s", it.Tag), u))
+		}
+		if u := link.Commit(info, it.Commit); it.Commit != "" && u != "" {
+			out = append(out, openURLAction(fmt.Sprintf("Open commit %s", shortSHA(it.Commit)), u))
+		}
+		for _, pr := range s.pullRequests(it.Commit) {
+			out = append(out, openURLAction(fmt.Sprintf("Open PR #%d: %s", pr.Number, pr.Title), pr.URL))
+		}
+	}
+	return out
+}
+
+func openURLAction(title, u string) lsp.CodeAction {
+	return lsp.CodeAction{Title: title, Command: &lsp.Command{Title: title, Command: lspOpenURLCommand, Arguments: []any{u}}}
+}
+
+// newIssueURL は項目の内容を書き込んだ新規 Issue 作成画面の URL を返します。
+func newIssueURL(webURL string, it engine.Item, permalink string) string {
+	if webURL == "" {
+		return ""
+	}
+	title := strings.TrimSpace(it.Comment)
+	if title == "" {
+		title = strings.TrimSpace(it.Text)
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "`%s:%d`\n\n> %s\n", it.File, it.Line, strings.TrimSpace(it.Text))
+	if permalink != "" {
+		fmt.Fprintf(&body, "\n%s\n", permalink)
+	}
+	if it.Commit != "" {
+		fmt.Fprintf(&body, "\nAdded by %s in %s (%s).\n", it.Author, shortSHA(it.Commit), it.Date)
+	}
+	q := url.Values{}
+	q.Set("title", title)
+	q.Set("body", body.String())
+	return webURL + "/issues/new?" + q.Encode()
+}
+
+func (s *lspServer) executeCommand(params lsp.ExecuteCommandParams) *lsp.ResponseError {
+	if params.Command != lspOpenURLCommand || len(params.Arguments) != 1 {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: "unknown command: " + params.Command}
+	}
+	target, ok := params.Arguments[0].(string)
+	if !ok || target == "" {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: "todox.openURL expects a URL"}
+	}
+	if err := s.conn.Request("window/showDocument", lsp.ShowDocumentParams{URI: target, External: true}); err != nil {
+		return &lsp.ResponseError{Code: lsp.CodeInternalError, Message: err.Error()}
+	}
+	return nil
+}
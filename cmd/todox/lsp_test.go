@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/execx"
+	"github.com/phyten/todox/internal/lsp"
+)
+
+// lspTestClient はサーバーと同じプロセス内でパイプ越しに JSON-RPC を話すクライアントです。
+type lspTestClient struct {
+	t       *testing.T
+	conn    *lsp.Conn
+	msgs    chan *lsp.Message
+	backlog []*lsp.Message
+	nextID  int
+	done    chan error
+	stdin   io.Closer
+}
+
+func startLSPTestServer(t *testing.T, cfg lspConfig, setup ...func(*lspServer)) *lspTestClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	srv := newLSPServer(inR, outW, cfg)
+	for _, f := range setup {
+		f(srv)
+	}
+	c := &lspTestClient{t: t, conn: lsp.NewConn(outR, inW), msgs: make(chan *lsp.Message, 64), done: make(chan error, 1), stdin: inW}
+	go func() {
+		err := srv.serve()
+		_ = outW.Close()
+		c.done <- err
+	}()
+	go func() {
+		defer close(c.msgs)
+		for {
+			msg, err := c.conn.Read()
+			if err != nil {
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	t.Cleanup(func() { _ = inW.Close() })
+	return c
+}
+
+func (c *lspTestClient) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.Notify(method, params); err != nil {
+		c.t.Fatalf("notify %s failed: %v", method, err)
+	}
+}
+
+func (c *lspTestClient) call(method string, params any, result any) {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	data, _ := json.Marshal(params)
+	if err := c.conn.Write(&lsp.Message{ID: id, Method: method, Params: data}); err != nil {
+		c.t.Fatalf("%s failed: %v", method, err)
+	}
+	resp := c.waitFor(func(m *lsp.Message) bool { return m.Method == "" && string(m.ID) == string(id) })
+	if resp.Error != nil {
+		c.t.Fatalf("%s returned error: %v", method, resp.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			c.t.Fatalf("decode %s result: %v", method, err)
+		}
+	}
+}
+
+// waitFor は条件に合うメッセージを待ちます。合わなかったメッセージは後の呼び出しのために残します。
+func (c *lspTestClient) waitFor(match func(*lsp.Message) bool) *lsp.Message {
+	c.t.Helper()
+	for i, m := range c.backlog {
+		if match(m) {
+			c.backlog = append(c.backlog[:i], c.backlog[i+1:]...)
+			return m
+		}
+	}
+	timeout := time.After(20 * time.Second)
+	for {
+		select {
+		case m, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatal("server closed the connection")
+			}
+			if match(m) {
+				return m
+			}
+			c.backlog = append(c.backlog, m)
+		case <-timeout:
+			c.t.Fatal("timed out waiting for a message from the server")
+		}
+	}
+}
+
+// waitDiagnostics は uri の診断のうち、check を満たすものが届くまで待ちます。
+func (c *lspTestClient) waitDiagnostics(uri string, check func([]lsp.Diagnostic) bool) []lsp.Diagnostic {
+	c.t.Helper()
+	var got []lsp.Diagnostic
+	c.waitFor(func(m *lsp.Message) bool {
+		if m.Method != "textDocument/publishDiagnostics" {
+			return false
+		}
+		var params lsp.PublishDiagnosticsParams
+		if err := json.Unmarshal(m.Params, &params); err != nil || params.URI != uri || !check(params.Diagnostics) {
+			return false
+		}
+		got = params.Diagnostics
+		return true
+	})
+	return got
+}
+
+func TestLSPServerはバッファとワークスペースの項目を診断として返す(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.name", "alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	saved := "package main\n\n// TODO: handle errors\nfunc main() {}\n"
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte(saved), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "util.go"), []byte("package main\n// FIXME: ünïcode first\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "add main")
+
+	c := startLSPTestServer(t, lspConfig{severity: map[string]lsp.DiagnosticSeverity{"FIXME": lsp.SeverityWarning, "TODO": lsp.SeverityHint}})
+	var initRes struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	c.call("initialize", lsp.InitializeParams{RootURI: lsp.PathToURI(repo)}, &initRes)
+	if initRes.Capabilities["hoverProvider"] != true || initRes.Capabilities["workspaceSymbolProvider"] != true {
+		t.Fatalf("unexpected capabilities: %+v", initRes.Capabilities)
+	}
+	c.notify("initialized", map[string]any{})
+
+	utilURI := lsp.PathToURI(filepath.Join(repo, "util.go"))
+	diags := c.waitDiagnostics(utilURI, func(d []lsp.Diagnostic) bool { return len(d) == 1 })
+	if d := diags[0]; d.Severity != lsp.SeverityWarning || d.Code != "FIXME" || !strings.Contains(d.Message, "FIXME: ünïcode first — alice, today (") {
+		t.Fatalf("unexpected workspace diagnostic: %+v", d)
+	}
+	if r := diags[0].Range; r.Start != (lsp.Position{Line: 1, Character: 3}) || r.End != (lsp.Position{Line: 1, Character: 8}) {
+		t.Fatalf("unexpected range: %+v", r)
+	}
+
+	// 未保存の編集: 既存の TODO は行がずれても blame を引き継ぎ、新しい TODO は未コミット扱い
+	mainURI := lsp.PathToURI(filepath.Join(repo, "main.go"))
+	edited := "package main\n\n// TODO: write docs\n\n// TODO: handle errors\nfunc main() {}\n"
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{TextDocument: lsp.TextDocumentItem{URI: mainURI, LanguageID: "go", Version: 1, Text: edited}})
+	diags = c.waitDiagnostics(mainURI, func(d []lsp.Diagnostic) bool { return len(d) == 2 })
+	if diags[0].Range.Start.Line != 2 || !strings.HasSuffix(diags[0].Message, "— not committed yet") || diags[0].Severity != lsp.SeverityHint {
+		t.Fatalf("unexpected diagnostic for the new TODO: %+v", diags[0])
+	}
+	if diags[1].Range.Start.Line != 4 || !strings.Contains(diags[1].Message, "alice") {
+		t.Fatalf("blame should follow the moved TODO: %+v", diags[1])
+	}
+
+	var hover lsp.Hover
+	c.call("textDocument/hover", lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 4, Character: 6}}, &hover)
+	if !strings.Contains(hover.Contents.Value, "alice <alice@example.com>") || !strings.Contains(hover.Contents.Value, "add main") || !strings.Contains(hover.Contents.Value, "https://github.com/acme/widgets/commit/") {
+		t.Fatalf("unexpected hover: %s", hover.Contents.Value)
+	}
+
+	var symbols []lsp.SymbolInformation
+	c.call("workspace/symbol", lsp.WorkspaceSymbolParams{Query: "docs"}, &symbols)
+	if len(symbols) != 1 || symbols[0].Location.URI != mainURI || symbols[0].Location.Range.Start.Line != 2 {
+		t.Fatalf("unexpected symbols: %+v", symbols)
+	}
+	c.call("workspace/symbol", lsp.WorkspaceSymbolParams{}, &symbols)
+	if len(symbols) != 3 {
+		t.Fatalf("expected every item as a symbol, got %+v", symbols)
+	}
+
+	var actions []lsp.CodeAction
+	c.call("textDocument/codeAction", lsp.CodeActionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Range: lsp.Range{Start: lsp.Position{Line: 4}, End: lsp.Position{Line: 4}}}, &actions)
+	if len(actions) != 2 || actions[0].Command == nil || !strings.HasPrefix(actions[0].Command.Arguments[0].(string), "https://github.com/acme/widgets/issues/new?") || !strings.HasPrefix(actions[1].Title, "Open commit ") {
+		t.Fatalf("unexpected code actions: %+v", actions)
+	}
+	c.call("workspace/executeCommand", lsp.ExecuteCommandParams{Command: actions[0].Command.Command, Arguments: actions[0].Command.Arguments}, nil)
+	show := c.waitFor(func(m *lsp.Message) bool { return m.Method == "window/showDocument" })
+	var showParams lsp.ShowDocumentParams
+	if err := json.Unmarshal(show.Params, &showParams); err != nil || !showParams.External || showParams.URI != actions[0].Command.Arguments[0] {
+		t.Fatalf("unexpected showDocument: %s", show.Params)
+	}
+
+	// 閉じると保存済みの内容の診断に戻る
+	c.notify("textDocument/didClose", lsp.DidCloseTextDocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}})
+	diags = c.waitDiagnostics(mainURI, func(d []lsp.Diagnostic) bool { return len(d) == 1 })
+	if diags[0].Range.Start.Line != 2 {
+		t.Fatalf("unexpected diagnostic after close: %+v", diags[0])
+	}
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Fatalf("serve returned error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not exit")
+	}
+}
+
+// blockingGHRunner は gh の呼び出しを release が閉じられるまで止め、それ以外のコマンドはそのまま実行します。
+type blockingGHRunner struct {
+	release chan struct{}
+}
+
+func (r blockingGHRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	if name != "gh" {
+		return execx.CommandRunner{}.Run(ctx, dir, name, args...)
+	}
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	return []byte(`[{"number":7,"title":"Add main","state":"open","html_url":"https://github.com/acme/widgets/pull/7"}]`), nil, nil
+}
+
+func TestLSPServerはPRの問い合わせ中も他のリクエストに応答する(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.name", "alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n\n// TODO: handle errors\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "add main")
+
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	c := startLSPTestServer(t, lspConfig{withPRs: true}, func(s *lspServer) { s.runner = blockingGHRunner{release: release} })
+	t.Cleanup(unblock) // 失敗しても止めた gh を解放し、サーバーを終了させる
+	c.call("initialize", lsp.InitializeParams{RootURI: lsp.PathToURI(repo)}, nil)
+	c.notify("initialized", map[string]any{})
+	mainURI := lsp.PathToURI(filepath.Join(repo, "main.go"))
+	c.waitDiagnostics(mainURI, func(d []lsp.Diagnostic) bool { return len(d) == 1 })
+
+	// hover は gh の応答を待つが、その間も workspace/symbol には答える
+	hoverID := json.RawMessage("100")
+	data, _ := json.Marshal(lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: mainURI}, Position: lsp.Position{Line: 2, Character: 4}})
+	if err := c.conn.Write(&lsp.Message{ID: hoverID, Method: "textDocument/hover", Params: data}); err != nil {
+		t.Fatalf("hover failed: %v", err)
+	}
+	// 読み取りが止まると書き込みも進まないため、時間切れで gh を解放して失敗として扱う
+	watchdog := time.AfterFunc(10*time.Second, unblock)
+	var symbols []lsp.SymbolInformation
+	c.call("workspace/symbol", lsp.WorkspaceSymbolParams{}, &symbols)
+	if !watchdog.Stop() {
+		t.Fatal("workspace/symbol was answered only after the pull request lookup finished")
+	}
+	if len(symbols) != 1 {
+		t.Fatalf("unexpected symbols: %+v", symbols)
+	}
+
+	unblock()
+	resp := c.waitFor(func(m *lsp.Message) bool { return m.Method == "" && string(m.ID) == string(hoverID) })
+	var hover lsp.Hover
+	if err := json.Unmarshal(resp.Result, &hover); err != nil {
+		t.Fatalf("decode hover: %v", err)
+	}
+	if !strings.Contains(hover.Contents.Value, "[#7 Add main](https://github.com/acme/widgets/pull/7) (open)") {
+		t.Fatalf("hover should list the pull request: %s", hover.Contents.Value)
+	}
+}
+
+func TestParseLSPArgsReadsSeverities(t *testing.T) {
+	cfg, err := parseLSPArgs([]string{"--stdio", "--severity", "todo=hint,HACK=error", "--with-pr-links"})
+	if err != nil {
+		t.Fatalf("parseLSPArgs failed: %v", err)
+	}
+	if cfg.severity["TODO"] != lsp.SeverityHint || cfg.severity["HACK"] != lsp.SeverityError || cfg.severity["FIXME"] != lsp.SeverityWarning || !cfg.withPRs {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if _, err := parseLSPArgs([]string{"--severity", "TODO=loud"}); err == nil {
+		t.Fatal("expected an error for an unknown severity")
+	}
+}
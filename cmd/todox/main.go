@@ -33,6 +33,7 @@ import (
 	"github.com/phyten/todox/internal/termcolor"
 	"github.com/phyten/todox/internal/textutil"
 	"github.com/phyten/todox/internal/web"
+	"golang.org/x/term"
 )
 
 var (
@@ -57,6 +58,24 @@ func main() {
 		case "pr":
 			prCmd(os.Args[2:])
 			return
+		case "trend":
+			trendCmd(os.Args[2:])
+			return
+		case "lsp":
+			lspCmd(os.Args[2:])
+			return
+		case "tui":
+			tuiCmd(os.Args[2:])
+			return
+		case "open":
+			openCmd(os.Args[2:])
+			return
+		case "watch":
+			watchCmd(os.Args[2:])
+			return
+		case "hook":
+			hookCmd(os.Args[2:])
+			return
 		}
 	}
 	scanCmd(os.Args[1:])
@@ -71,6 +90,8 @@ type scanConfig struct {
 	withCommit  bool
 	withPRs     bool
 	sortKey     string
+	groupBy     string
+	stream      string
 	fields      string
 	showHelp    bool
 	helpLang    string
@@ -101,6 +122,8 @@ func (u *usageError) Unwrap() error {
 type multiFlag struct {
 	values  []string
 	changed bool
+	// noSplit が true の場合はカンマで分割しない（正規表現などカンマを含み得る値向け）
+	noSplit bool
 }
 
 func (m *multiFlag) String() string {
@@ -117,7 +140,11 @@ func (m *multiFlag) Set(value string) error {
 		m.changed = true
 		return nil
 	}
-	for _, piece := range strings.Split(value, ",") {
+	pieces := []string{value}
+	if !m.noSplit {
+		pieces = strings.Split(value, ",")
+	}
+	for _, piece := range pieces {
 		trimmed := strings.TrimSpace(piece)
 		if trimmed == "" {
 			continue
@@ -207,7 +234,8 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 	mode := fs.String("mode", defaultsEngine.Mode, "last|first")
 	detect := fs.String("detect", defaultsEngine.Detect, "detection engine: auto|parse|regex")
 	author := fs.String("author", defaultsEngine.Author, "filter by author name/email (regexp)")
-	outputFmt := fs.String("output", defaultsEngine.Output, "table|tsv|json|csv|ndjson|md")
+	skipAuthors := fs.String("skip-authors", defaultsEngine.SkipAuthors, "attribute past commits whose author name/email matches (regexp)")
+	outputFmt := fs.String("output", defaultsEngine.Output, "table|tsv|json|csv|ndjson|md|quickfix|fzf")
 	colorMode := fs.String("color", defaultsEngine.Color, "color output for tables: auto|always|never")
 	withComment := fs.Bool("with-comment", defaultsEngine.WithComment, "show line text (from TODO/FIXME)")
 	withMessage := fs.Bool("with-message", defaultsEngine.WithMessage, "show commit subject (1st line)")
@@ -231,6 +259,8 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 	noProgress := fs.Bool("no-progress", false, "disable progress/ETA")
 	forceProg := fs.Bool("progress", false, "force progress even when piped")
 	sortKey := fs.String("sort", defaultsUI.Sort, "sort order (e.g. author,-date; default: file,line)")
+	groupBy := fs.String("group-by", defaultsUI.GroupBy, "group results: owners|author|type|file")
+	stream := fs.String("stream", defaultsUI.Stream, "ndjson streaming order: file|unsorted|off")
 	lang := fs.String("lang", "", "help language (en|ja)")
 	jobs := fs.Int("jobs", defaultsEngine.Jobs, "max parallel workers")
 	repo := fs.String("repo", defaultsEngine.Repo, "repo root (default: current dir)")
@@ -239,6 +269,9 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 	var pathRegex multiFlag
 	var detectLangs multiFlag
 	var tagList multiFlag
+	var tagCaseSensitive multiFlag
+	tagPatterns := multiFlag{noSplit: true}
+	var owners multiFlag
 	fs.Var(&paths, "path", "limit search to given pathspec(s). repeatable / CSV")
 	fs.Var(&excludes, "exclude", "exclude pathspec/glob(s). repeatable / CSV")
 	fs.Var(&pathRegex, "path-regex", "post-filter files by Go regexp (OR). repeatable / CSV")
@@ -246,9 +279,22 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 	fs.Var(&detectLangs, "detect-lang", "alias of --detect-langs")
 	fs.Var(&tagList, "tag", "add or replace detection tags. repeatable / CSV")
 	fs.Var(&tagList, "tags", "alias of --tag")
+	fs.Var(&tagCaseSensitive, "tag-case-sensitive", "match these tags case-sensitively ('*' for all). repeatable / CSV")
+	fs.Var(&tagPatterns, "tag-pattern", "custom Go regexp for a tag as TAG=REGEX. repeatable")
+	tagWordBoundary := fs.Bool("tag-word-boundary", defaultsEngine.TagWordBoundary, "ignore tags glued to identifiers, paths or URLs (todoList, TODOS, /todo/)")
+	tagSuffix := fs.String("tag-suffix", defaultsEngine.TagSuffix, "require one of these characters right after the tag (e.g. ':(')")
+	fs.Var(&owners, "owners", "filter by CODEOWNERS owner(s). repeatable / CSV")
+	fs.Var(&owners, "owner", "alias of --owners")
 	excludeTypical := fs.Bool("exclude-typical", defaultsEngine.ExcludeTypical, "apply typical excludes (vendor/**, node_modules/**, dist/**, build/**, target/**, *.min.*)")
+	includeGenerated := fs.Bool("include-generated", defaultsEngine.IncludeGenerated, "also scan generated/vendored files (linguist-generated, linguist-vendored, todox-ignore, \"Code generated ... DO NOT EDIT.\")")
 	maxFileBytes := fs.Int("max-file-bytes", defaultsEngine.MaxFileBytes, "skip parser detection for files larger than N bytes (0=unlimited)")
 	noPrefilter := fs.Bool("no-prefilter", defaultsEngine.NoPrefilter, "disable git grep prefilter before parsing")
+	recurseSubmodules := fs.Bool("recurse-submodules", defaultsEngine.RecurseSubmodules, "also scan initialized submodules")
+	scanArchives := fs.Bool("scan-archives", defaultsEngine.ScanArchives, "also scan files inside tracked .zip/.jar/.tar.gz/.gz archives (no blame)")
+	timeout := fs.String("timeout", "", "abort the whole scan after this duration (e.g. 90s, 5m; 0=unlimited)")
+	gitTimeout := fs.String("git-timeout", "", "limit each blame/git log -L call to this duration (e.g. 10s; 0=unlimited)")
+	contextLines := fs.Int("context", defaultsEngine.Context, "attach N source lines before/after each item (context_before/context_after in JSON/NDJSON)")
+	showSuppressed := fs.Bool("show-suppressed", defaultsEngine.ShowSuppressed, "include items silenced by todox:ignore markers or .todoxignore (marked with suppressed)")
 
 	shortMap := map[string]string{
 		"-t": "--type",
@@ -446,6 +492,10 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 		v := *author
 		flagEngine.Author = &v
 	}
+	if flagWasSet["skip-authors"] {
+		v := *skipAuthors
+		flagEngine.SkipAuthors = &v
+	}
 	if paths.WasSet() {
 		vals := paths.Slice()
 		flagEngine.Paths = &vals
@@ -466,10 +516,37 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 		vals := tagList.Slice()
 		flagEngine.Tags = &vals
 	}
+	if tagCaseSensitive.WasSet() {
+		vals := tagCaseSensitive.Slice()
+		flagEngine.TagCaseSensitive = &vals
+	}
+	if tagPatterns.WasSet() {
+		patterns, err := engine.ParseTagPatterns(tagPatterns.Slice())
+		if err != nil {
+			return scanConfig{}, err
+		}
+		flagEngine.TagPatterns = &patterns
+	}
+	if flagWasSet["tag-word-boundary"] {
+		v := *tagWordBoundary
+		flagEngine.TagWordBoundary = &v
+	}
+	if flagWasSet["tag-suffix"] {
+		v := *tagSuffix
+		flagEngine.TagSuffix = &v
+	}
+	if owners.WasSet() {
+		vals := owners.Slice()
+		flagEngine.Owners = &vals
+	}
 	if flagWasSet["exclude-typical"] {
 		v := *excludeTypical
 		flagEngine.ExcludeTypical = &v
 	}
+	if flagWasSet["include-generated"] {
+		v := *includeGenerated
+		flagEngine.IncludeGenerated = &v
+	}
 	if withCommentChanged {
 		v := *withComment
 		flagEngine.WithComment = &v
@@ -522,6 +599,36 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 		v := *noPrefilter
 		flagEngine.NoPrefilter = &v
 	}
+	if flagWasSet["recurse-submodules"] {
+		v := *recurseSubmodules
+		flagEngine.RecurseSubmodules = &v
+	}
+	if flagWasSet["scan-archives"] {
+		v := *scanArchives
+		flagEngine.ScanArchives = &v
+	}
+	if flagWasSet["context"] {
+		v := *contextLines
+		flagEngine.Context = &v
+	}
+	if flagWasSet["show-suppressed"] {
+		v := *showSuppressed
+		flagEngine.ShowSuppressed = &v
+	}
+	if flagWasSet["timeout"] {
+		d, err := engineopts.ParseDuration(*timeout, "--timeout")
+		if err != nil {
+			return cfg, &usageError{err: err}
+		}
+		flagEngine.Timeout = &d
+	}
+	if flagWasSet["git-timeout"] {
+		d, err := engineopts.ParseDuration(*gitTimeout, "--git-timeout")
+		if err != nil {
+			return cfg, &usageError{err: err}
+		}
+		flagEngine.GitTimeout = &d
+	}
 
 	var flagUI config.UIConfig
 	if flagWasSet["with-age"] {
@@ -560,6 +667,14 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 		v := *sortKey
 		flagUI.Sort = &v
 	}
+	if flagWasSet["group-by"] {
+		v := *groupBy
+		flagUI.GroupBy = &v
+	}
+	if flagWasSet["stream"] {
+		v := *stream
+		flagUI.Stream = &v
+	}
 
 	finalEngine := config.MergeEngine(defaultsEngine, flagEngine)
 	finalUI := config.MergeUI(defaultsUI, flagUI)
@@ -586,6 +701,8 @@ func parseScanArgs(args []string, envLang string) (scanConfig, error) {
 	cfg.withCommit = finalUI.WithCommitLink
 	cfg.withPRs = finalUI.WithPRLinks
 	cfg.sortKey = finalUI.Sort
+	cfg.groupBy = finalUI.GroupBy
+	cfg.stream = finalUI.Stream
 	cfg.fields = finalUI.Fields
 	cfg.prState = finalUI.PRState
 	cfg.prLimit = finalUI.PRLimit
@@ -626,10 +743,7 @@ func guessRepoDir(args []string, envCfg config.Config) string {
 }
 
 func writeJSONResult(w io.Writer, res *engine.Result) error {
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.SetEscapeHTML(false)
-	return enc.Encode(res)
+	return output.WriteJSON(w, res)
 }
 
 func findFlagValue(args []string, name string) (string, bool) {
@@ -679,9 +793,17 @@ func scanCmd(args []string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	groupBy, err := ParseGroupBy(cfg.groupBy)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	cfg.opts.WithComment = fieldSel.NeedComment
 	cfg.opts.WithMessage = fieldSel.NeedMessage
+	// quickfix / fzf は列を持たず、常に TODO/FIXME から始まる本文を出す。
+	if cfg.output == "quickfix" || cfg.output == "fzf" {
+		cfg.opts.WithComment = true
+	}
 
 	if err = engineopts.NormalizeAndValidate(&cfg.opts); err != nil {
 		log.Fatal(err)
@@ -703,19 +825,42 @@ func scanCmd(args []string) {
 		obs = cfg.opts.ProgressObserver
 	}
 
-	res, err := engine.Run(cfg.opts)
+	// --timeout は blame だけでなく、その後の PR 取得までを含めた上限です。
+	ctx := context.Background()
+	if cfg.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.opts.Timeout)
+		defer cancel()
+	}
+	var remoteCache remoteInfoCache
+	// ndjson は並べ替えや集約、PR 取得が不要なら項目を見つけ次第書き出します。
+	streamed := cfg.output == "ndjson" && cfg.stream != "off" && len(sortSpec.Keys) == 0 && groupBy == "" && !fieldSel.NeedPRs
+	linkErrs := &engine.Result{}
+	if streamed {
+		cfg.opts.StreamOrder = cfg.stream
+		cfg.opts.OnItem = streamItems(ctx, runner, cfg.opts.RepoDir, &remoteCache, fieldSel, linkErrs, output.NewNDJSONWriter(os.Stdout))
+	}
+	res, err := engine.RunContext(ctx, cfg.opts)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if streamed {
+		res.Errors = append(res.Errors, linkErrs.Errors...)
+		res.ErrorCount = len(res.Errors)
+	}
 
 	ApplySort(res.Items, sortSpec)
+	if groupBy != "" {
+		res.GroupBy = groupBy
+		res.Groups = ApplyGroup(res.Items, groupBy)
+	}
 	res.HasComment = fieldSel.ShowComment
 	res.HasMessage = fieldSel.ShowMessage
 	res.HasAge = fieldSel.ShowAge
 
-	ctx := context.Background()
-	var remoteCache remoteInfoCache
-	_ = applyLinkColumn(ctx, runner, cfg.opts.RepoDir, &remoteCache, res, fieldSel)
+	if !streamed {
+		_ = applyLinkColumn(ctx, runner, cfg.opts.RepoDir, &remoteCache, res, fieldSel)
+	}
 	prStart := time.Time{}
 	if fieldSel.NeedPRs {
 		prStart = time.Now()
@@ -730,42 +875,16 @@ func scanCmd(args []string) {
 		res.ElapsedMS += time.Since(prStart).Milliseconds()
 	}
 
-	switch strings.ToLower(cfg.output) {
-	case "json":
-		// NOTE: JSON は機械可読フォーマットのため常に非カラー。--color の指定は無視する。
-		if err := writeJSONResult(os.Stdout, res); err != nil {
-			log.Fatal(err)
-		}
-	case "csv":
-		if err := output.WriteCSV(os.Stdout, res.Items, fieldSel); err != nil {
-			log.Fatal(err)
-		}
-	case "tsv":
-		// NOTE: TSV もターミナル以外で扱われることが多いため常に非カラー。--color の指定は無視する。
-		printTSV(res, fieldSel)
-	case "ndjson":
-		if err := output.WriteNDJSON(os.Stdout, res.Items); err != nil {
+	// ストリーム出力した ndjson は書き出し済み。
+	if !streamed {
+		if err := writeResult(cfg, res, fieldSel); err != nil {
 			log.Fatal(err)
 		}
-	case "md":
-		if err := output.WriteMarkdownTable(os.Stdout, res.Items, fieldSel); err != nil {
-			log.Fatal(err)
-		}
-	default: // table
-		envMap := toEnvMap(os.Environ())
-		profile := termcolor.DetectProfile(envMap)
-		mode := cfg.colorMode
-		enabled := false
-		switch mode {
-		case termcolor.ModeAlways, termcolor.ModeNever:
-			enabled = termcolor.Enabled(mode, os.Stdout)
-		default:
-			auto := termcolor.DetectMode(os.Stdout, envMap)
-			enabled = termcolor.Enabled(auto, os.Stdout)
-		}
-		printTable(res, fieldSel, tableColorConfig{enabled: enabled, profile: profile})
 	}
 
+	if res.Suppressed > 0 && !cfg.opts.ShowSuppressed {
+		fmt.Fprintf(os.Stderr, "todox: %d item(s) suppressed by todox:ignore or .todoxignore (use --show-suppressed to list them)\n", res.Suppressed)
+	}
 	if res.ErrorCount > 0 {
 		reportErrors(res)
 		os.Exit(2)
@@ -791,14 +910,24 @@ Search & attribution:
   -m, --mode {last|first}        last: last modifier via blame (fast)
                                  first: first introducer via 'git log -L' (slow)
   -a, --author REGEX             Filter by author name or email (extended regex)
+      --owners LIST             Filter by CODEOWNERS owner(s) (repeatable / CSV; '@' optional)
+      --skip-authors REGEX      Attribute past commits by matching authors (bots, formatters);
+                               skipped commits are reported in the "skipped" field
       --path LIST               Limit search to pathspec(s) (repeatable / CSV)
       --exclude LIST            Exclude pathspec/glob(s) (repeatable / CSV)
       --path-regex REGEXP       Post-filter file paths by Go regexp (OR across entries)
       --exclude-typical         Apply typical excludes: vendor/**, node_modules/**, dist/**, build/**, target/**, *.min.*
+      --include-generated       Also scan generated/vendored files, which are skipped by default
+                               (.gitattributes linguist-generated / linguist-vendored / todox-ignore,
+                               or a "Code generated ... DO NOT EDIT." style header)
       --detect {auto|parse|regex}
                                Detection engine (default: auto)
       --detect-langs LIST       Limit parser-based detection to languages (repeatable / CSV)
       --tags LIST               Override detection tags (repeatable / CSV)
+      --tag-word-boundary       Ignore tags glued to identifiers, paths or URLs (todoList, TODOS, /todo/)
+      --tag-suffix CHARS        Require one of CHARS right after the tag (e.g. ':(')
+      --tag-case-sensitive LIST Match these tags case-sensitively ('*' = all; repeatable / CSV)
+      --tag-pattern TAG=REGEX   Custom Go regexp for TAG; the first capture group marks the tag (repeatable)
       --include-strings         Include string literals (default)
       --comments-only           Scan comments only (alias of --no-strings)
       --no-strings              Alias of --comments-only
@@ -806,14 +935,23 @@ Search & attribution:
                                the last flag wins.
       --max-file-bytes N        Skip parser detection above N bytes (0 = unlimited)
       --no-prefilter            Disable git grep prefilter prior to parsing
+      --recurse-submodules      Also scan initialized submodules (blame/links use each submodule's repo)
+      --scan-archives           Also scan files inside tracked .zip/.jar/.tar.gz/.gz archives
+                               (reported as archive.zip!inner/path:line with kind archive, no blame)
+      --show-suppressed         Include items silenced by todox:ignore markers or .todoxignore
+                               (JSON suppressed / column suppressed holds the reason)
 
 Output:
-  -o, --output {table|tsv|json|csv|ndjson|md}  Output format (default: table)
+  -o, --output {table|tsv|json|csv|ndjson|md|quickfix|fzf}  Output format (default: table)
+                               quickfix = file:line:col: TAG text (Vim -q, Emacs, grep -n style);
+                               fzf = the same records NUL-terminated for fzf --read0
       --color {auto|always|never} Colorize table output (default: auto)
       --fields LIST             Columns for tabular outputs (table/tsv/csv/md; comma-separated)
                                Available columns: type, tag, kind, lang, author, email,
-                               date, age, commit, location, text, span, comment, message,
-                               url/commit_url, pr/prs/pr_urls
+                               committer, committer_email,
+                               date, age, commit, location, text, span, cell, comment,
+                               body, message, url/commit_url, pr/prs/pr_urls,
+                               owner/owners, skipped, suppressed
                                type reports the normalized tag (TODO/FIXME); kind reports
                                where the match came from (comment/string/heredoc). Include
                                comment/message explicitly when overriding defaults.
@@ -824,6 +962,8 @@ Extra columns (hidden by default):
       --with-message             Show MESSAGE (commit subject = 1st line)
       --with-snippet             Alias of --with-comment (backward compatible)
       --with-age                 Show AGE (days since author date) in tabular outputs
+      --context N                Attach N source lines before/after each item (0-50, default: 0)
+                                 as context_before / context_after in JSON and NDJSON
       --with-commit-link         Show URL column with GitHub blob links
       --with-link                Deprecated alias of --with-commit-link
       --with-pr-links            Include pull requests containing each commit
@@ -841,12 +981,23 @@ Truncation (applies to COMMENT / MESSAGE only):
 
 Sorting:
       --sort KEYS                Sort order (e.g. --sort -age,file,line)
-                                 Keys: age, date, author, email, type, file, line, commit, location
+                                 Keys: age, date, author, email, committer, type, file, line, commit, location, owner
+      --group-by KEY             Group rows by owners, author, type or file
+                                 (table prints a header per group; JSON adds "groups")
+      --stream {file|unsorted|off}
+                                 How -o ndjson writes items while scanning (default: file):
+                                 file = each file's items in line order once the file is done,
+                                 unsorted = each item as soon as it is attributed,
+                                 off = wait and write everything sorted by file and line.
+                                 --sort, --group-by and --with-pr-links always wait.
 
 Blame / progress:
       --no-ignore-ws             Do not pass -w to git blame (whitespace changes count)
       --no-progress              Do not show progress/ETA
       --progress                 Force progress even when piped
+      --timeout DURATION         Abort the scan after DURATION (e.g. 90s, 5m; 0 = unlimited)
+      --git-timeout DURATION     Limit each blame / git log -L call; items that exceed it are
+                                 reported in errors and the scan continues
 
 Help / language:
   -h, --help [en|ja]             Show help in English (default) or Japanese
@@ -891,6 +1042,29 @@ GitHub helpers:
   todox pr open --commit <sha>    Open the first matching pull request in a browser
   todox pr create --commit <sha>  Create a pull request via gh CLI (see todox pr create --help)
 
+History:
+  todox trend --every 1w --since 1y
+                                  Count TODO/FIXME at sampled points of first-parent history
+                                  (CSV by default; -o json, --group-by dir|author; see todox trend --help)
+
+Editor integration:
+  todox lsp                       Language Server over stdio: diagnostics, hover, workspace symbols
+                                  and code actions (see todox lsp --help)
+
+Interactive:
+  todox tui                       Full-screen list with live search, grouping, source preview and
+                                  keys to open $EDITOR, the commit or the PR (see todox tui --help)
+  todox open N [options]          Open the Nth item of the report (same options) in $EDITOR;
+                                  FILE:LINE works too (see todox open --help for an fzf recipe)
+  todox watch [options]           Re-render the report whenever the worktree changes, re-parsing
+                                  only changed files and re-blaming only edited lines
+                                  (todox serve --watch pushes the updates to the web UI)
+
+Git hooks:
+  todox hook install [pre-push]   Install a hook that rejects new TODO/FIXMEs without an owner
+                                  (or issue reference, --require) in staged or pushed changes
+  todox hook pre-commit           Run the check on staged changes (see todox hook --help)
+
   7) Machine-friendly TSV:
        todox --full -o tsv > todo_full.tsv
 
@@ -912,28 +1086,47 @@ const helpJapanese = `todox — リポジトリ内の TODO / FIXME の「誰が
   -m, --mode {last|first}        last : その行を最後に変更した人（git blame で高速）
                                  first: その TODO/FIXME を最初に入れた人（git log -L で低速）
   -a, --author REGEX             作者名またはメールを正規表現でフィルタ
+      --owners LIST             CODEOWNERS の担当者でフィルタ（繰り返し/カンマ区切り。@ は省略可）
+      --skip-authors REGEX      一致する作者（bot や整形ツール）のコミットを飛ばして帰属
+                               （飛ばしたコミットは "skipped" フィールドに記録）
       --path LIST               検索対象の pathspec を指定（繰り返し/カンマ区切り）
       --exclude LIST            除外する pathspec/glob（繰り返し/カンマ区切り）
       --path-regex REGEXP       ファイルパスを Go の正規表現で後段フィルタ（OR 条件）
       --exclude-typical         典型的な除外セットを適用（vendor/**, node_modules/**, dist/**, build/**, target/**, *.min.*）
+      --include-generated       既定で除外する生成ファイル・vendored も走査
+                               （.gitattributes の linguist-generated / linguist-vendored / todox-ignore、
+                               または "Code generated ... DO NOT EDIT." などのヘッダ）
       --detect {auto|parse|regex}
                                 検出エンジン（既定: auto）
       --detect-langs LIST       構文解析対象の言語を限定（繰り返し/カンマ区切り）
       --tags LIST               検出タグを上書き（繰り返し/カンマ区切り）
+      --tag-word-boundary       識別子・パス・URL の一部になっているタグを無視（todoList, TODOS, /todo/）
+      --tag-suffix CHARS        タグの直後が CHARS のいずれかの場合のみ一致（例: ':('）
+      --tag-case-sensitive LIST 大文字小文字を区別するタグ（'*' で全て。繰り返し/カンマ区切り）
+      --tag-pattern TAG=REGEX   TAG 用の独自正規表現（最初のキャプチャグループをタグ位置とする。繰り返し可）
       --include-strings         文字列リテラルも対象（既定で有効）
       --comments-only           コメントのみを対象（--no-strings の別名）
       --no-strings              --comments-only の別名
                                これらを併用した場合は最後に指定したフラグが優先されます。
       --max-file-bytes N        N バイト超のファイルは構文解析をスキップ（0=無制限）
       --no-prefilter            git grep による事前フィルタを無効化
+      --recurse-submodules      初期化済みのサブモジュールも走査（blame・リンクは各サブモジュールで解決）
+      --scan-archives           追跡中の .zip/.jar/.tar.gz/.gz の中のファイルも走査
+                               （archive.zip!inner/path:line・kind archive として報告。blame なし）
+      --show-suppressed         todox:ignore や .todoxignore で抑制した項目も出力
+                               （JSON の suppressed・suppressed 列に理由が入ります）
 
 出力:
-  -o, --output {table|tsv|json|csv|ndjson|md}  出力形式（既定: table）
+  -o, --output {table|tsv|json|csv|ndjson|md|quickfix|fzf}  出力形式（既定: table）
+                               quickfix = file:line:col: TAG 本文（Vim -q・Emacs・grep -n 形式）、
+                               fzf = 同じ行を NUL 区切りにしたもの（fzf --read0 用）
       --color {auto|always|never} 表形式に色付け（既定: auto）
       --fields LIST             表形式（table/tsv/csv/md）の列を指定（カンマ区切り。--with-* より優先）
-                               指定可能な列: type, tag, kind, lang, author, email, date,
-                               age, commit, location, text, span, comment, message,
-                               url/commit_url, pr/prs/pr_urls
+                               指定可能な列: type, tag, kind, lang, author, email,
+                               committer, committer_email, date,
+                               age, commit, location, text, span, cell, comment, body, message,
+                               url/commit_url, pr/prs/pr_urls, owner/owners, skipped,
+                               suppressed
                                type は正規化タグ（TODO/FIXME など）、kind は検出元
                                （comment/string/heredoc 等）を表します。既定列を
                                上書きする場合は comment や message も明示的に
@@ -945,6 +1138,8 @@ const helpJapanese = `todox — リポジトリ内の TODO / FIXME の「誰が
       --with-message             MESSAGE（コミットメッセージの1行目）
       --with-snippet             --with-comment の別名（後方互換）
       --with-age                 AGE（日数）列を表形式に追加
+      --context N                各項目の前後 N 行のソースを付ける（0〜50、既定: 0）。
+                                 JSON / NDJSON の context_before / context_after に入る
       --with-commit-link         URL 列を追加（コミット行リンク）
       --with-link                --with-commit-link の非推奨エイリアス
       --with-pr-links            コミットを含む PR 情報を追加
@@ -962,12 +1157,23 @@ const helpJapanese = `todox — リポジトリ内の TODO / FIXME の「誰が
 
 並び替え:
       --sort KEYS                並び順（例: --sort -age,file,line）
-                                 利用可能キー: age, date, author, email, type, file, line, commit, location
+                                 利用可能キー: age, date, author, email, committer, type, file, line, commit, location, owner
+      --group-by KEY             owners / author / type / file 単位でまとめて表示
+                                 （table はグループごとに見出しを表示、JSON には "groups" を追加）
+      --stream {file|unsorted|off}
+                                 -o ndjson で走査中に項目を書き出す方法（既定: file）
+                                 file = ファイルの処理が終わるたびにその項目を行順で出力、
+                                 unsorted = 帰属が済んだ項目から順に出力、
+                                 off = 全件を待ってファイル・行順で出力。
+                                 --sort / --group-by / --with-pr-links 指定時は常に全件を待ちます。
 
 Blame / 進捗:
       --no-ignore-ws             git blame の -w を無効化（空白変更も追跡）
       --no-progress              進捗/ETA を表示しない
       --progress                 パイプ時でも進捗表示を強制
+      --timeout DURATION         DURATION を過ぎたら走査を中断（例: 90s, 5m。0 = 無制限）
+      --git-timeout DURATION     blame / git log -L 1 回あたりの上限。超えた項目は errors に
+                                 記録して走査を続行
 
 ヘルプ / 言語:
   -h, --help [en|ja]             ヘルプ表示（既定: 英語、ja を付けると日本語）
@@ -1012,6 +1218,29 @@ GitHub 連携コマンド:
   todox pr open --commit <sha>    最初に見つかった PR をブラウザで開く
   todox pr create --commit <sha>  gh CLI 経由で PR を作成（詳細は --help）
 
+履歴:
+  todox trend --every 1w --since 1y
+                                  first-parent 履歴を一定間隔でサンプリングし TODO/FIXME 件数を集計
+                                  （既定は CSV。-o json、--group-by dir|author。詳細は todox trend --help）
+
+エディタ連携:
+  todox lsp                       標準入出力の Language Server（診断・ホバー・ワークスペースシンボル・
+                                  コードアクション。詳細は todox lsp --help）
+
+対話操作:
+  todox tui                       全画面の一覧。インクリメンタル検索・グループ切り替え・ソースのプレビュー、
+                                  $EDITOR・コミット・PR を開くキー操作（詳細は todox tui --help）
+  todox open N [options]          同じオプションで得た一覧の N 件目を $EDITOR で開く。
+                                  FILE:LINE も指定可（fzf との連携例は todox open --help）
+  todox watch [options]           作業ツリーが変わるたびに一覧を描き直す。読み直すのは変更のあった
+                                  ファイル、blame し直すのは編集した行だけ
+                                  （todox serve --watch は更新を Web UI に送る）
+
+Git フック:
+  todox hook install [pre-push]   ステージした変更や push する変更に、担当者（--require で課題番号も）
+                                  のない TODO/FIXME が新しく入るのを止めるフックを入れる
+  todox hook pre-commit           ステージ済みの変更を検査する（詳細は todox hook --help）
+
   7) 機械処理向け TSV 出力:
        todox --full -o tsv > todo_full.tsv
 
@@ -1023,10 +1252,54 @@ GitHub 連携コマンド:
        todox --no-ignore-ws
 `
 
+// writeResult は res を cfg.output の形式で標準出力に書き出します。
+func writeResult(cfg scanConfig, res *engine.Result, fieldSel output.FieldSelection) error {
+	switch strings.ToLower(cfg.output) {
+	case "json":
+		// NOTE: JSON は機械可読フォーマットのため常に非カラー。--color の指定は無視する。
+		return writeJSONResult(os.Stdout, res)
+	case "csv":
+		return output.WriteCSV(os.Stdout, res.Items, fieldSel)
+	case "tsv":
+		// NOTE: TSV もターミナル以外で扱われることが多いため常に非カラー。--color の指定は無視する。
+		printTSV(res, fieldSel)
+	case "ndjson":
+		return output.WriteNDJSON(os.Stdout, res.Items)
+	case "md":
+		return output.WriteMarkdownTable(os.Stdout, res.Items, fieldSel)
+	case "quickfix":
+		return output.WriteQuickfix(os.Stdout, res.Items)
+	case "fzf":
+		if err := output.WriteFZF(os.Stdout, res.Items); err != nil {
+			return err
+		}
+		// パイプせずに実行したときだけ、fzf への渡し方を案内する。
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			fmt.Fprintf(os.Stderr, "\ntodox: pipe this into fzf, e.g.\n  %s\n", fzfHint)
+		}
+	default: // table
+		envMap := toEnvMap(os.Environ())
+		profile := termcolor.DetectProfile(envMap)
+		mode := cfg.colorMode
+		enabled := false
+		switch mode {
+		case termcolor.ModeAlways, termcolor.ModeNever:
+			enabled = termcolor.Enabled(mode, os.Stdout)
+		default:
+			auto := termcolor.DetectMode(os.Stdout, envMap)
+			enabled = termcolor.Enabled(auto, os.Stdout)
+		}
+		printTable(res, fieldSel, tableColorConfig{enabled: enabled, profile: profile})
+	}
+	return nil
+}
+
 type scanInputs struct {
 	Options  engine.Options
 	FieldSel output.FieldSelection
 	SortSpec SortSpec
+	GroupBy  string
+	Stream   string
 	PRState  string
 	PRLimit  int
 	PRPrefer string
@@ -1147,6 +1420,14 @@ func prepareScanInputs(repoDir string, q url.Values) (scanInputs, error) {
 	if err != nil {
 		return scanInputs{}, err
 	}
+	groupBy, err := ParseGroupBy(lastQueryValue(q, "group_by", mergedUI.GroupBy))
+	if err != nil {
+		return scanInputs{}, err
+	}
+	stream, err := config.CanonicalizeStream(lastQueryValue(q, "stream", mergedUI.Stream))
+	if err != nil {
+		return scanInputs{}, err
+	}
 
 	options.WithComment = fieldSel.NeedComment
 	options.WithMessage = fieldSel.NeedMessage
@@ -1159,6 +1440,8 @@ func prepareScanInputs(repoDir string, q url.Values) (scanInputs, error) {
 		Options:  options,
 		FieldSel: fieldSel,
 		SortSpec: sortSpec,
+		GroupBy:  groupBy,
+		Stream:   stream,
 		PRState:  prState,
 		PRLimit:  prLimit,
 		PRPrefer: prPrefer,
@@ -1301,17 +1584,21 @@ func apiScanHandler(repoDir string) http.HandlerFunc {
 
 		runner := execx.DefaultRunner()
 
-		res, err := engine.Run(inputs.Options)
+		ctx := r.Context()
+		res, err := engine.RunContext(ctx, inputs.Options)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		ApplySort(res.Items, inputs.SortSpec)
+		if inputs.GroupBy != "" {
+			res.GroupBy = inputs.GroupBy
+			res.Groups = ApplyGroup(res.Items, inputs.GroupBy)
+		}
 		res.HasComment = inputs.FieldSel.ShowComment
 		res.HasMessage = inputs.FieldSel.ShowMessage
 		res.HasAge = inputs.FieldSel.ShowAge
 
-		ctx := r.Context()
 		var remoteCache remoteInfoCache
 		_ = applyLinkColumn(ctx, runner, inputs.Options.RepoDir, &remoteCache, res, inputs.FieldSel)
 		prStart := time.Time{}
@@ -1332,7 +1619,14 @@ func apiScanHandler(repoDir string) http.HandlerFunc {
 	}
 }
 
-func apiScanStreamHandler(repoDir string) http.HandlerFunc {
+// apiScanStreamHandler は走査の進捗と結果を SSE で送ります。
+// 項目は見つけ次第 item イベントで（URL を埋めて）送り、最後の result は項目を含まない集計
+// （total・errors・groups）だけにします。項目を手元に残さないため、件数が多くてもメモリは増えません。
+// 並べ替えや PR 列を求められたときは全件がそろうまで待つ必要があるので item イベントは送らず、
+// result に全件を含めます（CLI の ndjson と同じ規則です）。
+// watch が正なら最初の result の後も接続を保ち、その間隔で作業ツリーを調べて変化のたびに result を送り直します。
+// このときの result はセッションが保持する全件を含み、表を置き換えるのに使えます。
+func apiScanStreamHandler(repoDir string, watch time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		flusher, ok := w.(http.Flusher)
 		if !ok {
@@ -1353,6 +1647,11 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 		flusher.Flush()
 		_, _ = fmt.Fprint(w, "retry: 3000\n\n")
 		flusher.Flush()
+		if watch > 0 {
+			if err := writeSSE(w, flusher, "watch", map[string]any{"interval_ms": watch.Milliseconds()}); err != nil {
+				return
+			}
+		}
 
 		obsCore, snapCh := newStreamObserver(64)
 		inputs.Options.Progress = false
@@ -1362,6 +1661,7 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 
 		resCh := make(chan *engine.Result, 1)
 		errCh := make(chan error, 1)
+		itemCh := make(chan engine.Item, 256)
 
 		type prStageResult struct {
 			elapsed time.Duration
@@ -1373,8 +1673,38 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 		pingTicker := time.NewTicker(pingInterval)
 		defer pingTicker.Stop()
 
+		// クライアントが切断すると r.Context() が取り消され、実行中の blame も止まります。
+		ctx := r.Context()
+		var remoteCache remoteInfoCache
+		// summary が真なら result は集計だけにし、グループの件数は項目を残さずに数えます。
+		// watch ではセッションが全件を保持するので、item イベントを送ったうえで result にも全件を含めます。
+		summary := watch <= 0 && len(inputs.SortSpec.Keys) == 0 && !inputs.FieldSel.NeedPRs
+		streaming := inputs.Stream != "off" && (summary || watch > 0)
+		linkErrs := &engine.Result{}
+		groupCounts := make(map[string]int)
+		if streaming {
+			inputs.Options.StreamOrder = inputs.Stream
+			inputs.Options.OnItem = streamItems(ctx, runner, inputs.Options.RepoDir, &remoteCache, inputs.FieldSel, linkErrs, func(it engine.Item) error {
+				if summary && inputs.GroupBy != "" {
+					groupCounts[groupKey(it, inputs.GroupBy)]++
+				}
+				select {
+				case itemCh <- it:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}
+		var sess *engine.Session
 		go func() {
-			res, runErr := engine.Run(inputs.Options)
+			var res *engine.Result
+			var runErr error
+			if watch > 0 {
+				sess, res, runErr = engine.NewSession(ctx, inputs.Options)
+			} else {
+				res, runErr = engine.RunContext(ctx, inputs.Options)
+			}
 			if runErr != nil {
 				errCh <- runErr
 				return
@@ -1382,8 +1712,6 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 			resCh <- res
 		}()
 
-		ctx := r.Context()
-		var remoteCache remoteInfoCache
 		var currentRes *engine.Result
 		var prDoneCh <-chan prStageResult
 
@@ -1465,15 +1793,47 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 				if flushPending() {
 					return
 				}
+			case it := <-itemCh:
+				if err := writeSSE(w, flusher, "item", it); err != nil {
+					obsCore.Close()
+					return
+				}
 			case res := <-resCh:
 				if flushPending() {
 					return
 				}
-				ApplySort(res.Items, inputs.SortSpec)
+			drain:
+				for {
+					select {
+					case it := <-itemCh:
+						if err := writeSSE(w, flusher, "item", it); err != nil {
+							obsCore.Close()
+							return
+						}
+					default:
+						break drain
+					}
+				}
+				if streaming && summary {
+					// 項目は item イベントで送り終えているので、集計だけを返す
+					if inputs.GroupBy != "" {
+						res.GroupBy = inputs.GroupBy
+						res.Groups = groupsFromCounts(groupCounts)
+					}
+					res.Errors = append(res.Errors, linkErrs.Errors...)
+					res.ErrorCount = len(res.Errors)
+					res.HasURL = inputs.FieldSel.NeedURL
+				} else {
+					ApplySort(res.Items, inputs.SortSpec)
+					if inputs.GroupBy != "" {
+						res.GroupBy = inputs.GroupBy
+						res.Groups = ApplyGroup(res.Items, inputs.GroupBy)
+					}
+					_ = applyLinkColumn(ctx, runner, inputs.Options.RepoDir, &remoteCache, res, inputs.FieldSel)
+				}
 				res.HasComment = inputs.FieldSel.ShowComment
 				res.HasMessage = inputs.FieldSel.ShowMessage
 				res.HasAge = inputs.FieldSel.ShowAge
-				_ = applyLinkColumn(ctx, runner, inputs.Options.RepoDir, &remoteCache, res, inputs.FieldSel)
 
 				currentRes = res
 				if !inputs.FieldSel.NeedPRs {
@@ -1537,6 +1897,40 @@ func apiScanStreamHandler(repoDir string) http.HandlerFunc {
 				return
 			}
 		}
+		if sess == nil {
+			return
+		}
+
+		// --watch: 変化のあったファイルだけを読み直し、更新した結果を result イベントで送り直す
+		pr := prOptions{State: inputs.PRState, Limit: inputs.PRLimit, Prefer: inputs.PRPrefer, Jobs: inputs.Options.Jobs}
+		watchTicker := time.NewTicker(watch)
+		defer watchTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pingTicker.C:
+				if err := writeSSE(w, flusher, "ping", map[string]string{"ts": time.Now().UTC().Format(time.RFC3339Nano)}); err != nil {
+					return
+				}
+			case <-watchTicker.C:
+				res, _, err := sess.Poll(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("todox serve: watch: %v", err)
+					continue
+				}
+				if res == nil {
+					continue
+				}
+				decorateResult(ctx, runner, inputs.Options.RepoDir, &remoteCache, res, inputs.FieldSel, inputs.SortSpec, inputs.GroupBy, pr)
+				if err := writeSSE(w, flusher, "result", res); err != nil {
+					return
+				}
+			}
+		}
 	}
 }
 
@@ -1544,15 +1938,29 @@ func serveCmd(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	var port = fs.Int("p", 8080, "port")
 	var repo = fs.String("repo", ".", "repo root")
+	var watch = fs.Bool("watch", false, "keep /api/scan/stream open and push updated results when the worktree changes")
+	var watchInterval = fs.Duration("watch-interval", time.Second, "how often --watch polls the worktree")
 	_ = fs.Parse(args)
+	if *watch && *watchInterval < minWatchInterval {
+		log.Fatalf("todox serve: --watch-interval must be at least %s", minWatchInterval)
+	}
+	var streamWatch time.Duration
+	if *watch {
+		streamWatch = *watchInterval
+	}
 
 	mux := http.NewServeMux()
 	web.Register(mux)
 	mux.HandleFunc("/api/scan", apiScanHandler(*repo))
-	mux.HandleFunc("/api/scan/stream", apiScanStreamHandler(*repo))
+	mux.HandleFunc("/api/scan/stream", apiScanStreamHandler(*repo, streamWatch))
+	mux.HandleFunc("/api/trend", apiTrendHandler(*repo))
 
 	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("todox serve listening on %s (repo=%s)", addr, mustAbs(*repo))
+	if *watch {
+		log.Printf("todox serve listening on %s (repo=%s, watching every %s)", addr, mustAbs(*repo), streamWatch)
+	} else {
+		log.Printf("todox serve listening on %s (repo=%s)", addr, mustAbs(*repo))
+	}
 	log.Fatal(http.ListenAndServe(addr, mux))
 }
 
@@ -1564,7 +1972,16 @@ type prOptions struct {
 }
 
 type remoteInfoCache struct {
-	once sync.Once
+	mu   sync.Mutex
+	done bool
+	info gitremote.Info
+	err  error
+
+	subMu sync.Mutex
+	subs  map[string]remoteInfoResult
+}
+
+type remoteInfoResult struct {
 	info gitremote.Info
 	err  error
 }
@@ -1573,10 +1990,53 @@ func (c *remoteInfoCache) Get(ctx context.Context, runner execx.Runner, repoDir
 	if c == nil {
 		return gitremote.Detect(ctx, runner, repoDir)
 	}
-	c.once.Do(func() {
-		c.info, c.err = gitremote.Detect(ctx, runner, repoDir)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return c.info, c.err
+	}
+	info, err := gitremote.Detect(ctx, runner, repoDir)
+	// 打ち切られた問い合わせの結果は覚えず、次の呼び出しで問い合わせ直します（TUI は 1 回ごとに --timeout の ctx を渡すため）。
+	if err != nil && ctx.Err() != nil {
+		return info, err
+	}
+	c.info, c.err, c.done = info, err, true
+	return info, err
+}
+
+// GetSubmodule は repoDir 配下のサブモジュール sub のリモート情報を返します（サブモジュールごとにキャッシュ）。
+func (c *remoteInfoCache) GetSubmodule(ctx context.Context, runner execx.Runner, repoDir, sub string) (gitremote.Info, error) {
+	dir := filepath.Join(repoDir, filepath.FromSlash(sub))
+	if c == nil {
+		return gitremote.Detect(ctx, runner, dir)
+	}
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if r, ok := c.subs[sub]; ok {
+		return r.info, r.err
+	}
+	info, err := gitremote.Detect(ctx, runner, dir)
+	if err != nil && ctx.Err() != nil {
+		return info, err
+	}
+	if c.subs == nil {
+		c.subs = make(map[string]remoteInfoResult)
+	}
+	c.subs[sub] = remoteInfoResult{info: info, err: err}
+	return info, err
+}
+
+// addLinkError は同じメッセージの link エラーを重複させずに res.Errors へ追加します。
+func addLinkError(res *engine.Result, msg string) {
+	for _, e := range res.Errors {
+		if e.Stage == "link" && e.Message == msg {
+			return
+		}
+	}
+	res.Errors = append(res.Errors, engine.ItemError{
+		Stage:   "link",
+		Message: msg,
 	})
-	return c.info, c.err
 }
 
 func applyLinkColumn(ctx context.Context, runner execx.Runner, repoDir string, cache *remoteInfoCache, res *engine.Result, sel output.FieldSelection) error {
@@ -1587,33 +2047,56 @@ func applyLinkColumn(ctx context.Context, runner execx.Runner, repoDir string, c
 	if !sel.NeedURL {
 		return nil
 	}
+	defer func() {
+		res.ErrorCount = len(res.Errors)
+	}()
 	info, err := cache.Get(ctx, runner, repoDir)
 	if err != nil {
-		for idx := range res.Items {
-			res.Items[idx].URL = ""
+		addLinkError(res, "failed to determine git remote: "+err.Error())
+	}
+	for idx := range res.Items {
+		linkItem(ctx, runner, repoDir, cache, info, err, res, &res.Items[idx])
+	}
+	return nil
+}
+
+// streamItems は URL 列を埋めてから write に渡す OnItem を返します。
+// リモートの取得で起きたエラーは errs に記録します。
+func streamItems(ctx context.Context, runner execx.Runner, repoDir string, cache *remoteInfoCache, sel output.FieldSelection, errs *engine.Result, write func(engine.Item) error) func(engine.Item) error {
+	var info gitremote.Info
+	var infoErr error
+	if sel.NeedURL {
+		info, infoErr = cache.Get(ctx, runner, repoDir)
+		if infoErr != nil {
+			addLinkError(errs, "failed to determine git remote: "+infoErr.Error())
 		}
-		msg := "failed to determine git remote: " + err.Error()
-		already := false
-		for _, e := range res.Errors {
-			if e.Stage == "link" && e.Message == msg {
-				already = true
-				break
-			}
+	}
+	return func(it engine.Item) error {
+		if sel.NeedURL {
+			linkItem(ctx, runner, repoDir, cache, info, infoErr, errs, &it)
 		}
-		if !already {
-			res.Errors = append(res.Errors, engine.ItemError{
-				Stage:   "link",
-				Message: msg,
-			})
+		return write(it)
+	}
+}
+
+// linkItem は 1 件分の URL を埋めます。infoErr はリポジトリ本体のリモートを取得したときのエラーで、
+// その場合は URL を空にします（エラーは呼び出し側で記録済み）。
+func linkItem(ctx context.Context, runner execx.Runner, repoDir string, cache *remoteInfoCache, info gitremote.Info, infoErr error, res *engine.Result, it *engine.Item) {
+	if it.Submodule != "" {
+		subInfo, subErr := cache.GetSubmodule(ctx, runner, repoDir, it.Submodule)
+		if subErr != nil {
+			it.URL = ""
+			addLinkError(res, fmt.Sprintf("failed to determine git remote for submodule %s: %v", it.Submodule, subErr))
+			return
 		}
-		res.ErrorCount = len(res.Errors)
-		return nil
+		it.URL = link.Blob(subInfo, it.Commit, strings.TrimPrefix(it.File, it.Submodule+"/"), it.Line)
+		return
 	}
-	for idx := range res.Items {
-		it := &res.Items[idx]
-		it.URL = link.Blob(info, it.Commit, it.File, it.Line)
+	if infoErr != nil {
+		it.URL = ""
+		return
 	}
-	return nil
+	it.URL = link.Blob(info, it.Commit, it.File, it.Line)
 }
 
 func applyPRColumns(ctx context.Context, runner execx.Runner, repoDir string, cache *remoteInfoCache, res *engine.Result, sel output.FieldSelection, opts prOptions, obs progress.Observer) error {
@@ -1632,7 +2115,8 @@ func applyPRColumns(ctx context.Context, runner execx.Runner, repoDir string, ca
 	commits := make([]string, 0, len(res.Items))
 	for idx := range res.Items {
 		sha := strings.TrimSpace(res.Items[idx].Commit)
-		if sha == "" {
+		// サブモジュールのコミットは別リポジトリのものなので PR は引きません。
+		if sha == "" || res.Items[idx].Submodule != "" {
 			continue
 		}
 		if _, ok := commitToIndexes[sha]; !ok {
@@ -1914,7 +2398,20 @@ func printTable(res *engine.Result, sel output.FieldSelection, colors tableColor
 		return b.String()
 	}
 	mustFprintln(os.Stdout, render(headers))
-	for _, row := range rows {
+	counts := make(map[string]int, len(res.Groups))
+	for _, g := range res.Groups {
+		counts[g.Key] = g.Count
+	}
+	prevGroup := ""
+	for i, row := range rows {
+		if res.GroupBy != "" {
+			// グループの切り替わりで見出し行を挟む（ApplyGroup で並べ替え済みであることが前提）。
+			if key := groupKey(res.Items[i], res.GroupBy); i == 0 || key != prevGroup {
+				label := fmt.Sprintf("== %s (%d) ==", sanitizeField(key), counts[key])
+				mustFprintln(os.Stdout, termcolor.Apply(termcolor.HeaderStyle(), label, colors.enabled))
+				prevGroup = key
+			}
+		}
 		mustFprintln(os.Stdout, render(row))
 	}
 }
@@ -482,6 +482,37 @@ func (errorRunner) Run(ctx context.Context, dir, name string, args ...string) ([
 	return nil, []byte("fatal: not a git repository"), fmt.Errorf("exit status 128")
 }
 
+type ctxAwareRunner struct{}
+
+func (ctxAwareRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return stubRunner{}.Run(ctx, dir, name, args...)
+}
+
+func TestRemoteInfoCacheRetriesAfterContextError(t *testing.T) {
+	var cache remoteInfoCache
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.Get(expired, ctxAwareRunner{}, "."); err == nil {
+		t.Fatalf("expected error from expired context")
+	}
+	if _, err := cache.GetSubmodule(expired, ctxAwareRunner{}, ".", "sub"); err == nil {
+		t.Fatalf("expected submodule error from expired context")
+	}
+	info, err := cache.Get(context.Background(), ctxAwareRunner{}, ".")
+	if err != nil {
+		t.Fatalf("lookup after timeout should retry: %v", err)
+	}
+	if info.Owner != "example" || info.Repo != "demo" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if _, err := cache.GetSubmodule(context.Background(), ctxAwareRunner{}, ".", "sub"); err != nil {
+		t.Fatalf("submodule lookup after timeout should retry: %v", err)
+	}
+}
+
 type prRunner struct{}
 
 func (prRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
@@ -583,6 +614,39 @@ func TestApplyLinkColumnAddsURL(t *testing.T) {
 	}
 }
 
+type submoduleRemoteRunner struct{}
+
+func (submoduleRemoteRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	if name == "git" && len(args) >= 3 && args[0] == "config" && args[1] == "--get" && args[2] == "remote.origin.url" {
+		if filepath.ToSlash(dir) == "repo/services/api" {
+			return []byte("git@github.com:example/api.git\n"), nil, nil
+		}
+		return []byte("https://github.com/example/platform.git\n"), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected command: %s %v", name, args)
+}
+
+func TestApplyLinkColumnUsesSubmoduleRemote(t *testing.T) {
+	res := &engine.Result{Items: []engine.Item{
+		{Commit: "1111111111111111111111111111111111111111", File: "main.go", Line: 3},
+		{Commit: "2222222222222222222222222222222222222222", File: "services/api/server.go", Line: 9, Submodule: "services/api"},
+	}}
+	sel := output.FieldSelection{NeedURL: true, ShowURL: true}
+	var cache remoteInfoCache
+	if err := applyLinkColumn(context.Background(), submoduleRemoteRunner{}, "repo", &cache, res, sel); err != nil {
+		t.Fatalf("applyLinkColumn failed: %v", err)
+	}
+	if got, want := res.Items[0].URL, "https://github.com/example/platform/blob/1111111111111111111111111111111111111111/main.go#L3"; got != want {
+		t.Fatalf("superproject URL = %s, want %s", got, want)
+	}
+	if got, want := res.Items[1].URL, "https://github.com/example/api/blob/2222222222222222222222222222222222222222/server.go#L9"; got != want {
+		t.Fatalf("submodule URL = %s, want %s", got, want)
+	}
+	if res.ErrorCount != 0 {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+}
+
 func TestApplyLinkColumnSetsHasURLWhenHidden(t *testing.T) {
 	res := &engine.Result{Items: []engine.Item{{Commit: "1234567890abcdef1234567890abcdef12345678", File: "main.go", Line: 42}}}
 	sel := output.FieldSelection{NeedURL: true, ShowURL: false}
@@ -839,6 +903,27 @@ func TestWebRenderIncludesPRColumn(t *testing.T) {
 	}
 }
 
+func TestWebRenderIncludesOwnerColumn(t *testing.T) {
+	rt := goja.New()
+	for _, fn := range []string{"escText", "escAttr", "renderBadge", "normalizePRTooltip", "renderPRCell", "buildHeaderMeta", "renderTableCell", "renderResultTable"} {
+		if _, err := rt.RunString(extractJSFunction(t, fn)); err != nil {
+			t.Fatalf("failed to load %s: %v", fn, err)
+		}
+	}
+	script := `renderResultTable({items:[{kind:"TODO",author:"Bob",email:"bob@example.com",date:"2024-01-02",file:"api/main.go",line:8,commit:"abcdef1234567890",owners:["@org/backend","<script>"]}],errors:[],has_comment:false,has_message:false,has_age:false,has_url:false,has_prs:false,has_owners:true});`
+	value, err := rt.RunString(script)
+	if err != nil {
+		t.Fatalf("renderResultTable with owners failed: %v", err)
+	}
+	html := value.String()
+	if !strings.Contains(html, "data-key=\"owners\">OWNER</button>") {
+		t.Fatalf("OWNER header missing: %s", html)
+	}
+	if !strings.Contains(html, "@org/backend &lt;script&gt;") {
+		t.Fatalf("owners cell missing or unescaped: %s", html)
+	}
+}
+
 func TestWebRenderAppliesAriaSortAttributes(t *testing.T) {
 	rt := goja.New()
 	for _, fn := range []string{"escText", "escAttr", "renderBadge", "normalizePRTooltip", "renderPRCell", "buildHeaderMeta", "renderTableCell", "renderResultTable"} {
@@ -929,6 +1014,40 @@ return sorted.map(r=>{const v=r&&r.age_days;return (typeof v==="number" && isFin
 	}
 }
 
+func TestWebTrendChartはグループ別の系列を描画する(t *testing.T) {
+	rt := goja.New()
+	if _, err := rt.RunString("const TREND_MAX_SERIES = 8;"); err != nil {
+		t.Fatalf("failed to define constant: %v", err)
+	}
+	for _, fn := range []string{"escText", "trendSeries", "buildTrendChart"} {
+		if _, err := rt.RunString(extractJSFunction(t, fn)); err != nil {
+			t.Fatalf("failed to load %s: %v", fn, err)
+		}
+	}
+	script := `buildTrendChart({group_by:"dir",tags:["TODO"],points:[
+{date:"2024-01-07",commit:"a",total:1,tags:{TODO:1},groups:[{key:"src",total:1,tags:{TODO:1}}]},
+{date:"2024-01-14",commit:"b",total:3,tags:{TODO:3},groups:[{key:"src",total:2,tags:{TODO:2}},{key:"<docs>",total:1,tags:{TODO:1}}]}]});`
+	value, err := rt.RunString(script)
+	if err != nil {
+		t.Fatalf("buildTrendChart failed: %v", err)
+	}
+	html := value.String()
+	if strings.Count(html, "<polyline") != 2 {
+		t.Fatalf("グループごとに折れ線が必要です: %s", html)
+	}
+	if !strings.Contains(html, "src (2)") || !strings.Contains(html, "&lt;docs&gt; (1)") {
+		t.Fatalf("凡例に最新件数とエスケープ済みのキーが必要です: %s", html)
+	}
+
+	empty, err := rt.RunString(`buildTrendChart({tags:["TODO"],points:[]})`)
+	if err != nil {
+		t.Fatalf("buildTrendChart(empty) failed: %v", err)
+	}
+	if strings.Contains(empty.String(), "<svg") {
+		t.Fatalf("サンプルが無い場合はグラフを描画しない想定です: %s", empty.String())
+	}
+}
+
 func TestReportErrorsは標準エラーに概要を出力する(t *testing.T) {
 	r, w, err := os.Pipe()
 	if err != nil {
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+// fzfHint は -o fzf の出力を fzf に渡す例です（ヘルプと README にも載せています）。
+const fzfHint = `todox -o fzf | fzf --read0 --delimiter : --preview 'bat --color=always --highlight-line {2} {1}' --bind 'enter:become(todox open {1}:{2})'`
+
+// openTarget は todox open の引数です。index が 0 なら file と line を直接開きます。
+type openTarget struct {
+	index int
+	file  string
+	line  int
+}
+
+func openCmd(args []string) {
+	err := runOpen(args)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printOpenHelp()
+		return
+	}
+	fmt.Fprintf(os.Stderr, "todox open: %v\n", err)
+	var uerr *usageError
+	if errors.As(err, &uerr) {
+		printOpenHelp()
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+func printOpenHelp() {
+	fmt.Print("Usage: todox open N [scan options]\n" +
+		"       todox open FILE:LINE[:COL] [--repo DIR]\n\n" +
+		"Open an item in $VISUAL / $EDITOR (default: vi) at its line.\n" +
+		"N is the 1-based position in the report produced by the same scan options,\n" +
+		"i.e. the Nth item of the table or the Nth line of -o quickfix. FILE is\n" +
+		"relative to --repo, as printed by -o quickfix and -o fzf.\n\n" +
+		"Editors are started as EDITOR +LINE FILE; VS Code (code, codium, cursor) gets\n" +
+		"--goto FILE:LINE and subl/zed/hx get FILE:LINE.\n\n" +
+		"Examples:\n" +
+		"  todox --sort=-age --with-age      # find the oldest item ...\n" +
+		"  todox open 1 --sort=-age          # ... and jump to it\n" +
+		"  " + fzfHint + "\n")
+}
+
+func runOpen(args []string) error {
+	if len(args) == 0 {
+		return &usageError{err: errors.New("missing item number or FILE:LINE")}
+	}
+	switch args[0] {
+	case "-h", "--help", "help":
+		return flag.ErrHelp
+	}
+	target, err := parseOpenTarget(args[0])
+	if err != nil {
+		return &usageError{err: err}
+	}
+	rest := args[1:]
+	var path string
+	var line int
+	if target.index > 0 {
+		it, repo, err := nthItem(target.index, rest)
+		if err != nil {
+			return err
+		}
+		path, line = filepath.Join(repo, filepath.FromSlash(it.File)), it.Line
+	} else {
+		repo := "."
+		if v, ok := findFlagValue(rest, "--repo"); ok && strings.TrimSpace(v) != "" {
+			repo = strings.TrimSpace(v)
+		}
+		path, line = target.file, target.line
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repo, filepath.FromSlash(path))
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot open %s: %w", path, err)
+	}
+	return launchEditor(path, line)
+}
+
+// parseOpenTarget は項目番号（1 始まり）か FILE:LINE[:COL] を解釈します。
+func parseOpenTarget(raw string) (openTarget, error) {
+	raw = strings.TrimSpace(raw)
+	if n, err := strconv.Atoi(raw); err == nil {
+		if n < 1 {
+			return openTarget{}, fmt.Errorf("item number must be >= 1: %d", n)
+		}
+		return openTarget{index: n}, nil
+	}
+	segs := strings.Split(raw, ":")
+	// 末尾の数値（LINE と COL の最大 2 つ）を取り除いた残りがファイル名
+	nums := 0
+	for nums < 2 && len(segs)-nums > 1 {
+		if _, err := strconv.Atoi(segs[len(segs)-1-nums]); err != nil {
+			break
+		}
+		nums++
+	}
+	if nums == 0 {
+		return openTarget{}, fmt.Errorf("invalid target %q (want N or FILE:LINE)", raw)
+	}
+	file := strings.Join(segs[:len(segs)-nums], ":")
+	line, _ := strconv.Atoi(segs[len(segs)-nums])
+	if file == "" || line < 1 {
+		return openTarget{}, fmt.Errorf("invalid target %q (want N or FILE:LINE)", raw)
+	}
+	return openTarget{file: file, line: line}, nil
+}
+
+// nthItem は scan と同じオプション・並び順で走査し、n 件目の項目とリポジトリのパスを返します。
+func nthItem(n int, args []string) (engine.Item, string, error) {
+	cfg, err := parseScanArgs(args, "en")
+	if err != nil {
+		return engine.Item{}, "", err
+	}
+	if cfg.showHelp {
+		return engine.Item{}, "", flag.ErrHelp
+	}
+	sortSpec, err := ParseSortSpec(cfg.sortKey)
+	if err != nil {
+		return engine.Item{}, "", &usageError{err: err}
+	}
+	groupBy, err := ParseGroupBy(cfg.groupBy)
+	if err != nil {
+		return engine.Item{}, "", &usageError{err: err}
+	}
+	ctx := context.Background()
+	if cfg.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.opts.Timeout)
+		defer cancel()
+	}
+	res, err := engine.RunContext(ctx, cfg.opts)
+	if err != nil {
+		return engine.Item{}, "", err
+	}
+	ApplySort(res.Items, sortSpec)
+	if groupBy != "" {
+		ApplyGroup(res.Items, groupBy)
+	}
+	if n > len(res.Items) {
+		return engine.Item{}, "", fmt.Errorf("item %d is out of range (%d items)", n, len(res.Items))
+	}
+	return res.Items[n-1], cfg.opts.RepoDir, nil
+}
+
+// editorFromEnv は $VISUAL、なければ $EDITOR を返します（どちらもなければ空文字列）。
+func editorFromEnv() string {
+	if v := strings.TrimSpace(os.Getenv("VISUAL")); v != "" {
+		return v
+	}
+	return strings.TrimSpace(os.Getenv("EDITOR"))
+}
+
+// launchEditor はエディタを端末につないで起動し、終了を待ちます。
+func launchEditor(path string, line int) error {
+	args := editorCommand(editorFromEnv(), path, line)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseOpenTarget(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want openTarget
+	}{
+		{"3", openTarget{index: 3}},
+		{"a.go:12", openTarget{file: "a.go", line: 12}},
+		{"dir/a.go:12:5", openTarget{file: "dir/a.go", line: 12}},
+		{`C:\src\a.go:7`, openTarget{file: `C:\src\a.go`, line: 7}},
+		{"odd:name.go:4", openTarget{file: "odd:name.go", line: 4}},
+	}
+	for _, tc := range cases {
+		got, err := parseOpenTarget(tc.raw)
+		if err != nil || got != tc.want {
+			t.Errorf("parseOpenTarget(%q) = %+v, %v; want %+v", tc.raw, got, err, tc.want)
+		}
+	}
+	for _, raw := range []string{"0", "-2", "a.go", ":12", "a.go:0"} {
+		if _, err := parseOpenTarget(raw); err == nil {
+			t.Errorf("parseOpenTarget(%q) should fail", raw)
+		}
+	}
+}
+
+func TestRunOpenはN件目をエディタで開く(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.name", "alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a\n// TODO: first\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "b.go"), []byte("package b\n\n\n// FIXME: second\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "init")
+
+	// エディタの代わりに引数を書き出すだけのスクリプトを使う
+	record := filepath.Join(t.TempDir(), "args")
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho \"$@\" > "+record+"\n"), 0o755); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+	opened := func(args ...string) string {
+		t.Helper()
+		if err := runOpen(args); err != nil {
+			t.Fatalf("runOpen(%v) failed: %v", args, err)
+		}
+		data, err := os.ReadFile(record)
+		if err != nil {
+			t.Fatalf("editor was not started: %v", err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	if got, want := opened("2", "--repo", repo, "--no-progress"), "+4 "+filepath.Join(repo, "b.go"); got != want {
+		t.Fatalf("opened %q, want %q", got, want)
+	}
+	// 並び順のオプションも一覧と同じく効く
+	if got, want := opened("1", "--repo", repo, "--no-progress", "--sort", "-file"), "+4 "+filepath.Join(repo, "b.go"); got != want {
+		t.Fatalf("opened %q with --sort -file, want %q", got, want)
+	}
+	if got, want := opened("a.go:2:4", "--repo", repo), "+2 "+filepath.Join(repo, "a.go"); got != want {
+		t.Fatalf("opened %q for FILE:LINE, want %q", got, want)
+	}
+
+	if err := runOpen([]string{"3", "--repo", repo, "--no-progress"}); err == nil || !strings.Contains(err.Error(), "out of range (2 items)") {
+		t.Fatalf("expected an out-of-range error, got %v", err)
+	}
+	if err := runOpen([]string{"missing.go:1", "--repo", repo}); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
@@ -44,8 +44,10 @@ func ParseSortSpec(raw string) (SortSpec, error) {
 		case "date":
 			name = "age"
 			desc = !desc
-		case "author", "email", "type", "file", "line", "commit":
+		case "author", "email", "committer", "type", "file", "line", "commit":
 			// accepted as-is
+		case "owner", "owners":
+			name = "owner"
 		case "location":
 			keys = append(keys, SortKey{Name: "file", Desc: desc}, SortKey{Name: "line", Desc: desc})
 			continue
@@ -87,6 +89,13 @@ func ApplySort(items []engine.Item, spec SortSpec) {
 					}
 					return left.Email < right.Email
 				}
+			case "committer":
+				if left.Committer != right.Committer {
+					if key.Desc {
+						return left.Committer > right.Committer
+					}
+					return left.Committer < right.Committer
+				}
 			case "type":
 				if left.Kind != right.Kind {
 					if key.Desc {
@@ -115,6 +124,14 @@ func ApplySort(items []engine.Item, spec SortSpec) {
 					}
 					return left.Commit < right.Commit
 				}
+			case "owner":
+				lo, ro := strings.Join(left.Owners, " "), strings.Join(right.Owners, " ")
+				if lo != ro {
+					if key.Desc {
+						return lo > ro
+					}
+					return lo < ro
+				}
 			}
 		}
 		if left.File != right.File {
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/phyten/todox/internal/config"
+	"github.com/phyten/todox/internal/engine"
+	engineopts "github.com/phyten/todox/internal/engine/opts"
+	"github.com/phyten/todox/internal/output"
+)
+
+const (
+	defaultTrendEvery = "1w"
+	defaultTrendSince = "1y"
+)
+
+type trendConfig struct {
+	opts   engine.TrendOptions
+	output string
+}
+
+func trendCmd(args []string) {
+	cfg, err := parseTrendArgs(args, time.Now())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printTrendHelp()
+			return
+		}
+		fmt.Fprintf(os.Stderr, "todox trend: %v\n", err)
+		printTrendHelp()
+		os.Exit(2)
+	}
+	res, err := engine.Trend(cfg.opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "todox trend: %v\n", err)
+		os.Exit(1)
+	}
+	switch cfg.output {
+	case "json":
+		if err := writeJSONTrend(os.Stdout, res); err != nil {
+			fmt.Fprintf(os.Stderr, "todox trend: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		if err := output.WriteTrendCSV(os.Stdout, res); err != nil {
+			fmt.Fprintf(os.Stderr, "todox trend: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if res.ErrorCount > 0 {
+		for _, e := range res.Errors {
+			fmt.Fprintf(os.Stderr, "todox trend: %s:%d [%s] %s\n", e.File, e.Line, e.Stage, e.Message)
+		}
+		os.Exit(2)
+	}
+}
+
+func printTrendHelp() {
+	fmt.Print("Usage: todox trend [options]\n\n" +
+		"Sample first-parent history and count TODO/FIXME per tag at each point.\n" +
+		"Trees are read with git grep <rev>; no checkout is performed.\n\n" +
+		"Options:\n" +
+		"      --every INTERVAL       Sampling interval: h, d, w, mo, y (default: 1w)\n" +
+		"      --since WHEN           Start: interval ago (e.g. 1y) or YYYY-MM-DD (default: 1y)\n" +
+		"      --until WHEN           End: YYYY-MM-DD or RFC3339 (default: now)\n" +
+		"      --rev REV              History to follow (default: HEAD)\n" +
+		"      --group-by {dir|author} Additionally count per directory or blamed author\n" +
+		"      --dir-depth N          Directory depth for --group-by dir (default: 1)\n" +
+		"  -o, --output {csv|json}    Output format (default: csv)\n" +
+		"  -t, --type {todo|fixme|both}\n" +
+		"      --tags LIST            Override detection tags (repeatable / CSV)\n" +
+		"      --path LIST            Limit to pathspec(s) (repeatable / CSV)\n" +
+		"      --exclude LIST         Exclude pathspec/glob(s) (repeatable / CSV)\n" +
+		"      --path-regex REGEXP    Post-filter file paths by Go regexp\n" +
+		"      --exclude-typical      Apply typical excludes (vendor/**, node_modules/**, ...)\n" +
+		"      --detect {auto|parse|regex}\n" +
+		"      --no-ignore-ws         Do not pass -w to git blame (--group-by author)\n" +
+		"      --repo DIR             Repository root (default: .)\n\n" +
+		"Example:\n" +
+		"  todox trend --every 1mo --since 2y --group-by dir > trend.csv\n")
+}
+
+func parseTrendArgs(args []string, now time.Time) (trendConfig, error) {
+	var cfg trendConfig
+	repo := "."
+	if v, ok := findFlagValue(args, "--repo"); ok && strings.TrimSpace(v) != "" {
+		repo = strings.TrimSpace(v)
+	}
+	base, err := layeredEngineOptions(repo)
+	if err != nil {
+		return cfg, err
+	}
+
+	fs := flag.NewFlagSet("trend", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	every := fs.String("every", defaultTrendEvery, "sampling interval")
+	since := fs.String("since", defaultTrendSince, "start of the range")
+	until := fs.String("until", "", "end of the range")
+	rev := fs.String("rev", "HEAD", "history to follow")
+	groupBy := fs.String("group-by", "", "dir or author")
+	dirDepth := fs.Int("dir-depth", 1, "directory depth for --group-by dir")
+	out := fs.String("output", "csv", "csv or json")
+	fs.StringVar(out, "o", "csv", "csv or json")
+	typ := fs.String("type", base.Type, "todo|fixme|both")
+	fs.StringVar(typ, "t", base.Type, "todo|fixme|both")
+	detectMode := fs.String("detect", base.DetectMode, "auto|parse|regex")
+	excludeTypical := fs.Bool("exclude-typical", base.ExcludeTypical, "apply typical excludes")
+	noIgnoreWS := fs.Bool("no-ignore-ws", !base.IgnoreWS, "do not pass -w to git blame")
+	fs.String("repo", repo, "repository root")
+	var tags, paths, excludes, pathRegex multiFlag
+	fs.Var(&tags, "tags", "detection tags")
+	fs.Var(&paths, "path", "pathspec")
+	fs.Var(&excludes, "exclude", "exclude pathspec")
+	fs.Var(&pathRegex, "path-regex", "path regexp")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	opts := base
+	opts.RepoDir = repo
+	opts.Type = *typ
+	opts.DetectMode = *detectMode
+	opts.ExcludeTypical = *excludeTypical
+	opts.IgnoreWS = !*noIgnoreWS
+	if tags.WasSet() {
+		opts.Tags = tags.Slice()
+	}
+	if paths.WasSet() {
+		opts.Paths = paths.Slice()
+	}
+	if excludes.WasSet() {
+		opts.Excludes = excludes.Slice()
+	}
+	if pathRegex.WasSet() {
+		opts.PathRegex = pathRegex.Slice()
+		opts.PathRegexCompiled = nil
+	}
+	if err := engineopts.NormalizeAndValidate(&opts); err != nil {
+		return cfg, err
+	}
+
+	format := strings.ToLower(strings.TrimSpace(*out))
+	switch format {
+	case "csv", "json":
+	default:
+		return cfg, fmt.Errorf("invalid --output: %s (allowed: csv, json)", *out)
+	}
+	if *dirDepth < 1 {
+		return cfg, fmt.Errorf("--dir-depth must be >= 1")
+	}
+
+	trendOpts, err := buildTrendOptions(opts, *every, *since, *until, *rev, *groupBy, *dirDepth, now)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.opts = trendOpts
+	cfg.output = format
+	return cfg, nil
+}
+
+func buildTrendOptions(opts engine.Options, every, since, until, rev, groupBy string, dirDepth int, now time.Time) (engine.TrendOptions, error) {
+	interval, err := engine.ParseInterval(every)
+	if err != nil {
+		return engine.TrendOptions{}, fmt.Errorf("invalid --every: %w", err)
+	}
+	untilTime, err := engine.ParseUntil(until, now)
+	if err != nil {
+		return engine.TrendOptions{}, fmt.Errorf("invalid --until: %w", err)
+	}
+	sinceTime, err := engine.ParseSince(since, untilTime)
+	if err != nil {
+		return engine.TrendOptions{}, fmt.Errorf("invalid --since: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(groupBy)) {
+	case "", "none", "dir", "author":
+	default:
+		return engine.TrendOptions{}, fmt.Errorf("invalid --group-by: %s (allowed: dir, author)", groupBy)
+	}
+	return engine.TrendOptions{
+		Options:  opts,
+		Rev:      strings.TrimSpace(rev),
+		Every:    interval,
+		Since:    sinceTime,
+		Until:    untilTime,
+		GroupBy:  groupBy,
+		DirDepth: dirDepth,
+	}, nil
+}
+
+// layeredEngineOptions は既定値・設定ファイル・環境変数を重ねたエンジン設定を返します。
+func layeredEngineOptions(repoDir string) (engine.Options, error) {
+	envCfg, err := config.FromEnv(os.Getenv)
+	if err != nil {
+		return engine.Options{}, err
+	}
+	configPath, _, err := config.Find(repoDir, os.Getenv("TODOX_CONFIG"), os.Getenv("XDG_CONFIG_HOME"), os.Getenv("HOME"))
+	if err != nil {
+		return engine.Options{}, err
+	}
+	fileCfg, err := config.Load(configPath)
+	if err != nil {
+		return engine.Options{}, err
+	}
+	opts := engineopts.Defaults(repoDir)
+	merged := config.MergeEngine(config.EngineSettingsFromOptions(opts), fileCfg.Engine, envCfg.Engine)
+	merged.ApplyToOptions(&opts)
+	return opts, nil
+}
+
+func writeJSONTrend(w io.Writer, res *engine.TrendResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(res)
+}
+
+func lastQueryValue(q url.Values, key, def string) string {
+	if vals := engineopts.SplitMulti(q[key]); len(vals) > 0 {
+		return vals[len(vals)-1]
+	}
+	return def
+}
+
+// apiTrendHandler は /api/trend で件数推移を JSON として返します。
+// 検出関連のパラメータは /api/scan と同じものを受け付けます。
+func apiTrendHandler(repoDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		// group_by は trend 独自の値（dir|author）を取るため、スキャン用の解釈からは外す。
+		scanQuery := url.Values{}
+		for k, v := range q {
+			if k != "group_by" {
+				scanQuery[k] = v
+			}
+		}
+		inputs, err := prepareScanInputs(repoDir, scanQuery)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		inputs.Options.Progress = false
+		inputs.Options.ProgressObserver = nil
+
+		dirDepth := 1
+		if raw := lastQueryValue(q, "dir_depth", ""); raw != "" {
+			dirDepth, err = engineopts.ParseIntInRange(raw, "dir_depth", 1, 32)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		opts, err := buildTrendOptions(inputs.Options,
+			lastQueryValue(q, "every", defaultTrendEvery),
+			lastQueryValue(q, "since", defaultTrendSince),
+			lastQueryValue(q, "until", ""),
+			lastQueryValue(q, "rev", "HEAD"),
+			lastQueryValue(q, "group_by", ""),
+			dirDepth, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := engine.TrendContext(r.Context(), opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
@@ -0,0 +1,942 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phyten/todox/internal/engine"
+	engineopts "github.com/phyten/todox/internal/engine/opts"
+	"github.com/phyten/todox/internal/execx"
+	ghclient "github.com/phyten/todox/internal/host/github"
+	"github.com/phyten/todox/internal/link"
+	"github.com/phyten/todox/internal/progress"
+	"github.com/phyten/todox/internal/termcolor"
+	"github.com/phyten/todox/internal/textutil"
+	"github.com/pkg/browser"
+	"golang.org/x/term"
+)
+
+const defaultTUIContext = 5
+
+type tuiConfig struct {
+	opts    engine.Options
+	groupBy string
+	withPRs bool
+	context int // プレビューに表示する前後の行数
+}
+
+func tuiCmd(args []string) {
+	cfg, err := parseTUIArgs(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printTUIHelp()
+			return
+		}
+		fmt.Fprintf(os.Stderr, "todox tui: %v\n", err)
+		printTUIHelp()
+		os.Exit(2)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintln(os.Stderr, "todox tui: stdin and stdout must be a terminal")
+		os.Exit(2)
+	}
+	res, err := engine.RunContext(context.Background(), cfg.opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "todox tui: %v\n", err)
+		os.Exit(1)
+	}
+	app := newTUIApp(cfg, res)
+	if err := app.run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "todox tui: %v\n", err)
+		os.Exit(1)
+	}
+	if res.ErrorCount > 0 {
+		reportErrors(res)
+	}
+}
+
+func printTUIHelp() {
+	fmt.Print("Usage: todox tui [options]\n\n" +
+		"Browse TODO/FIXME interactively in a full-screen terminal UI.\n" +
+		"The repository is scanned and blamed once; filtering and grouping happen in place.\n" +
+		"Detection settings come from .todox.yaml and TODOX_* environment variables.\n\n" +
+		"Options:\n" +
+		"  -t, --type {todo|fixme|both}\n" +
+		"      --tags LIST            Override detection tags (repeatable / CSV)\n" +
+		"  -a, --author REGEXP        Filter by author name or email\n" +
+		"      --path LIST            Limit to pathspec(s) (repeatable / CSV)\n" +
+		"      --exclude LIST         Exclude pathspec/glob(s) (repeatable / CSV)\n" +
+		"      --path-regex REGEXP    Post-filter file paths by Go regexp\n" +
+		"      --exclude-typical      Apply typical excludes (vendor/**, node_modules/**, ...)\n" +
+		"      --detect {auto|parse|regex}\n" +
+		"      --group-by {file|author|type|owners}\n" +
+		"                             Initial grouping (toggle with Tab)\n" +
+		"      --context N            Source lines shown around the item in the preview (default: 5)\n" +
+		"      --with-pr-links        Look up pull requests for the selected item's commit\n" +
+		"      --timeout DURATION     Abort the scan, and each remote or pull request lookup, after DURATION\n" +
+		"      --repo DIR             Repository root (default: .)\n\n" +
+		"Keys:\n" +
+		"  ↑/↓ j/k  move        PgUp/PgDn  page        g/G Home/End  first/last\n" +
+		"  /        search as you type (Enter keeps the filter, Esc clears it)\n" +
+		"  Tab      cycle grouping: none, file, author, type (owners with CODEOWNERS)\n" +
+		"  p        toggle the preview pane\n" +
+		"  Enter/e  open the file at the line in $VISUAL / $EDITOR (default: vi)\n" +
+		"  o        open the commit URL        P  open the pull request URL\n" +
+		"  q        quit\n")
+}
+
+func parseTUIArgs(args []string) (tuiConfig, error) {
+	var cfg tuiConfig
+	repo := "."
+	if v, ok := findFlagValue(args, "--repo"); ok && strings.TrimSpace(v) != "" {
+		repo = strings.TrimSpace(v)
+	}
+	base, err := layeredEngineOptions(repo)
+	if err != nil {
+		return cfg, err
+	}
+
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	typ := fs.String("type", base.Type, "todo|fixme|both")
+	fs.StringVar(typ, "t", base.Type, "todo|fixme|both")
+	author := fs.String("author", base.AuthorRegex, "author regexp")
+	fs.StringVar(author, "a", base.AuthorRegex, "author regexp")
+	detectMode := fs.String("detect", base.DetectMode, "auto|parse|regex")
+	excludeTypical := fs.Bool("exclude-typical", base.ExcludeTypical, "apply typical excludes")
+	groupBy := fs.String("group-by", "", "file|author|type|owners")
+	contextLines := fs.Int("context", defaultTUIContext, "preview context lines")
+	withPRs := fs.Bool("with-pr-links", false, "look up pull requests")
+	timeout := fs.String("timeout", "", "abort the scan and each lookup after this duration")
+	fs.String("repo", repo, "repository root")
+	var tags, paths, excludes, pathRegex multiFlag
+	fs.Var(&tags, "tags", "detection tags")
+	fs.Var(&paths, "path", "pathspec")
+	fs.Var(&excludes, "exclude", "exclude pathspec")
+	fs.Var(&pathRegex, "path-regex", "path regexp")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	opts := base
+	opts.RepoDir = repo
+	opts.Type = *typ
+	opts.AuthorRegex = *author
+	opts.DetectMode = *detectMode
+	opts.ExcludeTypical = *excludeTypical
+	if tags.WasSet() {
+		opts.Tags = tags.Slice()
+	}
+	if paths.WasSet() {
+		opts.Paths = paths.Slice()
+	}
+	if excludes.WasSet() {
+		opts.Excludes = excludes.Slice()
+	}
+	if pathRegex.WasSet() {
+		opts.PathRegex = pathRegex.Slice()
+		opts.PathRegexCompiled = nil
+	}
+	if *timeout != "" {
+		d, err := engineopts.ParseDuration(*timeout, "--timeout")
+		if err != nil {
+			return cfg, err
+		}
+		opts.Timeout = d
+	}
+	// プレビューにはコメント本文とコミットメッセージを出す。
+	opts.WithComment = true
+	opts.WithMessage = true
+	opts.Progress = progress.ShouldShowProgress(false, false)
+	if err := engineopts.NormalizeAndValidate(&opts); err != nil {
+		return cfg, err
+	}
+	group, err := ParseGroupBy(*groupBy)
+	if err != nil {
+		return cfg, err
+	}
+	if *contextLines < 0 {
+		return cfg, fmt.Errorf("--context must be >= 0")
+	}
+	cfg.opts = opts
+	cfg.groupBy = group
+	cfg.withPRs = *withPRs
+	cfg.context = *contextLines
+	return cfg, nil
+}
+
+// tuiAction はキー操作の結果、画面の外で行う処理です。
+type tuiAction int
+
+const (
+	tuiNone tuiAction = iota
+	tuiQuit
+	tuiEdit
+	tuiOpenCommit
+	tuiOpenPR
+)
+
+// キー名。通常の文字は 1 文字の文字列のまま扱います。
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdown"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyBackspace = "backspace"
+	keyTab       = "tab"
+	keyBackTab   = "backtab"
+	keyCtrlC     = "ctrl-c"
+	keyCtrlU     = "ctrl-u"
+)
+
+// tuiRow はリストの 1 行です。header が空でなければグループの見出しです。
+type tuiRow struct {
+	header string
+	item   int // items の添字
+}
+
+// tuiModel は画面に依存しない TUI の状態です。絞り込み・グループ化・カーソル移動を扱います。
+type tuiModel struct {
+	items     []engine.Item
+	groupKeys []string // Tab で切り替えるグループ化（先頭の "" はグループ化なし）
+	groupIdx  int
+	query     string
+	searching bool
+	preview   bool
+	rows      []tuiRow
+	matched   int
+	cursor    int // rows の添字（項目があれば常に項目行を指す）
+	offset    int // リストの先頭に表示している rows の添字
+	page      int // 直前に描画したリストの行数（PgUp/PgDn の移動量）
+	status    string
+}
+
+func newTUIModel(items []engine.Item, groupBy string, hasOwners bool) *tuiModel {
+	keys := []string{"", "file", "author", "type"}
+	if hasOwners || groupBy == "owners" {
+		keys = append(keys, "owners")
+	}
+	m := &tuiModel{items: items, groupKeys: keys, preview: true}
+	for i, k := range keys {
+		if k == groupBy {
+			m.groupIdx = i
+		}
+	}
+	m.refilter()
+	return m
+}
+
+func (m *tuiModel) groupBy() string {
+	return m.groupKeys[m.groupIdx]
+}
+
+// selected は選択中の項目を返します。
+func (m *tuiModel) selected() (engine.Item, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.rows) || m.rows[m.cursor].header != "" {
+		return engine.Item{}, false
+	}
+	return m.items[m.rows[m.cursor].item], true
+}
+
+// refilter は検索語とグループ化から行を組み立て直します。選択中の項目が残っていればその上にカーソルを置きます。
+func (m *tuiModel) refilter() {
+	prev := -1
+	if m.cursor >= 0 && m.cursor < len(m.rows) && m.rows[m.cursor].header == "" {
+		prev = m.rows[m.cursor].item
+	}
+	terms := strings.Fields(strings.ToLower(m.query))
+	idx := make([]int, 0, len(m.items))
+	for i, it := range m.items {
+		if tuiMatches(it, terms) {
+			idx = append(idx, i)
+		}
+	}
+	m.matched = len(idx)
+	m.rows = m.rows[:0]
+	groupBy := m.groupBy()
+	if groupBy == "" {
+		for _, i := range idx {
+			m.rows = append(m.rows, tuiRow{item: i})
+		}
+	} else {
+		subset := make([]engine.Item, len(idx))
+		for k, i := range idx {
+			subset[k] = m.items[i]
+		}
+		groups := ApplyGroup(subset, groupBy)
+		rank := make(map[string]int, len(groups))
+		for i, g := range groups {
+			rank[g.Key] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			return rank[groupKey(m.items[idx[a]], groupBy)] < rank[groupKey(m.items[idx[b]], groupBy)]
+		})
+		prevKey := ""
+		for k, i := range idx {
+			key := groupKey(m.items[i], groupBy)
+			if k == 0 || key != prevKey {
+				m.rows = append(m.rows, tuiRow{header: fmt.Sprintf("%s (%d)", key, groups[rank[key]].Count)})
+				prevKey = key
+			}
+			m.rows = append(m.rows, tuiRow{item: i})
+		}
+	}
+	m.cursor, m.offset = 0, 0
+	for r, row := range m.rows {
+		if row.header == "" && row.item == prev {
+			m.cursor = r
+			return
+		}
+	}
+	for m.cursor < len(m.rows)-1 && m.rows[m.cursor].header != "" {
+		m.cursor++
+	}
+}
+
+// tuiMatches はすべての検索語が種類・場所・作者・本文のどこかに含まれるかを返します。
+func tuiMatches(it engine.Item, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	hay := strings.ToLower(strings.Join([]string{it.Tag, it.Kind, fmt.Sprintf("%s:%d", it.File, it.Line), it.Author, it.Email, it.Text, it.Comment, strings.Join(it.Owners, " ")}, "\x00"))
+	for _, t := range terms {
+		if !strings.Contains(hay, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// move は項目行を delta 個分だけ移動します（見出しは数えません）。
+func (m *tuiModel) move(delta int) {
+	if len(m.rows) == 0 {
+		return
+	}
+	dir := 1
+	if delta < 0 {
+		dir, delta = -1, -delta
+	}
+	for pos := m.cursor; delta > 0; {
+		pos += dir
+		if pos < 0 || pos >= len(m.rows) {
+			break
+		}
+		if m.rows[pos].header == "" {
+			m.cursor = pos
+			delta--
+		}
+	}
+}
+
+// scroll はカーソル行が高さ height のリストに収まるよう表示位置を調整します。
+// グループ先頭の項目では見出しも見えるようにします。
+func (m *tuiModel) scroll(height int) {
+	if height <= 0 {
+		return
+	}
+	top := m.cursor
+	if top > 0 && m.rows[top-1].header != "" {
+		top--
+	}
+	if top < m.offset {
+		m.offset = top
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = clampInt(m.offset, 0, max(len(m.rows)-height, 0))
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+}
+
+// handleKey は 1 キー分の操作を反映し、画面の外で行う処理を返します。
+func (m *tuiModel) handleKey(key string) tuiAction {
+	m.status = ""
+	page := max(m.page, 1)
+	if m.searching {
+		switch key {
+		case keyEnter:
+			m.searching = false
+		case keyEsc:
+			m.searching = false
+			m.query = ""
+			m.refilter()
+		case keyBackspace:
+			if m.query != "" {
+				_, size := utf8.DecodeLastRuneInString(m.query)
+				m.query = m.query[:len(m.query)-size]
+				m.refilter()
+			}
+		case keyCtrlU:
+			m.query = ""
+			m.refilter()
+		case keyCtrlC:
+			return tuiQuit
+		case keyUp:
+			m.move(-1)
+		case keyDown:
+			m.move(1)
+		default:
+			if r, _ := utf8.DecodeRuneInString(key); utf8.RuneCountInString(key) == 1 && unicode.IsPrint(r) {
+				m.query += key
+				m.refilter()
+			}
+		}
+		return tuiNone
+	}
+	switch key {
+	case "q", keyCtrlC:
+		return tuiQuit
+	case "j", keyDown:
+		m.move(1)
+	case "k", keyUp:
+		m.move(-1)
+	case keyPageDown, " ":
+		m.move(page)
+	case keyPageUp:
+		m.move(-page)
+	case "g", keyHome:
+		m.move(-len(m.rows))
+	case "G", keyEnd:
+		m.move(len(m.rows))
+	case "/":
+		m.searching = true
+	case keyEsc:
+		if m.query != "" {
+			m.query = ""
+			m.refilter()
+		}
+	case keyTab:
+		m.groupIdx = (m.groupIdx + 1) % len(m.groupKeys)
+		m.refilter()
+	case keyBackTab:
+		m.groupIdx = (m.groupIdx + len(m.groupKeys) - 1) % len(m.groupKeys)
+		m.refilter()
+	case "p":
+		m.preview = !m.preview
+	case keyEnter, "e":
+		return m.withSelection(tuiEdit)
+	case "o":
+		return m.withSelection(tuiOpenCommit)
+	case "P":
+		return m.withSelection(tuiOpenPR)
+	}
+	return tuiNone
+}
+
+func (m *tuiModel) withSelection(action tuiAction) tuiAction {
+	if _, ok := m.selected(); !ok {
+		m.status = "no item selected"
+		return tuiNone
+	}
+	return action
+}
+
+// layout は画面の行数を見出し・リスト・プレビュー・フッターに割り振ります。
+func (m *tuiModel) layout(height, previewLines int) (list, preview int) {
+	body := height - 2 // 見出しとフッター
+	if body < 1 {
+		return 1, 0
+	}
+	if !m.preview || previewLines == 0 || body < 6 {
+		return body, 0
+	}
+	preview = min(previewLines, body/2)
+	return body - preview - 1, preview // 区切り線の 1 行
+}
+
+// render は width × height の画面を行ごとに返します。preview は選択中の項目のプレビューです。
+func (m *tuiModel) render(width, height int, colors tableColorConfig, preview []string) []string {
+	listH, previewH := m.layout(height, len(preview))
+	m.page = listH
+	m.scroll(listH)
+	lines := make([]string, 0, height)
+
+	title := fmt.Sprintf(" todox  %d/%d items", m.matched, len(m.items))
+	if g := m.groupBy(); g != "" {
+		title += "  group: " + g
+	}
+	if m.query != "" {
+		title += "  search: " + m.query
+	}
+	lines = append(lines, styleLine(termcolor.HeaderStyle(), title, width, colors.enabled))
+
+	authorW := 0
+	for _, row := range m.rows {
+		if row.header == "" {
+			authorW = max(authorW, textutil.VisibleWidth(sanitizeField(m.items[row.item].Author)))
+		}
+	}
+	authorW = min(authorW, max(width/6, 8))
+	for i := 0; i < listH; i++ {
+		r := m.offset + i
+		switch {
+		case r >= len(m.rows):
+			if len(m.rows) == 0 && i == 0 {
+				lines = append(lines, " no matching items")
+			} else {
+				lines = append(lines, "")
+			}
+		case m.rows[r].header != "":
+			lines = append(lines, styleLine(termcolor.Style{Bold: true}, "== "+sanitizeField(m.rows[r].header)+" ==", width, colors.enabled))
+		default:
+			lines = append(lines, m.renderItem(m.items[m.rows[r].item], r == m.cursor, width, authorW, colors))
+		}
+	}
+
+	if previewH > 0 {
+		lines = append(lines, styleLine(termcolor.Style{Dim: true}, strings.Repeat("─", width), width, colors.enabled))
+		for _, l := range preview[:previewH] {
+			lines = append(lines, textutil.TruncateByWidth(sanitizeField(l), width, "…"))
+		}
+	}
+
+	var footer string
+	switch {
+	case m.searching:
+		footer = "/" + m.query + "▏"
+	case m.status != "":
+		footer = m.status
+	default:
+		footer = "↑↓ move  / search  Tab group  p preview  Enter edit  o commit  P PR  q quit"
+	}
+	lines = append(lines, styleLine(termcolor.Style{Dim: !m.searching}, footer, width, colors.enabled))
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines[:height]
+}
+
+// renderItem は「種類 経過日数 作者 場所 本文」の 1 行を組み立てます。選択行は反転表示にします。
+func (m *tuiModel) renderItem(it engine.Item, selected bool, width, authorW int, colors tableColorConfig) string {
+	kind := textutil.PadRight(it.Kind, 5)
+	age := textutil.PadLeft(fmt.Sprintf("%dd", it.AgeDays), 5)
+	if it.Commit == "" {
+		age = textutil.PadLeft("-", 5)
+	}
+	author := textutil.PadRight(textutil.TruncateByWidth(sanitizeField(it.Author), authorW, "…"), authorW)
+	loc := fmt.Sprintf("%s:%d", it.File, it.Line)
+	locW := min(textutil.VisibleWidth(loc), max(width/3, 12))
+	loc = textutil.TruncateByWidth(loc, locW, "…")
+	text := sanitizeField(strings.TrimSpace(it.Text))
+
+	prefix := fmt.Sprintf("%s %s  %s  %s  ", kind, age, author, loc)
+	if !colors.enabled {
+		// 色が使えない端末では先頭の印で選択行を示す
+		mark := "  "
+		if selected {
+			mark = "> "
+		}
+		return textutil.TruncateByWidth(mark+prefix+text, width, "…")
+	}
+	if selected {
+		plain := textutil.TruncateByWidth(prefix+text, width, "…")
+		return "\x1b[7m" + textutil.PadRight(plain, width) + "\x1b[0m"
+	}
+	text = textutil.TruncateByWidth(text, width-textutil.VisibleWidth(prefix), "…")
+	ageStyle := termcolor.Style{Dim: true}
+	if it.Commit != "" {
+		ageStyle = ageCellStyle(it.AgeDays, colors)
+	}
+	return termcolor.Apply(termcolor.TypeStyle(it.Kind, colors.scheme, colors.profile), kind, true) + " " +
+		termcolor.Apply(ageStyle, age, true) + "  " + author + "  " +
+		termcolor.Apply(termcolor.Style{Dim: true}, loc, true) + "  " + text
+}
+
+func styleLine(style termcolor.Style, text string, width int, enabled bool) string {
+	return termcolor.Apply(style, textutil.TruncateByWidth(text, width, "…"), enabled)
+}
+
+// tuiPreview は項目の前後 context 行のソースと、コミットの情報を並べます。lines は nil でも構いません。
+func tuiPreview(it engine.Item, lines []string, context int) []string {
+	out := make([]string, 0, 2*context+4)
+	head := fmt.Sprintf("%s:%d", it.File, it.Line)
+	switch {
+	case it.Commit != "":
+		head += fmt.Sprintf("  %s  %s <%s>  %s (%s)", shortSHA(it.Commit), it.Author, it.Email, it.Date, ageText(it.AgeDays))
+	case it.Author != "":
+		head += "  " + it.Author
+	}
+	out = append(out, head)
+	if msg := strings.TrimSpace(it.Message); msg != "" {
+		out = append(out, "    "+msg)
+	}
+	if len(it.PRs) > 0 {
+		pr := it.PRs[0]
+		out = append(out, fmt.Sprintf("    #%d %s (%s)", pr.Number, pr.Title, strings.ToLower(pr.State)))
+	}
+	if len(lines) == 0 {
+		return append(out, "", "    (source unavailable)")
+	}
+	out = append(out, "")
+	from := max(it.Line-context, 1)
+	to := min(it.Line+context, len(lines))
+	numW := len(fmt.Sprint(to))
+	for n := from; n <= to; n++ {
+		mark := " "
+		if n == it.Line {
+			mark = "▶"
+		}
+		out = append(out, fmt.Sprintf("%s %*d │ %s", mark, numW, n, strings.ReplaceAll(lines[n-1], "\t", "    ")))
+	}
+	return out
+}
+
+// editorCommand は path の line 行目を開くエディタのコマンドを組み立てます。
+// editor は $VISUAL / $EDITOR の値で、引数を含んでいても構いません。
+func editorCommand(editor, path string, line int) []string {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	switch strings.TrimSuffix(filepath.Base(args[0]), ".exe") {
+	case "code", "code-insiders", "codium", "cursor":
+		return append(args, "--goto", fmt.Sprintf("%s:%d", path, line))
+	case "subl", "zed", "hx", "helix":
+		return append(args, fmt.Sprintf("%s:%d", path, line))
+	}
+	return append(args, fmt.Sprintf("+%d", line), path)
+}
+
+// readKey は端末から 1 キー分を読みます。矢印キーなどのエスケープシーケンスはキー名に変換します。
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 3:
+		return keyCtrlC, nil
+	case '\r', '\n':
+		return keyEnter, nil
+	case 127, 8:
+		return keyBackspace, nil
+	case '\t':
+		return keyTab, nil
+	case 14: // Ctrl-N
+		return keyDown, nil
+	case 16: // Ctrl-P
+		return keyUp, nil
+	case 21:
+		return keyCtrlU, nil
+	case 27:
+		if r.Buffered() == 0 {
+			return keyEsc, nil
+		}
+		return readEscape(r)
+	}
+	if b < utf8.RuneSelf {
+		return string(rune(b)), nil
+	}
+	if err := r.UnreadByte(); err != nil {
+		return "", err
+	}
+	ru, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return string(ru), nil
+}
+
+func readEscape(r *bufio.Reader) (string, error) {
+	intro, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if intro != '[' && intro != 'O' {
+		return keyEsc, nil
+	}
+	var param []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c >= '0' && c <= '9' || c == ';' {
+			param = append(param, c)
+			continue
+		}
+		switch c {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'H':
+			return keyHome, nil
+		case 'F':
+			return keyEnd, nil
+		case 'Z':
+			return keyBackTab, nil
+		case '~':
+			switch string(param) {
+			case "5":
+				return keyPageUp, nil
+			case "6":
+				return keyPageDown, nil
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			}
+		}
+		return "", nil
+	}
+}
+
+// tuiApp は tuiModel を端末に描画し、エディタやブラウザを起動します。
+type tuiApp struct {
+	cfg     tuiConfig
+	model   *tuiModel
+	colors  tableColorConfig
+	runner  execx.Runner
+	remotes remoteInfoCache
+	sources map[string][]string // プレビュー用に読み込んだファイル（読めなければ nil）
+	prs     map[string][]engine.PullRequestRef
+}
+
+func newTUIApp(cfg tuiConfig, res *engine.Result) *tuiApp {
+	env := toEnvMap(os.Environ())
+	colors := tableColorConfig{enabled: termcolor.DetectMode(os.Stdout, env) == termcolor.ModeAlways}
+	if colors.enabled {
+		colors.profile = termcolor.DetectProfile(env)
+		colors.scheme = termcolor.DetectScheme(env)
+		colors.ageScale = computeAgeScale(res.Items)
+	}
+	return &tuiApp{
+		cfg:     cfg,
+		model:   newTUIModel(res.Items, cfg.groupBy, res.HasOwners),
+		colors:  colors,
+		runner:  execx.DefaultRunner(),
+		sources: make(map[string][]string),
+		prs:     make(map[string][]engine.PullRequestRef),
+	}
+}
+
+// run は端末を raw モードと代替画面に切り替え、q で終了するまでキー入力を処理します。
+func (a *tuiApp) run(in, out *os.File) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+	// xdg-open などの出力で画面が崩れないようにする
+	browser.Stdout, browser.Stderr = io.Discard, io.Discard
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	// エディタの実行中に入力を横取りしないよう、キーは要求したときだけ読む
+	want := make(chan struct{})
+	keys := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(in)
+		for range want {
+			k, err := readKey(r)
+			if err != nil {
+				errc <- err
+				return
+			}
+			keys <- k
+		}
+	}()
+	defer close(want)
+
+	// 端末の大きさは定期的に調べ、変わったときだけ描き直す
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	size := func() (int, int) {
+		w, h, err := term.GetSize(int(out.Fd()))
+		if err != nil || w <= 0 || h <= 0 {
+			return 80, 24
+		}
+		return w, h
+	}
+	width, height := size()
+	a.draw(out, width, height)
+	want <- struct{}{}
+	for {
+		select {
+		case <-ticker.C:
+			if w, h := size(); w != width || h != height {
+				width, height = w, h
+				a.draw(out, width, height)
+			}
+		case k := <-keys:
+			if a.dispatch(k, fd, state, out) {
+				return nil
+			}
+			width, height = size()
+			a.draw(out, width, height)
+			want <- struct{}{}
+		case err := <-errc:
+			return err
+		}
+	}
+}
+
+// dispatch はキーを処理し、終了するなら true を返します。
+func (a *tuiApp) dispatch(key string, fd int, state *term.State, out *os.File) bool {
+	switch a.model.handleKey(key) {
+	case tuiQuit:
+		return true
+	case tuiEdit:
+		it, _ := a.model.selected()
+		a.edit(it, fd, state, out)
+	case tuiOpenCommit:
+		it, _ := a.model.selected()
+		a.openCommit(it)
+	case tuiOpenPR:
+		it, _ := a.model.selected()
+		a.openPR(it)
+	}
+	return false
+}
+
+func (a *tuiApp) draw(out io.Writer, width, height int) {
+	var preview []string
+	if it, ok := a.model.selected(); ok && a.model.preview {
+		preview = tuiPreview(it, a.source(it.File), a.cfg.context)
+	}
+	lines := a.model.render(width, height, a.colors, preview)
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\x1b[K")
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	_, _ = io.WriteString(out, b.String())
+}
+
+func (a *tuiApp) source(file string) []string {
+	if lines, ok := a.sources[file]; ok {
+		return lines
+	}
+	var lines []string
+	if data, err := os.ReadFile(filepath.Join(a.cfg.opts.RepoDir, filepath.FromSlash(file))); err == nil {
+		lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	}
+	a.sources[file] = lines
+	return lines
+}
+
+// edit は端末を元に戻してエディタを起動し、終わったら TUI に戻ります。
+func (a *tuiApp) edit(it engine.Item, fd int, state *term.State, out *os.File) {
+	path := filepath.Join(a.cfg.opts.RepoDir, filepath.FromSlash(it.File))
+	args := editorCommand(editorFromEnv(), path, it.Line)
+	fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+	_ = term.Restore(fd, state)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, out, os.Stderr
+	err := cmd.Run()
+	if _, rerr := term.MakeRaw(fd); rerr != nil && err == nil {
+		err = rerr
+	}
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	delete(a.sources, it.File)
+	if err != nil {
+		a.model.status = fmt.Sprintf("%s: %v", args[0], err)
+	}
+}
+
+func (a *tuiApp) openCommit(it engine.Item) {
+	if it.Commit == "" {
+		a.model.status = "not committed yet"
+		return
+	}
+	ctx, cancel := a.lookupContext()
+	defer cancel()
+	info, err := a.remotes.Get(ctx, a.runner, a.cfg.opts.RepoDir)
+	if err != nil {
+		a.model.status = err.Error()
+		return
+	}
+	a.open(link.Commit(info, it.Commit), "no commit URL for this remote")
+}
+
+// openPR はコミットを含む最初の PR を開きます。--with-pr-links がなければ案内だけを出します。
+func (a *tuiApp) openPR(it engine.Item) {
+	if !a.cfg.withPRs {
+		a.model.status = "pull request lookup is disabled (run with --with-pr-links)"
+		return
+	}
+	if it.Commit == "" {
+		a.model.status = "not committed yet"
+		return
+	}
+	prs, ok := a.prs[it.Commit]
+	if !ok {
+		a.model.status = "looking up pull requests…"
+		ctx, cancel := a.lookupContext()
+		defer cancel()
+		info, err := a.remotes.Get(ctx, a.runner, a.cfg.opts.RepoDir)
+		if err != nil {
+			a.model.status = err.Error()
+			return
+		}
+		found, err := ghclient.NewClient(info, a.cfg.opts.RepoDir, a.runner).FindPullRequestsByCommit(ctx, it.Commit)
+		if err != nil {
+			a.model.status = err.Error()
+			return
+		}
+		for _, pr := range found {
+			prs = append(prs, engine.PullRequestRef{Number: pr.Number, State: pr.State, URL: pr.URL, Title: pr.Title})
+		}
+		a.prs[it.Commit] = prs
+	}
+	if len(prs) == 0 {
+		a.model.status = "no pull requests contain " + shortSHA(it.Commit)
+		return
+	}
+	a.open(prs[0].URL, "the pull request has no URL")
+}
+
+// lookupContext は remote や PR の問い合わせ 1 回分の ctx です。--timeout があればその時間で打ち切ります。
+func (a *tuiApp) lookupContext() (context.Context, context.CancelFunc) {
+	if a.cfg.opts.Timeout > 0 {
+		return context.WithTimeout(context.Background(), a.cfg.opts.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (a *tuiApp) open(u, missing string) {
+	if u == "" {
+		a.model.status = missing
+		return
+	}
+	if err := browser.OpenURL(u); err != nil {
+		a.model.status = err.Error()
+		return
+	}
+	a.model.status = "opened " + u
+}
+
+func clampInt(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	return min(max(v, lo), hi)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/textutil"
+)
+
+func tuiTestItems() []engine.Item {
+	return []engine.Item{
+		{Kind: "TODO", Tag: "TODO", File: "a.go", Line: 3, Author: "alice", Text: "// TODO: handle errors", Commit: "1111111aaaa", AgeDays: 3},
+		{Kind: "FIXME", Tag: "FIXME", File: "b.go", Line: 10, Author: "bob", Text: "// FIXME: leaks", Commit: "2222222bbbb", AgeDays: 40},
+		{Kind: "TODO", Tag: "TODO", File: "a.go", Line: 8, Author: "bob", Text: "// TODO: write docs", Commit: "3333333cccc", AgeDays: 10},
+	}
+}
+
+func TestTUIModelはインクリメンタル検索で絞り込む(t *testing.T) {
+	m := newTUIModel(tuiTestItems(), "", false)
+	if m.matched != 3 || len(m.rows) != 3 {
+		t.Fatalf("expected every item, got matched=%d rows=%d", m.matched, len(m.rows))
+	}
+	m.handleKey("j")
+	if it, _ := m.selected(); it.File != "b.go" {
+		t.Fatalf("cursor should move to b.go, got %+v", it)
+	}
+	for _, k := range []string{"/", "b", "o", "b"} {
+		m.handleKey(k)
+	}
+	if !m.searching || m.query != "bob" || m.matched != 2 {
+		t.Fatalf("unexpected search state: searching=%v query=%q matched=%d", m.searching, m.query, m.matched)
+	}
+	if it, _ := m.selected(); it.File != "b.go" {
+		t.Fatalf("selection should survive filtering, got %+v", it)
+	}
+	// 複数の語はすべて含むものに絞る
+	m.handleKey(" ")
+	m.handleKey("d")
+	m.handleKey("o")
+	if m.matched != 1 {
+		t.Fatalf("expected one item for %q, got %d", m.query, m.matched)
+	}
+	if it, _ := m.selected(); it.Line != 8 {
+		t.Fatalf("cursor should fall back to the first match, got %+v", it)
+	}
+	m.handleKey(keyBackspace)
+	m.handleKey(keyEnter)
+	if m.searching || m.query != "bob d" {
+		t.Fatalf("Enter should keep the filter: searching=%v query=%q", m.searching, m.query)
+	}
+	if m.handleKey("q") != tuiQuit {
+		t.Fatal("q should quit outside of search mode")
+	}
+	m.handleKey(keyEsc)
+	if m.query != "" || m.matched != 3 {
+		t.Fatalf("Esc should clear the filter: query=%q matched=%d", m.query, m.matched)
+	}
+	m.handleKey("/")
+	m.handleKey("zzz")
+	if m.query != "" {
+		t.Fatalf("multi-rune keys must not be typed into the query: %q", m.query)
+	}
+	m.handleKey("z")
+	if m.matched != 0 || m.handleKey(keyEnter) != tuiNone || m.handleKey("e") != tuiNone || m.status == "" {
+		t.Fatalf("actions without a selection should only set a status: matched=%d status=%q", m.matched, m.status)
+	}
+}
+
+func TestTUIModelはグループ見出しを飛ばして移動する(t *testing.T) {
+	m := newTUIModel(tuiTestItems(), "file", false)
+	want := []string{"a.go (2)", "", "", "b.go (1)", ""}
+	if len(m.rows) != len(want) {
+		t.Fatalf("unexpected rows: %+v", m.rows)
+	}
+	for i, h := range want {
+		if m.rows[i].header != h {
+			t.Fatalf("row %d header = %q, want %q", i, m.rows[i].header, h)
+		}
+	}
+	if m.cursor != 1 {
+		t.Fatalf("cursor should start on the first item, got %d", m.cursor)
+	}
+	m.handleKey(keyDown)
+	m.handleKey(keyDown)
+	if m.cursor != 4 {
+		t.Fatalf("moving down should skip the header, cursor=%d", m.cursor)
+	}
+	m.handleKey("g")
+	if m.cursor != 1 {
+		t.Fatalf("g should jump to the first item, cursor=%d", m.cursor)
+	}
+	m.handleKey("G")
+	if m.cursor != 4 {
+		t.Fatalf("G should jump to the last item, cursor=%d", m.cursor)
+	}
+	m.handleKey(keyTab)
+	if m.groupBy() != "author" || m.rows[0].header != "bob (2)" {
+		t.Fatalf("Tab should switch to author grouping: %q %+v", m.groupBy(), m.rows)
+	}
+	if it, _ := m.selected(); it.File != "b.go" {
+		t.Fatalf("selection should survive regrouping, got %+v", it)
+	}
+	m.handleKey(keyTab)
+	m.handleKey(keyTab)
+	if m.groupBy() != "" || len(m.rows) != 3 {
+		t.Fatalf("grouping should wrap around to none without owners: %q", m.groupBy())
+	}
+	m.handleKey(keyBackTab)
+	if m.groupBy() != "type" {
+		t.Fatalf("Shift-Tab should go back, got %q", m.groupBy())
+	}
+}
+
+func TestTUIModelRenderFitsTheScreen(t *testing.T) {
+	items := tuiTestItems()
+	for i := 0; i < 30; i++ {
+		items = append(items, engine.Item{Kind: "TODO", File: "many.go", Line: i + 1, Author: "ｃａｒｏｌ", Text: "// TODO: 全角の本文がとても長くて画面の幅を超えてしまう場合", Commit: "4444444dddd"})
+	}
+	m := newTUIModel(items, "", false)
+	preview := tuiPreview(items[0], []string{"package a", "", "// TODO: handle errors", "func a() {}"}, 1)
+	m.handleKey(keyPageDown)
+	lines := m.render(40, 12, tableColorConfig{}, preview)
+	if len(lines) != 12 {
+		t.Fatalf("expected 12 lines, got %d", len(lines))
+	}
+	for i, l := range lines {
+		if w := textutil.VisibleWidth(l); w > 40 {
+			t.Fatalf("line %d is %d columns wide: %q", i, w, l)
+		}
+	}
+	if !strings.Contains(lines[0], "33/33 items") {
+		t.Fatalf("unexpected title: %q", lines[0])
+	}
+	// PgDn は描画済みのリストの高さだけ進み、カーソル行は画面内に残る
+	cursorShown := false
+	for _, l := range lines {
+		if strings.HasPrefix(l, "> ") {
+			cursorShown = true
+		}
+	}
+	if m.cursor == 0 || !cursorShown {
+		t.Fatalf("cursor should stay visible after paging: cursor=%d\n%s", m.cursor, strings.Join(lines, "\n"))
+	}
+	m.handleKey("p")
+	lines = m.render(40, 12, tableColorConfig{}, preview)
+	if strings.Contains(strings.Join(lines, "\n"), "─") {
+		t.Fatal("the preview pane should be hidden after p")
+	}
+}
+
+func TestTUIPreviewShowsSourceAndCommit(t *testing.T) {
+	it := engine.Item{File: "a.go", Line: 2, Author: "alice", Email: "alice@example.com", Date: "2024-01-02", AgeDays: 1, Commit: "abcdef1234", Message: "add a"}
+	got := tuiPreview(it, []string{"package a", "// TODO: x", "func a() {}", ""}, 1)
+	want := []string{
+		"a.go:2  abcdef1  alice <alice@example.com>  2024-01-02 (1 day ago)",
+		"    add a",
+		"",
+		"  1 │ package a",
+		"▶ 2 │ // TODO: x",
+		"  3 │ func a() {}",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected preview:\n%s", strings.Join(got, "\n"))
+	}
+	if got := tuiPreview(engine.Item{File: "x.zip!a.go", Line: 1}, nil, 3); got[len(got)-1] != "    (source unavailable)" {
+		t.Fatalf("missing source should be reported: %q", got)
+	}
+}
+
+func TestEditorCommandPlacesTheLine(t *testing.T) {
+	cases := []struct {
+		editor string
+		want   string
+	}{
+		{"", "vi +12 /r/a.go"},
+		{"nvim -u NONE", "nvim -u NONE +12 /r/a.go"},
+		{"/usr/bin/code -w", "/usr/bin/code -w --goto /r/a.go:12"},
+		{"hx", "hx /r/a.go:12"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(editorCommand(tc.editor, "/r/a.go", 12), " "); got != tc.want {
+			t.Errorf("editorCommand(%q) = %q, want %q", tc.editor, got, tc.want)
+		}
+	}
+}
+
+func TestReadKeyDecodesEscapeSequences(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[B\x1b[6~\x1b[5~\x1b[H\x1bOF\x1b[Z\r\x7f\té\x03"))
+	want := []string{"j", keyUp, keyDown, keyPageDown, keyPageUp, keyHome, keyEnd, keyBackTab, keyEnter, keyBackspace, keyTab, "é", keyCtrlC}
+	for i, w := range want {
+		got, err := readKey(r)
+		if err != nil || got != w {
+			t.Fatalf("key %d = %q (err=%v), want %q", i, got, err, w)
+		}
+	}
+	lone := bufio.NewReader(strings.NewReader("\x1b"))
+	if got, _ := readKey(lone); got != keyEsc {
+		t.Fatalf("a lone ESC should be Esc, got %q", got)
+	}
+}
+
+func TestParseTUIArgsValidatesOptions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	cfg, err := parseTUIArgs([]string{"--repo", repo, "--group-by", "Owner", "--context", "2", "-t", "fixme", "--timeout", "90s"})
+	if err != nil {
+		t.Fatalf("parseTUIArgs failed: %v", err)
+	}
+	if cfg.groupBy != "owners" || cfg.context != 2 || cfg.opts.Type != "fixme" || !cfg.opts.WithMessage || cfg.opts.RepoDir != repo || cfg.opts.Timeout != 90*time.Second {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	for _, args := range [][]string{{"--group-by", "dir"}, {"--context", "-1"}, {"--timeout", "soon"}, {"extra"}} {
+		if _, err := parseTUIArgs(append([]string{"--repo", repo}, args...)); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
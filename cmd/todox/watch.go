@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/phyten/todox/internal/engine"
+	engineopts "github.com/phyten/todox/internal/engine/opts"
+	"github.com/phyten/todox/internal/execx"
+	"github.com/phyten/todox/internal/output"
+	"github.com/phyten/todox/internal/progress"
+)
+
+// minWatchInterval は --interval に指定できる最短の間隔です。
+const minWatchInterval = 100 * time.Millisecond
+
+// watchConfig は todox watch の設定です。scan と同じオプションに監視の間隔を加えます。
+type watchConfig struct {
+	scan     scanConfig
+	interval time.Duration
+}
+
+func watchCmd(args []string) {
+	cfg, err := parseWatchArgs(args)
+	if err == nil && cfg.scan.showHelp {
+		printWatchHelp()
+		return
+	}
+	if err == nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err = runWatch(ctx, cfg)
+	}
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "todox watch: %v\n", err)
+	var uerr *usageError
+	if errors.As(err, &uerr) {
+		printWatchHelp()
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+func printWatchHelp() {
+	fmt.Print("Usage: todox watch [scan options] [--interval DURATION]\n\n" +
+		"Scan once, then keep watching the worktree and re-render the report when\n" +
+		"something changes. Only changed files are parsed again and only added or\n" +
+		"edited lines are blamed again; unchanged lines keep their attribution.\n" +
+		"When HEAD moves (commit, pull) the files touched by the new commits are\n" +
+		"blamed again, and a reset, rebase or branch switch triggers a full rescan.\n\n" +
+		"Options:\n" +
+		"  --interval DURATION   how often to poll the worktree (default: 1s, min: 100ms)\n" +
+		"  All scan options (-t, --path, --sort, --group-by, -o, --fields, ...) apply.\n\n" +
+		"Changes are detected by polling file sizes and modification times of the\n" +
+		"tracked files, .todoxignore and CODEOWNERS. Press Ctrl-C to stop.\n\n" +
+		"Examples:\n" +
+		"  todox watch --with-age --sort=-age\n" +
+		"  todox watch --path src --group-by owner --interval 2s\n" +
+		"  todox serve --watch                 # push updates to the web UI\n")
+}
+
+func parseWatchArgs(args []string) (watchConfig, error) {
+	cfg := watchConfig{interval: time.Second}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--interval" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return cfg, &usageError{err: errors.New("--interval requires a value")}
+			}
+			i++
+			value = args[i]
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < minWatchInterval {
+			return cfg, &usageError{err: fmt.Errorf("invalid --interval %q (want a duration of at least %s)", value, minWatchInterval)}
+		}
+		cfg.interval = d
+	}
+	envLang := os.Getenv("GIT_TODO_AUTHORS_LANG")
+	if envLang == "" {
+		envLang = os.Getenv("GTA_LANG")
+	}
+	scan, err := parseScanArgs(rest, envLang)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.scan = scan
+	return cfg, nil
+}
+
+func runWatch(ctx context.Context, cfg watchConfig) error {
+	sc := cfg.scan
+	fieldSel, err := output.ResolveFields(sc.fields, sc.withComment, sc.withMessage, sc.withAge, sc.withCommit, sc.withPRs)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	sortSpec, err := ParseSortSpec(sc.sortKey)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	groupBy, err := ParseGroupBy(sc.groupBy)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	sc.opts.WithComment = fieldSel.NeedComment
+	sc.opts.WithMessage = fieldSel.NeedMessage
+	if sc.output == "quickfix" || sc.output == "fzf" {
+		sc.opts.WithComment = true
+	}
+	if err := engineopts.NormalizeAndValidate(&sc.opts); err != nil {
+		return err
+	}
+	if sc.opts.Progress {
+		sc.opts.ProgressObserver = progress.NewAutoObserver(os.Stderr)
+		sc.opts.Progress = false
+	}
+
+	sess, res, err := engine.NewSession(ctx, sc.opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	runner := execx.DefaultRunner()
+	var remoteCache remoteInfoCache
+	pr := prOptions{State: sc.prState, Limit: sc.prLimit, Prefer: sc.prPrefer, Jobs: sc.opts.Jobs}
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	render := func(res *engine.Result, what string) error {
+		decorateResult(ctx, runner, sc.opts.RepoDir, &remoteCache, res, fieldSel, sortSpec, groupBy, pr)
+		if tty {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		if err := writeResult(sc, res, fieldSel); err != nil {
+			return err
+		}
+		if res.ErrorCount > 0 {
+			reportErrors(res)
+		}
+		if tty {
+			fmt.Fprintln(os.Stderr, watchStatus(res, what, sc.opts.ShowSuppressed, time.Now()))
+		}
+		return nil
+	}
+	if err := render(res, fmt.Sprintf("scanned in %dms", res.ElapsedMS)); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		res, changed, err := sess.Poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "todox watch: %v\n", err)
+			continue
+		}
+		if res == nil {
+			continue
+		}
+		if err := render(res, describeChanges(changed, res.ElapsedMS)); err != nil {
+			return err
+		}
+	}
+}
+
+// decorateResult は scan と同じく並べ替え・集約・表示する列・リンク・PR 列を結果に反映します。
+func decorateResult(ctx context.Context, runner execx.Runner, repoDir string, cache *remoteInfoCache, res *engine.Result, fieldSel output.FieldSelection, sortSpec SortSpec, groupBy string, pr prOptions) {
+	ApplySort(res.Items, sortSpec)
+	if groupBy != "" {
+		res.GroupBy = groupBy
+		res.Groups = ApplyGroup(res.Items, groupBy)
+	}
+	res.HasComment = fieldSel.ShowComment
+	res.HasMessage = fieldSel.ShowMessage
+	res.HasAge = fieldSel.ShowAge
+	_ = applyLinkColumn(ctx, runner, repoDir, cache, res, fieldSel)
+	if fieldSel.NeedPRs {
+		start := time.Now()
+		_ = applyPRColumns(ctx, runner, repoDir, cache, res, fieldSel, pr, nil)
+		res.ElapsedMS += time.Since(start).Milliseconds()
+	}
+}
+
+// describeChanges は読み直したファイルを状態行向けに短く表します。
+func describeChanges(changed []string, elapsedMS int64) string {
+	switch len(changed) {
+	case 0:
+		return fmt.Sprintf("rescanned in %dms", elapsedMS)
+	case 1:
+		return fmt.Sprintf("%s changed (%dms)", changed[0], elapsedMS)
+	}
+	return fmt.Sprintf("%d files changed (%dms)", len(changed), elapsedMS)
+}
+
+// watchStatus は再描画のたびに表の下へ出す状態行です。
+func watchStatus(res *engine.Result, what string, showSuppressed bool, now time.Time) string {
+	parts := []string{fmt.Sprintf("%d item(s)", len(res.Items))}
+	if res.Suppressed > 0 && !showSuppressed {
+		parts = append(parts, fmt.Sprintf("%d suppressed", res.Suppressed))
+	}
+	parts = append(parts, what, "updated "+now.Format("15:04:05"), "Ctrl-C to stop")
+	return "todox watch: " + strings.Join(parts, " · ")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+func TestParseWatchArgsSplitsInterval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TODOX_CONFIG", "")
+	repo := t.TempDir()
+	cfg, err := parseWatchArgs([]string{"--repo", repo, "--interval", "250ms", "-t", "fixme", "--sort=-age"})
+	if err != nil {
+		t.Fatalf("parseWatchArgs failed: %v", err)
+	}
+	if cfg.interval != 250*time.Millisecond || cfg.scan.opts.Type != "fixme" || cfg.scan.sortKey != "-age" || cfg.scan.opts.RepoDir != repo {
+		t.Fatalf("unexpected config: interval=%s scan=%+v", cfg.interval, cfg.scan)
+	}
+	if cfg, err := parseWatchArgs([]string{"--repo", repo, "--interval=2s"}); err != nil || cfg.interval != 2*time.Second {
+		t.Fatalf("--interval=2s: %v %v", cfg.interval, err)
+	}
+	if cfg, err := parseWatchArgs([]string{"--repo", repo}); err != nil || cfg.interval != time.Second {
+		t.Fatalf("default interval: %v %v", cfg.interval, err)
+	}
+	for _, args := range [][]string{{"--interval"}, {"--interval", "soon"}, {"--interval", "10ms"}, {"--bogus"}} {
+		if _, err := parseWatchArgs(append([]string{"--repo", repo}, args...)); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestDescribeChangesAndStatus(t *testing.T) {
+	if got := describeChanges([]string{"a.go"}, 12); got != "a.go changed (12ms)" {
+		t.Fatalf("describeChanges = %q", got)
+	}
+	if got := describeChanges([]string{"a.go", "b.go"}, 3); got != "2 files changed (3ms)" {
+		t.Fatalf("describeChanges = %q", got)
+	}
+	res := &engine.Result{Items: make([]engine.Item, 3), Suppressed: 2}
+	now := time.Date(2024, 5, 1, 9, 8, 7, 0, time.UTC)
+	if got, want := watchStatus(res, "a.go changed (12ms)", false, now), "todox watch: 3 item(s) · 2 suppressed · a.go changed (12ms) · updated 09:08:07 · Ctrl-C to stop"; got != want {
+		t.Fatalf("watchStatus = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+// Package codeowners は GitHub の CODEOWNERS ファイルを読み込み、パスごとの担当者を解決します。
+package codeowners
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SearchPaths は GitHub と同じ優先順位で探索する CODEOWNERS の候補です（最初に見つかったものだけを使います）。
+var SearchPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule は CODEOWNERS の 1 行分のルールです。Owners が空の行は「担当者なし」を意味します。
+type Rule struct {
+	Pattern string
+	Owners  []string
+	Line    int
+	rx      *regexp.Regexp
+}
+
+// File は読み込み済みの CODEOWNERS です。
+type File struct {
+	Path  string
+	Rules []Rule
+}
+
+// Load は repoDir 配下から CODEOWNERS を探して読み込みます。見つからない場合は nil, nil を返します。
+func Load(repoDir string) (*File, error) {
+	for _, rel := range SearchPaths {
+		full := filepath.Join(repoDir, filepath.FromSlash(rel))
+		fh, err := os.Open(full)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("codeowners: %w", err)
+		}
+		parsed, err := Parse(fh)
+		_ = fh.Close()
+		if err != nil {
+			return nil, fmt.Errorf("codeowners: %s: %w", rel, err)
+		}
+		parsed.Path = rel
+		return parsed, nil
+	}
+	return nil, nil
+}
+
+// Parse は CODEOWNERS の内容を解析します。GitHub が無視する不正なパターンの行は読み飛ばします。
+func Parse(r io.Reader) (*File, error) {
+	out := &File{}
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern := fields[0]
+		var owners []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			owners = append(owners, f)
+		}
+		rx, err := compilePattern(pattern)
+		if err != nil {
+			continue
+		}
+		out.Rules = append(out.Rules, Rule{Pattern: pattern, Owners: owners, Line: lineNo, rx: rx})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Owners はパスに一致する最後のルールの担当者を返します（last match wins）。
+func (f *File) Owners(path string) []string {
+	if f == nil {
+		return nil
+	}
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].rx.MatchString(path) {
+			if len(f.Rules[i].Owners) == 0 {
+				return nil
+			}
+			out := make([]string, len(f.Rules[i].Owners))
+			copy(out, f.Rules[i].Owners)
+			return out
+		}
+	}
+	return nil
+}
+
+// splitFields は空白区切りでトークン化します。バックスラッシュでエスケープされた空白はパターンの一部として扱います。
+func splitFields(line string) []string {
+	var fields []string
+	var b strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if r != ' ' && r != '\t' && r != '#' {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ' ' || r == '\t':
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// compilePattern は gitignore 風のパターンを正規表現へ変換します。
+//
+//   - 先頭または途中に "/" を含むパターンはリポジトリのルート基準、それ以外は任意の階層に一致します。
+//   - 末尾の "/" はディレクトリ配下のみに一致します。
+//   - 末尾が "/*" のパターンは直下のファイルだけに一致します（GitHub の挙動）。
+//   - "*" と "?" は "/" をまたがず、"**" は任意の階層に一致します。
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	if strings.HasPrefix(p, "!") || strings.Contains(p, "[") {
+		return nil, fmt.Errorf("unsupported pattern: %s", pattern)
+	}
+	anchored := strings.HasPrefix(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	if strings.Contains(p, "/") {
+		anchored = true
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*") && !strings.HasSuffix(p, "/**"):
+		b.WriteString("$")
+	default:
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// NormalizeOwner は比較用に担当者名を正規化します（先頭の "@" を除去し小文字化）。
+func NormalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(owner), "@"))
+}
@@ -0,0 +1,113 @@
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `# GitHub docs example
+*       @global-owner1 @global-owner2
+*.js    @js-owner #This is an inline comment.
+*.go docs@example.com
+/build/logs/ @doctocat
+docs/*  docs@example.com
+apps/ @octocat
+/docs/ @doctocat
+/scripts/ @doctocat @octocat
+**/logs @octocat
+/apps/ @octocat
+/apps/github
+`
+
+func TestOwnersはlast_match_winsで解決する(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse に失敗しました: %v", err)
+	}
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@global-owner1", "@global-owner2"}},
+		{"web/app.js", []string{"@js-owner"}},
+		{"cmd/main.go", []string{"docs@example.com"}},
+		{"build/logs/out.txt", []string{"@octocat"}},
+		{"docs/getting-started.md", []string{"@doctocat"}},
+		{"scripts/deploy.sh", []string{"@doctocat", "@octocat"}},
+		{"src/logs/trace.txt", []string{"@octocat"}},
+		{"apps/api/main.go", []string{"@octocat"}},
+		{"apps/github/main.go", nil},
+	}
+	for _, tc := range cases {
+		if got := f.Owners(tc.path); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Owners(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestCompilePatternの境界ケース(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"docs/*", "docs/a.md", true},
+		{"docs/*", "docs/sub/a.md", false},
+		{"apps/", "nested/apps/x.go", true},
+		{"apps/", "apps", false},
+		{"/apps/github", "apps/github/x", true},
+		{"/apps/github", "other/apps/github/x", false},
+		{"*.md", "deep/dir/file.md", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"docs/**/*.md", "docs/c.md", true},
+		{"Makefile", "sub/Makefile", true},
+		{"a?c", "abc", true},
+		{"a?c", "a/c", false},
+	}
+	for _, tc := range cases {
+		rx, err := compilePattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) でエラー: %v", tc.pattern, err)
+		}
+		if got := rx.MatchString(tc.path); got != tc.want {
+			t.Fatalf("pattern %q vs %q = %v, want %v (rx=%s)", tc.pattern, tc.path, got, tc.want, rx)
+		}
+	}
+}
+
+func TestLoadは優先順位に従ってファイルを選ぶ(t *testing.T) {
+	dir := t.TempDir()
+	if f, err := Load(dir); err != nil || f != nil {
+		t.Fatalf("CODEOWNERS が無い場合は nil を返す想定です: %v, %v", f, err)
+	}
+	write := func(rel, content string) {
+		t.Helper()
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("ディレクトリ作成に失敗しました: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("ファイル作成に失敗しました: %v", err)
+		}
+	}
+	write("docs/CODEOWNERS", "* @docs-team\n")
+	write("CODEOWNERS", "* @root-team\n")
+	f, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load に失敗しました: %v", err)
+	}
+	if f.Path != "CODEOWNERS" || !reflect.DeepEqual(f.Owners("x.go"), []string{"@root-team"}) {
+		t.Fatalf("ルートの CODEOWNERS が優先されていません: %+v", f)
+	}
+	write(".github/CODEOWNERS", "* @gh-team\n")
+	f, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load に失敗しました: %v", err)
+	}
+	if f.Path != ".github/CODEOWNERS" {
+		t.Fatalf(".github/CODEOWNERS が最優先されていません: %s", f.Path)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/engine"
 )
 
 func strPtr(s string) *string { return &s }
@@ -69,34 +72,44 @@ func TestMergeUIPrecedence(t *testing.T) {
 
 func TestFromEnv(t *testing.T) {
 	env := map[string]string{
-		"TODOX_TYPE":             "todo",
-		"TODOX_DETECT":           "regex",
-		"TODOX_AUTHOR":           "Alice",
-		"TODOX_WITH_COMMENT":     "1",
-		"TODOX_WITH_MESSAGE":     "true",
-		"TODOX_PATH":             "src,cmd",
-		"TODOX_PATH_REGEX":       ".*\\.go$",
-		"TODOX_EXCLUDE":          "vendor,dist",
-		"TODOX_EXCLUDE_TYPICAL":  "yes",
-		"TODOX_DETECT_LANGS":     "go,py",
-		"TODOX_TAGS":             "TODO,FIXME",
-		"TODOX_INCLUDE_STRINGS":  "1",
-		"TODOX_NO_STRINGS":       "true",
-		"TODOX_COMMENTS_ONLY":    "1",
-		"TODOX_TRUNCATE":         "5000",
-		"TODOX_TRUNCATE_COMMENT": "80",
-		"TODOX_TRUNCATE_MESSAGE": "72",
-		"TODOX_IGNORE_WS":        "0",
-		"TODOX_MAX_FILE_BYTES":   "8192",
-		"TODOX_JOBS":             "128",
-		"TODOX_PR_STATE":         "open",
-		"TODOX_PR_LIMIT":         "4",
-		"TODOX_PR_PREFER":        "merged",
-		"TODOX_WITH_AGE":         "true",
-		"TODOX_WITH_PR_LINKS":    "yes",
-		"TODOX_FIELDS":           "type,author",
-		"TODOX_SORT":             "-age",
-		"TODOX_NO_PREFILTER":     "1",
+		"TODOX_TYPE":               "todo",
+		"TODOX_DETECT":             "regex",
+		"TODOX_AUTHOR":             "Alice",
+		"TODOX_SKIP_AUTHORS":       "\\[bot\\]$",
+		"TODOX_RECURSE_SUBMODULES": "1",
+		"TODOX_WITH_COMMENT":       "1",
+		"TODOX_WITH_MESSAGE":       "true",
+		"TODOX_PATH":               "src,cmd",
+		"TODOX_PATH_REGEX":         ".*\\.go$",
+		"TODOX_EXCLUDE":            "vendor,dist",
+		"TODOX_EXCLUDE_TYPICAL":    "yes",
+		"TODOX_DETECT_LANGS":       "go,py",
+		"TODOX_TAGS":               "TODO,FIXME",
+		"TODOX_INCLUDE_STRINGS":    "1",
+		"TODOX_NO_STRINGS":         "true",
+		"TODOX_COMMENTS_ONLY":      "1",
+		"TODOX_TRUNCATE":           "5000",
+		"TODOX_TRUNCATE_COMMENT":   "80",
+		"TODOX_TRUNCATE_MESSAGE":   "72",
+		"TODOX_IGNORE_WS":          "0",
+		"TODOX_MAX_FILE_BYTES":     "8192",
+		"TODOX_JOBS":               "128",
+		"TODOX_PR_STATE":           "open",
+		"TODOX_PR_LIMIT":           "4",
+		"TODOX_PR_PREFER":          "merged",
+		"TODOX_WITH_AGE":           "true",
+		"TODOX_WITH_PR_LINKS":      "yes",
+		"TODOX_FIELDS":             "type,author",
+		"TODOX_SORT":               "-age",
+		"TODOX_NO_PREFILTER":       "1",
+		"TODOX_SCAN_ARCHIVES":      "true",
+		"TODOX_INCLUDE_GENERATED":  "on",
+		"TODOX_SHOW_SUPPRESSED":    "yes",
+		"TODOX_TIMEOUT":            "5m",
+		"TODOX_STREAM":             "unsorted",
+		"TODOX_GIT_TIMEOUT":        "15",
+		"TODOX_OWNERS":             "@org/backend,@alice",
+		"TODOX_GROUP_BY":           "owners",
 	}
 	cfg, err := FromEnv(func(key string) string { return env[key] })
 	if err != nil {
@@ -108,6 +121,30 @@ func TestFromEnv(t *testing.T) {
 	if cfg.Engine.Author == nil || *cfg.Engine.Author != "Alice" {
 		t.Fatalf("expected Author Alice, got %+v", cfg.Engine.Author)
 	}
+	if cfg.Engine.SkipAuthors == nil || *cfg.Engine.SkipAuthors != `\[bot\]$` {
+		t.Fatalf("expected SkipAuthors, got %+v", cfg.Engine.SkipAuthors)
+	}
+	if cfg.Engine.RecurseSubmodules == nil || !*cfg.Engine.RecurseSubmodules {
+		t.Fatalf("expected RecurseSubmodules, got %+v", cfg.Engine.RecurseSubmodules)
+	}
+	if cfg.Engine.ScanArchives == nil || !*cfg.Engine.ScanArchives {
+		t.Fatalf("expected ScanArchives, got %+v", cfg.Engine.ScanArchives)
+	}
+	if cfg.Engine.IncludeGenerated == nil || !*cfg.Engine.IncludeGenerated {
+		t.Fatalf("expected IncludeGenerated, got %+v", cfg.Engine.IncludeGenerated)
+	}
+	if cfg.Engine.ShowSuppressed == nil || !*cfg.Engine.ShowSuppressed {
+		t.Fatalf("expected ShowSuppressed, got %+v", cfg.Engine.ShowSuppressed)
+	}
+	if cfg.Engine.Timeout == nil || *cfg.Engine.Timeout != 5*time.Minute {
+		t.Fatalf("expected Timeout 5m, got %+v", cfg.Engine.Timeout)
+	}
+	if cfg.Engine.GitTimeout == nil || *cfg.Engine.GitTimeout != 15*time.Second {
+		t.Fatalf("expected GitTimeout 15s, got %+v", cfg.Engine.GitTimeout)
+	}
+	if cfg.UI.Stream == nil || *cfg.UI.Stream != "unsorted" {
+		t.Fatalf("expected Stream unsorted, got %+v", cfg.UI.Stream)
+	}
 	if cfg.Engine.Detect == nil || *cfg.Engine.Detect != "regex" {
 		t.Fatalf("expected Detect regex, got %+v", cfg.Engine.Detect)
 	}
@@ -180,6 +217,12 @@ func TestFromEnv(t *testing.T) {
 	if cfg.UI.Sort == nil || *cfg.UI.Sort != "-age" {
 		t.Fatalf("unexpected sort: %+v", cfg.UI.Sort)
 	}
+	if cfg.Engine.Owners == nil || !reflect.DeepEqual(*cfg.Engine.Owners, []string{"@org/backend", "@alice"}) {
+		t.Fatalf("unexpected owners: %+v", cfg.Engine.Owners)
+	}
+	if cfg.UI.GroupBy == nil || *cfg.UI.GroupBy != "owners" {
+		t.Fatalf("unexpected group_by: %+v", cfg.UI.GroupBy)
+	}
 }
 
 func TestAssignEngineNoStrings(t *testing.T) {
@@ -273,6 +316,120 @@ func TestLoadConfigFormats(t *testing.T) {
 	}
 }
 
+func TestLoadTagRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "tag_word_boundary: true\ntag_suffix: \":(\"\ntag_case_sensitive: [TODO]\ntag_patterns:\n  NOTE: 'NOTE\\(\\w+\\):'\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	merged := MergeEngine(EngineSettings{}, cfg.Engine)
+	var opts engine.Options
+	merged.ApplyToOptions(&opts)
+	want := engine.TagRules{
+		WordBoundary:  true,
+		Suffix:        ":(",
+		CaseSensitive: []string{"TODO"},
+		Patterns:      map[string]string{"NOTE": `NOTE\(\w+\):`},
+	}
+	if !reflect.DeepEqual(opts.TagRules, want) {
+		t.Fatalf("unexpected tag rules: got=%+v want=%+v", opts.TagRules, want)
+	}
+}
+
+func TestLoadLanguages(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := `languages:
+  pipeline:
+    extensions: [.pipeline, rules]
+    filenames: Pipelinefile
+    line_comments: ["#", "//"]
+    block_comments:
+      - {start: "(*", end: "*)", nested: true}
+    strings: ['"', {start: "'", raw: true, multiline: true}]
+    heredocs: "<<"
+  ruby:
+    extensions: .rbx
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	merged := MergeEngine(EngineSettings{}, cfg.Engine)
+	var opts engine.Options
+	merged.ApplyToOptions(&opts)
+	want := map[string]engine.LanguageDef{
+		"pipeline": {
+			Extensions:    []string{".pipeline", "rules"},
+			Filenames:     []string{"Pipelinefile"},
+			LineComments:  []string{"#", "//"},
+			BlockComments: []engine.BlockCommentDef{{Start: "(*", End: "*)", Nested: true}},
+			Strings:       []engine.StringDef{{Start: `"`}, {Start: "'", Raw: true, Multiline: true}},
+			Heredocs:      []string{"<<"},
+		},
+		"ruby": {Extensions: []string{".rbx"}},
+	}
+	if !reflect.DeepEqual(opts.Languages, want) {
+		t.Fatalf("unexpected languages: got=%+v want=%+v", opts.Languages, want)
+	}
+}
+
+func TestLoadLanguagesRejectsInvalidEntries(t *testing.T) {
+	cases := map[string]string{
+		"unknown key":       "languages:\n  dsl:\n    comment: '#'\n",
+		"block without end": "languages:\n  dsl:\n    block_comments: [{start: '/*'}]\n",
+		"not a map":         "languages: [dsl]\n",
+	}
+	for name, content := range cases {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadAuthorAliases(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "authors:\n  \"Alice Example <alice@example.com>\":\n    - alice@old.example.com\n    - Alice E\n  Bob: bob@users.noreply.github.com\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := map[string][]string{
+		"Alice Example <alice@example.com>": {"alice@old.example.com", "Alice E"},
+		"Bob":                               {"bob@users.noreply.github.com"},
+	}
+	if cfg.Engine.Authors == nil || !reflect.DeepEqual(*cfg.Engine.Authors, want) {
+		t.Fatalf("unexpected authors: %+v", cfg.Engine.Authors)
+	}
+	merged := MergeEngine(EngineSettings{}, cfg.Engine)
+	if !reflect.DeepEqual(merged.Authors, want) {
+		t.Fatalf("authors not merged: %+v", merged.Authors)
+	}
+
+	if err := os.WriteFile(path, []byte("authors: [alice]\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for non-map authors")
+	}
+}
+
 func TestLoadUnknownKey(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.yaml")
@@ -362,10 +519,16 @@ func TestNormalizeUI(t *testing.T) {
 	if normalized.Fields != "type,author" {
 		t.Fatalf("expected fields trimmed, got %q", normalized.Fields)
 	}
+	if normalized.Stream != "file" {
+		t.Fatalf("expected stream to default to file, got %q", normalized.Stream)
+	}
 
 	if _, err := NormalizeUI(UISettings{PRState: "open", PRLimit: 0}); err == nil {
 		t.Fatal("expected error for invalid pr_limit")
 	}
+	if _, err := NormalizeUI(UISettings{PRLimit: 3, Stream: "later"}); err == nil {
+		t.Fatal("expected error for invalid stream")
+	}
 }
 
 func ptrString(v *string) string {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"strings"
+	"time"
 
 	engineopts "github.com/phyten/todox/internal/engine/opts"
 )
@@ -51,6 +52,19 @@ func FromEnv(getenv func(string) string) (Config, error) {
 		value := v
 		*target = &value
 	}
+	setDuration := func(target **time.Duration, key string) {
+		raw := strings.TrimSpace(getenv(key))
+		if raw == "" {
+			return
+		}
+		d, err := engineopts.ParseDuration(raw, key)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		value := d
+		*target = &value
+	}
 	setInt := func(target **int, key string, min, max int) {
 		raw := strings.TrimSpace(getenv(key))
 		if raw == "" {
@@ -69,12 +83,17 @@ func FromEnv(getenv func(string) string) (Config, error) {
 	setString(&cfg.Engine.Mode, "TODOX_MODE")
 	setString(&cfg.Engine.Detect, "TODOX_DETECT")
 	setString(&cfg.Engine.Author, "TODOX_AUTHOR")
+	setString(&cfg.Engine.SkipAuthors, "TODOX_SKIP_AUTHORS")
 	setList(&cfg.Engine.Paths, "TODOX_PATH")
 	setList(&cfg.Engine.Excludes, "TODOX_EXCLUDE")
 	setList(&cfg.Engine.PathRegex, "TODOX_PATH_REGEX")
 	setList(&cfg.Engine.DetectLangs, "TODOX_DETECT_LANGS")
 	setList(&cfg.Engine.Tags, "TODOX_TAGS")
+	setBool(&cfg.Engine.TagWordBoundary, "TODOX_TAG_WORD_BOUNDARY")
+	setString(&cfg.Engine.TagSuffix, "TODOX_TAG_SUFFIX")
+	setList(&cfg.Engine.TagCaseSensitive, "TODOX_TAG_CASE_SENSITIVE")
 	setBool(&cfg.Engine.ExcludeTypical, "TODOX_EXCLUDE_TYPICAL")
+	setBool(&cfg.Engine.IncludeGenerated, "TODOX_INCLUDE_GENERATED")
 	setString(&cfg.Engine.Output, "TODOX_OUTPUT")
 	setString(&cfg.Engine.Color, "TODOX_COLOR")
 	setBool(&cfg.Engine.WithComment, "TODOX_WITH_COMMENT")
@@ -100,6 +119,13 @@ func FromEnv(getenv func(string) string) (Config, error) {
 	setInt(&cfg.Engine.Jobs, "TODOX_JOBS", 0, math.MaxInt)
 	setString(&cfg.Engine.Repo, "TODOX_REPO")
 	setBool(&cfg.Engine.NoPrefilter, "TODOX_NO_PREFILTER")
+	setBool(&cfg.Engine.RecurseSubmodules, "TODOX_RECURSE_SUBMODULES")
+	setBool(&cfg.Engine.ScanArchives, "TODOX_SCAN_ARCHIVES")
+	setBool(&cfg.Engine.ShowSuppressed, "TODOX_SHOW_SUPPRESSED")
+	setInt(&cfg.Engine.Context, "TODOX_CONTEXT", 0, math.MaxInt)
+	setDuration(&cfg.Engine.Timeout, "TODOX_TIMEOUT")
+	setDuration(&cfg.Engine.GitTimeout, "TODOX_GIT_TIMEOUT")
+	setList(&cfg.Engine.Owners, "TODOX_OWNERS")
 
 	setBool(&cfg.UI.WithCommitLink, "TODOX_WITH_COMMIT_LINK")
 	setBool(&cfg.UI.WithPRLinks, "TODOX_WITH_PR_LINKS")
@@ -109,6 +135,8 @@ func FromEnv(getenv func(string) string) (Config, error) {
 	setString(&cfg.UI.PRPrefer, "TODOX_PR_PREFER")
 	setString(&cfg.UI.Fields, "TODOX_FIELDS")
 	setString(&cfg.UI.Sort, "TODOX_SORT")
+	setString(&cfg.UI.GroupBy, "TODOX_GROUP_BY")
+	setString(&cfg.UI.Stream, "TODOX_STREAM")
 
 	if len(errs) > 0 {
 		return cfg, errors.Join(errs...)
@@ -7,45 +7,69 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	toml "github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
 
+	"github.com/phyten/todox/internal/engine"
 	engineopts "github.com/phyten/todox/internal/engine/opts"
 )
 
 var engineKeyMap = map[string]string{
-	"type":             "type",
-	"mode":             "mode",
-	"detect":           "detect",
-	"detect_mode":      "detect",
-	"author":           "author",
-	"path":             "path",
-	"paths":            "path",
-	"exclude":          "exclude",
-	"excludes":         "exclude",
-	"path_regex":       "path_regex",
-	"path_regexes":     "path_regex",
-	"detect_langs":     "detect_langs",
-	"detect_languages": "detect_langs",
-	"tags":             "tags",
-	"exclude_typical":  "exclude_typical",
-	"with_comment":     "with_comment",
-	"with_message":     "with_message",
-	"include_strings":  "include_strings",
-	"no_strings":       "no_strings",
-	"comments_only":    "comments_only",
-	"truncate":         "truncate",
-	"truncate_comment": "truncate_comment",
-	"truncate_message": "truncate_message",
-	"ignore_ws":        "ignore_ws",
-	"max_file_bytes":   "max_file_bytes",
-	"max_bytes":        "max_file_bytes",
-	"jobs":             "jobs",
-	"repo":             "repo",
-	"output":           "output",
-	"color":            "color",
-	"no_prefilter":     "no_prefilter",
+	"type":               "type",
+	"mode":               "mode",
+	"detect":             "detect",
+	"detect_mode":        "detect",
+	"author":             "author",
+	"skip_authors":       "skip_authors",
+	"path":               "path",
+	"paths":              "path",
+	"exclude":            "exclude",
+	"excludes":           "exclude",
+	"path_regex":         "path_regex",
+	"path_regexes":       "path_regex",
+	"detect_langs":       "detect_langs",
+	"detect_languages":   "detect_langs",
+	"tags":               "tags",
+	"tag_word_boundary":  "tag_word_boundary",
+	"tag_suffix":         "tag_suffix",
+	"tag_case_sensitive": "tag_case_sensitive",
+	"tag_patterns":       "tag_patterns",
+	"tag_pattern":        "tag_patterns",
+	"exclude_typical":    "exclude_typical",
+	"include_generated":  "include_generated",
+	"with_comment":       "with_comment",
+	"with_message":       "with_message",
+	"include_strings":    "include_strings",
+	"no_strings":         "no_strings",
+	"comments_only":      "comments_only",
+	"truncate":           "truncate",
+	"truncate_comment":   "truncate_comment",
+	"truncate_message":   "truncate_message",
+	"ignore_ws":          "ignore_ws",
+	"max_file_bytes":     "max_file_bytes",
+	"max_bytes":          "max_file_bytes",
+	"jobs":               "jobs",
+	"repo":               "repo",
+	"output":             "output",
+	"color":              "color",
+	"no_prefilter":       "no_prefilter",
+	"recurse_submodules": "recurse_submodules",
+	"submodules":         "recurse_submodules",
+	"scan_archives":      "scan_archives",
+	"archives":           "scan_archives",
+	"show_suppressed":    "show_suppressed",
+	"context":            "context",
+	"context_lines":      "context",
+	"timeout":            "timeout",
+	"git_timeout":        "git_timeout",
+	"owners":             "owners",
+	"owner":              "owners",
+	"authors":            "authors",
+	"author_aliases":     "authors",
+	"languages":          "languages",
+	"language":           "languages",
 }
 
 var uiKeyMap = map[string]string{
@@ -57,6 +81,8 @@ var uiKeyMap = map[string]string{
 	"pr_prefer":        "pr_prefer",
 	"fields":           "fields",
 	"sort":             "sort",
+	"group_by":         "group_by",
+	"stream":           "stream",
 }
 
 func Load(path string) (Config, error) {
@@ -213,6 +239,12 @@ func assignEngine(section map[string]any, dst *EngineConfig) error {
 				return err
 			}
 			dst.Author = &str
+		case "skip_authors":
+			str, err := expectString(value, key)
+			if err != nil {
+				return err
+			}
+			dst.SkipAuthors = &str
 		case "path":
 			list, err := expectStringList(value, key)
 			if err != nil {
@@ -243,12 +275,42 @@ func assignEngine(section map[string]any, dst *EngineConfig) error {
 				return err
 			}
 			dst.Tags = &list
+		case "tag_word_boundary":
+			b, err := expectBool(value, key)
+			if err != nil {
+				return err
+			}
+			dst.TagWordBoundary = &b
+		case "tag_suffix":
+			str, err := expectString(value, key)
+			if err != nil {
+				return err
+			}
+			dst.TagSuffix = &str
+		case "tag_case_sensitive":
+			list, err := expectStringList(value, key)
+			if err != nil {
+				return err
+			}
+			dst.TagCaseSensitive = &list
+		case "tag_patterns":
+			patterns, err := expectStringMap(value, key)
+			if err != nil {
+				return err
+			}
+			dst.TagPatterns = &patterns
 		case "exclude_typical":
 			b, err := expectBool(value, key)
 			if err != nil {
 				return err
 			}
 			dst.ExcludeTypical = &b
+		case "include_generated":
+			b, err := expectBool(value, key)
+			if err != nil {
+				return err
+			}
+			dst.IncludeGenerated = &b
 		case "with_comment":
 			b, err := expectBool(value, key)
 			if err != nil {
@@ -291,6 +353,12 @@ func assignEngine(section map[string]any, dst *EngineConfig) error {
 				return err
 			}
 			dst.MaxFileBytes = &n
+		case "context":
+			n, err := expectInt(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Context = &n
 		case "jobs":
 			n, err := expectInt(value, key)
 			if err != nil {
@@ -323,6 +391,54 @@ func assignEngine(section map[string]any, dst *EngineConfig) error {
 				return err
 			}
 			dst.NoPrefilter = &b
+		case "recurse_submodules":
+			b, err := expectBool(value, key)
+			if err != nil {
+				return err
+			}
+			dst.RecurseSubmodules = &b
+		case "scan_archives":
+			b, err := expectBool(value, key)
+			if err != nil {
+				return err
+			}
+			dst.ScanArchives = &b
+		case "show_suppressed":
+			b, err := expectBool(value, key)
+			if err != nil {
+				return err
+			}
+			dst.ShowSuppressed = &b
+		case "timeout":
+			d, err := expectDuration(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Timeout = &d
+		case "git_timeout":
+			d, err := expectDuration(value, key)
+			if err != nil {
+				return err
+			}
+			dst.GitTimeout = &d
+		case "owners":
+			list, err := expectStringList(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Owners = &list
+		case "authors":
+			aliases, err := expectAliasMap(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Authors = &aliases
+		case "languages":
+			langs, err := expectLanguages(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Languages = &langs
 		default:
 			return fmt.Errorf("unknown key: %s", key)
 		}
@@ -381,6 +497,18 @@ func assignUI(section map[string]any, dst *UIConfig) error {
 				return err
 			}
 			dst.Sort = &str
+		case "group_by":
+			str, err := expectString(value, key)
+			if err != nil {
+				return err
+			}
+			dst.GroupBy = &str
+		case "stream":
+			str, err := expectString(value, key)
+			if err != nil {
+				return err
+			}
+			dst.Stream = &str
 		default:
 			return fmt.Errorf("unknown key: %s", key)
 		}
@@ -409,6 +537,18 @@ func expectBool(value any, field string) (bool, error) {
 	}
 }
 
+// expectDuration は "90s" のような文字列か、秒数の整数を受け付けます。
+func expectDuration(value any, field string) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		return engineopts.ParseDuration(s, field)
+	}
+	n, err := expectInt(value, field)
+	if err != nil {
+		return 0, fmt.Errorf("expected duration for %s, got %T", field, value)
+	}
+	return engineopts.ParseDuration(strconv.Itoa(n), field)
+}
+
 func expectInt(value any, field string) (int, error) {
 	switch v := value.(type) {
 	case int:
@@ -463,6 +603,212 @@ func expectStringList(value any, field string) ([]string, error) {
 	}
 }
 
+// expectAliasMap は「正規名 → 別名（文字列またはリスト）」のマップを読み取ります。
+func expectAliasMap(value any, field string) (map[string][]string, error) {
+	m, err := toStringKeyMap(value)
+	if err != nil {
+		return nil, fmt.Errorf("expected map for %s: %w", field, err)
+	}
+	out := make(map[string][]string, len(m))
+	for canonical, raw := range m {
+		name := strings.TrimSpace(canonical)
+		if name == "" {
+			return nil, fmt.Errorf("%s: empty author name", field)
+		}
+		list, err := expectStringList(raw, field+"."+name)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = list
+	}
+	return out, nil
+}
+
+var languageKeyMap = map[string]string{
+	"extensions":     "extensions",
+	"extension":      "extensions",
+	"filenames":      "filenames",
+	"filename":       "filenames",
+	"basenames":      "filenames",
+	"shebangs":       "shebangs",
+	"shebang":        "shebangs",
+	"line_comments":  "line_comments",
+	"line_comment":   "line_comments",
+	"block_comments": "block_comments",
+	"block_comment":  "block_comments",
+	"strings":        "strings",
+	"string":         "strings",
+	"heredocs":       "heredocs",
+	"heredoc":        "heredocs",
+}
+
+// expectLanguages は「言語名 → 定義」のマップを読み取ります。
+func expectLanguages(value any, field string) (map[string]engine.LanguageDef, error) {
+	m, err := toStringKeyMap(value)
+	if err != nil {
+		return nil, fmt.Errorf("expected map for %s: %w", field, err)
+	}
+	out := make(map[string]engine.LanguageDef, len(m))
+	for name, raw := range m {
+		lang := strings.TrimSpace(name)
+		if lang == "" {
+			return nil, fmt.Errorf("%s: empty language name", field)
+		}
+		prefix := field + "." + lang
+		entries, err := toStringKeyMap(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected map for %s: %w", prefix, err)
+		}
+		var def engine.LanguageDef
+		for key, v := range entries {
+			canonical, ok := languageKeyMap[normalizeKey(key)]
+			if !ok {
+				return nil, fmt.Errorf("unknown %s key: %s", prefix, key)
+			}
+			name := prefix + "." + canonical
+			switch canonical {
+			case "extensions":
+				def.Extensions, err = expectStringList(v, name)
+			case "filenames":
+				def.Filenames, err = expectStringList(v, name)
+			case "shebangs":
+				def.Shebangs, err = expectStringList(v, name)
+			case "line_comments":
+				def.LineComments, err = expectRawStringList(v, name)
+			case "heredocs":
+				def.Heredocs, err = expectRawStringList(v, name)
+			case "block_comments":
+				def.BlockComments, err = expectBlockComments(v, name)
+			case "strings":
+				def.Strings, err = expectStringDefs(v, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		out[lang] = def
+	}
+	return out, nil
+}
+
+// expectRawStringList は区切り記号のリストを読み取ります。"," を含む記号もあるため
+// 文字列 1 つはそのまま 1 要素として扱います。
+func expectRawStringList(value any, field string) ([]string, error) {
+	if s, ok := value.(string); ok {
+		return normalizeList([]string{s}), nil
+	}
+	return expectStringList(value, field)
+}
+
+func expectMapList(value any, field string) ([]map[string]any, error) {
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	default:
+		items = []any{v}
+	}
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, map[string]any{"start": s})
+			continue
+		}
+		m, err := toStringKeyMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("expected map or list of maps for %s: %w", field, err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// expectBlockComments は {start, end, nested} のリストを読み取ります。
+func expectBlockComments(value any, field string) ([]engine.BlockCommentDef, error) {
+	items, err := expectMapList(value, field)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]engine.BlockCommentDef, 0, len(items))
+	for _, item := range items {
+		var def engine.BlockCommentDef
+		for key, v := range item {
+			switch normalizeKey(key) {
+			case "start":
+				def.Start, err = expectString(v, field+".start")
+			case "end":
+				def.End, err = expectString(v, field+".end")
+			case "nested":
+				def.Nested, err = expectBool(v, field+".nested")
+			default:
+				return nil, fmt.Errorf("unknown %s key: %s", field, key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if strings.TrimSpace(def.Start) == "" || strings.TrimSpace(def.End) == "" {
+			return nil, fmt.Errorf("%s: start and end are required", field)
+		}
+		out = append(out, def)
+	}
+	return out, nil
+}
+
+// expectStringDefs は {start, end, raw, multiline} のリスト（または区切り文字だけのリスト）を読み取ります。
+func expectStringDefs(value any, field string) ([]engine.StringDef, error) {
+	items, err := expectMapList(value, field)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]engine.StringDef, 0, len(items))
+	for _, item := range items {
+		var def engine.StringDef
+		for key, v := range item {
+			switch normalizeKey(key) {
+			case "start":
+				def.Start, err = expectString(v, field+".start")
+			case "end":
+				def.End, err = expectString(v, field+".end")
+			case "raw":
+				def.Raw, err = expectBool(v, field+".raw")
+			case "multiline":
+				def.Multiline, err = expectBool(v, field+".multiline")
+			default:
+				return nil, fmt.Errorf("unknown %s key: %s", field, key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if def.Start == "" {
+			return nil, fmt.Errorf("%s: start is required", field)
+		}
+		out = append(out, def)
+	}
+	return out, nil
+}
+
+func expectStringMap(value any, field string) (map[string]string, error) {
+	m, err := toStringKeyMap(value)
+	if err != nil {
+		return nil, fmt.Errorf("expected map for %s: %w", field, err)
+	}
+	out := make(map[string]string, len(m))
+	for key, raw := range m {
+		name := strings.TrimSpace(key)
+		if name == "" {
+			return nil, fmt.Errorf("%s: empty key", field)
+		}
+		str, err := expectString(raw, field+"."+name)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = str
+	}
+	return out, nil
+}
+
 func normalizeList(values []string) []string {
 	out := make([]string, 0, len(values))
 	for _, v := range values {
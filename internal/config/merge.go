@@ -14,10 +14,12 @@ func MergeEngine(base EngineSettings, layers ...EngineConfig) EngineSettings {
 		out.Mode = ResolveString(out.Mode, layer.Mode)
 		out.Detect = ResolveString(out.Detect, layer.Detect)
 		out.Author = ResolveString(out.Author, layer.Author)
+		out.SkipAuthors = ResolveString(out.SkipAuthors, layer.SkipAuthors)
 		out.Paths = ResolveStrings(out.Paths, layer.Paths)
 		out.Excludes = ResolveStrings(out.Excludes, layer.Excludes)
 		out.PathRegex = ResolveStrings(out.PathRegex, layer.PathRegex)
 		out.ExcludeTypical = ResolveBool(out.ExcludeTypical, layer.ExcludeTypical)
+		out.IncludeGenerated = ResolveBool(out.IncludeGenerated, layer.IncludeGenerated)
 		out.WithComment = ResolveBool(out.WithComment, layer.WithComment)
 		out.WithMessage = ResolveBool(out.WithMessage, layer.WithMessage)
 		out.IncludeStrings = ResolveBool(out.IncludeStrings, layer.IncludeStrings)
@@ -26,6 +28,14 @@ func MergeEngine(base EngineSettings, layers ...EngineConfig) EngineSettings {
 		}
 		out.DetectLangs = ResolveStrings(out.DetectLangs, layer.DetectLangs)
 		out.Tags = ResolveStrings(out.Tags, layer.Tags)
+		out.TagWordBoundary = ResolveBool(out.TagWordBoundary, layer.TagWordBoundary)
+		if layer.TagSuffix != nil {
+			out.TagSuffix = strings.TrimSpace(*layer.TagSuffix)
+		}
+		out.TagCaseSensitive = ResolveStrings(out.TagCaseSensitive, layer.TagCaseSensitive)
+		if layer.TagPatterns != nil {
+			out.TagPatterns = cloneStringMap(*layer.TagPatterns)
+		}
 		out.TruncAll = ResolveInt(out.TruncAll, layer.TruncAll)
 		out.TruncComment = ResolveInt(out.TruncComment, layer.TruncComment)
 		out.TruncMessage = ResolveInt(out.TruncMessage, layer.TruncMessage)
@@ -36,6 +46,19 @@ func MergeEngine(base EngineSettings, layers ...EngineConfig) EngineSettings {
 		out.Color = ResolveAndTrim(out.Color, layer.Color)
 		out.MaxFileBytes = ResolveInt(out.MaxFileBytes, layer.MaxFileBytes)
 		out.NoPrefilter = ResolveBool(out.NoPrefilter, layer.NoPrefilter)
+		out.RecurseSubmodules = ResolveBool(out.RecurseSubmodules, layer.RecurseSubmodules)
+		out.ScanArchives = ResolveBool(out.ScanArchives, layer.ScanArchives)
+		out.ShowSuppressed = ResolveBool(out.ShowSuppressed, layer.ShowSuppressed)
+		out.Context = ResolveInt(out.Context, layer.Context)
+		out.Timeout = ResolveDuration(out.Timeout, layer.Timeout)
+		out.GitTimeout = ResolveDuration(out.GitTimeout, layer.GitTimeout)
+		out.Owners = ResolveStrings(out.Owners, layer.Owners)
+		if layer.Authors != nil {
+			out.Authors = cloneAliases(*layer.Authors)
+		}
+		if layer.Languages != nil {
+			out.Languages = cloneLanguages(*layer.Languages)
+		}
 	}
 	if strings.TrimSpace(out.Output) == "" {
 		out.Output = "table"
@@ -57,6 +80,8 @@ func MergeUI(base UISettings, layers ...UIConfig) UISettings {
 		out.PRPrefer = ResolveString(out.PRPrefer, layer.PRPrefer)
 		out.Fields = ResolveAndTrim(out.Fields, layer.Fields)
 		out.Sort = ResolveAndTrim(out.Sort, layer.Sort)
+		out.GroupBy = ResolveAndTrim(out.GroupBy, layer.GroupBy)
+		out.Stream = ResolveAndTrim(out.Stream, layer.Stream)
 	}
 	out.PRState = strings.TrimSpace(out.PRState)
 	out.PRPrefer = strings.TrimSpace(out.PRPrefer)
@@ -1,6 +1,9 @@
 package config
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 func ResolveString(def string, values ...*string) string {
 	result := def
@@ -22,6 +25,16 @@ func ResolveInt(def int, values ...*int) int {
 	return result
 }
 
+func ResolveDuration(def time.Duration, values ...*time.Duration) time.Duration {
+	result := def
+	for _, v := range values {
+		if v != nil {
+			result = *v
+		}
+	}
+	return result
+}
+
 func ResolveBool(def bool, values ...*bool) bool {
 	result := def
 	for _, v := range values {
@@ -2,35 +2,51 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/phyten/todox/internal/engine"
 )
 
 type EngineConfig struct {
-	Type           *string   `yaml:"type" toml:"type" json:"type"`
-	Mode           *string   `yaml:"mode" toml:"mode" json:"mode"`
-	Detect         *string   `yaml:"detect" toml:"detect" json:"detect"`
-	Author         *string   `yaml:"author" toml:"author" json:"author"`
-	Paths          *[]string `yaml:"path" toml:"path" json:"path"`
-	Excludes       *[]string `yaml:"exclude" toml:"exclude" json:"exclude"`
-	PathRegex      *[]string `yaml:"path_regex" toml:"path_regex" json:"path_regex"`
-	ExcludeTypical *bool     `yaml:"exclude_typical" toml:"exclude_typical" json:"exclude_typical"`
-	WithComment    *bool     `yaml:"with_comment" toml:"with_comment" json:"with_comment"`
-	WithMessage    *bool     `yaml:"with_message" toml:"with_message" json:"with_message"`
-	IncludeStrings *bool     `yaml:"include_strings" toml:"include_strings" json:"include_strings"`
-	CommentsOnly   *bool     `yaml:"comments_only" toml:"comments_only" json:"comments_only"`
-	DetectLangs    *[]string `yaml:"detect_langs" toml:"detect_langs" json:"detect_langs"`
-	Tags           *[]string `yaml:"tags" toml:"tags" json:"tags"`
-	TruncAll       *int      `yaml:"truncate" toml:"truncate" json:"truncate"`
-	TruncComment   *int      `yaml:"truncate_comment" toml:"truncate_comment" json:"truncate_comment"`
-	TruncMessage   *int      `yaml:"truncate_message" toml:"truncate_message" json:"truncate_message"`
-	IgnoreWS       *bool     `yaml:"ignore_ws" toml:"ignore_ws" json:"ignore_ws"`
-	Jobs           *int      `yaml:"jobs" toml:"jobs" json:"jobs"`
-	Repo           *string   `yaml:"repo" toml:"repo" json:"repo"`
-	Output         *string   `yaml:"output" toml:"output" json:"output"`
-	Color          *string   `yaml:"color" toml:"color" json:"color"`
-	MaxFileBytes   *int      `yaml:"max_file_bytes" toml:"max_file_bytes" json:"max_file_bytes"`
-	NoPrefilter    *bool     `yaml:"no_prefilter" toml:"no_prefilter" json:"no_prefilter"`
+	Type              *string                        `yaml:"type" toml:"type" json:"type"`
+	Mode              *string                        `yaml:"mode" toml:"mode" json:"mode"`
+	Detect            *string                        `yaml:"detect" toml:"detect" json:"detect"`
+	Author            *string                        `yaml:"author" toml:"author" json:"author"`
+	SkipAuthors       *string                        `yaml:"skip_authors" toml:"skip_authors" json:"skip_authors"`
+	Paths             *[]string                      `yaml:"path" toml:"path" json:"path"`
+	Excludes          *[]string                      `yaml:"exclude" toml:"exclude" json:"exclude"`
+	PathRegex         *[]string                      `yaml:"path_regex" toml:"path_regex" json:"path_regex"`
+	ExcludeTypical    *bool                          `yaml:"exclude_typical" toml:"exclude_typical" json:"exclude_typical"`
+	IncludeGenerated  *bool                          `yaml:"include_generated" toml:"include_generated" json:"include_generated"`
+	WithComment       *bool                          `yaml:"with_comment" toml:"with_comment" json:"with_comment"`
+	WithMessage       *bool                          `yaml:"with_message" toml:"with_message" json:"with_message"`
+	IncludeStrings    *bool                          `yaml:"include_strings" toml:"include_strings" json:"include_strings"`
+	CommentsOnly      *bool                          `yaml:"comments_only" toml:"comments_only" json:"comments_only"`
+	DetectLangs       *[]string                      `yaml:"detect_langs" toml:"detect_langs" json:"detect_langs"`
+	Tags              *[]string                      `yaml:"tags" toml:"tags" json:"tags"`
+	TagWordBoundary   *bool                          `yaml:"tag_word_boundary" toml:"tag_word_boundary" json:"tag_word_boundary"`
+	TagSuffix         *string                        `yaml:"tag_suffix" toml:"tag_suffix" json:"tag_suffix"`
+	TagCaseSensitive  *[]string                      `yaml:"tag_case_sensitive" toml:"tag_case_sensitive" json:"tag_case_sensitive"`
+	TagPatterns       *map[string]string             `yaml:"tag_patterns" toml:"tag_patterns" json:"tag_patterns"`
+	TruncAll          *int                           `yaml:"truncate" toml:"truncate" json:"truncate"`
+	TruncComment      *int                           `yaml:"truncate_comment" toml:"truncate_comment" json:"truncate_comment"`
+	TruncMessage      *int                           `yaml:"truncate_message" toml:"truncate_message" json:"truncate_message"`
+	IgnoreWS          *bool                          `yaml:"ignore_ws" toml:"ignore_ws" json:"ignore_ws"`
+	Jobs              *int                           `yaml:"jobs" toml:"jobs" json:"jobs"`
+	Repo              *string                        `yaml:"repo" toml:"repo" json:"repo"`
+	Output            *string                        `yaml:"output" toml:"output" json:"output"`
+	Color             *string                        `yaml:"color" toml:"color" json:"color"`
+	MaxFileBytes      *int                           `yaml:"max_file_bytes" toml:"max_file_bytes" json:"max_file_bytes"`
+	NoPrefilter       *bool                          `yaml:"no_prefilter" toml:"no_prefilter" json:"no_prefilter"`
+	RecurseSubmodules *bool                          `yaml:"recurse_submodules" toml:"recurse_submodules" json:"recurse_submodules"`
+	ScanArchives      *bool                          `yaml:"scan_archives" toml:"scan_archives" json:"scan_archives"`
+	ShowSuppressed    *bool                          `yaml:"show_suppressed" toml:"show_suppressed" json:"show_suppressed"`
+	Context           *int                           `yaml:"context" toml:"context" json:"context"`
+	Timeout           *time.Duration                 `yaml:"timeout" toml:"timeout" json:"timeout"`
+	GitTimeout        *time.Duration                 `yaml:"git_timeout" toml:"git_timeout" json:"git_timeout"`
+	Owners            *[]string                      `yaml:"owners" toml:"owners" json:"owners"`
+	Authors           *map[string][]string           `yaml:"authors" toml:"authors" json:"authors"`
+	Languages         *map[string]engine.LanguageDef `yaml:"languages" toml:"languages" json:"languages"`
 }
 
 type UIConfig struct {
@@ -42,6 +58,8 @@ type UIConfig struct {
 	PRPrefer       *string `yaml:"pr_prefer" toml:"pr_prefer" json:"pr_prefer"`
 	Fields         *string `yaml:"fields" toml:"fields" json:"fields"`
 	Sort           *string `yaml:"sort" toml:"sort" json:"sort"`
+	GroupBy        *string `yaml:"group_by" toml:"group_by" json:"group_by"`
+	Stream         *string `yaml:"stream" toml:"stream" json:"stream"`
 }
 
 type Config struct {
@@ -50,29 +68,44 @@ type Config struct {
 }
 
 type EngineSettings struct {
-	Type           string
-	Mode           string
-	Detect         string
-	Author         string
-	Paths          []string
-	Excludes       []string
-	PathRegex      []string
-	ExcludeTypical bool
-	WithComment    bool
-	WithMessage    bool
-	IncludeStrings bool
-	DetectLangs    []string
-	Tags           []string
-	TruncAll       int
-	TruncComment   int
-	TruncMessage   int
-	IgnoreWS       bool
-	Jobs           int
-	Repo           string
-	Output         string
-	Color          string
-	MaxFileBytes   int
-	NoPrefilter    bool
+	Type              string
+	Mode              string
+	Detect            string
+	Author            string
+	SkipAuthors       string
+	Paths             []string
+	Excludes          []string
+	PathRegex         []string
+	ExcludeTypical    bool
+	IncludeGenerated  bool
+	WithComment       bool
+	WithMessage       bool
+	IncludeStrings    bool
+	DetectLangs       []string
+	Tags              []string
+	TagWordBoundary   bool
+	TagSuffix         string
+	TagCaseSensitive  []string
+	TagPatterns       map[string]string
+	TruncAll          int
+	TruncComment      int
+	TruncMessage      int
+	IgnoreWS          bool
+	Jobs              int
+	Repo              string
+	Output            string
+	Color             string
+	MaxFileBytes      int
+	NoPrefilter       bool
+	RecurseSubmodules bool
+	ScanArchives      bool
+	ShowSuppressed    bool
+	Context           int
+	Timeout           time.Duration
+	GitTimeout        time.Duration
+	Owners            []string
+	Authors           map[string][]string
+	Languages         map[string]engine.LanguageDef
 }
 
 type UISettings struct {
@@ -84,33 +117,50 @@ type UISettings struct {
 	PRPrefer       string
 	Fields         string
 	Sort           string
+	GroupBy        string
+	Stream         string
 }
 
 func EngineSettingsFromOptions(opts engine.Options) EngineSettings {
 	return EngineSettings{
-		Type:           opts.Type,
-		Mode:           opts.Mode,
-		Detect:         opts.DetectMode,
-		Author:         opts.AuthorRegex,
-		Paths:          cloneStrings(opts.Paths),
-		Excludes:       cloneStrings(opts.Excludes),
-		PathRegex:      cloneStrings(opts.PathRegex),
-		ExcludeTypical: opts.ExcludeTypical,
-		WithComment:    opts.WithComment,
-		WithMessage:    opts.WithMessage,
-		IncludeStrings: opts.IncludeStrings,
-		DetectLangs:    cloneStrings(opts.DetectLangs),
-		Tags:           cloneStrings(opts.Tags),
-		TruncAll:       opts.TruncAll,
-		TruncComment:   opts.TruncComment,
-		TruncMessage:   opts.TruncMessage,
-		IgnoreWS:       opts.IgnoreWS,
-		Jobs:           opts.Jobs,
-		Repo:           opts.RepoDir,
-		Output:         "table",
-		Color:          "auto",
-		MaxFileBytes:   opts.MaxFileBytes,
-		NoPrefilter:    opts.NoPrefilter,
+		Type:              opts.Type,
+		Mode:              opts.Mode,
+		Detect:            opts.DetectMode,
+		Author:            opts.AuthorRegex,
+		SkipAuthors:       opts.SkipAuthors,
+		Paths:             cloneStrings(opts.Paths),
+		Excludes:          cloneStrings(opts.Excludes),
+		PathRegex:         cloneStrings(opts.PathRegex),
+		ExcludeTypical:    opts.ExcludeTypical,
+		IncludeGenerated:  opts.IncludeGenerated,
+		WithComment:       opts.WithComment,
+		WithMessage:       opts.WithMessage,
+		IncludeStrings:    opts.IncludeStrings,
+		DetectLangs:       cloneStrings(opts.DetectLangs),
+		Tags:              cloneStrings(opts.Tags),
+		TagWordBoundary:   opts.TagRules.WordBoundary,
+		TagSuffix:         opts.TagRules.Suffix,
+		TagCaseSensitive:  cloneStrings(opts.TagRules.CaseSensitive),
+		TagPatterns:       cloneStringMap(opts.TagRules.Patterns),
+		TruncAll:          opts.TruncAll,
+		TruncComment:      opts.TruncComment,
+		TruncMessage:      opts.TruncMessage,
+		IgnoreWS:          opts.IgnoreWS,
+		Jobs:              opts.Jobs,
+		Repo:              opts.RepoDir,
+		Output:            "table",
+		Color:             "auto",
+		MaxFileBytes:      opts.MaxFileBytes,
+		NoPrefilter:       opts.NoPrefilter,
+		RecurseSubmodules: opts.RecurseSubmodules,
+		ScanArchives:      opts.ScanArchives,
+		ShowSuppressed:    opts.ShowSuppressed,
+		Context:           opts.ContextLines,
+		Timeout:           opts.Timeout,
+		GitTimeout:        opts.GitTimeout,
+		Owners:            cloneStrings(opts.Owners),
+		Authors:           cloneAliases(opts.AuthorAliases),
+		Languages:         cloneLanguages(opts.Languages),
 	}
 }
 
@@ -122,15 +172,23 @@ func (s EngineSettings) ApplyToOptions(opts *engine.Options) {
 	opts.Mode = s.Mode
 	opts.DetectMode = s.Detect
 	opts.AuthorRegex = s.Author
+	opts.SkipAuthors = s.SkipAuthors
 	opts.Paths = cloneStrings(s.Paths)
 	opts.Excludes = cloneStrings(s.Excludes)
 	opts.PathRegex = cloneStrings(s.PathRegex)
 	opts.ExcludeTypical = s.ExcludeTypical
+	opts.IncludeGenerated = s.IncludeGenerated
 	opts.WithComment = s.WithComment
 	opts.WithMessage = s.WithMessage
 	opts.IncludeStrings = s.IncludeStrings
 	opts.DetectLangs = cloneStrings(s.DetectLangs)
 	opts.Tags = cloneStrings(s.Tags)
+	opts.TagRules = engine.TagRules{
+		WordBoundary:  s.TagWordBoundary,
+		Suffix:        s.TagSuffix,
+		CaseSensitive: cloneStrings(s.TagCaseSensitive),
+		Patterns:      cloneStringMap(s.TagPatterns),
+	}
 	opts.TruncAll = s.TruncAll
 	opts.TruncComment = s.TruncComment
 	opts.TruncMessage = s.TruncMessage
@@ -141,6 +199,15 @@ func (s EngineSettings) ApplyToOptions(opts *engine.Options) {
 	}
 	opts.MaxFileBytes = s.MaxFileBytes
 	opts.NoPrefilter = s.NoPrefilter
+	opts.RecurseSubmodules = s.RecurseSubmodules
+	opts.ScanArchives = s.ScanArchives
+	opts.ShowSuppressed = s.ShowSuppressed
+	opts.ContextLines = s.Context
+	opts.Timeout = s.Timeout
+	opts.GitTimeout = s.GitTimeout
+	opts.Owners = cloneStrings(s.Owners)
+	opts.AuthorAliases = cloneAliases(s.Authors)
+	opts.Languages = cloneLanguages(s.Languages)
 }
 
 func DefaultUISettings() UISettings {
@@ -153,7 +220,49 @@ func DefaultUISettings() UISettings {
 		PRPrefer:       "open",
 		Fields:         "",
 		Sort:           "",
+		GroupBy:        "",
+		Stream:         "file",
+	}
+}
+
+func cloneAliases(in map[string][]string) map[string][]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(in))
+	for k, v := range in {
+		out[k] = cloneStrings(v)
+	}
+	return out
+}
+
+func cloneLanguages(in map[string]engine.LanguageDef) map[string]engine.LanguageDef {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]engine.LanguageDef, len(in))
+	for name, def := range in {
+		def.Extensions = cloneStrings(def.Extensions)
+		def.Filenames = cloneStrings(def.Filenames)
+		def.Shebangs = cloneStrings(def.Shebangs)
+		def.LineComments = cloneStrings(def.LineComments)
+		def.BlockComments = append([]engine.BlockCommentDef(nil), def.BlockComments...)
+		def.Strings = append([]engine.StringDef(nil), def.Strings...)
+		def.Heredocs = cloneStrings(def.Heredocs)
+		out[name] = def
 	}
+	return out
+}
+
+func cloneStringMap(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
 }
 
 func cloneStrings(in []string) []string {
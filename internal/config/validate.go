@@ -31,6 +31,19 @@ func CanonicalizePRPrefer(raw string) (string, error) {
 	}
 }
 
+func CanonicalizeStream(raw string) (string, error) {
+	stream := strings.ToLower(strings.TrimSpace(raw))
+	if stream == "" {
+		return "file", nil
+	}
+	switch stream {
+	case "file", "unsorted", "off":
+		return stream, nil
+	default:
+		return "", fmt.Errorf("invalid stream: %s", raw)
+	}
+}
+
 func ValidatePRLimit(limit int) error {
 	if limit < 1 || limit > 20 {
 		return fmt.Errorf("pr_limit must be between 1 and 20")
@@ -42,6 +55,11 @@ func NormalizeUI(values UISettings) (UISettings, error) {
 	var err error
 	values.Fields = strings.TrimSpace(values.Fields)
 	values.Sort = strings.TrimSpace(values.Sort)
+	values.GroupBy = strings.ToLower(strings.TrimSpace(values.GroupBy))
+	values.Stream, err = CanonicalizeStream(values.Stream)
+	if err != nil {
+		return values, err
+	}
 
 	values.PRState, err = CanonicalizePRState(values.PRState)
 	if err != nil {
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// maxArchiveEntryBytes は展開するアーカイブ内ファイル 1 件あたりの上限です（圧縮爆弾対策）。
+const maxArchiveEntryBytes = 64 << 20
+
+// archiveSeparator はアーカイブのパスと中のファイルのパスを区切る文字です（archive.zip!inner/path.py）。
+const archiveSeparator = "!"
+
+type archiveFormat int
+
+const (
+	archiveNone archiveFormat = iota
+	archiveZip
+	archiveTarGz
+	archiveGzip
+)
+
+func archiveFormatOf(p string) archiveFormat {
+	lower := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return archiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz
+	case strings.HasSuffix(lower, ".gz"):
+		return archiveGzip
+	}
+	return archiveNone
+}
+
+// collectArchiveMatches は --scan-archives 指定時に、追跡されているアーカイブの中のファイルを走査します。
+// 見つかった項目の File は "archive.zip!inner/path.py"、Kind は MatchKindArchive になり、blame の対象外です。
+func collectArchiveMatches(ctx context.Context, opts Options, tags []string, mode detectionMode) ([]model.Match, []ItemError, error) {
+	files, err := gitListFiles(ctx, opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = filterPathsByRegex(files, opts.PathRegexCompiled)
+	if !opts.IncludeGenerated {
+		files, err = filterGeneratedPaths(ctx, opts.RepoDir, files)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	specs, err := compileTagSpecs(tags, opts.TagRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	var all []model.Match
+	var errs []ItemError
+	for _, rel := range files {
+		format := archiveFormatOf(rel)
+		if format == archiveNone {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		err := walkArchive(filepath.Join(opts.RepoDir, rel), rel, format, func(inner string, data []byte, entryErr error) {
+			name := rel + archiveSeparator + inner
+			if entryErr != nil {
+				errs = append(errs, newItemError(name, 0, "archive", entryErr))
+				return
+			}
+			var found []model.Match
+			if mode == detectionModeRegex {
+				// 作業ツリーの regex モードと同じく、バイナリ・生成ファイル・todox:ignore・文字コードを扱う
+				if !opts.IncludeGenerated && hasGeneratedHeader(data) {
+					return
+				}
+				found = scanPlainContent(name, data, specs)
+			} else {
+				found = parseContent(name, data, opts, specs, mode == detectionModeAuto)
+			}
+			for i := range found {
+				found[i].Kind = model.MatchKindArchive
+			}
+			all = append(all, found...)
+		})
+		if err != nil {
+			errs = append(errs, newItemError(rel, 0, "archive", err))
+		}
+	}
+	return all, errs, nil
+}
+
+// walkArchive はアーカイブ内の通常ファイルを順に展開して fn に渡します。
+// 展開できないファイル（上限超過など）は entryErr 付きで fn に渡して続行し、
+// アーカイブ自体を読めない場合だけエラーを返します。
+func walkArchive(full, rel string, format archiveFormat, fn func(inner string, data []byte, entryErr error)) error {
+	switch format {
+	case archiveZip:
+		zr, err := zip.OpenReader(full)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				fn(f.Name, nil, err)
+				continue
+			}
+			data, err := readArchiveEntry(rc)
+			rc.Close()
+			fn(f.Name, data, err)
+		}
+		return nil
+	case archiveTarGz, archiveGzip:
+		fh, err := os.Open(full)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		if format == archiveGzip {
+			data, err := readArchiveEntry(gz)
+			base := path.Base(rel)
+			fn(base[:len(base)-len(".gz")], data, err)
+			return nil
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			data, err := readArchiveEntry(tr)
+			fn(strings.TrimPrefix(hdr.Name, "./"), data, err)
+		}
+	}
+	return nil
+}
+
+func readArchiveEntry(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, maxArchiveEntryBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxArchiveEntryBytes {
+		return nil, fmt.Errorf("entry exceeds %d bytes", maxArchiveEntryBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// archiveOuterPath は "archive.zip!inner/path" からアーカイブ自体のパスを返します。
+func archiveOuterPath(file string) string {
+	for i := 0; i < len(file); i++ {
+		if strings.HasPrefix(file[i:], archiveSeparator) && archiveFormatOf(file[:i]) != archiveNone {
+			return file[:i]
+		}
+	}
+	return file
+}
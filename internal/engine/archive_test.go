@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatalf("tar write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestRunScansArchivesOnDemand(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	if err := os.MkdirAll(filepath.Join(repoDir, "vendor"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	writeZip(t, filepath.Join(repoDir, "vendor", "sdk.zip"), map[string]string{
+		"sdk/client.py": "import os\n\n# TODO: retry on 503\nURL = \"FIXME not a comment\"\n",
+	})
+	writeTarGz(t, filepath.Join(repoDir, "vendor", "tools.tar.gz"), map[string]string{
+		"./bin/run.sh": "#!/bin/sh\necho hi # FIXME: quote args\n",
+	})
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	gz.Write([]byte("// TODO: inline me\n"))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(repoDir, "vendor", "shim.js.gz"), gzBuf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n// TODO: top level\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "parse", RepoDir: repoDir, Jobs: 1}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 1 {
+		t.Fatalf("archives should be skipped by default: %+v", res.Items)
+	}
+
+	opts.ScanArchives = true
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	type want struct {
+		file string
+		line int
+		lang string
+	}
+	expected := []want{
+		{"main.go", 2, "go"},
+		{"vendor/sdk.zip!sdk/client.py", 3, "python"},
+		{"vendor/shim.js.gz!shim.js", 1, "javascript"},
+		{"vendor/tools.tar.gz!bin/run.sh", 2, "shell"},
+	}
+	if len(res.Items) != len(expected) {
+		t.Fatalf("expected %d items, got %+v", len(expected), res.Items)
+	}
+	for i, w := range expected {
+		it := res.Items[i]
+		if it.File != w.file || it.Line != w.line || it.Lang != w.lang {
+			t.Fatalf("item %d: got %s:%d (%s), want %s:%d (%s)", i, it.File, it.Line, it.Lang, w.file, w.line, w.lang)
+		}
+		if i == 0 {
+			if it.Author != "alice" || it.Commit == "" {
+				t.Fatalf("regular files should still be blamed: %+v", it)
+			}
+			continue
+		}
+		if it.MatchKind != string(model.MatchKindArchive) || it.Author != archiveAuthor || it.Commit != "" {
+			t.Fatalf("archive items should not be blamed: %+v", it)
+		}
+	}
+
+	opts.AuthorRegex = "alice"
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].File != "main.go" {
+		t.Fatalf("--author should drop archive items: %+v", res.Items)
+	}
+}
+
+func TestWalkArchiveReportsBrokenArchives(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.zip")
+	if err := os.WriteFile(path, []byte("not a zip"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	err := walkArchive(path, "broken.zip", archiveZip, func(string, []byte, error) {
+		t.Fatalf("no entries expected")
+	})
+	if err == nil {
+		t.Fatalf("expected an error for a broken archive")
+	}
+}
+
+func TestArchiveOuterPath(t *testing.T) {
+	cases := map[string]string{
+		"vendor/sdk.zip!a/b.py":    "vendor/sdk.zip",
+		"x.tar.gz!y!z.py":          "x.tar.gz",
+		"notes!important.md":       "notes!important.md",
+		"plain/file.go":            "plain/file.go",
+		"lib/app.jar!META-INF/x.k": "lib/app.jar",
+	}
+	for in, want := range cases {
+		if got := archiveOuterPath(in); got != want {
+			t.Fatalf("archiveOuterPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunSkipsBinaryAndGeneratedArchiveEntriesInRegexMode(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	writeZip(t, filepath.Join(repoDir, "lib.jar"), map[string]string{
+		"A.class":    "\xca\xfe\xba\xbe\x00\x00\x00\x34todo_field\x00",
+		"gen/B.java": "// Code generated by tool. DO NOT EDIT.\n// TODO: generated\n",
+		"src/C.java": "// TODO: real\n// TODO: hidden todox:ignore\n",
+		"src/README": "FIXME: plain text\n",
+	})
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	res, err := Run(Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1, ScanArchives: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var got []string
+	for _, it := range res.Items {
+		got = append(got, it.File+":"+it.Text)
+	}
+	if len(res.Items) != 2 || res.Items[0].File != "lib.jar!src/C.java" || res.Items[1].File != "lib.jar!src/README" {
+		t.Fatalf("binary, generated and ignored entries should be skipped: %q", got)
+	}
+	if res.Suppressed != 1 {
+		t.Fatalf("the todox:ignore line should be counted as suppressed, got %d", res.Suppressed)
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"strings"
+)
+
+// authorIdentity は別名解決後の正規の作者です。Email が空の場合は元のメールアドレスを残します。
+type authorIdentity struct {
+	Name  string
+	Email string
+}
+
+// authorAliases は設定ファイルの authors: から作られる別名表です。
+// キーは小文字化した名前またはメールアドレスで、.mailmap 適用後の値に対して引きます。
+type authorAliases map[string]authorIdentity
+
+// newAuthorAliases は「正規名 → 別名一覧」の設定から別名表を構築します。
+// 正規名は "Name" または "Name <email>" の形式を受け付けます。
+func newAuthorAliases(raw map[string][]string) authorAliases {
+	if len(raw) == 0 {
+		return nil
+	}
+	out := make(authorAliases)
+	for canonical, aliases := range raw {
+		id := parseAuthorIdentity(canonical)
+		if id.Name == "" {
+			continue
+		}
+		out[strings.ToLower(id.Name)] = id
+		if id.Email != "" {
+			out[strings.ToLower(id.Email)] = id
+		}
+		for _, alias := range aliases {
+			a := parseAuthorIdentity(alias)
+			if a.Name != "" {
+				out[strings.ToLower(a.Name)] = id
+			}
+			if a.Email != "" {
+				out[strings.ToLower(a.Email)] = id
+			}
+		}
+	}
+	return out
+}
+
+func parseAuthorIdentity(raw string) authorIdentity {
+	raw = strings.TrimSpace(raw)
+	if open := strings.LastIndex(raw, "<"); open >= 0 && strings.HasSuffix(raw, ">") {
+		return authorIdentity{
+			Name:  strings.TrimSpace(raw[:open]),
+			Email: strings.TrimSpace(raw[open+1 : len(raw)-1]),
+		}
+	}
+	return authorIdentity{Name: raw}
+}
+
+// resolve はメールアドレスを優先して別名を引き、正規の名前とメールアドレスを返します。
+func (a authorAliases) resolve(name, email string) (string, string) {
+	if len(a) == 0 {
+		return name, email
+	}
+	id, ok := a[strings.ToLower(strings.TrimSpace(email))]
+	if !ok || email == "" {
+		id, ok = a[strings.ToLower(strings.TrimSpace(name))]
+	}
+	if !ok {
+		return name, email
+	}
+	if id.Email != "" {
+		email = id.Email
+	}
+	return id.Name, email
+}
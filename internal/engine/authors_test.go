@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthorAliasesはメール優先で正規名に寄せる(t *testing.T) {
+	aliases := newAuthorAliases(map[string][]string{
+		"Alice Example <alice@example.com>": {"alice@old.example.com", "Alice E."},
+		"Bob":                               {"bob@users.noreply.github.com"},
+	})
+	cases := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"alice", "ALICE@old.example.com", "Alice Example", "alice@example.com"},
+		{"Alice E.", "a@laptop.local", "Alice Example", "alice@example.com"},
+		{"bobby", "bob@users.noreply.github.com", "Bob", "bob@users.noreply.github.com"},
+		{"Carol", "carol@example.com", "Carol", "carol@example.com"},
+	}
+	for _, tc := range cases {
+		gotName, gotEmail := aliases.resolve(tc.name, tc.email)
+		if gotName != tc.wantName || gotEmail != tc.wantEmail {
+			t.Fatalf("resolve(%q, %q) = (%q, %q), want (%q, %q)", tc.name, tc.email, gotName, gotEmail, tc.wantName, tc.wantEmail)
+		}
+	}
+	var none authorAliases
+	if n, e := none.resolve("x", "y"); n != "x" || e != "y" {
+		t.Fatalf("別名表が空のときは入力をそのまま返す想定です: %q %q", n, e)
+	}
+}
+
+func TestRunはmailmapと別名設定で作者を正規化しコミッターを返す(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "Merge Bot")
+	runGit(t, repoDir, "config", "user.email", "bot@example.com")
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("ファイルの作成に失敗しました: %v", err)
+		}
+	}
+	write(".mailmap", "Alice Example <alice@example.com> <alice@old.example.com>\n")
+	write("a.go", "// TODO from old address\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "--author", "alice <alice@old.example.com>", "-m", "a")
+	write("b.go", "// TODO from laptop\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "--author", "Alice Laptop <a@laptop.local>", "-m", "b")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 2 {
+		t.Fatalf("件数が期待と異なります: %+v", res.Items)
+	}
+	if got := res.Items[0]; got.Author != "Alice Example" || got.Email != "alice@example.com" {
+		t.Fatalf(".mailmap が適用されていません: %+v", got)
+	}
+	if got := res.Items[1]; got.Author != "Alice Laptop" {
+		t.Fatalf("別名設定なしでは元の作者のままの想定です: %+v", got)
+	}
+	for _, it := range res.Items {
+		if it.Committer != "Merge Bot" || it.CommitterEmail != "bot@example.com" {
+			t.Fatalf("コミッターが期待と異なります: %+v", it)
+		}
+	}
+
+	opts.AuthorAliases = map[string][]string{"Alice Example": {"a@laptop.local"}}
+	opts.AuthorRegex = "^Alice Example$"
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 2 {
+		t.Fatalf("別名で統合した作者で絞り込めていません: %+v", res.Items)
+	}
+	if got := res.Items[1]; got.Author != "Alice Example" || got.Email != "a@laptop.local" {
+		t.Fatalf("別名設定が適用されていません: %+v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// maxBodyLines は 1 件の本文として集める継続行の上限です。
+const maxBodyLines = 32
+
+// commentMarkers はスタイルの行コメント記号とブロックコメントの開始・終了記号を返します。
+func (cs commentStyle) commentMarkers() (prefixes, openers, closers []string) {
+	if cs.lexer != nil {
+		prefixes = append(prefixes, cs.lexer.lineComments...)
+		for _, blk := range cs.lexer.blockComments {
+			openers = append(openers, blk.open)
+			closers = append(closers, blk.close)
+		}
+		return prefixes, openers, closers
+	}
+	prefixes = append(prefixes, cs.linePrefixes...)
+	for _, blk := range cs.block {
+		if blk.kind == model.MatchKindComment {
+			openers = append(openers, blk.start)
+			closers = append(closers, blk.end)
+		}
+	}
+	return prefixes, openers, closers
+}
+
+// attachBodies はコメント中の各タグについて、後続の連続したコメント行（空のコメント行・
+// 別のタグ・コメントの終わりまで）を本文として Match.Body に集め、Span の終端を本文の末尾まで延ばします。
+// ブロックコメントでは Text をブロック全体からタグのある行に絞ります。
+func attachBodies(data []byte, matches []model.Match, tags []tagSpec, style commentStyle) {
+	if len(matches) == 0 {
+		return
+	}
+	prefixes, openers, closers := style.commentMarkers()
+	lineOffsets := computeLineOffsets(data)
+	lines := strings.Split(string(data), "\n")
+	for i := range matches {
+		m := &matches[i]
+		if m.Kind != model.MatchKindComment || m.Span.StartLine < 1 || m.Span.StartLine > len(lines) {
+			continue
+		}
+		lineIdx := m.Span.StartLine - 1
+		line := strings.TrimRight(lines[lineIdx], "\r")
+		tagStart := m.Span.StartCol - 1
+		if tagStart < 0 || tagStart > len(line) {
+			continue
+		}
+		prefix := linePrefixBefore(line[:tagStart], prefixes)
+		first, closed := line[tagStart:], false
+		if prefix == "" {
+			first, closed = cutAtCloser(first, closers)
+			m.Text = strings.TrimSpace(stripBlockLead(afterOpener(line[:tagStart], openers)) + first)
+		}
+		body := []string{strings.TrimSpace(first)}
+		endLine, endByte := m.Span.StartLine, -1
+		for k := lineIdx + 1; !closed && k < len(lines) && len(body) < maxBodyLines; k++ {
+			raw := strings.TrimRight(lines[k], "\r")
+			var content string
+			if prefix != "" {
+				trimmed := strings.TrimLeft(raw, " \t")
+				if !strings.HasPrefix(trimmed, prefix) {
+					break
+				}
+				content = strings.TrimLeft(trimmed[len(prefix):], prefix)
+			} else {
+				content, closed = cutAtCloser(raw, closers)
+				content = stripBlockLead(content)
+			}
+			content = strings.TrimSpace(content)
+			if content == "" || hasAnyTag(content, tags) {
+				break
+			}
+			body = append(body, content)
+			endLine = k + 1
+			endByte = lineOffsets[k] + strings.LastIndex(raw, content) + len(content)
+		}
+		m.Body = strings.Join(body, "\n")
+		if endByte >= 0 {
+			m.Span.EndLine = endLine
+			m.Span.EndCol = endByte - lineOffsets[endLine-1] + 1
+			m.Span.ByteEnd = endByte
+		}
+	}
+}
+
+// linePrefixBefore は head（タグより前の部分）に含まれる行コメント記号を返します。
+func linePrefixBefore(head string, prefixes []string) string {
+	best, bestIdx := "", -1
+	for _, p := range prefixes {
+		if p == "" {
+			continue
+		}
+		if idx := strings.Index(head, p); idx >= 0 && (bestIdx < 0 || idx < bestIdx || (idx == bestIdx && len(p) > len(best))) {
+			best, bestIdx = p, idx
+		}
+	}
+	return best
+}
+
+// cutAtCloser は s をブロックコメントの終了記号の手前で切り、終了記号があったかを返します。
+func cutAtCloser(s string, closers []string) (string, bool) {
+	cut := -1
+	for _, c := range closers {
+		if c == "" {
+			continue
+		}
+		if idx := strings.Index(s, c); idx >= 0 && (cut < 0 || idx < cut) {
+			cut = idx
+		}
+	}
+	if cut < 0 {
+		return s, false
+	}
+	return s[:cut], true
+}
+
+// afterOpener は head に含まれる最後のブロックコメント開始記号より後ろを返します。
+func afterOpener(head string, openers []string) string {
+	cut := -1
+	for _, o := range openers {
+		if o == "" {
+			continue
+		}
+		if idx := strings.LastIndex(head, o); idx >= 0 && idx+len(o) > cut {
+			cut = idx + len(o)
+		}
+	}
+	if cut < 0 {
+		return head
+	}
+	return head[cut:]
+}
+
+// stripBlockLead はブロックコメント行の先頭の空白と "*" の飾りを取り除きます。
+func stripBlockLead(s string) string {
+	s = strings.TrimLeft(s, " \t")
+	if strings.HasPrefix(s, "*") && !strings.HasPrefix(s, "*/") {
+		s = strings.TrimLeft(s, "*")
+	}
+	return strings.TrimLeft(s, " \t")
+}
+
+func hasAnyTag(text string, tags []tagSpec) bool {
+	upper := strings.ToUpper(text)
+	for _, tag := range tags {
+		if len(findTagHits(text, upper, tag)) > 0 {
+			return true
+		}
+	}
+	return false
+}
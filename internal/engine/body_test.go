@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func parseBodies(t *testing.T, path, src string) []model.Match {
+	t.Helper()
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	return parseContent(path, []byte(src), Options{}, tags, false)
+}
+
+func TestAttachBodiesCollectsLineCommentContinuation(t *testing.T) {
+	src := "package main\n\n// TODO: refactor this because\n//   the cache is racy\n//\n// unrelated note\nfunc main() {}\n"
+	matches := parseBodies(t, "main.go", src)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %+v", matches)
+	}
+	m := matches[0]
+	if m.Body != "TODO: refactor this because\nthe cache is racy" {
+		t.Fatalf("unexpected body: %q", m.Body)
+	}
+	if m.Text != "TODO: refactor this because" {
+		t.Fatalf("text should stay on the tag line: %q", m.Text)
+	}
+	if m.Span.StartLine != 3 || m.Span.EndLine != 4 || m.Span.EndCol != 23 {
+		t.Fatalf("unexpected span: %+v", m.Span)
+	}
+}
+
+func TestAttachBodiesStopsAtNextTagAndCode(t *testing.T) {
+	src := "# TODO first\n# FIXME second\n# continues second\nx = 1\n# not part of anything\n"
+	matches := parseBodies(t, "script.py", src)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	if matches[0].Body != "TODO first" || matches[0].Span.EndLine != 1 {
+		t.Fatalf("first body should stop at the next tag: %+v", matches[0])
+	}
+	if matches[1].Body != "FIXME second\ncontinues second" || matches[1].Span.EndLine != 3 {
+		t.Fatalf("second body should stop at code: %+v", matches[1])
+	}
+}
+
+func TestAttachBodiesNarrowsBlockComments(t *testing.T) {
+	src := "/*\n * Package overview.\n *\n * TODO: split this file\n * into smaller pieces */\nint x;\n"
+	matches := parseBodies(t, "main.c", src)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %+v", matches)
+	}
+	m := matches[0]
+	if m.Text != "TODO: split this file" {
+		t.Fatalf("block text should be narrowed to the tag line: %q", m.Text)
+	}
+	if m.Body != "TODO: split this file\ninto smaller pieces" || m.Span.EndLine != 5 {
+		t.Fatalf("unexpected block body: %+v", m)
+	}
+
+	single := parseBodies(t, "main.c", "int y; /* FIXME overflow */ int z;\n")
+	if len(single) != 1 || single[0].Body != "FIXME overflow" || single[0].Text != "FIXME overflow" || single[0].Span.EndLine != 1 {
+		t.Fatalf("unexpected single-line block: %+v", single)
+	}
+}
+
+func TestRunExposesBodyWithTruncation(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	src := "package main\n// TODO: refactor because\n// the cache is racy\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "parse", RepoDir: repoDir, Jobs: 1, WithComment: true}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 1 {
+		t.Fatalf("expected 1 item, got %+v", res.Items)
+	}
+	it := res.Items[0]
+	if it.Comment != "TODO: refactor because" || it.Body != "TODO: refactor because\nthe cache is racy" || it.Span.EndLine != 3 {
+		t.Fatalf("unexpected item: %+v", it)
+	}
+
+	opts.TruncComment = 10
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := res.Items[0].Body; got != "TODO: ref…" {
+		t.Fatalf("body should follow --truncate-comment: %q", got)
+	}
+
+	opts.WithComment = false
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Items[0].Body != "" {
+		t.Fatalf("body should only be filled with --with-comment: %q", res.Items[0].Body)
+	}
+}
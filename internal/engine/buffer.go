@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// ScanContent は保存前のエディタのバッファなど、作業ツリーとは異なる内容から項目を抽出します。
+// relPath はリポジトリ相対のパスで、言語判定と .todoxignore の判定に使います。
+// 検出方法・タグ・言語定義・抑制マーカーは Run と同じ規則に従いますが、blame はしないため
+// Author や Commit は空のままです。Comment と Body は opts.WithComment のときに埋めます。
+func ScanContent(relPath string, data []byte, opts Options) ([]Item, error) {
+	opts, tags, searchTags, err := prepareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := compileTagSpecs(searchTags, opts.TagRules)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := detectContent(relPath, data, opts, specs)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RepoDir != "" {
+		if err := markTodoxIgnored(opts.RepoDir, matches); err != nil {
+			return nil, err
+		}
+	}
+
+	matches = filterMatchesByType(matches, opts.Type, tags)
+	matches, _ = splitSuppressed(matches, opts.ShowSuppressed)
+
+	var src contextSource
+	if opts.ContextLines > 0 {
+		src = newContextSource(relPath, data)
+	}
+	items := make([]Item, 0, len(matches))
+	for _, m := range matches {
+		it := newItem(m)
+		applyCommentFields(opts, m, &it)
+		src.attach(&it, opts.ContextLines)
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// detectContent は opts.DetectMode に従って 1 ファイル分の内容からマッチを抽出します。
+// regex モードでも todox:ignore などの抑制マーカーは解釈します。
+func detectContent(relPath string, data []byte, opts Options, specs []tagSpec) ([]model.Match, error) {
+	switch strings.ToLower(strings.TrimSpace(opts.DetectMode)) {
+	case "", "auto":
+		return parseContent(relPath, data, opts, specs, true), nil
+	case "parse":
+		return parseContent(relPath, data, opts, specs, false), nil
+	case "regex":
+		return scanPlainContent(relPath, data, specs), nil
+	default:
+		return nil, fmt.Errorf("invalid detect mode: %s", opts.DetectMode)
+	}
+}
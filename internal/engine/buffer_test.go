@@ -0,0 +1,36 @@
+package engine
+
+import "testing"
+
+func TestScanContentは未保存の内容から項目を抽出する(t *testing.T) {
+	src := "package main\n// TODO: first\nvar s = \"TODO in string\"\n// FIXME: second todox:ignore\n// FIXME: third\n"
+	opts := Options{Type: "fixme", WithComment: true, IncludeStrings: true}
+	items, err := ScanContent("main.go", []byte(src), opts)
+	if err != nil {
+		t.Fatalf("ScanContent failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Line != 5 || items[0].Tag != "FIXME" || items[0].Comment != "FIXME: third" || items[0].Commit != "" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+
+	opts.Type = "both"
+	opts.IncludeStrings = false
+	items, err = ScanContent("main.go", []byte(src), opts)
+	if err != nil {
+		t.Fatalf("ScanContent failed: %v", err)
+	}
+	if len(items) != 2 || items[0].Line != 2 || items[0].MatchKind != "comment" || items[0].Lang != "go" {
+		t.Fatalf("unexpected items without strings: %+v", items)
+	}
+
+	opts.DetectMode = "nope"
+	if _, err := ScanContent("main.go", []byte(src), opts); err == nil {
+		t.Fatal("expected an error for an unknown detect mode")
+	}
+
+	opts.DetectMode = ""
+	opts.PathRegex = []string{"("}
+	if _, err := ScanContent("main.go", []byte(src), opts); err == nil {
+		t.Fatal("expected an error for an invalid --path-regex")
+	}
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// sourceContext は Options.ContextLines のために、項目のあるファイルの行を読み込んで共有します。
+// ワーカーから並行に呼ばれます。ファイルは最初の項目で一度だけ読み、そのファイルの項目すべてに
+// 付け終えたところで手放すので、保持するのは処理中のファイルだけです。
+type sourceContext struct {
+	repo  string
+	n     int
+	mu    sync.Mutex
+	files map[string]*contextFile
+}
+
+// contextFile は 1 ファイル分の行と、まだ前後の行を付けていない項目の数です。
+type contextFile struct {
+	once      sync.Once
+	src       contextSource
+	remaining int
+}
+
+// newSourceContext は n が 0 以下なら nil を返します（attach は何もしません）。
+// matches は帰属する全件で、ファイルごとの件数を数えて手放す時期を決めます。
+func newSourceContext(repo string, n int, matches []model.Match) *sourceContext {
+	if n <= 0 {
+		return nil
+	}
+	c := &sourceContext{repo: repo, n: n, files: make(map[string]*contextFile)}
+	for _, m := range matches {
+		if m.Kind == model.MatchKindArchive {
+			continue
+		}
+		f := c.files[m.File]
+		if f == nil {
+			f = &contextFile{}
+			c.files[m.File] = f
+		}
+		f.remaining++
+	}
+	return c
+}
+
+// attach は it の前後 n 行を作業ツリーのファイルから読んで付けます。アーカイブ内の項目には付けません。
+// 読み込みは c.mu の外で行い、同じファイルを待つのはそのファイルの項目だけです。
+func (c *sourceContext) attach(it *Item) {
+	if c == nil || it.MatchKind == string(model.MatchKindArchive) {
+		return
+	}
+	c.mu.Lock()
+	f := c.files[it.File]
+	c.mu.Unlock()
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		if data, err := os.ReadFile(filepath.Join(c.repo, filepath.FromSlash(it.File))); err == nil {
+			f.src = newContextSource(it.File, data)
+		}
+	})
+	f.src.attach(it, c.n)
+	c.mu.Lock()
+	if f.remaining--; f.remaining <= 0 {
+		delete(c.files, it.File)
+	}
+	c.mu.Unlock()
+}
+
+// contextSource は前後の行の元になる 1 ファイル分の行です。ノートブックでは、セル内の項目に
+// .ipynb の JSON の行ではなくそのセルのソースを付けるため、セルも持ちます。
+type contextSource struct {
+	lines []string
+	cells []notebookCell
+}
+
+func newContextSource(path string, data []byte) contextSource {
+	src := contextSource{lines: sourceLines(data)}
+	if isNotebookPath(path) {
+		if cells, _, err := parseNotebook(data); err == nil {
+			src.cells = cells
+		}
+	}
+	return src
+}
+
+// attach は it の前後 n 行を付けます。セル内の項目には同じセルの行だけを付けます。
+func (s contextSource) attach(it *Item, n int) {
+	if it.Cell == nil {
+		attachContextLines(it, s.lines, n)
+		return
+	}
+	if it.Cell.Index < 0 || it.Cell.Index >= len(s.cells) {
+		return
+	}
+	cell := s.cells[it.Cell.Index]
+	// Span の終了行は .ipynb 上の行なので、セル内の行に読み替える
+	start, end := it.Cell.Line, it.Cell.Line
+	for end < len(cell.rawLines) && cell.rawLines[end] <= it.Span.EndLine {
+		end++
+	}
+	local := Item{Line: start, Span: model.Span{StartLine: start, EndLine: end}}
+	attachContextLines(&local, cell.lines, n)
+	it.ContextBefore, it.ContextAfter = local.ContextBefore, local.ContextAfter
+}
+
+// attachContextLines は項目の開始行より前の n 行を ContextBefore に、終了行より後の n 行を ContextAfter に入れます。
+// 複数行にわたるコメントの項目では、その行は前後どちらにも含めません。
+func attachContextLines(it *Item, lines []string, n int) {
+	if n <= 0 || len(lines) == 0 {
+		return
+	}
+	start := it.Span.StartLine
+	if start < 1 {
+		start = it.Line
+	}
+	end := max(it.Span.EndLine, start)
+	if start < 1 || start > len(lines) {
+		return
+	}
+	if from := max(1, start-n); from < start {
+		it.ContextBefore = append([]string(nil), lines[from-1:start-1]...)
+	}
+	if to := min(len(lines), end+n); end < to {
+		it.ContextAfter = append([]string(nil), lines[end:to]...)
+	}
+}
+
+// sourceLines は内容を行に分けます。UTF-16 や Shift_JIS などは UTF-8 に直し、行末の \r は取り除きます。
+func sourceLines(data []byte) []string {
+	if src, ok := decodeLegacySource(data); ok {
+		data = src.text
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
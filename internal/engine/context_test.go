@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func TestRunはContextLinesで前後の行を付ける(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	src := "// TODO: top\npackage a\n\nfunc a() {\n\t/* FIXME: spans\n\t   two lines */\n\treturn\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "a.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	res, err := Run(Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 2, ContextLines: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", res.Items)
+	}
+	top, block := res.Items[0], res.Items[1]
+	if top.ContextBefore != nil || !reflect.DeepEqual(top.ContextAfter, []string{"package a", ""}) {
+		t.Fatalf("unexpected context for the first line: before=%q after=%q", top.ContextBefore, top.ContextAfter)
+	}
+	// 複数行のコメントは終了行の後から数える
+	if !reflect.DeepEqual(block.ContextBefore, []string{"", "func a() {"}) || !reflect.DeepEqual(block.ContextAfter, []string{"\treturn", "}"}) {
+		t.Fatalf("unexpected context for the block comment: before=%q after=%q", block.ContextBefore, block.ContextAfter)
+	}
+
+	res, err = Run(Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 1})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Items[0].ContextBefore != nil || res.Items[0].ContextAfter != nil {
+		t.Fatalf("no context should be attached by default: %+v", res.Items[0])
+	}
+
+	items, err := ScanContent("a.go", []byte("package a\n// TODO: buffer\n"), Options{Type: "both", ContextLines: 3})
+	if err != nil {
+		t.Fatalf("ScanContent failed: %v", err)
+	}
+	if len(items) != 1 || !reflect.DeepEqual(items[0].ContextBefore, []string{"package a"}) || items[0].ContextAfter != nil {
+		t.Fatalf("unexpected buffer context: %+v", items)
+	}
+}
+
+func TestSourceContextは項目を付け終えたファイルを手放す(t *testing.T) {
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "a.go"), []byte("package a\n// TODO: one\n// TODO: two\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	matches := []model.Match{
+		{File: "a.go", Tag: "TODO", Span: model.Span{StartLine: 2, StartCol: 4, EndLine: 2}},
+		{File: "a.go", Tag: "TODO", Span: model.Span{StartLine: 3, StartCol: 4, EndLine: 3}},
+	}
+	c := newSourceContext(repoDir, 1, matches)
+	first, second := newItem(matches[0]), newItem(matches[1])
+	c.attach(&first)
+	if _, ok := c.files["a.go"]; !ok {
+		t.Fatal("the file should stay loaded while items remain")
+	}
+	c.attach(&second)
+	if _, ok := c.files["a.go"]; ok {
+		t.Fatal("the file should be released once every item has its context")
+	}
+	if !reflect.DeepEqual(first.ContextBefore, []string{"package a"}) || !reflect.DeepEqual(second.ContextBefore, []string{"// TODO: one"}) {
+		t.Fatalf("unexpected context: %q %q", first.ContextBefore, second.ContextBefore)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,6 +33,9 @@ type commentStyle struct {
 	linePrefixes []string
 	block        []blockPattern
 	stringDelims []string
+	heredocs     []string // ヒアドキュメントの演算子（長いものを先に並べる）
+	// lexer が設定されている場合は行単位の走査の代わりに言語別の状態機械で字句解析します。
+	lexer *codeLexer
 }
 
 type blockPattern struct {
@@ -39,6 +43,7 @@ type blockPattern struct {
 	end                string
 	kind               model.MatchKind
 	allowIndentedStart bool
+	nested             bool // 開始記号の入れ子を数える（/* /* */ */、{- {- -} -} など）
 }
 
 type parseJob struct {
@@ -53,6 +58,12 @@ type parseResult struct {
 type tagSpec struct {
 	raw   string
 	upper string
+
+	// 以下は TagRules から設定される照合規則です（tagmatch.go を参照）。
+	boundary      bool
+	suffix        string
+	caseSensitive bool
+	pattern       *regexp.Regexp
 }
 
 func collectMatches(ctx context.Context, opts Options, searchTags []string) ([]model.Match, []ItemError, error) {
@@ -67,48 +78,134 @@ func collectMatches(ctx context.Context, opts Options, searchTags []string) ([]m
 	default:
 		return nil, nil, fmt.Errorf("invalid detect mode: %s", opts.DetectMode)
 	}
+	var matches []model.Match
+	var errs []ItemError
+	var err error
 	switch mode {
 	case detectionModeRegex:
-		matches, errs, err := collectMatchesRegex(opts, searchTags)
-		return matches, errs, err
+		matches, errs, err = collectMatchesRegex(ctx, opts, searchTags)
 	case detectionModeParse:
-		return collectMatchesParse(ctx, opts, searchTags, false)
+		matches, errs, err = collectMatchesParse(ctx, opts, searchTags, false)
 	case detectionModeAuto:
-		return collectMatchesParse(ctx, opts, searchTags, true)
+		matches, errs, err = collectMatchesParse(ctx, opts, searchTags, true)
 	default:
 		return nil, nil, fmt.Errorf("unknown detect mode")
 	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.ScanArchives {
+		archived, archiveErrs, err := collectArchiveMatches(ctx, opts, searchTags, mode)
+		if err != nil {
+			return nil, nil, err
+		}
+		matches, errs = append(matches, archived...), append(errs, archiveErrs...)
+	}
+	if err := markTodoxIgnored(opts.RepoDir, matches); err != nil {
+		return nil, nil, err
+	}
+	return matches, errs, nil
 }
 
-func collectMatchesRegex(opts Options, tags []string) ([]model.Match, []ItemError, error) {
-	pattern := patternForTags(tags)
-	matches, err := gitGrepMatches(opts.RepoDir, pattern, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+func collectMatchesRegex(ctx context.Context, opts Options, tags []string) ([]model.Match, []ItemError, error) {
+	specs, err := compileTagSpecs(tags, opts.TagRules)
 	if err != nil {
 		return nil, nil, err
 	}
-	matches = filterByPathRegex(matches, opts.PathRegexCompiled)
-	// refine tags inside each match (multiple per line)
-	expanded := expandLineMatches(matches, tags)
-	return expanded, nil, nil
+	// read は git grep の行を使わず、読み込んで走査するファイルです。
+	var (
+		expanded []model.Match
+		read     []string
+	)
+	if pattern, ok := prefilterPattern(tags, opts.TagRules); ok {
+		matches, err := gitGrepMatches(ctx, opts.RepoDir, pattern, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+		if err != nil {
+			return nil, nil, err
+		}
+		utf16Files, err := gitGrepUTF16Files(ctx, opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+		if err != nil {
+			return nil, nil, err
+		}
+		// UTF-8 でないファイルは git grep の行をそのまま使うと文字化けするため、読み込んで変換してから走査します。
+		legacy := make(map[string]bool, len(utf16Files))
+		for _, file := range utf16Files {
+			legacy[file] = true
+		}
+		for _, m := range matches {
+			if !utf8.ValidString(m.text) {
+				legacy[m.file] = true
+			}
+		}
+		lines := matches[:0]
+		for _, m := range matches {
+			if !legacy[m.file] {
+				lines = append(lines, m)
+			}
+		}
+		lines = filterByPathRegex(lines, opts.PathRegexCompiled)
+		// refine tags inside each match (multiple per line)
+		expanded = expandLineSpecs(lines, specs, opts.TagRules.Active())
+		for file := range legacy {
+			read = append(read, file)
+		}
+		sort.Strings(read)
+	} else {
+		// git grep -E で絞れないときは、すべての行を git grep で受け取らずにファイルごとに読みます。
+		if read, err = gitListFiles(ctx, opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical); err != nil {
+			return nil, nil, err
+		}
+	}
+	var errs []ItemError
+	for _, file := range filterPathsByRegex(read, opts.PathRegexCompiled) {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		full := filepath.Join(opts.RepoDir, filepath.FromSlash(file))
+		data, readErr := os.ReadFile(full)
+		if readErr != nil {
+			if info, statErr := os.Stat(full); statErr == nil && info.IsDir() {
+				continue // サブモジュール
+			}
+			errs = append(errs, newItemError(file, 0, "read", readErr))
+			continue
+		}
+		expanded = append(expanded, scanPlainContent(file, data, specs)...)
+	}
+	if !opts.IncludeGenerated {
+		expanded, err = excludeGeneratedMatches(ctx, opts.RepoDir, expanded)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return expanded, errs, nil
 }
 
 func collectMatchesParse(ctx context.Context, opts Options, tags []string, allowFallback bool) ([]model.Match, []ItemError, error) {
-	pattern := patternForTags(tags)
+	pattern, ok := prefilterPattern(tags, opts.TagRules)
 	var candidateFiles []string
 	var err error
-	if opts.NoPrefilter {
-		candidateFiles, err = gitListFiles(opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+	if opts.NoPrefilter || !ok {
+		candidateFiles, err = gitListFiles(ctx, opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical)
 	} else {
-		candidateFiles, err = gitGrepFiles(opts.RepoDir, pattern, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+		candidateFiles, err = prefilterFiles(ctx, opts, pattern)
 	}
 	if err != nil {
 		return nil, nil, err
 	}
 	candidateFiles = filterPathsByRegex(candidateFiles, opts.PathRegexCompiled)
+	if !opts.IncludeGenerated {
+		candidateFiles, err = filterGeneratedPaths(ctx, opts.RepoDir, candidateFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	if len(candidateFiles) == 0 {
 		return nil, nil, nil
 	}
-	tagsSpec := normalizeTags(tags)
+	tagsSpec, err := compileTagSpecs(tags, opts.TagRules)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	jobs := make(chan parseJob)
 	results := make(chan parseResult)
@@ -163,6 +260,9 @@ func collectMatchesParse(ctx context.Context, opts Options, tags []string, allow
 			errs = append(errs, res.errs...)
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	sort.SliceStable(all, func(i, j int) bool {
 		if all[i].File == all[j].File {
 			if all[i].Span.StartLine == all[j].Span.StartLine {
@@ -190,39 +290,79 @@ func parseFile(relPath string, opts Options, tags []tagSpec, allowFallback bool)
 	if err != nil {
 		return nil, []ItemError{newItemError(relPath, 0, "read", err)}
 	}
+	return parseContent(relPath, data, opts, tags, allowFallback), nil
+}
+
+// parseContent は読み込み済みの内容を言語判定してコメント・文字列からタグを抽出します。
+// ワークツリー以外（git の blob など）から取得した内容にも使います。
+func parseContent(relPath string, data []byte, opts Options, tags []tagSpec, allowFallback bool) []model.Match {
+	if src, ok := decodeLegacySource(data); ok {
+		matches := parseContent(relPath, src.text, opts, tags, allowFallback)
+		src.remap(matches)
+		return matches
+	}
+	return applyInlineSuppressions(data, scanContent(relPath, data, opts, tags, allowFallback))
+}
+
+// scanPlainContent は --detect regex と同じく内容を行単位で走査します。UTF-8 でない内容は変換してから走査し、
+// 位置を元のファイルのバイト位置に戻します。
+func scanPlainContent(relPath string, data []byte, tags []tagSpec) []model.Match {
+	if src, ok := decodeLegacySource(data); ok {
+		matches := applyInlineSuppressions(src.text, scanPlainText(relPath, src.text, tags))
+		src.remap(matches)
+		return matches
+	}
 	if bytes.IndexByte(data, 0) >= 0 {
-		return nil, nil
+		return nil // BOM の無いバイナリ
+	}
+	return applyInlineSuppressions(data, scanPlainText(relPath, data, tags))
+}
+
+// scanContent は UTF-8 に揃えた内容を、言語に応じた方法で走査します。
+func scanContent(relPath string, data []byte, opts Options, tags []tagSpec, allowFallback bool) []model.Match {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return nil
+	}
+	if !opts.IncludeGenerated && hasGeneratedHeader(data) {
+		return nil
 	}
 	if !utf8.Valid(data) {
-		matches := scanPlainText(relPath, data, tags)
-		return matches, nil
+		return scanPlainText(relPath, data, tags)
 	}
 	if opts.MaxFileBytes > 0 && len(data) > opts.MaxFileBytes {
-		matches := scanPlainText(relPath, data, tags)
-		return matches, nil
+		return scanPlainText(relPath, data, tags)
 	}
-	info := detect.FromPathAndContent(relPath, data)
-	if len(opts.DetectLangs) > 0 && !detect.MatchesLang(info, opts.DetectLangs) {
+	if isNotebookPath(relPath) {
+		if matches, ok := scanNotebook(relPath, data, opts, tags); ok {
+			return matches
+		}
+	}
+	lang := opts.LanguagesCompiled.detectLanguage(relPath, data)
+	if len(opts.DetectLangs) > 0 && !detect.MatchesLang(detect.Info{Name: lang}, opts.DetectLangs) {
 		if allowFallback {
-			return scanPlainText(relPath, data, tags), nil
+			return scanPlainText(relPath, data, tags)
 		}
-		return nil, nil
+		return nil
 	}
-	style, ok := styleForLanguage(detect.NormalizeLangName(info.Name))
+	style, ok := opts.LanguagesCompiled.style(lang)
 	if !ok {
 		if allowFallback {
-			return scanPlainText(relPath, data, tags), nil
+			return scanPlainText(relPath, data, tags)
 		}
-		return nil, nil
+		return nil
+	}
+	matches, embedded := scanEmbedded(relPath, data, opts, tags, lang, style)
+	if !embedded {
+		matches = scanWithStyle(relPath, data, tags, lang, style, opts.IncludeStrings)
+		attachBodies(data, matches, tags, style)
 	}
-	matches := scanWithStyle(relPath, data, tags, info.Name, style, opts.IncludeStrings)
 	if allowFallback && len(matches) == 0 {
 		fallback := scanPlainText(relPath, data, tags)
 		if len(fallback) > 0 {
-			return fallback, nil
+			return fallback
 		}
 	}
-	return matches, nil
+	return matches
 }
 
 func normalizeTags(tags []string) []tagSpec {
@@ -239,44 +379,41 @@ func scanWithStyle(path string, data []byte, tags []tagSpec, lang string, style
 	if len(data) == 0 {
 		return nil
 	}
+	if style.lexer != nil {
+		return scanWithLexer(path, data, tags, lang, style.lexer, includeStrings)
+	}
 	lineOffsets := computeLineOffsets(data)
 	var matches []model.Match
 
 	type blockState struct {
 		pattern     blockPattern
 		startOffset int
-		buffer      bytes.Buffer
+		depth       int
+		buffer      strings.Builder
 	}
 
 	var state *blockState
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	// allow very long lines
-	buf := make([]byte, 0, 1024*16)
-	scanner.Buffer(buf, len(data)+1)
 	offset := 0
-	for scanner.Scan() {
-		lineBytes := scanner.Bytes()
-		line := string(lineBytes)
-		lineLen := len(lineBytes)
-		lineEnd := offset + lineLen
+	for offset < len(data) {
+		lineEnd := len(data)
+		next := len(data)
+		if idx := bytes.IndexByte(data[offset:], '\n'); idx >= 0 {
+			lineEnd = offset + idx
+			next = lineEnd + 1
+		}
+		line := strings.TrimSuffix(string(data[offset:lineEnd]), "\r")
 		if state != nil {
-			state.buffer.Write(lineBytes)
+			searchFrom := state.buffer.Len()
+			state.buffer.WriteString(line)
 			state.buffer.WriteByte('\n')
-			if idx := strings.Index(state.buffer.String(), state.pattern.end); idx >= 0 {
-				content := state.buffer.String()
-				endIdx := strings.Index(content, state.pattern.end)
-				if endIdx >= 0 {
-					inner := content[:endIdx]
-					blockMatches := findMatchesInText(path, inner, tags, lang, state.pattern.kind, state.startOffset, lineOffsets)
-					matches = append(matches, blockMatches...)
-				}
+			content := state.buffer.String()
+			if endIdx, depth := findBlockEnd(content, searchFrom, state.pattern, state.depth); endIdx >= 0 {
+				matches = append(matches, findMatchesInText(path, content[:endIdx], tags, lang, state.pattern.kind, state.startOffset, lineOffsets)...)
 				state = nil
-			}
-			if lineEnd < len(data) && data[lineEnd] == '\n' {
-				offset = lineEnd + 1
 			} else {
-				offset = lineEnd
+				state.depth = depth
 			}
+			offset = next
 			continue
 		}
 
@@ -284,19 +421,22 @@ func scanWithStyle(path string, data []byte, tags []tagSpec, lang string, style
 		started := false
 		for _, block := range style.block {
 			if idx := indexBlockStart(line, block); idx >= 0 {
-				state = &blockState{pattern: block, startOffset: offset + idx + len(block.start)}
-				remainder := line[idx+len(block.start):] + "\n"
-				state.buffer.WriteString(remainder)
+				state = &blockState{pattern: block, startOffset: offset + idx + len(block.start), depth: 1}
+				state.buffer.WriteString(line[idx+len(block.start):])
+				state.buffer.WriteByte('\n')
+				content := state.buffer.String()
+				if endIdx, depth := findBlockEnd(content, 0, block, 1); endIdx >= 0 {
+					matches = append(matches, findMatchesInText(path, content[:endIdx], tags, lang, block.kind, state.startOffset, lineOffsets)...)
+					state = nil
+				} else {
+					state.depth = depth
+				}
 				started = true
 				break
 			}
 		}
 		if started {
-			if lineEnd < len(data) && data[lineEnd] == '\n' {
-				offset = lineEnd + 1
-			} else {
-				offset = lineEnd
-			}
+			offset = next
 			continue
 		}
 
@@ -340,16 +480,24 @@ func scanWithStyle(path string, data []byte, tags []tagSpec, lang string, style
 			}
 		}
 
-		if lineEnd < len(data) && data[lineEnd] == '\n' {
-			offset = lineEnd + 1
-		} else {
-			offset = lineEnd
+		// heredoc: 本文は次の行から終端識別子の行までで、通常の走査はその後から再開します。
+		if pending := findHeredocStarts(line, style); len(pending) > 0 && next < len(data) {
+			bodies, pos := consumeHeredocs(data, next, pending)
+			if includeStrings {
+				for _, r := range bodies {
+					if r.end > r.start {
+						matches = append(matches, findMatchesInText(path, string(data[r.start:r.end]), tags, lang, r.kind, r.start, lineOffsets)...)
+					}
+				}
+			}
+			next = pos
+			if next < len(data) {
+				next++
+			}
 		}
+		offset = next
 	}
 
-	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
-		return matches
-	}
 	if state != nil {
 		content := state.buffer.String()
 		blockMatches := findMatchesInText(path, content, tags, lang, state.pattern.kind, state.startOffset, lineOffsets)
@@ -358,6 +506,70 @@ func scanWithStyle(path string, data []byte, tags []tagSpec, lang string, style
 	return matches
 }
 
+// findBlockEnd は content[from:] からブロックの終了記号を探します。nested なパターンでは
+// 開始記号ごとに depth を増やし、対応する終了記号の位置を返します。見つからなければ -1 と現在の depth を返します。
+func findBlockEnd(content string, from int, block blockPattern, depth int) (int, int) {
+	if !block.nested {
+		if idx := strings.Index(content[from:], block.end); idx >= 0 {
+			return from + idx, 0
+		}
+		return -1, depth
+	}
+	for i := from; i < len(content); {
+		if strings.HasPrefix(content[i:], block.end) {
+			depth--
+			if depth == 0 {
+				return i, 0
+			}
+			i += len(block.end)
+			continue
+		}
+		if strings.HasPrefix(content[i:], block.start) {
+			depth++
+			i += len(block.start)
+			continue
+		}
+		i++
+	}
+	return -1, depth
+}
+
+// findHeredocStarts は行のコード部分（文字列・行コメントの外）にあるヒアドキュメントの開始を返します。
+func findHeredocStarts(line string, style commentStyle) []pendingHeredoc {
+	if len(style.heredocs) == 0 {
+		return nil
+	}
+	lx := codeLexer{heredocs: style.heredocs}
+	data := []byte(line)
+	var pending []pendingHeredoc
+	var quote byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' || c == '`' {
+			quote = c
+			continue
+		}
+		for _, prefix := range style.linePrefixes {
+			if strings.HasPrefix(line[i:], prefix) {
+				return pending
+			}
+		}
+		if h, end, ok := lx.heredocAt(data, i); ok {
+			pending = append(pending, h)
+			i = end - 1
+		}
+	}
+	return pending
+}
+
 func indexBlockStart(line string, block blockPattern) int {
 	if block.allowIndentedStart {
 		trimmed := strings.TrimLeft(line, " \t")
@@ -432,22 +644,17 @@ func findMatchesInText(path string, text string, tags []tagSpec, lang string, ki
 	}
 	upper := strings.ToUpper(text)
 	var hits []struct {
-		idx int
-		tag tagSpec
+		idx    int
+		length int
+		tag    tagSpec
 	}
 	for _, tag := range tags {
-		searchFrom := 0
-		for {
-			pos := strings.Index(upper[searchFrom:], tag.upper)
-			if pos < 0 {
-				break
-			}
-			abs := searchFrom + pos
+		for _, h := range findTagHits(text, upper, tag) {
 			hits = append(hits, struct {
-				idx int
-				tag tagSpec
-			}{idx: abs, tag: tag})
-			searchFrom = abs + len(tag.upper)
+				idx    int
+				length int
+				tag    tagSpec
+			}{idx: h[0], length: h[1], tag: tag})
 		}
 	}
 	if len(hits) == 0 {
@@ -457,7 +664,7 @@ func findMatchesInText(path string, text string, tags []tagSpec, lang string, ki
 	matches := make([]model.Match, 0, len(hits))
 	for _, hit := range hits {
 		byteStart := baseOffset + hit.idx
-		span := spanFromOffset(byteStart, len(hit.tag.raw), lineOffsets)
+		span := spanFromOffset(byteStart, hit.length, lineOffsets)
 		matches = append(matches, model.Match{
 			File: path,
 			Lang: detect.NormalizeLangName(lang),
@@ -531,11 +738,11 @@ func matchAny(rx []*regexp.Regexp, text string) bool {
 	return false
 }
 
-func gitGrepMatches(repo, pattern string, includes, excludes []string, typical bool) ([]match, error) {
+func gitGrepMatches(ctx context.Context, repo, pattern string, includes, excludes []string, typical bool) ([]match, error) {
 	pathspecs := buildGrepPathspecs(includes, excludes, typical)
 	args := []string{"-c", "core.quotePath=false", "grep", "-nI", "--no-color", "-i", "-E", pattern, "--"}
 	args = append(args, pathspecs...)
-	cmd := exec.Command("git", args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repo
 	out, err := cmd.Output()
 	if err != nil {
@@ -568,11 +775,63 @@ func gitGrepMatches(repo, pattern string, includes, excludes []string, typical b
 	return res, nil
 }
 
-func gitGrepFiles(repo, pattern string, includes, excludes []string, typical bool) ([]string, error) {
+// prefilterFiles は pattern に一致するファイルと、BOM 付きの UTF-16 ファイルを候補として返します。
+func prefilterFiles(ctx context.Context, opts Options, pattern string) ([]string, error) {
+	files, err := gitGrepFiles(ctx, opts.RepoDir, pattern, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+	if err != nil {
+		return nil, err
+	}
+	utf16Files, err := gitGrepUTF16Files(ctx, opts.RepoDir, opts.Paths, opts.Excludes, opts.ExcludeTypical)
+	if err != nil {
+		return nil, err
+	}
+	if len(utf16Files) == 0 {
+		return files, nil
+	}
+	return uniqueStrings(append(files, utf16Files...)), nil
+}
+
+func gitGrepFiles(ctx context.Context, repo, pattern string, includes, excludes []string, typical bool) ([]string, error) {
+	return gitGrepPaths(ctx, repo, []string{"-I", "-i", "-E", pattern}, includes, excludes, typical)
+}
+
+// gitGrepUTF16Files は BOM で始まるファイルを返します。git grep -I は NUL を含む UTF-16 をバイナリとして飛ばし、
+// ASCII のタグのパターンも UTF-16 のバイト列には一致しないため、BOM のバイト列で別に探します。
+// git grep は途中に同じバイト列を含むだけの画像やアーカイブにも一致するので、先頭の 2 バイトを読んで絞ります。
+func gitGrepUTF16Files(ctx context.Context, repo string, includes, excludes []string, typical bool) ([]string, error) {
+	paths, err := gitGrepPaths(ctx, repo, []string{"-a", "-F", "-e", "\xff\xfe", "-e", "\xfe\xff"}, includes, excludes, typical)
+	if err != nil {
+		return nil, err
+	}
+	out := paths[:0]
+	for _, p := range paths {
+		if startsWithUTF16BOM(filepath.Join(repo, filepath.FromSlash(p))) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// startsWithUTF16BOM は path の先頭が UTF-16 の BOM かを返します。読めなければ false です。
+func startsWithUTF16BOM(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return hasUTF16BOM(head)
+}
+
+func gitGrepPaths(ctx context.Context, repo string, grepArgs []string, includes, excludes []string, typical bool) ([]string, error) {
 	pathspecs := buildGrepPathspecs(includes, excludes, typical)
-	args := []string{"-c", "core.quotePath=false", "grep", "-Ilz", "-i", "-E", pattern, "--"}
+	args := append([]string{"-c", "core.quotePath=false", "grep", "-lz"}, grepArgs...)
+	args = append(args, "--")
 	args = append(args, pathspecs...)
-	cmd := exec.Command("git", args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repo
 	out, err := cmd.Output()
 	if err != nil {
@@ -596,10 +855,10 @@ func gitGrepFiles(repo, pattern string, includes, excludes []string, typical boo
 	return paths, nil
 }
 
-func gitListFiles(repo string, includes, excludes []string, typical bool) ([]string, error) {
+func gitListFiles(ctx context.Context, repo string, includes, excludes []string, typical bool) ([]string, error) {
 	args := []string{"ls-files", "-z"}
 	args = append(args, buildGrepPathspecs(includes, excludes, typical)...)
-	cmd := exec.Command("git", args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repo
 	out, err := cmd.Output()
 	if err != nil {
@@ -620,11 +879,23 @@ func gitListFiles(repo string, includes, excludes []string, typical bool) ([]str
 }
 
 func expandLineMatches(matches []match, tags []string) []model.Match {
-	specs := normalizeTags(tags)
+	return expandLineSpecs(matches, normalizeTags(tags), false)
+}
+
+// expandLineSpecs は git grep の行単位の結果を specs で照合し直します。
+// strict が true（TagRules 指定時）の場合、規則に合わない行は行単位のフォールバックをせずに捨てます。
+func expandLineSpecs(matches []match, specs []tagSpec, strict bool) []model.Match {
+	tags := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		tags = append(tags, spec.raw)
+	}
 	out := make([]model.Match, 0, len(matches))
 	for _, m := range matches {
 		text := m.text
 		mt := findMatchesInText(m.file, text, specs, "", model.MatchKindUnknown, 0, []int{0})
+		if len(mt) == 0 && strict {
+			continue
+		}
 		if len(mt) == 0 {
 			// fallback to original line-level match
 			clean := strings.TrimRight(text, "\r\n")
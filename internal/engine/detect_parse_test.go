@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -71,7 +72,7 @@ func TestGitGrepCaseInsensitive(t *testing.T) {
 	runGit("add", "notes.txt")
 
 	pattern := "(TODO|FIXME)"
-	matches, err := gitGrepMatches(repo, pattern, nil, nil, false)
+	matches, err := gitGrepMatches(context.Background(), repo, pattern, nil, nil, false)
 	if err != nil {
 		t.Fatalf("gitGrepMatches error: %v", err)
 	}
@@ -82,7 +83,7 @@ func TestGitGrepCaseInsensitive(t *testing.T) {
 		t.Fatalf("unexpected line number: got %d want 1", matches[0].line)
 	}
 
-	files, err := gitGrepFiles(repo, pattern, nil, nil, false)
+	files, err := gitGrepFiles(context.Background(), repo, pattern, nil, nil, false)
 	if err != nil {
 		t.Fatalf("gitGrepFiles error: %v", err)
 	}
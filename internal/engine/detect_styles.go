@@ -8,17 +8,14 @@ var (
 		block:        []blockPattern{{start: "/*", end: "*/", kind: model.MatchKindComment}},
 		stringDelims: []string{"\""},
 	}
-	styleGo = commentStyle{
-		linePrefixes: []string{"//"},
-		block:        []blockPattern{{start: "/*", end: "*/", kind: model.MatchKindComment}, {start: "`", end: "`", kind: model.MatchKindString}},
-		stringDelims: []string{"\""},
-	}
+	styleGo = commentStyle{lexer: lexerGo}
 	styleJS = commentStyle{
 		linePrefixes: []string{"//"},
 		block:        []blockPattern{{start: "/*", end: "*/", kind: model.MatchKindComment}, {start: "`", end: "`", kind: model.MatchKindString}},
 		stringDelims: []string{"\"", "'"},
 	}
-	styleHash = commentStyle{
+	styleECMAScript = commentStyle{lexer: lexerJS}
+	styleHash       = commentStyle{
 		linePrefixes: []string{"#"},
 		stringDelims: []string{"\"", "'"},
 	}
@@ -26,13 +23,21 @@ var (
 		linePrefixes: []string{"#"},
 		block:        []blockPattern{{start: "=begin", end: "=end", kind: model.MatchKindComment, allowIndentedStart: true}},
 		stringDelims: []string{"\"", "'"},
+		heredocs:     []string{"<<~", "<<-", "<<"},
 	}
-	stylePython = commentStyle{
+	stylePerl = commentStyle{
 		linePrefixes: []string{"#"},
-		block:        []blockPattern{{start: "\"\"\"", end: "\"\"\"", kind: model.MatchKindString}, {start: "'''", end: "'''", kind: model.MatchKindString}},
 		stringDelims: []string{"\"", "'"},
+		heredocs:     []string{"<<~", "<<"},
+	}
+	stylePHP = commentStyle{
+		linePrefixes: []string{"//", "#"},
+		block:        []blockPattern{{start: "/*", end: "*/", kind: model.MatchKindComment}},
+		stringDelims: []string{"\"", "'"},
+		heredocs:     []string{"<<<"},
 	}
-	styleHTML = commentStyle{
+	stylePython = commentStyle{lexer: lexerPython}
+	styleHTML   = commentStyle{
 		block: []blockPattern{{start: "<!--", end: "-->", kind: model.MatchKindComment}},
 	}
 	styleSQL = commentStyle{
@@ -55,7 +60,17 @@ var (
 	}
 	styleHaskell = commentStyle{
 		linePrefixes: []string{"--"},
-		block:        []blockPattern{{start: "{-", end: "-}", kind: model.MatchKindComment}},
+		block:        []blockPattern{{start: "{-", end: "-}", kind: model.MatchKindComment, nested: true}},
+	}
+	styleOCaml = commentStyle{
+		block:        []blockPattern{{start: "(*", end: "*)", kind: model.MatchKindComment, nested: true}},
+		stringDelims: []string{"\""},
+	}
+	// styleCNested は /* */ が入れ子になる C 系言語（Swift / Scala / Dart など）用です。
+	styleCNested = commentStyle{
+		linePrefixes: []string{"//"},
+		block:        []blockPattern{{start: "/*", end: "*/", kind: model.MatchKindComment, nested: true}},
+		stringDelims: []string{"\""},
 	}
 	stylePowershell = commentStyle{
 		linePrefixes: []string{"#"},
@@ -74,32 +89,34 @@ var (
 	stylePug = commentStyle{
 		linePrefixes: []string{"//", "//-"},
 	}
-	styleBash = commentStyle{
-		linePrefixes: []string{"#"},
-		stringDelims: []string{"\"", "'", "`"},
-	}
+	styleBash   = commentStyle{lexer: lexerShell}
+	styleCLang  = commentStyle{lexer: lexerC}
+	styleCpp    = commentStyle{lexer: lexerCpp}
+	styleRust   = commentStyle{lexer: lexerRust}
+	styleJava   = commentStyle{lexer: lexerJava}
+	styleKotlin = commentStyle{lexer: lexerKotlin}
 )
 
 var languageStyleMap = map[string]commentStyle{
-	"c":               styleC,
-	"cpp":             styleC,
-	"objective-c":     styleC,
-	"objective-cpp":   styleC,
+	"c":               styleCLang,
+	"cpp":             styleCpp,
+	"objective-c":     styleCLang,
+	"objective-cpp":   styleCpp,
 	"go":              styleGo,
-	"java":            styleC,
+	"java":            styleJava,
 	"csharp":          styleC,
-	"scala":           styleC,
-	"kotlin":          styleC,
-	"swift":           styleC,
+	"scala":           styleCNested,
+	"kotlin":          styleKotlin,
+	"swift":           styleCNested,
 	"groovy":          styleC,
-	"dart":            styleC,
-	"rust":            styleC,
-	"typescript":      styleJS,
-	"typescriptreact": styleJS,
-	"javascript":      styleJS,
-	"javascriptreact": styleJS,
+	"dart":            styleCNested,
+	"rust":            styleRust,
+	"typescript":      styleECMAScript,
+	"typescriptreact": styleECMAScript,
+	"javascript":      styleECMAScript,
+	"javascriptreact": styleECMAScript,
 	"coffeescript":    styleJS,
-	"php":             styleJS,
+	"php":             stylePHP,
 	"proto":           styleC,
 	"thrift":          styleC,
 	"hcl":             styleHCL,
@@ -108,7 +125,7 @@ var languageStyleMap = map[string]commentStyle{
 	"starlark":        stylePython,
 	"python":          stylePython,
 	"ruby":            styleRuby,
-	"perl":            styleHash,
+	"perl":            stylePerl,
 	"shell":           styleBash,
 	"fish":            styleHash,
 	"powershell":      stylePowershell,
@@ -153,7 +170,7 @@ var languageStyleMap = map[string]commentStyle{
 	"erlang":          styleHash,
 	"elixir":          styleHash,
 	"elm":             styleJS,
-	"ocaml":           styleHaskell,
+	"ocaml":           styleOCaml,
 	"pascal":          styleHash,
 	"ada":             styleHash,
 	"verilog":         styleC,
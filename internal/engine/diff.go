@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// EmptyTree は空のツリーのオブジェクト ID です。まだ祖先の無い範囲を比較するときの比較元に使います。
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// diffFile は 1 ファイル分の差分のうち、追加された行番号と削除された行の内容です。
+type diffFile struct {
+	path    string
+	added   map[int]bool
+	removed map[string]int // 前後の空白を除いた内容ごとの行数
+}
+
+// ScanAdded は from と to の差分で追加された行にある項目を返します。pre-commit などのフックで使います。
+//
+// to が空ならインデックス（ステージ済みの内容）を、さらに from も空なら HEAD を比較元にします。
+// 内容は作業ツリーではなく比較先の blob から読むため、ステージしていない編集は対象になりません。
+// 削除された行と前後の空白を除いて同じ内容の行は、移動やインデントの変更とみなして除きます。
+// パスの絞り込み・生成ファイル・抑制の規則は Run と同じですが、blame はしないため Author や Commit は空のままです。
+func ScanAdded(ctx context.Context, opts Options, from, to string) ([]Item, error) {
+	opts, tags, searchTags, err := prepareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := compileTagSpecs(searchTags, opts.TagRules)
+	if err != nil {
+		return nil, err
+	}
+	files, err := gitDiffAdded(ctx, opts, from, to)
+	if err != nil {
+		return nil, err
+	}
+	files = filterDiffFiles(files, opts)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	var generated map[string]struct{}
+	if !opts.IncludeGenerated {
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = f.path
+		}
+		if generated, err = gitGeneratedAttrs(ctx, opts.RepoDir, paths); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []Item
+	for _, f := range files {
+		if _, skip := generated[f.path]; skip {
+			continue
+		}
+		// パスが ./ で始まると :<path> は作業ディレクトリ（RepoDir）からの相対になります。
+		data, err := gitCatBlob(ctx, opts.RepoDir, to+":./"+f.path)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.IncludeGenerated && hasGeneratedHeader(data) {
+			continue
+		}
+		matches, err := detectContent(f.path, data, opts, specs)
+		if err != nil {
+			return nil, err
+		}
+		if err := markTodoxIgnored(opts.RepoDir, matches); err != nil {
+			return nil, err
+		}
+		matches = filterMatchesByType(matches, opts.Type, tags)
+		matches, _ = splitSuppressed(matches, opts.ShowSuppressed)
+		src := newContextSource(f.path, data)
+		for _, m := range matches {
+			if !f.isNew(m, src.lines) {
+				continue
+			}
+			it := newItem(m)
+			applyCommentFields(opts, m, &it)
+			src.attach(&it, opts.ContextLines)
+			items = append(items, it)
+		}
+	}
+	return items, nil
+}
+
+// isNew は m のタグのある行が追加された行で、削除された行の移動でもないかを返します。
+func (f *diffFile) isNew(m model.Match, lines []string) bool {
+	line := normalizeSpan(m.Span).StartLine
+	if !f.added[line] {
+		return false
+	}
+	if line <= len(lines) {
+		key := strings.TrimSpace(lines[line-1])
+		if f.removed[key] > 0 {
+			f.removed[key]--
+			return false
+		}
+	}
+	return true
+}
+
+// filterDiffFiles は --path-regex に一致しないファイルを除きます。
+func filterDiffFiles(files []*diffFile, opts Options) []*diffFile {
+	if len(opts.PathRegexCompiled) == 0 {
+		return files
+	}
+	out := files[:0]
+	for _, f := range files {
+		for _, rx := range opts.PathRegexCompiled {
+			if rx.MatchString(f.path) {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// gitDiffAdded は git diff -U0 を読み、ファイルごとの追加行と削除行を返します。
+// パスは --relative で RepoDir からの相対になり、RepoDir の外の変更は含みません。
+func gitDiffAdded(ctx context.Context, opts Options, from, to string) ([]*diffFile, error) {
+	args := []string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "--no-textconv",
+		"-U0", "--find-renames", "--diff-filter=AMR", "--relative", "--ignore-submodules",
+		"--src-prefix=a/", "--dst-prefix=b/"}
+	if to == "" {
+		args = append(args, "--cached")
+		if from != "" {
+			args = append(args, from)
+		}
+	} else {
+		if from == "" {
+			return nil, fmt.Errorf("a base revision is required to compare with %s", to)
+		}
+		args = append(args, from, to)
+	}
+	args = append(args, "--")
+	args = append(args, buildGrepPathspecs(opts.Paths, opts.Excludes, opts.ExcludeTypical)...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = opts.RepoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+	return parseDiffAdded(out)
+}
+
+// parseDiffAdded は git diff -U0 の出力を解析します。ハンク内の行数を数えて読むため、
+// "--- " や "+++ " で始まる内容の行もヘッダーと取り違えません。
+func parseDiffAdded(out []byte) ([]*diffFile, error) {
+	var files []*diffFile
+	var cur *diffFile
+	oldLeft, newLeft, newLine := 0, 0, 0
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if cur != nil {
+					cur.added[newLine] = true
+				}
+				newLine++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				if cur != nil {
+					cur.removed[strings.TrimSpace(line[1:])]++
+				}
+				oldLeft--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = nil
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")
+			if strings.HasPrefix(name, `"`) {
+				unquoted, err := strconv.Unquote(name)
+				if err != nil {
+					return nil, fmt.Errorf("git diff: unexpected path %s", name)
+				}
+				name = unquoted
+			}
+			if !strings.HasPrefix(name, "b/") {
+				cur = nil // /dev/null（削除）
+				continue
+			}
+			cur = &diffFile{path: name[2:], added: make(map[int]bool), removed: make(map[string]int)}
+			files = append(files, cur)
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("git diff: unexpected hunk header %q", line)
+			}
+			oldLeft, newLeft = hunkCount(m[1]), hunkCount(m[3])
+			newLine, _ = strconv.Atoi(m[2])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+	return files, nil
+}
+
+// hunkCount はハンクヘッダーの行数を返します。省略されていれば 1 行です。
+func hunkCount(raw string) int {
+	if raw == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(raw)
+	return n
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanAddedはステージ済みの追加行だけを返す(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	ctx := context.Background()
+	opts := Options{Type: "both", Mode: "last", RepoDir: repoDir, WithComment: true}
+
+	// 最初のコミット前は空のツリーと比べる
+	write("a.go", "package a\n// TODO: old\n")
+	runGit(t, repoDir, "add", ".")
+	items, err := ScanAdded(ctx, opts, "", "")
+	if err != nil {
+		t.Fatalf("ScanAdded failed: %v", err)
+	}
+	if len(items) != 1 || items[0].File != "a.go" || items[0].Line != 2 {
+		t.Fatalf("expected the TODO of the first commit, got %+v", items)
+	}
+	runGit(t, repoDir, "commit", "-m", "initial")
+	base := gitHeadForTest(t, repoDir)
+
+	// インデントを変えただけの行は新しい項目にしない
+	write("a.go", "package a\n\nfunc f() {\n\t// TODO: old\n\t// FIXME: new\n}\n")
+	write("b.go", "package b\n// --- TODO: dashes\n")
+	runGit(t, repoDir, "add", ".")
+	// ステージしていない編集は対象外
+	write("a.go", "package a\n\nfunc f() {\n\t// TODO: old\n\t// FIXME: new\n\t// TODO: unstaged\n}\n")
+
+	items, err = ScanAdded(ctx, opts, "", "")
+	if err != nil {
+		t.Fatalf("ScanAdded failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 new items, got %+v", items)
+	}
+	if items[0].File != "a.go" || items[0].Line != 5 || items[0].Comment != "FIXME: new" {
+		t.Fatalf("unexpected first item: %+v", items[0])
+	}
+	if items[1].File != "b.go" || items[1].Line != 2 || items[1].Author != "" {
+		t.Fatalf("unexpected second item: %+v", items[1])
+	}
+
+	// コミットどうしの比較ではその時点の内容を読む
+	runGit(t, repoDir, "commit", "-m", "second")
+	items, err = ScanAdded(ctx, Options{Type: "fixme", Mode: "last", RepoDir: repoDir}, base, "HEAD")
+	if err != nil {
+		t.Fatalf("ScanAdded between commits failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Kind != "FIXME" || items[0].Line != 5 {
+		t.Fatalf("expected only the FIXME between the commits, got %+v", items)
+	}
+}
+
+func TestParseDiffAdded(t *testing.T) {
+	out := "diff --git a/x.go b/x.go\n" +
+		"--- a/x.go\n" +
+		"+++ b/x.go\n" +
+		"@@ -2 +2,2 @@ func f() {\n" +
+		"-\t// TODO: old\n" +
+		"+// TODO: old\n" +
+		"+--- not a header\n" +
+		"@@ -9,0 +11 @@\n" +
+		"+x\n" +
+		"\\ No newline at end of file\n" +
+		"diff --git a/gone.go b/gone.go\n" +
+		"--- a/gone.go\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-bye\n" +
+		"diff --git \"a/sp ace\\t.go\" \"b/sp ace\\t.go\"\n" +
+		"+++ \"b/sp ace\\t.go\"\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+hi\n"
+	files, err := parseDiffAdded([]byte(out))
+	if err != nil {
+		t.Fatalf("parseDiffAdded failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	x := files[0]
+	if x.path != "x.go" || !x.added[2] || !x.added[3] || !x.added[11] || len(x.added) != 3 {
+		t.Fatalf("unexpected added lines for x.go: %+v", x)
+	}
+	if x.removed["// TODO: old"] != 1 {
+		t.Fatalf("removed lines should be keyed by trimmed content: %+v", x.removed)
+	}
+	if files[1].path != "sp ace\t.go" || !files[1].added[1] {
+		t.Fatalf("quoted paths should be unquoted: %+v", files[1])
+	}
+}
+
+func gitHeadForTest(t *testing.T, repoDir string) string {
+	t.Helper()
+	sha, err := gitHead(context.Background(), repoDir)
+	if err != nil {
+		t.Fatalf("rev-parse failed: %v", err)
+	}
+	return sha
+}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/phyten/todox/internal/detect"
+	"github.com/phyten/todox/internal/model"
+)
+
+// embeddedRegion はホスト言語のファイルに埋め込まれた別言語のブロック（バイト範囲 [start, end)）です。
+type embeddedRegion struct {
+	lang  string // 埋め込み先の表記（フェンスの info string や lang= 属性）そのまま
+	start int
+	end   int
+}
+
+// embeddedSplitters はホスト言語ごとに埋め込みブロックを切り出す関数です。
+var embeddedSplitters = map[string]func([]byte) []embeddedRegion{
+	"markdown": splitMarkdownFences,
+	"html":     splitScriptStyle,
+	"vue":      splitScriptStyle,
+	"svelte":   splitScriptStyle,
+}
+
+// scanEmbedded はホスト言語の部分と埋め込みブロックをそれぞれのコメント構文で走査します。
+// 各ブロックは範囲外を空白で塗りつぶしたコピーを走査するため、行・桁・バイト位置は元ファイルのままです。
+// 言語が分からないブロックはホスト側に残します。
+func scanEmbedded(path string, data []byte, opts Options, tags []tagSpec, hostLang string, hostStyle commentStyle) ([]model.Match, bool) {
+	split := embeddedSplitters[hostLang]
+	if split == nil {
+		return nil, false
+	}
+	type resolved struct {
+		region embeddedRegion
+		lang   string
+		style  commentStyle
+	}
+	var blocks []resolved
+	for _, r := range split(data) {
+		lang := resolveEmbeddedLang(r.lang)
+		if lang == "" || lang == hostLang {
+			continue
+		}
+		style, ok := opts.LanguagesCompiled.style(lang)
+		if !ok {
+			continue
+		}
+		blocks = append(blocks, resolved{region: r, lang: lang, style: style})
+	}
+	if len(blocks) == 0 {
+		return nil, false
+	}
+	host := append([]byte(nil), data...)
+	for _, b := range blocks {
+		maskRange(host, b.region.start, b.region.end)
+	}
+	matches := scanWithStyle(path, host, tags, hostLang, hostStyle, opts.IncludeStrings)
+	attachBodies(host, matches, tags, hostStyle)
+	for _, b := range blocks {
+		src := maskOutside(data, b.region.start, b.region.end)
+		found := scanWithStyle(path, src, tags, b.lang, b.style, opts.IncludeStrings)
+		attachBodies(src, found, tags, b.style)
+		matches = append(matches, found...)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Span.ByteStart < matches[j].Span.ByteStart
+	})
+	return matches, true
+}
+
+// resolveEmbeddedLang はフェンスの info string や lang= 属性の値を言語名に正規化します。
+// "ts" のような別名のほか、"rs" のような拡張子としても解釈します。
+func resolveEmbeddedLang(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, ".")
+	name = strings.TrimPrefix(name, "language-")
+	if name == "" {
+		return ""
+	}
+	lang := detect.NormalizeLangName(name)
+	if _, ok := styleForLanguage(lang); ok {
+		return lang
+	}
+	if byExt := detect.FromPathAndContent("embedded."+name, nil).Name; byExt != "" {
+		return detect.NormalizeLangName(byExt)
+	}
+	return lang
+}
+
+func maskRange(data []byte, start, end int) {
+	for i := start; i < end && i < len(data); i++ {
+		if data[i] != '\n' && data[i] != '\r' {
+			data[i] = ' '
+		}
+	}
+}
+
+func maskOutside(data []byte, start, end int) []byte {
+	out := append([]byte(nil), data...)
+	maskRange(out, 0, start)
+	maskRange(out, end, len(out))
+	return out
+}
+
+// splitMarkdownFences は ``` / ~~~ のフェンスで囲まれたコードブロックの本文を返します。
+// 閉じフェンスのないブロックは CommonMark と同じくファイル末尾まで続きます。
+func splitMarkdownFences(data []byte) []embeddedRegion {
+	var regions []embeddedRegion
+	var open *embeddedRegion
+	var fenceChar byte
+	var fenceLen int
+	offset := 0
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		next := len(data)
+		if end >= 0 {
+			next = offset + end + 1
+		}
+		line := strings.TrimRight(string(data[offset:next]), "\r\n")
+		ch, n, info, ok := parseFence(line)
+		switch {
+		case open == nil && ok:
+			open = &embeddedRegion{lang: fenceInfoLang(info), start: next}
+			fenceChar, fenceLen = ch, n
+		case open != nil && ok && ch == fenceChar && n >= fenceLen && strings.TrimSpace(info) == "":
+			open.end = offset
+			regions = append(regions, *open)
+			open = nil
+		}
+		offset = next
+	}
+	if open != nil {
+		open.end = len(data)
+		regions = append(regions, *open)
+	}
+	return regions
+}
+
+// parseFence は行がフェンス（最大 3 文字の字下げと 3 文字以上の ` または ~）なら、その文字・長さ・info string を返します。
+func parseFence(line string) (byte, int, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return 0, 0, "", false
+	}
+	ch := trimmed[0]
+	if ch != '`' && ch != '~' {
+		return 0, 0, "", false
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return 0, 0, "", false
+	}
+	info := trimmed[n:]
+	if ch == '`' && strings.ContainsRune(info, '`') {
+		return 0, 0, "", false
+	}
+	return ch, n, info, true
+}
+
+// fenceInfoLang は info string の先頭の語（"python title=x" や "{.python}" 形式）から言語名を取り出します。
+func fenceInfoLang(info string) string {
+	info = strings.TrimSpace(info)
+	info = strings.TrimPrefix(info, "{")
+	if idx := strings.IndexAny(info, " \t,{}"); idx >= 0 {
+		info = info[:idx]
+	}
+	return strings.TrimPrefix(info, ".")
+}
+
+var (
+	scriptStyleOpenPattern = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style)\b((?:"[^"]*"|'[^']*'|[^'">])*)>`)
+	htmlAttrPattern        = regexp.MustCompile(`(?is)([a-z_:][-a-z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// splitScriptStyle は HTML / Vue / Svelte の <script> と <style> の中身を返します。
+// 言語は lang= 属性、なければ type= 属性から決め、<!-- --> 内のタグは無視します。
+func splitScriptStyle(data []byte) []embeddedRegion {
+	var regions []embeddedRegion
+	offset := 0
+	for offset < len(data) {
+		loc := scriptStyleOpenPattern.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			break
+		}
+		if loc[2] < 0 {
+			offset += loc[1]
+			continue
+		}
+		tag := strings.ToLower(string(data[offset+loc[2] : offset+loc[3]]))
+		attrs := parseHTMLAttrs(string(data[offset+loc[4] : offset+loc[5]]))
+		start := offset + loc[1]
+		closeIdx := indexFold(data[start:], "</"+tag)
+		end := len(data)
+		if closeIdx >= 0 {
+			end = start + closeIdx
+		}
+		if lang := scriptStyleLang(tag, attrs); lang != "" {
+			regions = append(regions, embeddedRegion{lang: lang, start: start, end: end})
+		}
+		offset = end
+	}
+	return regions
+}
+
+func parseHTMLAttrs(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range htmlAttrPattern.FindAllStringSubmatch(raw, -1) {
+		attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+	}
+	return attrs
+}
+
+// scriptStyleLang は <script> / <style> の属性から埋め込み言語を決めます。
+// JavaScript 以外の type（JSON やテンプレートなど）は空文字列を返し、ホスト側に残します。
+func scriptStyleLang(tag string, attrs map[string]string) string {
+	if lang := strings.TrimSpace(attrs["lang"]); lang != "" {
+		return lang
+	}
+	typ := strings.ToLower(strings.TrimSpace(attrs["type"]))
+	if tag == "style" {
+		if typ == "" || typ == "text/css" {
+			return "css"
+		}
+		return strings.TrimPrefix(typ, "text/")
+	}
+	switch {
+	case typ == "", typ == "module", strings.Contains(typ, "javascript"), strings.Contains(typ, "ecmascript"):
+		return "javascript"
+	case strings.Contains(typ, "typescript"):
+		return "typescript"
+	case strings.Contains(typ, "babel"), strings.Contains(typ, "jsx"):
+		return "javascriptreact"
+	case strings.Contains(typ, "coffeescript"):
+		return "coffeescript"
+	}
+	return ""
+}
+
+// indexFold は ASCII の大文字小文字を無視して needle（小文字）を探します。
+func indexFold(data []byte, needle string) int {
+	pat := []byte(needle)
+	for i := 0; i+len(pat) <= len(data); i++ {
+		if bytes.EqualFold(data[i:i+len(pat)], pat) {
+			return i
+		}
+	}
+	return -1
+}
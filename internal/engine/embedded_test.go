@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func embeddedSummary(matches []model.Match) []string {
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, fmt.Sprintf("%d:%d %s %s %s", m.Span.StartLine, m.Span.StartCol, m.Lang, m.Kind, m.Tag))
+	}
+	return out
+}
+
+func TestEmbeddedMarkdownFences(t *testing.T) {
+	src := "# Guide\n\n" +
+		"```python title=\"x\"\n" +
+		"x = \"TODO string\"  # FIXME python comment\n" +
+		"```\n\n" +
+		"~~~ {.go}\n" +
+		"// TODO go comment\n" +
+		"~~~\n\n" +
+		"```console\n" +
+		"# TODO unknown fence stays markdown\n" +
+		"```\n\n" +
+		"````py\n" +
+		"```\n" +
+		"# TODO still inside the longer fence\n" +
+		"````\n"
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	got := embeddedSummary(parseContent("README.md", []byte(src), Options{IncludeStrings: true}, tags, false))
+	want := []string{
+		"4:6 python string TODO",
+		"4:22 python comment FIXME",
+		"8:4 go comment TODO",
+		"12:3 markdown comment TODO",
+		"17:3 python comment TODO",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestEmbeddedScriptAndStyle(t *testing.T) {
+	src := "<template>\n" +
+		"  <!-- TODO template comment -->\n" +
+		"  <!-- <script>// FIXME commented out</script> -->\n" +
+		"</template>\n" +
+		"<script setup lang=\"ts\">\n" +
+		"// TODO typed script\n" +
+		"const s = '<!-- FIXME not html -->'\n" +
+		"</script>\n" +
+		"<style lang=scss>\n" +
+		"/* FIXME scss */\n" +
+		"</style>\n" +
+		"<script type=\"application/json\">{\"TODO\": 1}</script>\n" +
+		"<SCRIPT>/* TODO upper */</SCRIPT>\n"
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	got := embeddedSummary(parseContent("App.vue", []byte(src), Options{}, tags, false))
+	want := []string{
+		"2:8 vue comment TODO",
+		"3:19 vue comment FIXME",
+		"6:4 typescript comment TODO",
+		"10:4 scss comment FIXME",
+		"13:12 javascript comment TODO",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestResolveEmbeddedLang(t *testing.T) {
+	cases := map[string]string{
+		"py":         "python",
+		"TS":         "typescript",
+		"rs":         "rust",
+		"language-c": "c",
+		"":           "",
+	}
+	for in, want := range cases {
+		if got := resolveEmbeddedLang(in); got != want {
+			t.Fatalf("resolveEmbeddedLang(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
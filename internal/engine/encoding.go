@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/phyten/todox/internal/model"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// sourceEncoding は UTF-8 以外のソースの符号化方式と、1 文字分のバイト数の求め方です。
+type sourceEncoding struct {
+	enc     encoding.Encoding
+	charLen func(data []byte, i int) int
+}
+
+var (
+	encodingUTF16LE = sourceEncoding{enc: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), charLen: utf16CharLen(false)}
+	encodingUTF16BE = sourceEncoding{enc: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), charLen: utf16CharLen(true)}
+	encodingEUCJP   = sourceEncoding{enc: japanese.EUCJP, charLen: eucJPCharLen}
+	encodingSJIS    = sourceEncoding{enc: japanese.ShiftJIS, charLen: shiftJISCharLen}
+	encodingLatin1  = sourceEncoding{enc: charmap.ISO8859_1, charLen: func([]byte, int) int { return 1 }}
+)
+
+var (
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// hasUTF16BOM は data が UTF-16 の BOM で始まるかを返します。
+func hasUTF16BOM(data []byte) bool {
+	return bytes.HasPrefix(data, bomUTF16LE) || bytes.HasPrefix(data, bomUTF16BE)
+}
+
+// decodedSource は UTF-8 に変換した内容と、変換後の各バイトが元ファイルの何バイト目の文字に由来するかの対応です。
+type decodedSource struct {
+	text []byte
+	orig []int // len(text)+1 要素。orig[len(text)] は元ファイルの長さ
+}
+
+// decodeLegacySource は UTF-16（BOM 付き）か、UTF-8 として不正な内容を Shift_JIS / EUC-JP / Latin-1 として読みます。
+// UTF-8 として正しい内容や、判定できない内容では ok=false を返します。
+func decodeLegacySource(data []byte) (decodedSource, bool) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeWith(data, 2, encodingUTF16LE)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeWith(data, 2, encodingUTF16BE)
+	}
+	if utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return decodedSource{}, false
+	}
+	// EUC-JP の 2 バイト文字は Shift_JIS としても読めることが多いため、厳密な EUC-JP を先に試します。
+	// Shift_JIS のかな・漢字の先頭バイト（0x81–0x9F）は EUC-JP では不正なので取り違えません。
+	for _, enc := range []sourceEncoding{encodingEUCJP, encodingSJIS} {
+		if src, ok := decodeWith(data, 0, enc); ok {
+			return src, true
+		}
+	}
+	return decodeWith(data, 0, encodingLatin1)
+}
+
+// decodeWith は start バイト目以降を 1 文字ずつ UTF-8 に変換します。不正な文字があれば ok=false を返します。
+func decodeWith(data []byte, start int, se sourceEncoding) (decodedSource, bool) {
+	dec := se.enc.NewDecoder()
+	out := make([]byte, 0, len(data))
+	orig := make([]int, 0, len(data)+1)
+	for i := start; i < len(data); {
+		n := se.charLen(data, i)
+		if i+n > len(data) {
+			return decodedSource{}, false
+		}
+		chunk := data[i : i+n]
+		var decoded []byte
+		if n == 1 && chunk[0] < utf8.RuneSelf {
+			// UTF-16 以外の対象はすべて ASCII 互換です（UTF-16 は 1 文字が 2 バイト以上）。
+			decoded = chunk
+		} else {
+			var err error
+			decoded, err = dec.Bytes(chunk)
+			if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) || len(decoded) == 0 {
+				return decodedSource{}, false
+			}
+		}
+		for range decoded {
+			orig = append(orig, i)
+		}
+		out = append(out, decoded...)
+		i += n
+	}
+	orig = append(orig, len(data))
+	return decodedSource{text: out, orig: orig}, true
+}
+
+func shiftJISCharLen(data []byte, i int) int {
+	b := data[i]
+	if (b >= 0x81 && b <= 0x9F) || (b >= 0xE0 && b <= 0xFC) {
+		return 2
+	}
+	return 1
+}
+
+func eucJPCharLen(data []byte, i int) int {
+	b := data[i]
+	switch {
+	case b == 0x8F:
+		return 3
+	case b == 0x8E, b >= 0xA1 && b <= 0xFE:
+		return 2
+	}
+	return 1
+}
+
+func utf16CharLen(bigEndian bool) func([]byte, int) int {
+	return func(data []byte, i int) int {
+		if i+1 >= len(data) {
+			return 2
+		}
+		unit := uint16(data[i]) | uint16(data[i+1])<<8
+		if bigEndian {
+			unit = uint16(data[i])<<8 | uint16(data[i+1])
+		}
+		if unit >= 0xD800 && unit <= 0xDBFF {
+			return 4
+		}
+		return 2
+	}
+}
+
+// remap は変換後の内容に対する Span を元ファイルのバイト位置・桁に戻します。行番号は変わりません。
+func (s decodedSource) remap(matches []model.Match) {
+	lineOffsets := computeLineOffsets(s.text)
+	origAt := func(off int) int {
+		if off < 0 {
+			off = 0
+		}
+		if off >= len(s.orig) {
+			off = len(s.orig) - 1
+		}
+		return s.orig[off]
+	}
+	lineStart := func(line int) int {
+		if line < 1 || line > len(lineOffsets) {
+			return 0
+		}
+		return origAt(lineOffsets[line-1])
+	}
+	for i := range matches {
+		sp := &matches[i].Span
+		sp.ByteStart = origAt(sp.ByteStart)
+		sp.ByteEnd = origAt(sp.ByteEnd)
+		sp.StartCol = sp.ByteStart - lineStart(sp.StartLine) + 1
+		sp.EndCol = sp.ByteEnd - lineStart(sp.EndLine) + 1
+	}
+}
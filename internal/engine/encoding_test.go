@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encodeSource(t *testing.T, enc encoding.Encoding, src string) []byte {
+	t.Helper()
+	out, err := enc.NewEncoder().Bytes([]byte(src))
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	return out
+}
+
+func TestParseContentDecodesLegacyEncodings(t *testing.T) {
+	src := "package main\n\n// 初期化処理 TODO: 設定を読む\nvar s = \"FIXME 文字列\"\n"
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"shift_jis", encodeSource(t, japanese.ShiftJIS, src)},
+		{"euc-jp", encodeSource(t, japanese.EUCJP, src)},
+		{"utf-16le", append([]byte{0xFF, 0xFE}, encodeSource(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), src)...)},
+		{"utf-16be", append([]byte{0xFE, 0xFF}, encodeSource(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), src)...)},
+	}
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	for _, tc := range cases {
+		matches := parseContent("main.go", tc.data, Options{}, tags, false)
+		if len(matches) != 1 {
+			t.Fatalf("%s: expected 1 comment match, got %+v", tc.name, matches)
+		}
+		m := matches[0]
+		if m.Lang != "go" || m.Text != "初期化処理 TODO: 設定を読む" || !utf8.ValidString(m.Text) {
+			t.Fatalf("%s: unexpected match %+v", tc.name, m)
+		}
+		if m.Span.StartLine != 3 {
+			t.Fatalf("%s: unexpected line %d", tc.name, m.Span.StartLine)
+		}
+		if got := tc.data[m.Span.ByteStart:m.Span.ByteEnd]; !bytes.Equal(got, encodeSource(t, encodingFor(tc.name), "TODO")) {
+			t.Fatalf("%s: span should point into the original bytes, got %q", tc.name, got)
+		}
+		lineStart := bytes.LastIndexByte(tc.data[:m.Span.ByteStart], '\n') + 1
+		if tc.name == "utf-16le" {
+			lineStart++ // LE の改行は 0x0A 0x00 なので、0x00 の次から行が始まる
+		}
+		if m.Span.StartCol != m.Span.ByteStart-lineStart+1 {
+			t.Fatalf("%s: column should count original bytes: %+v (line starts at %d)", tc.name, m.Span, lineStart)
+		}
+	}
+}
+
+func encodingFor(name string) encoding.Encoding {
+	switch name {
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	return encoding.Nop
+}
+
+func TestParseContentFallsBackToLatin1(t *testing.T) {
+	data := encodeSource(t, charmap.ISO8859_1, "# Café: TODO réviser\nx = 1\n")
+	if utf8.Valid(data) {
+		t.Fatalf("fixture should not be valid UTF-8")
+	}
+	tags := normalizeTags([]string{"TODO"})
+	matches := parseContent("script.py", data, Options{}, tags, false)
+	if len(matches) != 1 || matches[0].Text != "Café: TODO réviser" || matches[0].Lang != "python" {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if matches[0].Span.StartCol != 9 || matches[0].Span.ByteStart != 8 {
+		t.Fatalf("span should count Latin-1 bytes: %+v", matches[0].Span)
+	}
+}
+
+func TestDecodeLegacySourceLeavesUTF8Alone(t *testing.T) {
+	if _, ok := decodeLegacySource([]byte("// TODO: 日本語\n")); ok {
+		t.Fatalf("valid UTF-8 should not be decoded")
+	}
+	if _, ok := decodeLegacySource([]byte{'a', 0, 'b'}); ok {
+		t.Fatalf("binary content should not be decoded")
+	}
+}
+
+func TestRunFindsLegacyEncodedFilesWithThePrefilter(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	src := "package main\n\n// 初期化処理 TODO: 設定を読む\n"
+	files := map[string][]byte{
+		"sjis.go":  encodeSource(t, japanese.ShiftJIS, src),
+		"utf16.go": append([]byte{0xFF, 0xFE}, encodeSource(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), src)...),
+		// BOM のバイト列を途中に含むだけのバイナリは、どちらのモードでも走査しない
+		"img.png": append([]byte{0x89, 'P', 'N', 'G', 0x00, 0xFF, 0xFE}, " TODO garbage\x00"...),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), data, 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	for _, mode := range []string{"parse", "regex"} {
+		res, err := Run(Options{Type: "todo", Mode: "last", RepoDir: repoDir, Jobs: 2, DetectMode: mode})
+		if err != nil {
+			t.Fatalf("Run(%s) failed: %v", mode, err)
+		}
+		if len(res.Items) != 2 {
+			t.Fatalf("Run(%s) expected the Shift_JIS and UTF-16 files, got %+v", mode, res.Items)
+		}
+		for _, it := range res.Items {
+			if it.Line != 3 || !strings.Contains(it.Text, "TODO: 設定を読む") || it.Author != "alice" {
+				t.Fatalf("Run(%s) unexpected item %+v", mode, it)
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -23,6 +24,9 @@ import (
 var reLine = regexp.MustCompile(`:(\d+):`) // first :<num>:
 var defaultTags = []string{"TODO", "FIXME"}
 
+// archiveAuthor は blame できないアーカイブ内の項目の作者欄に入れる値です。
+const archiveAuthor = "(archive)"
+
 type match struct {
 	file string
 	line int
@@ -62,73 +66,76 @@ func normalizeSpan(span model.Span) model.Span {
 // 成功時には発見した項目と補助情報を保持した Result を返し、
 // 途中で発生したエラー情報は Result.Errors に集約されます。
 func Run(opts Options) (*Result, error) {
-	start := time.Now()
-	if opts.Now.IsZero() {
-		opts.Now = time.Now().UTC()
-	} else {
-		opts.Now = opts.Now.UTC()
-	}
-	if opts.Jobs <= 0 {
-		opts.Jobs = runtime.NumCPU()
-	}
-	tags := effectiveTags(opts.Tags)
-	var searchTags []string
-	switch strings.ToLower(opts.Type) {
-	case "todo":
-		filtered := filterTagsByType(tags, "TODO")
-		if len(filtered) == 0 {
-			searchTags = []string{"TODO"}
-		} else {
-			searchTags = filtered
-		}
-	case "fixme":
-		filtered := filterTagsByType(tags, "FIXME")
-		if len(filtered) == 0 {
-			searchTags = []string{"FIXME"}
-		} else {
-			searchTags = filtered
-		}
-	case "", "both":
-		searchTags = tags
-	default:
-		return nil, fmt.Errorf("invalid --type: %s", opts.Type)
-	}
+	return RunContext(context.Background(), opts)
+}
 
-	rx := opts.PathRegexCompiled
-	if len(rx) == 0 && len(opts.PathRegex) > 0 {
-		compiled, compileErr := CompilePathRegex(opts.PathRegex)
-		if compileErr != nil {
-			return nil, fmt.Errorf("invalid --path-regex: %w", compileErr)
-		}
-		rx = compiled
+// RunContext は ctx が取り消されるか opts.Timeout を過ぎた時点で、走査中のファイル読み込みと
+// 実行中の git コマンド（grep / blame / log -L / show）を止めてエラーを返します。
+// opts.GitTimeout は 1 件ごとの git 呼び出しの上限で、超えた項目は Result.Errors に記録して続行します。
+//
+// opts.OnItem を設定すると、項目は帰属を終えた時点で（StreamOrder に従って）順に渡され、
+// 返る Result の Items は nil、Total は渡した件数になります。OnItem がエラーを返すと走査を止めてそのエラーを返します。
+func RunContext(parent context.Context, opts Options) (*Result, error) {
+	start := time.Now()
+	opts, tags, searchTags, err := prepareOptions(opts)
+	if err != nil {
+		return nil, err
 	}
-	opts.PathRegexCompiled = rx
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := withTimeout(parent, opts.Timeout)
 	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return nil, runError(ctx, opts, err)
+	}
 
 	modelMatches, detectErrs, err := collectMatches(ctx, opts, searchTags)
 	if err != nil {
-		return nil, err
+		return nil, runError(ctx, opts, err)
+	}
+	var subs submoduleSet
+	if opts.RecurseSubmodules {
+		var subErrs []ItemError
+		subs, subErrs, err = listSubmodules(ctx, opts.RepoDir)
+		if err != nil {
+			return nil, runError(ctx, opts, err)
+		}
+		subMatches, matchErrs, err := collectSubmoduleMatches(ctx, opts, searchTags, subs)
+		if err != nil {
+			return nil, runError(ctx, opts, err)
+		}
+		modelMatches = append(modelMatches, subMatches...)
+		detectErrs = append(append(detectErrs, subErrs...), matchErrs...)
 	}
 	if len(modelMatches) == 0 {
 		return &Result{Items: nil, HasComment: opts.WithComment, HasMessage: opts.WithMessage, Total: 0, ElapsedMS: msSince(start), Errors: detectErrs, ErrorCount: len(detectErrs)}, nil
 	}
 
-	normalized := normalizedTags(tags)
-	switch opts.Type {
-	case "todo":
-		todoTags := normalizedTagsForType(normalized, "TODO")
-		modelMatches = filterModelMatchesByTags(modelMatches, todoTags, []string{"TODO"})
-	case "fixme":
-		fixmeTags := normalizedTagsForType(normalized, "FIXME")
-		modelMatches = filterModelMatchesByTags(modelMatches, fixmeTags, []string{"FIXME"})
+	modelMatches = filterMatchesByType(modelMatches, opts.Type, tags)
+	modelMatches, suppressed := splitSuppressed(modelMatches, opts.ShowSuppressed)
+	if len(modelMatches) == 0 {
+		return &Result{Items: nil, HasComment: opts.WithComment, HasMessage: opts.WithMessage, Total: 0, Suppressed: suppressed, ElapsedMS: msSince(start), Errors: detectErrs, ErrorCount: len(detectErrs)}, nil
+	}
+
+	modelMatches, matchOwners, hasOwners, err := resolveOwners(opts, modelMatches)
+	if err != nil {
+		return nil, runError(ctx, opts, err)
 	}
 	if len(modelMatches) == 0 {
-		return &Result{Items: nil, HasComment: opts.WithComment, HasMessage: opts.WithMessage, Total: 0, ElapsedMS: msSince(start), Errors: detectErrs, ErrorCount: len(detectErrs)}, nil
+		return &Result{Items: nil, HasComment: opts.WithComment, HasMessage: opts.WithMessage, HasOwners: hasOwners, Total: 0, Suppressed: suppressed, ElapsedMS: msSince(start), Errors: detectErrs, ErrorCount: len(detectErrs)}, nil
 	}
 
-	out := make([]Item, len(modelMatches))
+	var (
+		out    []Item
+		stream *itemStream
+	)
+	if opts.OnItem != nil {
+		stream, err = newItemStream(opts.OnItem, opts.StreamOrder, modelMatches)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		out = make([]Item, len(modelMatches))
+	}
 
 	var observers []progress.Observer
 	if opts.ProgressObserver != nil {
@@ -161,10 +168,29 @@ func Run(opts Options) (*Result, error) {
 		}
 	}
 
+	attr := attribution{aliases: newAuthorAliases(opts.AuthorAliases)}
+	srcCtx := newSourceContext(opts.RepoDir, opts.ContextLines, modelMatches)
+	if opts.SkipAuthors != "" {
+		if strings.ToLower(opts.Mode) == "first" {
+			return nil, fmt.Errorf("--skip-authors cannot be combined with --mode first")
+		}
+		attr.skip, err = regexp.Compile(opts.SkipAuthors)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --skip-authors regex: %w", err)
+		}
+	}
+
 	worker := func() {
 		defer wg.Done()
 		for j := range jobs {
-			item, itemErrs := processOne(ctx, opts, j.m)
+			if ctx.Err() != nil {
+				continue
+			}
+			item, itemErrs := processItem(ctx, opts, attr, subs, j.m)
+			if matchOwners != nil {
+				item.Owners = matchOwners[j.idx]
+			}
+			srcCtx.attach(&item)
 			if len(itemErrs) > 0 {
 				errsMu.Lock()
 				errs = append(errs, itemErrs...)
@@ -175,7 +201,13 @@ func Run(opts Options) (*Result, error) {
 					item.Commit = ""
 				}
 			}
-			out[j.idx] = item
+			if stream != nil {
+				if stream.done(j.m.File, item, keepItem(item, authorRe)) != nil {
+					cancel()
+				}
+			} else {
+				out[j.idx] = item
+			}
 			if snap, notify := estimator.Advance(1); notify {
 				observer.Publish(snap)
 			}
@@ -190,11 +222,22 @@ func Run(opts Options) (*Result, error) {
 	for i := 0; i < nw; i++ {
 		go worker()
 	}
+feed:
 	for i, m := range modelMatches {
-		jobs <- job{idx: i, m: m}
+		select {
+		case jobs <- job{idx: i, m: m}:
+		case <-ctx.Done():
+			break feed
+		}
 	}
 	close(jobs)
 	wg.Wait()
+	if stream != nil && stream.err != nil {
+		return nil, stream.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, runError(ctx, opts, err)
+	}
 
 	finalSnap := estimator.Complete()
 	observer.Publish(finalSnap)
@@ -203,7 +246,7 @@ func Run(opts Options) (*Result, error) {
 	// compact skipped
 	final := out[:0]
 	for _, it := range out {
-		if it.Commit != "" || (it.Author == "(working tree)" && it.Commit == "") {
+		if keepItem(it, authorRe) {
 			final = append(final, it)
 		}
 	}
@@ -226,17 +269,128 @@ func Run(opts Options) (*Result, error) {
 		return errs[i].File < errs[j].File
 	})
 
+	total := len(final)
+	if stream != nil {
+		final = nil
+		total = stream.emitted
+	}
 	return &Result{
 		Items:      final,
 		HasComment: opts.WithComment,
 		HasMessage: opts.WithMessage,
-		Total:      len(final),
+		HasOwners:  hasOwners,
+		Total:      total,
+		Suppressed: suppressed,
 		ElapsedMS:  msSince(start),
 		Errors:     errs,
 		ErrorCount: len(errs),
 	}, nil
 }
 
+// prepareOptions は Now と Jobs の既定値を埋め、タグの規則を検証してパスの正規表現と
+// 言語定義をコンパイルします。返り値は準備済みの opts、有効なタグ、--type で絞った検索対象のタグです。
+func prepareOptions(opts Options) (Options, []string, []string, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now().UTC()
+	} else {
+		opts.Now = opts.Now.UTC()
+	}
+	if opts.Jobs <= 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	tags := effectiveTags(opts.Tags)
+	searchTags, err := resolveSearchTags(opts.Type, tags)
+	if err != nil {
+		return opts, nil, nil, err
+	}
+	if err := opts.TagRules.Validate(); err != nil {
+		return opts, nil, nil, err
+	}
+
+	rx := opts.PathRegexCompiled
+	if len(rx) == 0 && len(opts.PathRegex) > 0 {
+		compiled, compileErr := CompilePathRegex(opts.PathRegex)
+		if compileErr != nil {
+			return opts, nil, nil, fmt.Errorf("invalid --path-regex: %w", compileErr)
+		}
+		rx = compiled
+	}
+	opts.PathRegexCompiled = rx
+	if opts.LanguagesCompiled == nil && len(opts.Languages) > 0 {
+		langs, langErr := CompileLanguages(opts.Languages)
+		if langErr != nil {
+			return opts, nil, nil, langErr
+		}
+		opts.LanguagesCompiled = langs
+	}
+	return opts, tags, searchTags, nil
+}
+
+// keepItem は帰属できた項目（作業ツリーの変更とアーカイブ内の項目を含む）かを返します。
+// --author に一致しなかった項目は worker で Commit を空にしてあるため除かれます。
+func keepItem(it Item, authorRe *regexp.Regexp) bool {
+	archived := it.MatchKind == string(model.MatchKindArchive) && authorRe == nil
+	return it.Commit != "" || (it.Author == "(working tree)" && it.Commit == "") || archived
+}
+
+// processItem は 1 件分の帰属を opts.GitTimeout の範囲で求めます。
+// 時間切れの場合は git の "signal: killed" の代わりに上限を超えた旨をエラーに記録します。
+func processItem(ctx context.Context, opts Options, attr attribution, subs submoduleSet, m model.Match) (Item, []ItemError) {
+	if opts.GitTimeout <= 0 {
+		return processInRepo(ctx, opts, attr, subs, m)
+	}
+	itemCtx, cancel := context.WithTimeout(ctx, opts.GitTimeout)
+	defer cancel()
+	item, errs := processInRepo(itemCtx, opts, attr, subs, m)
+	if errors.Is(itemCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		for i := range errs {
+			errs[i].Message = fmt.Sprintf("timed out after %s", opts.GitTimeout)
+		}
+	}
+	return item, errs
+}
+
+// withTimeout は timeout が正なら期限付きの、そうでなければ取り消しだけできる子の ctx を返します。
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// runError は走査の中断理由を返します。--timeout による打ち切りはその旨を明示します。
+func runError(ctx context.Context, opts Options, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) && opts.Timeout > 0 {
+			return fmt.Errorf("scan timed out after %s: %w", opts.Timeout, ctxErr)
+		}
+		return ctxErr
+	}
+	return err
+}
+
+// resolveSearchTags は --type に応じて検索対象のタグを絞り込みます。
+func resolveSearchTags(typ string, tags []string) ([]string, error) {
+	switch strings.ToLower(typ) {
+	case "todo":
+		filtered := filterTagsByType(tags, "TODO")
+		if len(filtered) == 0 {
+			return []string{"TODO"}, nil
+		}
+		return filtered, nil
+	case "fixme":
+		filtered := filterTagsByType(tags, "FIXME")
+		if len(filtered) == 0 {
+			return []string{"FIXME"}, nil
+		}
+		return filtered, nil
+	case "", "both":
+		return tags, nil
+	default:
+		return nil, fmt.Errorf("invalid --type: %s", typ)
+	}
+}
+
 func newItemError(file string, line int, stage string, err error) ItemError {
 	msg := strings.TrimSpace(err.Error())
 	if msg == "" {
@@ -245,18 +399,31 @@ func newItemError(file string, line int, stage string, err error) ItemError {
 	return ItemError{File: file, Line: line, Stage: stage, Message: msg}
 }
 
-func processOne(ctx context.Context, opts Options, m model.Match) (Item, []ItemError) {
+// newItem は帰属前の検出結果から Item の位置とタグの情報を埋めます。
+func newItem(m model.Match) Item {
 	span := normalizeSpan(m.Span)
-	line := span.StartLine
-	it := Item{
-		Kind:      m.Tag,
-		Tag:       m.Tag,
-		Lang:      m.Lang,
-		MatchKind: string(m.Kind),
-		Text:      m.Text,
-		Span:      span,
-		File:      m.File,
-		Line:      line,
+	return Item{
+		Kind:       m.Tag,
+		Tag:        m.Tag,
+		Lang:       m.Lang,
+		MatchKind:  string(m.Kind),
+		Text:       m.Text,
+		Span:       span,
+		Cell:       m.Cell,
+		File:       m.File,
+		Suppressed: m.Suppressed,
+		Line:       span.StartLine,
+	}
+}
+
+func processOne(ctx context.Context, opts Options, attr attribution, m model.Match) (Item, []ItemError) {
+	it := newItem(m)
+	line := it.Line
+	if m.Kind == model.MatchKindArchive {
+		// アーカイブ内のファイルは git の追跡対象ではないため blame しません。
+		it.Author, it.Email, it.Date = archiveAuthor, "-", "-"
+		applyCommentFields(opts, m, &it)
+		return it, nil
 	}
 	var sha string
 	var errs []ItemError
@@ -291,17 +458,29 @@ func processOne(ctx context.Context, opts Options, m model.Match) (Item, []ItemE
 		it.Date = "(uncommitted)"
 		it.Commit = ""
 	} else {
-		a, e, d, authorTime, s, err := commitMeta(ctx, opts.RepoDir, sha)
+		info, err := commitMeta(ctx, opts.RepoDir, sha)
 		if err != nil {
 			errs = append(errs, newItemError(m.File, line, "git show", err))
+		} else if attr.isSkipped(info) {
+			var skipErrs []ItemError
+			sha, info, it.Skipped, skipErrs = walkPastSkipped(ctx, opts, attr, m.File, line, sha, info)
+			errs = append(errs, skipErrs...)
 		}
-		it.Author, it.Email, it.Date, it.Commit = a, e, d, sha
-		it.AgeDays = ageDays(opts.Now, authorTime)
+		it.Author, it.Email = attr.aliases.resolve(info.Author, info.Email)
+		it.Date, it.Commit = info.Date, sha
+		it.Committer, it.CommitterEmail = attr.aliases.resolve(info.Committer, info.CommitterEmail)
+		it.AgeDays = ageDays(opts.Now, info.AuthorTime)
 		if opts.WithMessage {
-			it.Message = truncateDisplayWidth(s, effectiveTrunc(opts.TruncMessage, opts.TruncAll))
+			it.Message = truncateDisplayWidth(info.Subject, effectiveTrunc(opts.TruncMessage, opts.TruncAll))
 		}
 	}
 
+	applyCommentFields(opts, m, &it)
+	return it, errs
+}
+
+// applyCommentFields は --with-comment 指定時に COMMENT / BODY 列を埋めます。
+func applyCommentFields(opts Options, m model.Match, it *Item) {
 	if opts.WithComment {
 		text := strings.TrimSpace(m.Text)
 		comment := extractComment(text, opts.Type, opts.Tags)
@@ -309,22 +488,26 @@ func processOne(ctx context.Context, opts Options, m model.Match) (Item, []ItemE
 			comment = m.Tag
 		}
 		it.Comment = truncateDisplayWidth(comment, effectiveTrunc(opts.TruncComment, opts.TruncAll))
+		if body := strings.TrimSpace(m.Body); body != "" {
+			it.Body = truncateDisplayWidth(body, effectiveTrunc(opts.TruncComment, opts.TruncAll))
+		}
 	}
-
-	return it, errs
 }
 
-func buildBlameArgs(file string, line int, ignoreWS bool) []string {
+func buildBlameArgs(file string, line int, ignoreWS bool, ignoreRevs ...string) []string {
 	args := []string{"blame"}
 	if ignoreWS {
 		args = append(args, "-w")
 	}
+	for _, rev := range ignoreRevs {
+		args = append(args, "--ignore-rev", rev)
+	}
 	lineSpec := fmt.Sprintf("%d,%d", line, line)
 	return append(args, "--line-porcelain", "-L", lineSpec, "--", file)
 }
 
-func blameSHA(ctx context.Context, repo, file string, line int, ignoreWS bool) (string, error) {
-	args := buildBlameArgs(file, line, ignoreWS)
+func blameSHA(ctx context.Context, repo, file string, line int, ignoreWS bool, ignoreRevs ...string) (string, error) {
+	args := buildBlameArgs(file, line, ignoreWS, ignoreRevs...)
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repo
 	out, err := cmd.Output()
@@ -355,22 +538,43 @@ func firstCommitForLine(ctx context.Context, repo, file string, line int) (strin
 	return "", nil
 }
 
-func commitMeta(ctx context.Context, repo, sha string) (author, email, date string, authorTime time.Time, subject string, err error) {
-	cmd := exec.CommandContext(ctx, "git", "show", "-s", "--date=iso-strict-local", "--format=%an%x09%ae%x09%ad%x09%at%x09%s", sha)
+// commitInfo は git show から取得したコミットのメタデータです。
+// 作者・コミッターは .mailmap を適用した値（%aN/%aE/%cN/%cE）です。
+type commitInfo struct {
+	Author         string
+	Email          string
+	Date           string
+	AuthorTime     time.Time
+	Committer      string
+	CommitterEmail string
+	Subject        string
+}
+
+func commitMeta(ctx context.Context, repo, sha string) (commitInfo, error) {
+	placeholder := commitInfo{Author: "-", Email: "-", Date: "-", Subject: "-"}
+	cmd := exec.CommandContext(ctx, "git", "show", "-s", "--date=iso-strict-local", "--format=%aN%x09%aE%x09%ad%x09%at%x09%cN%x09%cE%x09%s", sha)
 	cmd.Dir = repo
 	out, err := cmd.Output()
 	if err != nil {
-		return "-", "-", "-", time.Time{}, "-", fmt.Errorf("git show: %w", err)
+		return placeholder, fmt.Errorf("git show: %w", err)
 	}
-	parts := strings.SplitN(strings.TrimSpace(string(out)), "\t", 5)
-	if len(parts) != 5 {
-		return "-", "-", "-", time.Time{}, "-", fmt.Errorf("git show unexpected output: %q", strings.TrimSpace(string(out)))
+	parts := strings.SplitN(strings.TrimSpace(string(out)), "\t", 7)
+	if len(parts) != 7 {
+		return placeholder, fmt.Errorf("git show unexpected output: %q", strings.TrimSpace(string(out)))
 	}
 	ts, err := strconv.ParseInt(parts[3], 10, 64)
 	if err != nil {
-		return "-", "-", "-", time.Time{}, "-", fmt.Errorf("git show timestamp parse: %w", err)
-	}
-	return parts[0], parts[1], parts[2], time.Unix(ts, 0).UTC(), parts[4], nil
+		return placeholder, fmt.Errorf("git show timestamp parse: %w", err)
+	}
+	return commitInfo{
+		Author:         parts[0],
+		Email:          parts[1],
+		Date:           parts[2],
+		AuthorTime:     time.Unix(ts, 0).UTC(),
+		Committer:      parts[4],
+		CommitterEmail: parts[5],
+		Subject:        parts[6],
+	}, nil
 }
 
 func effectiveTags(tags []string) []string {
@@ -448,6 +652,18 @@ func normalizedTagsForType(normalized []string, target string) []string {
 	return out
 }
 
+// filterMatchesByType は --type todo / fixme のとき、該当する種類のタグのマッチだけを残します。
+func filterMatchesByType(matches []model.Match, typ string, tags []string) []model.Match {
+	normalized := normalizedTags(tags)
+	switch typ {
+	case "todo":
+		return filterModelMatchesByTags(matches, normalizedTagsForType(normalized, "TODO"), []string{"TODO"})
+	case "fixme":
+		return filterModelMatchesByTags(matches, normalizedTagsForType(normalized, "FIXME"), []string{"FIXME"})
+	}
+	return matches
+}
+
 func filterModelMatchesByTags(matches []model.Match, include []string, fallback []string) []model.Match {
 	tags := include
 	if len(tags) == 0 {
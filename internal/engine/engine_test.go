@@ -99,15 +99,15 @@ func TestCommitMetaエラー時はプレースホルダーとエラーを返す(
 	ctx := context.Background()
 	repo := t.TempDir()
 
-	author, email, date, ts, subject, err := commitMeta(ctx, repo, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	info, err := commitMeta(ctx, repo, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
 	if err == nil {
 		t.Fatalf("エラーが返される想定でした")
 	}
-	if author != "-" || email != "-" || date != "-" || subject != "-" {
-		t.Fatalf("エラー時のプレースホルダーが想定外です: %q %q %q %q", author, email, date, subject)
+	if info.Author != "-" || info.Email != "-" || info.Date != "-" || info.Subject != "-" {
+		t.Fatalf("エラー時のプレースホルダーが想定外です: %q %q %q %q", info.Author, info.Email, info.Date, info.Subject)
 	}
-	if !ts.IsZero() {
-		t.Fatalf("エラー時のタイムスタンプはゼロ値の想定です: %v", ts)
+	if !info.AuthorTime.IsZero() {
+		t.Fatalf("エラー時のタイムスタンプはゼロ値の想定です: %v", info.AuthorTime)
 	}
 	if !strings.Contains(err.Error(), "git show") {
 		t.Fatalf("エラーメッセージにコマンド名が含まれていません: %v", err)
@@ -268,7 +268,7 @@ func TestGitGrepHandlesLongLines(t *testing.T) {
 	runGit(t, repoDir, "add", "long.go")
 	runGit(t, repoDir, "commit", "-m", "add long line")
 
-	matches, err := gitGrepMatches(repoDir, "TODO", nil, nil, false)
+	matches, err := gitGrepMatches(context.Background(), repoDir, "TODO", nil, nil, false)
 	if err != nil {
 		t.Fatalf("gitGrep returned error: %v", err)
 	}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// generatedAttributes は .gitattributes で生成物・vendored として扱い、既定で走査しない属性です。
+var generatedAttributes = []string{"linguist-generated", "linguist-vendored", "todox-ignore"}
+
+// generatedHeaderBytes は生成ファイルの目印を探すファイル先頭の範囲です。
+const generatedHeaderBytes = 4096
+
+// goGeneratedLine は Go の生成コードの目印（https://go.dev/s/generatedcode）です。大文字小文字も含めて完全に一致する行だけを認めます。
+var goGeneratedLine = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// autoGeneratedFileNote は "Auto-generated file, do not edit" のような定型文です。
+var autoGeneratedFileNote = regexp.MustCompile(`(?i)^auto-?generated file\b.*\bdo not edit\b`)
+
+// hasGeneratedHeader はファイル先頭のコメント（最初のコード行より前）に生成ファイルの目印があるかを返します。
+// 目印は Go の "// Code generated ... DO NOT EDIT." の行、コメントの先頭に置かれた "@generated" タグ、
+// protoc の "Generated by the protocol buffer compiler"、.NET の "<auto-generated>" などです。
+// コードより後ろのコメントや、文中でこれらの語句に触れているだけのコメントは目印とみなしません。
+func hasGeneratedHeader(data []byte) bool {
+	if len(data) > generatedHeaderBytes {
+		data = data[:generatedHeaderBytes]
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	blockEnd := "" // 複数行のブロックコメントの中にいる間はその終了記号
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+		var text string
+		switch {
+		case blockEnd != "":
+			text = trimmed
+			if idx := strings.Index(text, blockEnd); idx >= 0 {
+				text, blockEnd = text[:idx], ""
+			}
+		case trimmed == "":
+			continue
+		case i == 0 && (strings.HasPrefix(trimmed, "#!") || strings.HasPrefix(trimmed, "<?xml")):
+			continue
+		case strings.HasPrefix(trimmed, "/*"), strings.HasPrefix(trimmed, "<!--"):
+			open, end := "/*", "*/"
+			if strings.HasPrefix(trimmed, "<!--") {
+				open, end = "<!--", "-->"
+			}
+			text = trimmed[len(open):]
+			if idx := strings.Index(text, end); idx >= 0 {
+				text = text[:idx]
+			} else {
+				blockEnd = end
+			}
+		case strings.HasPrefix(trimmed, "//"), strings.HasPrefix(trimmed, "#"),
+			strings.HasPrefix(trimmed, "--"), strings.HasPrefix(trimmed, ";"):
+			text = strings.TrimLeft(trimmed, "/#-;!")
+		default:
+			return false // 最初のコード行で先頭のコメントは終わります
+		}
+		if goGeneratedLine.MatchString(line) {
+			return true
+		}
+		text = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), "*"))
+		if isGeneratedTag(text) ||
+			strings.HasPrefix(text, "Generated by the protocol buffer compiler") ||
+			strings.HasPrefix(text, "<auto-generated") ||
+			autoGeneratedFileNote.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedTag はコメントの本文が "@generated" タグで始まるかを返します（"@generated SignedSource<<...>>" など）。
+func isGeneratedTag(text string) bool {
+	rest, ok := strings.CutPrefix(text, "@generated")
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
+
+// filterGeneratedPaths は git check-attr で generatedAttributes のいずれかが設定されたパスを取り除きます。
+func filterGeneratedPaths(ctx context.Context, repo string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return paths, nil
+	}
+	marked, err := gitGeneratedAttrs(ctx, repo, paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(marked) == 0 {
+		return paths, nil
+	}
+	out := paths[:0]
+	for _, p := range paths {
+		if _, skip := marked[p]; !skip {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func gitGeneratedAttrs(ctx context.Context, repo string, paths []string) (map[string]struct{}, error) {
+	args := append([]string{"-c", "core.quotePath=false", "check-attr", "-z", "--stdin"}, generatedAttributes...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo
+	var stdin bytes.Buffer
+	for _, p := range paths {
+		stdin.WriteString(p)
+		stdin.WriteByte(0)
+	}
+	cmd.Stdin = &stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git check-attr: %w", err)
+	}
+	// -z の出力は <path> NUL <attribute> NUL <value> NUL の繰り返しです。
+	fields := bytes.Split(out, []byte{0})
+	marked := make(map[string]struct{})
+	for i := 0; i+2 < len(fields); i += 3 {
+		if attrIsSet(string(fields[i+2])) {
+			marked[filepath.ToSlash(string(fields[i]))] = struct{}{}
+		}
+	}
+	return marked, nil
+}
+
+// attrIsSet は check-attr の値が属性の指定（"set"、"true" など）を表すかを返します。
+func attrIsSet(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "unspecified", "unset", "false", "0":
+		return false
+	}
+	return true
+}
+
+// excludeGeneratedMatches は正規表現モードの結果から、属性または先頭の目印で生成ファイルと分かるファイルの項目を除きます。
+func excludeGeneratedMatches(ctx context.Context, repo string, matches []model.Match) ([]model.Match, error) {
+	var files []string
+	seen := make(map[string]struct{})
+	for _, m := range matches {
+		if _, ok := seen[m.File]; !ok {
+			seen[m.File] = struct{}{}
+			files = append(files, m.File)
+		}
+	}
+	kept, err := filterGeneratedPaths(ctx, repo, files)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(kept))
+	for _, f := range kept {
+		keep[f] = !fileHasGeneratedHeader(filepath.Join(repo, f))
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		if keep[m.File] {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func fileHasGeneratedHeader(path string) bool {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+	head, err := io.ReadAll(io.LimitReader(fh, generatedHeaderBytes))
+	if err != nil {
+		return false
+	}
+	return hasGeneratedHeader(head)
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestHasGeneratedHeaderは定型の目印を判定する(t *testing.T) {
+	cases := map[string]bool{
+		"// Code generated by protoc-gen-go. DO NOT EDIT.\npackage pb\n":                        true,
+		"# -*- coding: utf-8 -*-\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n": true,
+		"/**\n * @generated SignedSource<<abc>>\n */\n":                                         true,
+		"// <auto-generated>\n//   This code was generated by a tool.\n":                        true,
+		"package main\n\n// TODO: generated code should not be edited by hand\n":                false,
+		"// Code generated for tests; feel free to edit\n":                                      false,
+	}
+	for src, want := range cases {
+		if got := hasGeneratedHeader([]byte(src)); got != want {
+			t.Fatalf("hasGeneratedHeader(%q) = %v, want %v", src, got, want)
+		}
+	}
+	late := make([]byte, generatedHeaderBytes+10)
+	for i := range late {
+		late[i] = '\n'
+	}
+	late = append(late, "// Code generated by x. DO NOT EDIT.\n"...)
+	if hasGeneratedHeader(late) {
+		t.Fatalf("markers beyond the header window should be ignored")
+	}
+}
+
+func TestHasGeneratedHeaderは文中の語句を目印とみなさない(t *testing.T) {
+	prose := []string{
+		"// Package gen explains that generated files start with\n// \"// Code generated by x. DO NOT EDIT.\" and are skipped.\npackage gen\n",
+		"// This tool emits @generated markers and <auto-generated> headers.\npackage tool\n",
+		"// Files generated by the protocol buffer compiler are skipped.\npackage pb\n",
+		"// code generated by hand, do not edit lightly.\npackage main\n",
+		"//Code generated by x. DO NOT EDIT.\npackage main\n",
+		"// Code generated by x. DO NOT EDIT\npackage main\n",
+		"package main\n\n// Code generated by x. DO NOT EDIT.\n",
+		"package main\n\n/**\n * @generated\n */\n",
+		"x = 1\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n",
+		"/* @generatedBy: alice */\nint x;\n",
+	}
+	for _, src := range prose {
+		if hasGeneratedHeader([]byte(src)) {
+			t.Errorf("hasGeneratedHeader(%q) should be false", src)
+		}
+	}
+	// 目印の判定を実装しているファイル自身も生成ファイルではない
+	self, err := os.ReadFile("generated.go")
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if hasGeneratedHeader(self) {
+		t.Fatal("generated.go should not be detected as generated")
+	}
+	headers := []string{
+		"#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n",
+		"//go:build linux\n\n// Code generated by stringer; DO NOT EDIT.\n\npackage x\n",
+		"/*\n * Copyright 2024\n *\n * @generated\n */\nint x;\n",
+		"<?xml version=\"1.0\"?>\n<!-- <auto-generated /> -->\n<root/>\n",
+	}
+	for _, src := range headers {
+		if !hasGeneratedHeader([]byte(src)) {
+			t.Errorf("hasGeneratedHeader(%q) should be true", src)
+		}
+	}
+}
+
+func TestRunは生成ファイルを既定で除外する(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	files := map[string]string{
+		".gitattributes":       "gen/** linguist-generated\nthird_party/** linguist-vendored=true\nlegacy.go todox-ignore\nkeep/** linguist-vendored=false\n",
+		"main.go":              "package main\n// TODO: ours\n",
+		"api.pb.go":            "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n// TODO: upstream\n",
+		"gen/client.go":        "package gen\n// TODO: generated client\n",
+		"third_party/lib.go":   "package lib\n// TODO: vendored\n",
+		"legacy.go":            "package main\n// TODO: ignored by attribute\n",
+		"keep/vendored_off.go": "package keep\n// TODO: explicitly not vendored\n",
+	}
+	for name, body := range files {
+		full := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	collect := func(opts Options) []string {
+		t.Helper()
+		res, err := Run(opts)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		var got []string
+		for _, it := range res.Items {
+			// 正規表現モードでは .gitattributes の "todox-ignore" 自体も TODO として拾われる
+			if it.File != ".gitattributes" {
+				got = append(got, it.File)
+			}
+		}
+		sort.Strings(got)
+		return got
+	}
+	for _, mode := range []string{"parse", "regex"} {
+		opts := Options{Type: "both", Mode: "last", DetectMode: mode, RepoDir: repoDir, Jobs: 1}
+		if got, want := collect(opts), []string{"keep/vendored_off.go", "main.go"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v want %v", mode, got, want)
+		}
+		opts.IncludeGenerated = true
+		if got := collect(opts); len(got) != 6 {
+			t.Fatalf("%s: --include-generated should keep every file, got %v", mode, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func matchSummary(matches []model.Match) []string {
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, fmt.Sprintf("%d %s %s", m.Span.StartLine, m.Kind, m.Tag))
+	}
+	return out
+}
+
+func scanLang(t *testing.T, lang, src string, includeStrings bool) []string {
+	t.Helper()
+	style, ok := styleForLanguage(lang)
+	if !ok {
+		t.Fatalf("no style for %s", lang)
+	}
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	return matchSummary(scanWithStyle("sample", []byte(src), tags, lang, style, includeStrings))
+}
+
+func TestHeredocsAreClassified(t *testing.T) {
+	cases := []struct {
+		lang string
+		src  string
+		want []string
+	}{
+		{
+			lang: "shell",
+			src:  "cat <<-EOF > out # TODO real comment\n\tTODO inside heredoc # not a comment\n\tEOF\necho done # FIXME after\n",
+			want: []string{"1 comment TODO", "2 heredoc TODO", "4 comment FIXME"},
+		},
+		{
+			lang: "shell",
+			src:  "cat << 'END'\nFIXME quoted heredoc\nEND\ngrep x <<< \"TODO here-string\"\n",
+			want: []string{"2 heredoc FIXME", "4 string TODO"},
+		},
+		{
+			lang: "ruby",
+			src:  "sql = <<~SQL\n  SELECT 1 -- TODO in heredoc\n  SQL\nclass << self # FIXME comment\nend\nlist<<item # TODO shift\n",
+			want: []string{"2 heredoc TODO", "4 comment FIXME", "6 comment TODO"},
+		},
+		{
+			lang: "perl",
+			src:  "print <<\"END\";\nTODO in perl heredoc\nEND\n# FIXME comment\n",
+			want: []string{"2 heredoc TODO", "4 comment FIXME"},
+		},
+		{
+			lang: "php",
+			src:  "<?php\n$a = <<<'X'\n  # TODO in nowdoc\n  X;\n$b = <<<EOT\nFIXME in heredoc\nEOT;\n// TODO comment\n",
+			want: []string{"3 heredoc TODO", "6 heredoc FIXME", "8 comment TODO"},
+		},
+	}
+	for _, tc := range cases {
+		if got := scanLang(t, tc.lang, tc.src, true); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %q want %q", tc.lang, got, tc.want)
+		}
+		comments := []string{}
+		for _, w := range tc.want {
+			if strings.Contains(w, " comment ") {
+				comments = append(comments, w)
+			}
+		}
+		if got := scanLang(t, tc.lang, tc.src, false); !reflect.DeepEqual(got, comments) {
+			t.Fatalf("%s comments only: got %q want %q", tc.lang, got, comments)
+		}
+	}
+}
+
+func TestNestedBlockComments(t *testing.T) {
+	cases := []struct {
+		lang string
+		src  string
+		want []string
+	}{
+		{lang: "swift", src: "/* outer /* inner */\nTODO still comment */\nlet s = \"FIXME string\"\n", want: []string{"2 comment TODO", "3 string FIXME"}},
+		{lang: "haskell", src: "{- outer {- inner -}\nTODO still comment -}\nmain = pure () -- FIXME\n", want: []string{"2 comment TODO", "3 comment FIXME"}},
+		{lang: "ocaml", src: "(* outer (* inner *)\n   TODO still comment *)\nlet s = \"FIXME string\"\n", want: []string{"2 comment TODO", "3 string FIXME"}},
+		{lang: "scala", src: "/* a /* b */ c */ val x = 1\n// TODO after\n", want: []string{"2 comment TODO"}},
+		{lang: "c", src: "/* a /* b */ int x; // TODO after\n", want: []string{"1 comment TODO"}},
+	}
+	for _, tc := range cases {
+		if got := scanLang(t, tc.lang, tc.src, true); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %q want %q", tc.lang, got, tc.want)
+		}
+	}
+}
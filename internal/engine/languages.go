@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phyten/todox/internal/detect"
+	"github.com/phyten/todox/internal/model"
+)
+
+// LanguageDef は設定ファイルの languages: で追加・上書きする言語定義です。
+// コメント関連の項目（LineComments / BlockComments / Strings / Heredocs）がすべて空なら
+// 既存の言語の判定規則だけを拡張し、コメント構文は組み込みのものを使います。
+// 組み込みの言語では、指定した項目だけを置き換え、指定しなかった項目は組み込みの構文を引き継ぎます。
+type LanguageDef struct {
+	Extensions    []string          // ".pipeline" のような拡張子（先頭の "." は省略可）
+	Filenames     []string          // "Pipelinefile" のようなファイル名（大文字小文字は無視）
+	Shebangs      []string          // shebang 行のインタプリタ名（"python3.11" のような版番号の付いた名前にも一致）
+	LineComments  []string          // 行コメントの開始記号
+	BlockComments []BlockCommentDef // ブロックコメントの区切り
+	Strings       []StringDef       // 文字列リテラルの区切り
+	Heredocs      []string          // ヒアドキュメントの演算子（"<<-" / "<<" など）
+}
+
+// BlockCommentDef はブロックコメントの開始・終了記号です。
+type BlockCommentDef struct {
+	Start  string
+	End    string
+	Nested bool // /* /* */ */ のような入れ子を許す
+}
+
+// StringDef は文字列リテラルの開始・終了記号です。End が空なら Start と同じです。
+type StringDef struct {
+	Start     string
+	End       string
+	Raw       bool // バックスラッシュによるエスケープを解釈しない
+	Multiline bool // 改行をまたげる
+}
+
+func (d LanguageDef) hasStyle() bool {
+	return len(d.LineComments) > 0 || len(d.BlockComments) > 0 || len(d.Strings) > 0 || len(d.Heredocs) > 0
+}
+
+// LanguageSet は LanguageDef をコンパイルした判定表です。nil は組み込みの判定だけを使います。
+type LanguageSet struct {
+	extensions []langKey // 長い拡張子から順に照合する
+	filenames  map[string]string
+	shebangs   []langKey
+	styles     map[string]commentStyle
+}
+
+type langKey struct {
+	key  string
+	lang string
+}
+
+// CompileLanguages は言語名 → 定義のマップを検証して LanguageSet にします。
+func CompileLanguages(defs map[string]LanguageDef) (*LanguageSet, error) {
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	set := &LanguageSet{filenames: make(map[string]string), styles: make(map[string]commentStyle)}
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := defs[name]
+		lang := detect.NormalizeLangName(name)
+		if lang == "" {
+			return nil, fmt.Errorf("invalid languages entry: empty name")
+		}
+		for _, ext := range def.Extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			set.extensions = append(set.extensions, langKey{key: ext, lang: lang})
+		}
+		for _, file := range def.Filenames {
+			if file = strings.ToLower(strings.TrimSpace(file)); file != "" {
+				set.filenames[file] = lang
+			}
+		}
+		for _, interp := range def.Shebangs {
+			if interp = strings.ToLower(strings.TrimSpace(interp)); interp != "" {
+				set.shebangs = append(set.shebangs, langKey{key: interp, lang: lang})
+			}
+		}
+		if !def.hasStyle() {
+			continue
+		}
+		lexer, err := compileLanguageLexer(lang, def)
+		if err != nil {
+			return nil, err
+		}
+		set.styles[lang] = commentStyle{lexer: lexer}
+	}
+	byLength := func(keys []langKey) {
+		sort.SliceStable(keys, func(i, j int) bool { return len(keys[i].key) > len(keys[j].key) })
+	}
+	byLength(set.extensions)
+	byLength(set.shebangs)
+	return set, nil
+}
+
+func compileLanguageLexer(lang string, def LanguageDef) (*codeLexer, error) {
+	lx := builtinLexer(lang)
+	if len(def.LineComments) > 0 {
+		lx.lineComments = nil
+		for _, prefix := range def.LineComments {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				lx.lineComments = append(lx.lineComments, prefix)
+			}
+		}
+	}
+	if len(def.BlockComments) > 0 {
+		lx.blockComments = nil
+		for _, blk := range def.BlockComments {
+			start, end := strings.TrimSpace(blk.Start), strings.TrimSpace(blk.End)
+			if start == "" || end == "" {
+				return nil, fmt.Errorf("languages.%s: block comment needs both start and end", lang)
+			}
+			lx.blockComments = append(lx.blockComments, lexBlock{open: start, close: end, nested: blk.Nested})
+		}
+	}
+	if len(def.Strings) > 0 {
+		lx.quotes = nil
+		for _, str := range def.Strings {
+			if str.Start == "" {
+				return nil, fmt.Errorf("languages.%s: string delimiter needs start", lang)
+			}
+			end := str.End
+			if end == "" {
+				end = str.Start
+			}
+			lx.quotes = append(lx.quotes, lexQuote{open: str.Start, close: end, escape: !str.Raw, multiline: str.Multiline})
+		}
+	}
+	if len(def.Heredocs) > 0 {
+		lx.heredocs = nil
+		for _, op := range def.Heredocs {
+			if op = strings.TrimSpace(op); op != "" {
+				lx.heredocs = append(lx.heredocs, op)
+			}
+		}
+	}
+	// 同じ文字で始まる区切りは長いものを優先します（""" と " など）。
+	sort.SliceStable(lx.lineComments, func(i, j int) bool { return len(lx.lineComments[i]) > len(lx.lineComments[j]) })
+	sort.SliceStable(lx.blockComments, func(i, j int) bool { return len(lx.blockComments[i].open) > len(lx.blockComments[j].open) })
+	sort.SliceStable(lx.quotes, func(i, j int) bool { return len(lx.quotes[i].open) > len(lx.quotes[j].open) })
+	sort.SliceStable(lx.heredocs, func(i, j int) bool { return len(lx.heredocs[i]) > len(lx.heredocs[j]) })
+	return lx, nil
+}
+
+// builtinLexer は lang の組み込みのコメント構文を複製した codeLexer を返します。組み込みに無い言語は空です。
+// 行単位の走査を使う言語は、区切りを codeLexer の形に写します（行頭だけで始まるブロックの制約は失われます）。
+func builtinLexer(lang string) *codeLexer {
+	cs, ok := styleForLanguage(lang)
+	if !ok {
+		return &codeLexer{}
+	}
+	var lx codeLexer
+	if cs.lexer != nil {
+		lx = *cs.lexer
+	} else {
+		lx.lineComments = cs.linePrefixes
+		lx.heredocs = cs.heredocs
+		for _, blk := range cs.block {
+			if blk.kind == model.MatchKindString {
+				lx.quotes = append(lx.quotes, lexQuote{open: blk.start, close: blk.end, escape: true, multiline: true})
+				continue
+			}
+			lx.blockComments = append(lx.blockComments, lexBlock{open: blk.start, close: blk.end, nested: blk.nested})
+		}
+		for _, delim := range cs.stringDelims {
+			lx.quotes = append(lx.quotes, lexQuote{open: delim, close: delim, escape: true})
+		}
+	}
+	// 組み込みの表を並べ替えで書き換えないよう、スライスは複製します。
+	lx.lineComments = append([]string(nil), lx.lineComments...)
+	lx.blockComments = append([]lexBlock(nil), lx.blockComments...)
+	lx.quotes = append([]lexQuote(nil), lx.quotes...)
+	lx.heredocs = append([]string(nil), lx.heredocs...)
+	return &lx
+}
+
+// shebangInterpreter は "#!" 行のインタプリタ名を小文字で返します。
+// "/usr/bin/env [-S] [NAME=VALUE...] python3" のような env 経由の指定は env の後の最初の引数を使います。
+func shebangInterpreter(data []byte) string {
+	if !bytes.HasPrefix(data, []byte("#!")) {
+		return ""
+	}
+	line := data[2:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := path.Base(fields[0])
+	if name == "env" {
+		name = ""
+		args := fields[1:]
+		for i := 0; i < len(args); i++ {
+			arg := args[i]
+			if arg == "-u" || arg == "-C" {
+				i++ // 値を取るオプション
+				continue
+			}
+			if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+				continue
+			}
+			name = path.Base(arg)
+			break
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// matchInterpreter は name が key と一致するか、key に版番号（"3"、"3.11"、"5.36.0" など）が続いたものかを返します。
+func matchInterpreter(name, key string) bool {
+	if !strings.HasPrefix(name, key) {
+		return false
+	}
+	return strings.Trim(name[len(key):], "0123456789.") == ""
+}
+
+// detectLanguage は設定で定義されたファイル名・拡張子・shebang を優先して言語を判定し、
+// 該当しなければ組み込みの判定にフォールバックします。
+func (s *LanguageSet) detectLanguage(path string, data []byte) string {
+	if s != nil {
+		base := strings.ToLower(filepath.Base(path))
+		if lang, ok := s.filenames[base]; ok {
+			return lang
+		}
+		for _, ext := range s.extensions {
+			if strings.HasSuffix(base, ext.key) {
+				return ext.lang
+			}
+		}
+		if len(s.shebangs) > 0 {
+			if interp := shebangInterpreter(data); interp != "" {
+				for _, sb := range s.shebangs {
+					if matchInterpreter(interp, sb.key) {
+						return sb.lang
+					}
+				}
+			}
+		}
+	}
+	return detect.NormalizeLangName(detect.FromPathAndContent(path, data).Name)
+}
+
+// style は設定で定義されたコメント構文を優先して返します。
+func (s *LanguageSet) style(lang string) (commentStyle, bool) {
+	if s != nil {
+		if cs, ok := s.styles[lang]; ok {
+			return cs, true
+		}
+	}
+	return styleForLanguage(lang)
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func mustCompileLanguages(t *testing.T, defs map[string]LanguageDef) *LanguageSet {
+	t.Helper()
+	set, err := CompileLanguages(defs)
+	if err != nil {
+		t.Fatalf("CompileLanguages failed: %v", err)
+	}
+	return set
+}
+
+func TestLanguageSetDetectsCustomLanguages(t *testing.T) {
+	set := mustCompileLanguages(t, map[string]LanguageDef{
+		"Pipeline": {Extensions: []string{"pipeline"}, Filenames: []string{"Pipelinefile"}, Shebangs: []string{"pipeline-run"}, LineComments: []string{"#"}},
+		"rules":    {Extensions: []string{".rules.yaml"}, LineComments: []string{"--"}},
+		"ruby":     {Extensions: []string{".rbx"}},
+	})
+	cases := map[string]struct {
+		data string
+		want string
+	}{
+		"ci/build.pipeline":      {want: "pipeline"},
+		"PIPELINEFILE":           {want: "pipeline"},
+		"bin/deploy":             {data: "#!/usr/bin/env pipeline-run\n", want: "pipeline"},
+		"policy/auth.rules.yaml": {want: "rules"},
+		"config.yaml":            {want: "yaml"},
+		"lib/legacy.rbx":         {want: "ruby"},
+		"main.go":                {want: "go"},
+	}
+	for path, tc := range cases {
+		if got := set.detectLanguage(path, []byte(tc.data)); got != tc.want {
+			t.Fatalf("%s: got %q want %q", path, got, tc.want)
+		}
+	}
+	if _, ok := set.style("ruby"); !ok {
+		t.Fatal("extension-only override should keep the built-in ruby style")
+	}
+	if cs, _ := set.style("ruby"); cs.lexer != nil {
+		t.Fatal("extension-only override must not replace the built-in ruby style")
+	}
+	var nilSet *LanguageSet
+	if got := nilSet.detectLanguage("main.go", nil); got != "go" {
+		t.Fatalf("nil set should fall back to built-ins, got %q", got)
+	}
+}
+
+func TestParseContentUsesCustomCommentStyle(t *testing.T) {
+	set := mustCompileLanguages(t, map[string]LanguageDef{
+		"pipeline": {
+			Extensions:    []string{".pipeline"},
+			LineComments:  []string{"#"},
+			BlockComments: []BlockCommentDef{{Start: "(*", End: "*)", Nested: true}},
+			Strings:       []StringDef{{Start: `"`}, {Start: "'", Raw: true, Multiline: true}},
+			Heredocs:      []string{"<<"},
+		},
+	})
+	data := []byte(`step "http://x#TODO" # TODO real comment
+(* outer (* inner *) FIXME still comment *)
+run 'C:\dir\' # FIXME after raw
+script <<EOF
+TODO inside heredoc
+EOF
+`)
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	opts := Options{IncludeStrings: false, LanguagesCompiled: set}
+	var got []string
+	for _, m := range parseContent("ci/build.pipeline", data, opts, tags, false) {
+		got = append(got, fmt.Sprintf("%s:%s@%d", m.Kind, m.Tag, m.Span.StartLine))
+	}
+	want := []string{"comment:TODO@1", "comment:FIXME@2", "comment:FIXME@3"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected matches: %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected matches: %v", got)
+		}
+	}
+
+	opts.IncludeStrings = true
+	matches := parseContent("ci/build.pipeline", data, opts, tags, false)
+	var heredoc *model.Match
+	for i := range matches {
+		if matches[i].Kind == model.MatchKindHeredoc {
+			heredoc = &matches[i]
+		}
+	}
+	if heredoc == nil || heredoc.Span.StartLine != 5 || heredoc.Lang != "pipeline" {
+		t.Fatalf("expected heredoc match on line 5, got %+v", matches)
+	}
+}
+
+func TestShebangMatchesTheInterpreterExactly(t *testing.T) {
+	set := mustCompileLanguages(t, map[string]LanguageDef{
+		"pipeline": {Shebangs: []string{"pipe"}, LineComments: []string{"#"}},
+	})
+	cases := map[string]bool{
+		"#!/usr/local/bin/pipe\n":                    true,
+		"#!/usr/bin/env pipe\n":                      true,
+		"#!/usr/bin/env -S pipe --strict\r\n":        true,
+		"#!/usr/bin/env -u HOME LANG=C pipe2.1 -x\n": true,
+		"#!/usr/bin/env pipeline-run\n":              false,
+		"#!/opt/pipe/bin/runner\n":                   false,
+		"#!/bin/runner --with pipe\n":                false,
+		"no shebang, just a line mentioning pipe\n":  false,
+	}
+	for data, want := range cases {
+		if got := set.detectLanguage("bin/tool", []byte(data)) == "pipeline"; got != want {
+			t.Errorf("%q: matched=%v want %v", data, got, want)
+		}
+	}
+}
+
+func TestCustomDefinitionMergesWithBuiltinSyntax(t *testing.T) {
+	set := mustCompileLanguages(t, map[string]LanguageDef{
+		"go":   {LineComments: []string{"//", "#"}},
+		"ruby": {BlockComments: []BlockCommentDef{{Start: "%{", End: "}%"}}},
+	})
+	tags := normalizeTags([]string{"TODO"})
+	opts := Options{LanguagesCompiled: set}
+	collect := func(path, data string) []int {
+		var lines []int
+		for _, m := range parseContent(path, []byte(data), opts, tags, false) {
+			lines = append(lines, m.Span.StartLine)
+		}
+		return lines
+	}
+	// Go keeps its block comments and raw strings; only the line comments changed.
+	goSrc := "# TODO hash\n/* TODO block */\nvar s = `// TODO raw`\n"
+	if got := collect("main.go", goSrc); fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("unexpected Go matches: %v", got)
+	}
+	// Ruby keeps "#" comments and its string delimiters.
+	rbSrc := "# TODO line\n%{ TODO block }%\nx = \"# TODO str\"\n"
+	if got := collect("a.rb", rbSrc); fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("unexpected Ruby matches: %v", got)
+	}
+	if lexerGo.lineComments[0] != "//" || len(lexerGo.lineComments) != 1 {
+		t.Fatalf("the built-in Go lexer was modified: %v", lexerGo.lineComments)
+	}
+}
+
+func TestCompileLanguagesRejectsIncompleteDelimiters(t *testing.T) {
+	if _, err := CompileLanguages(map[string]LanguageDef{"dsl": {BlockComments: []BlockCommentDef{{Start: "/*"}}}}); err == nil {
+		t.Fatal("expected error for block comment without end")
+	}
+	if _, err := CompileLanguages(map[string]LanguageDef{"dsl": {Strings: []StringDef{{End: `"`}}}}); err == nil {
+		t.Fatal("expected error for string without start")
+	}
+}
+
+func TestRunUsesConfiguredLanguages(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	content := "stage build ; TODO not a comment in this DSL\n-- FIXME real comment\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "app.rules"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "parse", RepoDir: repoDir, Jobs: 1}
+	opts.Languages = map[string]LanguageDef{"rules": {Extensions: []string{".rules"}, LineComments: []string{"--"}}}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Tag != "FIXME" || res.Items[0].Line != 2 {
+		t.Fatalf("expected only the FIXME comment, got %+v", res.Items)
+	}
+
+	opts.Languages = map[string]LanguageDef{"rules": {Strings: []StringDef{{Start: ""}}}}
+	if _, err := Run(opts); err == nil {
+		t.Fatal("expected invalid language definition to fail")
+	}
+}
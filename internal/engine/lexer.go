@@ -0,0 +1,560 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// lexRegion はレキサーが切り出したコメント・文字列の中身（区切り記号を除く）のバイト範囲です。
+type lexRegion struct {
+	kind  model.MatchKind
+	start int
+	end   int
+}
+
+// lexQuote は文字列リテラルの区切りです。
+type lexQuote struct {
+	open      string
+	close     string
+	escape    bool // バックスラッシュで閉じ記号をエスケープできる
+	multiline bool // 改行をまたげる（false の場合は行末で打ち切る）
+}
+
+// lexBlock はブロックコメントの区切りです。
+type lexBlock struct {
+	open   string
+	close  string
+	nested bool
+}
+
+// codeLexer は言語ごとの字句規則を持つ状態機械です。
+// 行頭からの strings.Index ではなく先頭から 1 文字ずつ読み進めるため、
+// 文字列中の "//" や "#"、コメント中の引用符に惑わされません。
+type codeLexer struct {
+	lineComments  []string
+	blockComments []lexBlock
+	quotes        []lexQuote // 長い区切り（""" など）を先に並べる
+	// charLiterals は 'x' / '\n' を文字リテラルとして読み飛ばします。
+	// 閉じ引用符が続かない場合（Rust のライフタイム 'a など）は単なる記号として扱います。
+	charLiterals bool
+	// regexLiterals は JavaScript の /.../ 正規表現リテラルを読み飛ばします。
+	regexLiterals bool
+	// wordComments はシェルのように単語の先頭にある場合だけ行コメントとみなします（${#x} や a#b は除外）。
+	wordComments bool
+	// codeEscapes はコード中のバックスラッシュが次の 1 文字をエスケープすることを表します（シェルの \" など）。
+	codeEscapes bool
+	// heredocs はヒアドキュメントの演算子です（長いものを先に並べる）。本文は演算子のある行の次の行から
+	// 終端識別子だけの行の直前までで、MatchKindHeredoc として返します。
+	heredocs []string
+	// special は識別子の先頭で呼ばれ、Rust の r#"..."# や C++ の R"d(...)d" のような
+	// 言語固有のリテラルを読み取ります。
+	special func(data []byte, i int) (lexRegion, int, bool)
+}
+
+var (
+	lexerGo = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/"}},
+		quotes: []lexQuote{
+			{open: "\"", close: "\"", escape: true},
+			{open: "`", close: "`", multiline: true},
+		},
+		charLiterals: true,
+	}
+	lexerJS = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/"}},
+		quotes: []lexQuote{
+			{open: "\"", close: "\"", escape: true},
+			{open: "'", close: "'", escape: true},
+			{open: "`", close: "`", escape: true, multiline: true},
+		},
+		regexLiterals: true,
+	}
+	lexerPython = &codeLexer{
+		lineComments: []string{"#"},
+		// 文字列の接頭辞（r/b/f/u とその組み合わせ）は識別子として読み飛ばされるため、区切りだけで判定できます。
+		quotes: []lexQuote{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+			{open: "'''", close: "'''", escape: true, multiline: true},
+			{open: "\"", close: "\"", escape: true},
+			{open: "'", close: "'", escape: true},
+		},
+	}
+	lexerRust = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/", nested: true}},
+		quotes:        []lexQuote{{open: "\"", close: "\"", escape: true, multiline: true}},
+		charLiterals:  true,
+		special:       lexRustRawString,
+	}
+	lexerJava = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/"}},
+		quotes: []lexQuote{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+			{open: "\"", close: "\"", escape: true},
+		},
+		charLiterals: true,
+	}
+	lexerKotlin = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/", nested: true}},
+		quotes: []lexQuote{
+			{open: `"""`, close: `"""`, multiline: true},
+			{open: "\"", close: "\"", escape: true},
+		},
+		charLiterals: true,
+	}
+	lexerC = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/"}},
+		quotes:        []lexQuote{{open: "\"", close: "\"", escape: true}},
+		charLiterals:  true,
+	}
+	lexerCpp = &codeLexer{
+		lineComments:  []string{"//"},
+		blockComments: []lexBlock{{open: "/*", close: "*/"}},
+		quotes:        []lexQuote{{open: "\"", close: "\"", escape: true}},
+		charLiterals:  true,
+		special:       lexCppRawString,
+	}
+	lexerShell = &codeLexer{
+		lineComments: []string{"#"},
+		quotes: []lexQuote{
+			{open: "\"", close: "\"", escape: true, multiline: true},
+			{open: "'", close: "'", multiline: true},
+			{open: "`", close: "`", escape: true, multiline: true},
+		},
+		wordComments: true,
+		codeEscapes:  true,
+		heredocs:     []string{"<<-", "<<"},
+	}
+)
+
+// lex は data を走査し、コメントと文字列の中身を出現順に返します。
+func (lx *codeLexer) lex(data []byte) []lexRegion {
+	var regions []lexRegion
+	var lastSig byte // 直前の空白以外の記号（正規表現リテラルの判定用）
+	lastWord := ""
+	var pending []pendingHeredoc
+	n := len(data)
+	i := 0
+	for i < n {
+		c := data[i]
+		if c == '\n' && len(pending) > 0 {
+			var bodies []lexRegion
+			bodies, i = consumeHeredocs(data, i+1, pending)
+			regions = append(regions, bodies...)
+			pending = nil
+			continue
+		}
+		if lx.codeEscapes && c == '\\' {
+			i += 2
+			lastSig, lastWord = c, ""
+			continue
+		}
+		if prefix := lx.lineCommentAt(data, i); prefix != "" {
+			end := i + len(prefix)
+			for end < n && data[end] != '\n' {
+				end++
+			}
+			regions = append(regions, lexRegion{kind: model.MatchKindComment, start: i + len(prefix), end: end})
+			i = end
+			continue
+		}
+		if blk, ok := lx.blockAt(data, i); ok {
+			start := i + len(blk.open)
+			end, next := scanBlock(data, start, blk)
+			regions = append(regions, lexRegion{kind: model.MatchKindComment, start: start, end: end})
+			i = next
+			continue
+		}
+		if h, next, ok := lx.heredocAt(data, i); ok {
+			pending = append(pending, h)
+			i = next
+			lastSig, lastWord = '"', ""
+			continue
+		}
+		if isIdentByte(c) && lx.special != nil {
+			if r, next, ok := lx.special(data, i); ok {
+				regions = append(regions, r)
+				i = next
+				lastSig, lastWord = '"', ""
+				continue
+			}
+		}
+		if q, ok := lx.quoteAt(data, i); ok {
+			start := i + len(q.open)
+			end, next := scanQuoted(data, start, q)
+			regions = append(regions, lexRegion{kind: model.MatchKindString, start: start, end: end})
+			i = next
+			lastSig, lastWord = '"', ""
+			continue
+		}
+		if c == '\'' && lx.charLiterals {
+			if next, ok := charLiteralEnd(data, i); ok {
+				i = next
+				lastSig, lastWord = '\'', ""
+				continue
+			}
+		}
+		if c == '/' && lx.regexLiterals && regexAllowed(lastSig, lastWord) {
+			if next, ok := regexLiteralEnd(data, i); ok {
+				i = next
+				lastSig, lastWord = '/', ""
+				continue
+			}
+		}
+		if isIdentByte(c) {
+			j := i + 1
+			for j < n && isIdentByte(data[j]) {
+				j++
+			}
+			lastSig, lastWord = data[j-1], string(data[i:j])
+			i = j
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			lastSig, lastWord = c, ""
+		}
+		i++
+	}
+	return regions
+}
+
+func (lx *codeLexer) lineCommentAt(data []byte, i int) string {
+	for _, prefix := range lx.lineComments {
+		if !bytes.HasPrefix(data[i:], []byte(prefix)) {
+			continue
+		}
+		if lx.wordComments && i > 0 && !isShellWordBreak(data[i-1]) {
+			continue
+		}
+		return prefix
+	}
+	return ""
+}
+
+func (lx *codeLexer) blockAt(data []byte, i int) (lexBlock, bool) {
+	for _, blk := range lx.blockComments {
+		if bytes.HasPrefix(data[i:], []byte(blk.open)) {
+			return blk, true
+		}
+	}
+	return lexBlock{}, false
+}
+
+func (lx *codeLexer) quoteAt(data []byte, i int) (lexQuote, bool) {
+	for _, q := range lx.quotes {
+		if bytes.HasPrefix(data[i:], []byte(q.open)) {
+			return q, true
+		}
+	}
+	return lexQuote{}, false
+}
+
+type pendingHeredoc struct {
+	ident  string
+	indent bool // 終端識別子の前の空白を許す（<<- / <<~ / <<<）
+}
+
+// heredocAt は i にあるヒアドキュメントの開始（<<EOF、<<~'EOS'、<<<"X" など）を読み取ります。
+// 素の "<<" や演算子の後に空白を挟む場合は、シフト演算子や Ruby の arr<<item と区別するため
+// 引用符付きか小文字を含まない識別子に限ります。
+func (lx *codeLexer) heredocAt(data []byte, i int) (pendingHeredoc, int, bool) {
+	if i > 0 && data[i-1] == '<' {
+		// <<< のヒアストリングの 2 文字目以降などは開始とみなしません。
+		return pendingHeredoc{}, 0, false
+	}
+	for _, op := range lx.heredocs {
+		if !bytes.HasPrefix(data[i:], []byte(op)) {
+			continue
+		}
+		j := i + len(op)
+		spaced := false
+		for j < len(data) && (data[j] == ' ' || data[j] == '\t') {
+			j++
+			spaced = true
+		}
+		if j >= len(data) {
+			return pendingHeredoc{}, 0, false
+		}
+		var ident string
+		next := j
+		if q := data[j]; q == '\'' || q == '"' || q == '`' {
+			end := bytes.IndexByte(data[j+1:], q)
+			if end <= 0 || bytes.IndexByte(data[j+1:j+1+end], '\n') >= 0 {
+				return pendingHeredoc{}, 0, false
+			}
+			ident = string(data[j+1 : j+1+end])
+			next = j + end + 2
+		} else {
+			k := j
+			for k < len(data) && isIdentByte(data[k]) {
+				k++
+			}
+			ident = string(data[j:k])
+			next = k
+			if ident == "" || ('0' <= ident[0] && ident[0] <= '9') || ((spaced || op == "<<") && strings.ToUpper(ident) != ident) {
+				return pendingHeredoc{}, 0, false
+			}
+		}
+		indent := strings.HasSuffix(op, "-") || strings.HasSuffix(op, "~") || strings.HasSuffix(op, "<<<")
+		return pendingHeredoc{ident: ident, indent: indent}, next, true
+	}
+	return pendingHeredoc{}, 0, false
+}
+
+// consumeHeredocs は start 行から順に pending の本文を読み取り、本文の領域と
+// 最後の終端行の行末（改行の位置）を返します。
+func consumeHeredocs(data []byte, start int, pending []pendingHeredoc) ([]lexRegion, int) {
+	var regions []lexRegion
+	pos := start
+	for _, h := range pending {
+		bodyStart := pos
+		for {
+			lineEnd := bytes.IndexByte(data[pos:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(data)
+			} else {
+				lineEnd += pos
+			}
+			if isHeredocTerminator(data[pos:lineEnd], h) {
+				regions = append(regions, lexRegion{kind: model.MatchKindHeredoc, start: bodyStart, end: pos})
+				pos = lineEnd
+				break
+			}
+			if lineEnd >= len(data) {
+				regions = append(regions, lexRegion{kind: model.MatchKindHeredoc, start: bodyStart, end: len(data)})
+				return regions, len(data)
+			}
+			pos = lineEnd + 1
+		}
+		if pos < len(data) {
+			pos++
+		}
+	}
+	// 最後の終端行の改行は通常の走査に戻して扱います。
+	if pos > start && pos <= len(data) && data[pos-1] == '\n' {
+		pos--
+	}
+	return regions, pos
+}
+
+func isHeredocTerminator(line []byte, h pendingHeredoc) bool {
+	line = bytes.TrimRight(line, "\r")
+	if h.indent {
+		line = bytes.TrimLeft(line, " \t")
+	}
+	if !bytes.HasPrefix(line, []byte(h.ident)) {
+		return false
+	}
+	rest := strings.TrimSpace(string(line[len(h.ident):]))
+	return rest == "" || rest[0] == ';' || rest[0] == ',' || rest[0] == ')' || rest[0] == '.'
+}
+
+// scanBlock は start からブロックコメントの終わりを探し、中身の終端と次の読み取り位置を返します。
+// 閉じていない場合はファイル末尾までをコメントとします。
+func scanBlock(data []byte, start int, blk lexBlock) (end, next int) {
+	depth := 1
+	j := start
+	for j < len(data) {
+		if blk.nested && bytes.HasPrefix(data[j:], []byte(blk.open)) {
+			depth++
+			j += len(blk.open)
+			continue
+		}
+		if bytes.HasPrefix(data[j:], []byte(blk.close)) {
+			depth--
+			if depth == 0 {
+				return j, j + len(blk.close)
+			}
+			j += len(blk.close)
+			continue
+		}
+		j++
+	}
+	return len(data), len(data)
+}
+
+// scanQuoted は start から文字列の閉じ記号を探し、中身の終端と次の読み取り位置を返します。
+func scanQuoted(data []byte, start int, q lexQuote) (end, next int) {
+	j := start
+	for j < len(data) {
+		if q.escape && data[j] == '\\' {
+			j += 2
+			continue
+		}
+		if bytes.HasPrefix(data[j:], []byte(q.close)) {
+			return j, j + len(q.close)
+		}
+		if !q.multiline && data[j] == '\n' {
+			return j, j
+		}
+		j++
+	}
+	if j > len(data) {
+		j = len(data)
+	}
+	return j, j
+}
+
+// charLiteralEnd は i にある 'x' / '\x' 形式の文字リテラルの次の位置を返します。
+func charLiteralEnd(data []byte, i int) (int, bool) {
+	n := len(data)
+	if i+1 >= n {
+		return 0, false
+	}
+	if data[i+1] == '\\' {
+		for j := i + 2; j < n && j <= i+12; j++ {
+			if data[j] == '\n' {
+				return 0, false
+			}
+			if data[j] == '\'' && j > i+2 {
+				return j + 1, true
+			}
+		}
+		return 0, false
+	}
+	r, size := utf8.DecodeRune(data[i+1:])
+	if r == '\'' || r == '\n' {
+		return 0, false
+	}
+	if i+1+size < n && data[i+1+size] == '\'' {
+		return i + 2 + size, true
+	}
+	return 0, false
+}
+
+var regexPrecedingKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "instanceof": true, "yield": true, "await": true,
+}
+
+// regexAllowed は直前のトークンから "/" が正規表現リテラルの開始になり得るかを判定します。
+func regexAllowed(lastSig byte, lastWord string) bool {
+	if lastWord != "" {
+		return regexPrecedingKeywords[lastWord]
+	}
+	switch lastSig {
+	case ')', ']', '"', '\'', '`', '/':
+		return false
+	default:
+		return true
+	}
+}
+
+// regexLiteralEnd は i から始まる /.../flags の次の位置を返します。行内で閉じなければ除算とみなします。
+func regexLiteralEnd(data []byte, i int) (int, bool) {
+	inClass := false
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\n':
+			return 0, false
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			k := j + 1
+			for k < len(data) && isIdentByte(data[k]) {
+				k++
+			}
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// lexRustRawString は r"..." / r#"..."# / br#"..."# を読み取ります。
+func lexRustRawString(data []byte, i int) (lexRegion, int, bool) {
+	j := i
+	if j < len(data) && data[j] == 'b' {
+		j++
+	}
+	if j >= len(data) || data[j] != 'r' {
+		return lexRegion{}, 0, false
+	}
+	j++
+	hashes := 0
+	for j < len(data) && data[j] == '#' {
+		hashes++
+		j++
+	}
+	if j >= len(data) || data[j] != '"' {
+		return lexRegion{}, 0, false
+	}
+	start := j + 1
+	closer := append([]byte{'"'}, bytes.Repeat([]byte{'#'}, hashes)...)
+	idx := bytes.Index(data[start:], closer)
+	if idx < 0 {
+		return lexRegion{kind: model.MatchKindString, start: start, end: len(data)}, len(data), true
+	}
+	end := start + idx
+	return lexRegion{kind: model.MatchKindString, start: start, end: end}, end + len(closer), true
+}
+
+// lexCppRawString は R"delim(...)delim"（u8R/uR/UR/LR 接頭辞を含む）を読み取ります。
+func lexCppRawString(data []byte, i int) (lexRegion, int, bool) {
+	j := i
+	for _, prefix := range []string{"u8R", "uR", "UR", "LR", "R"} {
+		if bytes.HasPrefix(data[j:], []byte(prefix+"\"")) {
+			j += len(prefix) + 1
+			break
+		}
+	}
+	if j == i {
+		return lexRegion{}, 0, false
+	}
+	open := bytes.IndexByte(data[j:], '(')
+	if open < 0 || open > 16 || bytes.ContainsAny(data[j:j+open], " \\)\n\t") {
+		return lexRegion{}, 0, false
+	}
+	delim := data[j : j+open]
+	start := j + open + 1
+	closer := append(append([]byte{')'}, delim...), '"')
+	idx := bytes.Index(data[start:], closer)
+	if idx < 0 {
+		return lexRegion{kind: model.MatchKindString, start: start, end: len(data)}, len(data), true
+	}
+	end := start + idx
+	return lexRegion{kind: model.MatchKindString, start: start, end: end}, end + len(closer), true
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isShellWordBreak(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', ';', '|', '&', '(', ')', '<', '>':
+		return true
+	default:
+		return false
+	}
+}
+
+// scanWithLexer は lexer が切り出したコメント・文字列からタグを探します。
+func scanWithLexer(path string, data []byte, tags []tagSpec, lang string, lexer *codeLexer, includeStrings bool) []model.Match {
+	lineOffsets := computeLineOffsets(data)
+	var matches []model.Match
+	for _, r := range lexer.lex(data) {
+		if r.kind != model.MatchKindComment && !includeStrings {
+			continue
+		}
+		if r.end <= r.start {
+			continue
+		}
+		matches = append(matches, findMatchesInText(path, string(data[r.start:r.end]), tags, lang, r.kind, r.start, lineOffsets)...)
+	}
+	return matches
+}
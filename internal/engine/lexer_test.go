@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phyten/todox/internal/detect"
+)
+
+var updateLexerGolden = flag.Bool("update", false, "update lexer golden files")
+
+// TestLexersGolden は testdata/lexers 以下の各サンプルを言語別レキサーで走査し、
+// 検出結果を同名の .golden ファイルと比較します。
+func TestLexersGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "lexers", "*"))
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	seen := 0
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden") {
+			continue
+		}
+		seen++
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			lang := detect.NormalizeLangName(detect.FromPathAndContent(input, data).Name)
+			style, ok := styleForLanguage(lang)
+			if !ok || style.lexer == nil {
+				t.Fatalf("expected a lexer for %s (lang=%q)", input, lang)
+			}
+			var b strings.Builder
+			for _, m := range scanWithStyle(input, data, tags, lang, style, true) {
+				fmt.Fprintf(&b, "%d:%d %s %s\n", m.Span.StartLine, m.Span.StartCol, m.Kind, m.Tag)
+			}
+			golden := input + ".golden"
+			if *updateLexerGolden {
+				if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+					t.Fatalf("write golden failed: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file %s: %v", golden, err)
+			}
+			if string(want) != b.String() {
+				t.Fatalf("output mismatch for %s\nwant:\n%sgot:\n%s", input, want, b.String())
+			}
+		})
+	}
+	if seen == 0 {
+		t.Fatal("no lexer samples found")
+	}
+}
+
+func TestLexerSkipsStringsWhenDisabled(t *testing.T) {
+	tags := normalizeTags([]string{"TODO"})
+	data := []byte("const url = \"http://example.com/TODO\" // TODO: real\n")
+	matches := scanWithStyle("a.go", data, tags, "go", styleGo, false)
+	if len(matches) != 1 || matches[0].Span.StartCol != 42 {
+		t.Fatalf("expected only the comment TODO, got %+v", matches)
+	}
+}
+
+func TestLexerUnterminatedBlockRunsToEOF(t *testing.T) {
+	tags := normalizeTags([]string{"TODO"})
+	data := []byte("x := 1 /* open\nTODO never closed\n")
+	matches := scanWithStyle("a.go", data, tags, "go", styleGo, false)
+	if len(matches) != 1 || matches[0].Span.StartLine != 2 {
+		t.Fatalf("expected TODO inside unterminated block, got %+v", matches)
+	}
+}
@@ -0,0 +1,367 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/phyten/todox/internal/detect"
+	"github.com/phyten/todox/internal/model"
+)
+
+// notebookCell は .ipynb のセル 1 つ分のソースと、各行が書かれている元ファイルの行番号です。
+type notebookCell struct {
+	index    int
+	cellType string
+	lines    []string // 末尾の改行を含まないセル内の各行
+	rawLines []int    // lines[i] が書かれている .ipynb 上の行（1 始まり）
+	// rawBytes は source を連結した内容の各バイトが書かれている .ipynb 上のバイト位置です（エスケープは先頭の "\\"）。
+	// rawEnd は最後の要素の閉じ引用符の位置です。位置を対応づけられなかった場合、rawBytes は nil です。
+	rawBytes []int
+	rawEnd   int
+}
+
+func isNotebookPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// scanNotebook は Jupyter ノートブックのセルごとに TODO を探します。コードセルはカーネルの言語の
+// コメント構文で、Markdown / raw セルはテキストとして走査します。Span は blame や quickfix で使えるよう
+// .ipynb 上の行・桁・バイト位置（JSON のエスケープを含む生の位置）に読み替え、セル番号とセル内の行は
+// Match.Cell に記録します。生の位置に対応づけられないセルでは桁とバイト位置を 0 にします。
+// JSON として読めない場合は ok=false を返します。
+func scanNotebook(path string, data []byte, opts Options, tags []tagSpec) ([]model.Match, bool) {
+	cells, lang, err := parseNotebook(data)
+	if err != nil {
+		return nil, false
+	}
+	codeAllowed := len(opts.DetectLangs) == 0 || detect.MatchesLang(detect.Info{Name: lang}, opts.DetectLangs)
+	textAllowed := len(opts.DetectLangs) == 0 || detect.MatchesLang(detect.Info{Name: "markdown"}, opts.DetectLangs)
+	style, hasStyle := opts.LanguagesCompiled.style(lang)
+	lineOffsets := computeLineOffsets(data)
+	var out []model.Match
+	for _, cell := range cells {
+		if len(cell.lines) == 0 {
+			continue
+		}
+		src := []byte(strings.Join(cell.lines, "\n"))
+		var matches []model.Match
+		switch {
+		case cell.cellType == "code" && codeAllowed && hasStyle:
+			matches = scanWithStyle(path, src, tags, lang, style, opts.IncludeStrings)
+			attachBodies(src, matches, tags, style)
+		case cell.cellType == "code" && codeAllowed:
+			matches = scanPlainText(path, src, tags)
+		case cell.cellType != "code" && textAllowed:
+			matches = scanPlainText(path, src, tags)
+			for i := range matches {
+				matches[i].Lang = "markdown"
+			}
+		}
+		matches = applyInlineSuppressions(src, matches)
+		for _, m := range matches {
+			m.Cell = &model.CellRef{Index: cell.index, Line: m.Span.StartLine}
+			m.Span.StartLine = cell.rawLine(m.Span.StartLine)
+			m.Span.EndLine = cell.rawLine(m.Span.EndLine)
+			cell.remapBytes(&m.Span, len(src), lineOffsets)
+			out = append(out, m)
+		}
+	}
+	if hasFileSuppressMarker(data) {
+		markSuppressed(out, suppressedFile)
+	}
+	return out, true
+}
+
+func (c notebookCell) rawLine(line int) int {
+	if line < 1 {
+		line = 1
+	}
+	if line > len(c.rawLines) {
+		line = len(c.rawLines)
+	}
+	return c.rawLines[line-1]
+}
+
+// remapBytes はセルのソースに対するバイト位置と桁を .ipynb 上の位置に読み替えます。行は読み替え済みとします。
+func (c notebookCell) remapBytes(sp *model.Span, srcLen int, lineOffsets []int) {
+	if c.rawBytes == nil || len(c.rawBytes) < srcLen {
+		sp.StartCol, sp.EndCol, sp.ByteStart, sp.ByteEnd = 0, 0, 0, 0
+		return
+	}
+	rawAt := func(off int) int {
+		off = max(0, min(off, srcLen))
+		if off < len(c.rawBytes) {
+			return c.rawBytes[off]
+		}
+		return c.rawEnd
+	}
+	lineStart := func(line int) int {
+		if line < 1 || line > len(lineOffsets) {
+			return 0
+		}
+		return lineOffsets[line-1]
+	}
+	sp.ByteStart = rawAt(sp.ByteStart)
+	sp.ByteEnd = rawAt(sp.ByteEnd)
+	sp.StartCol = sp.ByteStart - lineStart(sp.StartLine) + 1
+	sp.EndCol = sp.ByteEnd - lineStart(sp.EndLine) + 1
+}
+
+// parseNotebook は nbformat 4 の JSON を読み、セルとカーネルの言語を返します。
+// source の各要素の位置を json.Decoder のオフセットから求めるため、値を順に読み進めます。
+func parseNotebook(data []byte) ([]notebookCell, string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lineOffsets := computeLineOffsets(data)
+	lineAt := func(offset int64) int {
+		line, _ := lineColFromOffset(int(offset), lineOffsets)
+		return line
+	}
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, "", err
+	}
+	var cells []notebookCell
+	var meta struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	}
+	sawCells := false
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return nil, "", err
+		}
+		switch key {
+		case "cells":
+			sawCells = true
+			if err := expectDelim(dec, '['); err != nil {
+				return nil, "", err
+			}
+			for dec.More() {
+				cell, err := readNotebookCell(dec, data, lineAt)
+				if err != nil {
+					return nil, "", err
+				}
+				cell.index = len(cells)
+				cells = append(cells, cell)
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return nil, "", err
+			}
+		case "metadata":
+			if err := dec.Decode(&meta); err != nil {
+				return nil, "", err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if !sawCells {
+		return nil, "", fmt.Errorf("not a notebook: missing cells")
+	}
+	lang := detect.NormalizeLangName(meta.LanguageInfo.Name)
+	if lang == "" {
+		lang = detect.NormalizeLangName(meta.Kernelspec.Language)
+	}
+	if lang == "" {
+		lang = "python"
+	}
+	return cells, lang, nil
+}
+
+func readNotebookCell(dec *json.Decoder, data []byte, lineAt func(int64) int) (notebookCell, error) {
+	var cell notebookCell
+	if err := expectDelim(dec, '{'); err != nil {
+		return cell, err
+	}
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return cell, err
+		}
+		switch key {
+		case "cell_type":
+			if err := dec.Decode(&cell.cellType); err != nil {
+				return cell, err
+			}
+		case "source":
+			if err := readCellSource(dec, data, lineAt, &cell); err != nil {
+				return cell, err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return cell, err
+			}
+		}
+	}
+	return cell, expectDelim(dec, '}')
+}
+
+// readCellSource は source（文字列または文字列の配列）を行に分け、各行の .ipynb 上の行番号を記録します。
+// JSON の文字列は生の改行を含まないため、文字列トークンの終端の行がその要素の行になります。
+func readCellSource(dec *json.Decoder, data []byte, lineAt func(int64) int, cell *notebookCell) error {
+	cell.rawBytes = []int{}
+	before := dec.InputOffset()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	appendChunk := func(chunk string) {
+		offsets, ok := jsonStringOffsets(data, int(before))
+		if !ok || len(offsets) != len(chunk)+1 {
+			cell.rawBytes = nil
+		} else if cell.rawBytes != nil {
+			cell.rawBytes = append(cell.rawBytes, offsets[:len(chunk)]...)
+			cell.rawEnd = offsets[len(chunk)]
+		}
+		raw := lineAt(dec.InputOffset() - 1)
+		parts := strings.Split(chunk, "\n")
+		if last := len(cell.lines) - 1; last >= 0 {
+			// 前の要素の最終行に続けます。改行で終わっていた場合、最終行は空のプレースホルダです。
+			if cell.lines[last] == "" {
+				cell.rawLines[last] = raw
+			}
+			cell.lines[last] += parts[0]
+			parts = parts[1:]
+		}
+		for _, p := range parts {
+			cell.lines = append(cell.lines, p)
+			cell.rawLines = append(cell.rawLines, raw)
+		}
+	}
+	switch v := tok.(type) {
+	case string:
+		appendChunk(v)
+		cell.trimTrailing()
+		return nil
+	case json.Delim:
+		if v != '[' {
+			return fmt.Errorf("unexpected source token %v", v)
+		}
+	default:
+		return fmt.Errorf("unexpected source token %v", tok)
+	}
+	for dec.More() {
+		before = dec.InputOffset()
+		var chunk string
+		if err := dec.Decode(&chunk); err != nil {
+			return err
+		}
+		appendChunk(chunk)
+	}
+	cell.trimTrailing()
+	return expectDelim(dec, ']')
+}
+
+func (c *notebookCell) trimTrailing() {
+	for len(c.lines) > 0 && c.lines[len(c.lines)-1] == "" {
+		c.lines = c.lines[:len(c.lines)-1]
+		c.rawLines = c.rawLines[:len(c.rawLines)-1]
+	}
+}
+
+// jsonStringOffsets は data[from:] の区切り（空白・","・":"）の後にある JSON 文字列を読み、
+// 復号した各バイトが書かれている位置と、最後に閉じ引用符の位置を返します。
+// エスケープから復号したバイトはすべてそのエスケープの先頭の位置になります。
+func jsonStringOffsets(data []byte, from int) ([]int, bool) {
+	i := from
+	for i < len(data) && strings.IndexByte(" \t\r\n,:", data[i]) >= 0 {
+		i++
+	}
+	if i >= len(data) || data[i] != '"' {
+		return nil, false
+	}
+	i++
+	var offsets []int
+	for i < len(data) {
+		switch c := data[i]; {
+		case c == '"':
+			return append(offsets, i), true
+		case c != '\\':
+			offsets = append(offsets, i)
+			i++
+		case i+1 < len(data) && data[i+1] != 'u':
+			offsets = append(offsets, i)
+			i += 2
+		default:
+			r, n := decodeJSONEscape(data[i:])
+			if n == 0 {
+				return nil, false
+			}
+			for range utf8.RuneLen(r) {
+				offsets = append(offsets, i)
+			}
+			i += n
+		}
+	}
+	return nil, false
+}
+
+// decodeJSONEscape は \uXXXX（サロゲートペアなら 2 つ分）を復号し、文字と読んだバイト数を返します。
+// 対になっていないサロゲートは encoding/json と同じく U+FFFD になります。
+func decodeJSONEscape(b []byte) (rune, int) {
+	r := hexRune(b)
+	if r < 0 {
+		return 0, 0
+	}
+	if utf16.IsSurrogate(r) {
+		if r2 := hexRune(b[min(6, len(b)):]); r2 >= 0 {
+			if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+				return pair, 12
+			}
+		}
+		return utf8.RuneError, 6
+	}
+	return r, 6
+}
+
+func hexRune(b []byte) rune {
+	if len(b) < 6 || b[0] != '\\' || b[1] != 'u' {
+		return -1
+	}
+	v, err := strconv.ParseUint(string(b[2:6]), 16, 16)
+	if err != nil {
+		return -1
+	}
+	return rune(v)
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
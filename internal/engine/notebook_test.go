@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+const sampleNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Analysis\n",
+    "TODO: describe the dataset"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [{"name": "stdout", "text": ["TODO in output\n"]}],
+   "source": [
+    "import pandas as pd\n",
+    "label = \"TODO not a comment\"\n",
+    "df = pd.read_csv(path)  # FIXME: hard-coded path\n",
+    "# TODO: drop outliers"
+   ]
+  },
+  {
+   "source": "x = 1\n# TODO single string source",
+   "cell_type": "code"
+  }
+ ],
+ "metadata": {
+  "kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"},
+  "language_info": {"name": "python"}
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+type notebookHit struct {
+	Line int
+	Kind model.MatchKind
+	Lang string
+	Tag  string
+	Cell model.CellRef
+}
+
+func notebookHits(matches []model.Match) []notebookHit {
+	out := make([]notebookHit, 0, len(matches))
+	for _, m := range matches {
+		hit := notebookHit{Line: m.Span.StartLine, Kind: m.Kind, Lang: m.Lang, Tag: m.Tag}
+		if m.Cell != nil {
+			hit.Cell = *m.Cell
+		}
+		out = append(out, hit)
+	}
+	return out
+}
+
+func TestScanNotebookMapsCellsToFileLines(t *testing.T) {
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	got := notebookHits(parseContent("analysis.ipynb", []byte(sampleNotebook), Options{IncludeStrings: true}, tags, true))
+	want := []notebookHit{
+		{Line: 8, Kind: model.MatchKindUnknown, Lang: "markdown", Tag: "TODO", Cell: model.CellRef{Index: 0, Line: 2}},
+		{Line: 18, Kind: model.MatchKindString, Lang: "python", Tag: "TODO", Cell: model.CellRef{Index: 1, Line: 2}},
+		{Line: 19, Kind: model.MatchKindComment, Lang: "python", Tag: "FIXME", Cell: model.CellRef{Index: 1, Line: 3}},
+		{Line: 20, Kind: model.MatchKindComment, Lang: "python", Tag: "TODO", Cell: model.CellRef{Index: 1, Line: 4}},
+		{Line: 24, Kind: model.MatchKindComment, Lang: "python", Tag: "TODO", Cell: model.CellRef{Index: 2, Line: 2}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("hit %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+
+	comments := parseContent("analysis.ipynb", []byte(sampleNotebook), Options{}, tags, true)
+	for _, m := range comments {
+		if m.Kind == model.MatchKindString {
+			t.Fatalf("strings should be skipped without IncludeStrings: %+v", m)
+		}
+	}
+}
+
+func TestNotebookContextUsesCellSource(t *testing.T) {
+	items, err := ScanContent("analysis.ipynb", []byte(sampleNotebook), Options{Type: "both", ContextLines: 1})
+	if err != nil {
+		t.Fatalf("ScanContent failed: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %+v", items)
+	}
+	// Context comes from the cell's decoded source and stops at the cell boundary, not at the JSON around it.
+	if md := items[0]; !reflect.DeepEqual(md.ContextBefore, []string{"# Analysis"}) || md.ContextAfter != nil {
+		t.Fatalf("unexpected markdown context: before=%q after=%q", md.ContextBefore, md.ContextAfter)
+	}
+	fixme := items[1]
+	if !reflect.DeepEqual(fixme.ContextBefore, []string{`label = "TODO not a comment"`}) || !reflect.DeepEqual(fixme.ContextAfter, []string{"# TODO: drop outliers"}) {
+		t.Fatalf("unexpected code context: before=%q after=%q", fixme.ContextBefore, fixme.ContextAfter)
+	}
+}
+
+func TestScanNotebookMapsColumnsToRawJSON(t *testing.T) {
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	data := []byte(sampleNotebook)
+	lineOffsets := computeLineOffsets(data)
+	matches := parseContent("analysis.ipynb", data, Options{IncludeStrings: true}, tags, true)
+	// The string TODO sits after an escaped quote, so its raw column is one past the decoded one.
+	wantCols := []int{6, 16, 32, 8, 24}
+	if len(matches) != len(wantCols) {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	for i, m := range matches {
+		sp := m.Span
+		if sp.StartCol != wantCols[i] || sp.EndCol != sp.StartCol+len(m.Tag) {
+			t.Errorf("match %d: cols %d-%d, want %d-%d", i, sp.StartCol, sp.EndCol, wantCols[i], wantCols[i]+len(m.Tag))
+		}
+		if got := string(data[sp.ByteStart:sp.ByteEnd]); got != m.Tag {
+			t.Errorf("match %d: raw bytes %q, want %q", i, got, m.Tag)
+		}
+		if sp.ByteStart-lineOffsets[sp.StartLine-1]+1 != sp.StartCol {
+			t.Errorf("match %d: ByteStart %d disagrees with line %d col %d", i, sp.ByteStart, sp.StartLine, sp.StartCol)
+		}
+	}
+
+	escaped := []byte(`{"cells": [{"cell_type": "code", "source": ["s = \"\u00e9\ud83d\ude00\"  # TODO: x"]}]}`)
+	matches = parseContent("e.ipynb", escaped, Options{}, tags, false)
+	if len(matches) != 1 || string(escaped[matches[0].Span.ByteStart:matches[0].Span.ByteEnd]) != "TODO" {
+		t.Fatalf("\\u escapes should map back to raw offsets: %+v", matches)
+	}
+}
+
+func TestScanNotebookFiltersByDetectLangs(t *testing.T) {
+	tags := normalizeTags([]string{"TODO", "FIXME"})
+	matches := parseContent("analysis.ipynb", []byte(sampleNotebook), Options{DetectLangs: []string{"markdown"}}, tags, true)
+	if len(matches) != 1 || matches[0].Lang != "markdown" {
+		t.Fatalf("expected only the markdown cell, got %+v", matches)
+	}
+}
+
+func TestScanNotebookUsesKernelLanguage(t *testing.T) {
+	src := `{"cells": [{"cell_type": "code", "source": ["// TODO: typed\n", "# not a comment TODO"]}],
+ "metadata": {"kernelspec": {"language": "javascript"}}}`
+	tags := normalizeTags([]string{"TODO"})
+	matches := parseContent("deno.ipynb", []byte(src), Options{}, tags, false)
+	if len(matches) != 1 || matches[0].Lang != "javascript" || matches[0].Text != "TODO: typed" {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+}
+
+func TestScanNotebookFallsBackOnInvalidJSON(t *testing.T) {
+	tags := normalizeTags([]string{"TODO"})
+	matches := parseContent("broken.ipynb", []byte("not json # TODO here\n"), Options{}, tags, true)
+	if len(matches) != 1 || matches[0].Cell != nil {
+		t.Fatalf("invalid notebooks should be scanned as plain files: %+v", matches)
+	}
+}
+
+func TestRunBlamesNotebookCells(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repoDir, "analysis.ipynb"), []byte(sampleNotebook), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "fixme", Mode: "last", DetectMode: "parse", RepoDir: repoDir, Jobs: 1}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Items) != 1 {
+		t.Fatalf("expected 1 item, got %+v", res.Items)
+	}
+	it := res.Items[0]
+	if it.Line != 19 || it.Author != "alice" || it.Cell == nil || *it.Cell != (model.CellRef{Index: 1, Line: 3}) {
+		t.Fatalf("unexpected item: %+v", it)
+	}
+	raw, err := json.Marshal(it)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	cell, ok := decoded["cell"].(map[string]any)
+	if !ok || cell["index"] != float64(1) || cell["line"] != float64(3) {
+		t.Fatalf("unexpected cell JSON: %s", raw)
+	}
+}
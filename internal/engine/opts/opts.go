@@ -3,9 +3,11 @@ package opts
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/phyten/todox/internal/detect"
 	"github.com/phyten/todox/internal/engine"
@@ -13,6 +15,8 @@ import (
 
 const (
 	maxJobs = 64
+	// maxContext is the largest number of source lines attached before and after each item.
+	maxContext = 50
 )
 
 var (
@@ -68,6 +72,9 @@ func ApplyWebQueryToOptions(def engine.Options, q url.Values) (engine.Options, e
 	if raw, ok := lastRawValue(q["author"]); ok {
 		out.AuthorRegex = raw
 	}
+	if raw, ok := lastRawValue(q["skip_authors"]); ok {
+		out.SkipAuthors = raw
+	}
 	if raw, ok := lastLiteralValue(q["with_comment"]); ok {
 		v, err := ParseBool(raw, "with_comment")
 		if err != nil {
@@ -160,6 +167,48 @@ func ApplyWebQueryToOptions(def engine.Options, q url.Values) (engine.Options, e
 		}
 		out.NoPrefilter = v
 	}
+	if raw, ok := lastLiteralValue(q["recurse_submodules"]); ok {
+		v, err := ParseBool(raw, "recurse_submodules")
+		if err != nil {
+			return out, err
+		}
+		out.RecurseSubmodules = v
+	}
+	if raw, ok := lastLiteralValue(q["scan_archives"]); ok {
+		v, err := ParseBool(raw, "scan_archives")
+		if err != nil {
+			return out, err
+		}
+		out.ScanArchives = v
+	}
+	if raw, ok := lastLiteralValue(q["show_suppressed"]); ok {
+		v, err := ParseBool(raw, "show_suppressed")
+		if err != nil {
+			return out, err
+		}
+		out.ShowSuppressed = v
+	}
+	if raw, ok := lastLiteralValue(q["context"]); ok {
+		n, err := ParseIntInRange(raw, "context", 0, maxContext)
+		if err != nil {
+			return out, err
+		}
+		out.ContextLines = n
+	}
+	if raw, ok := lastLiteralValue(q["timeout"]); ok {
+		d, err := ParseDuration(raw, "timeout")
+		if err != nil {
+			return out, err
+		}
+		out.Timeout = d
+	}
+	if raw, ok := lastLiteralValue(q["git_timeout"]); ok {
+		d, err := ParseDuration(raw, "git_timeout")
+		if err != nil {
+			return out, err
+		}
+		out.GitTimeout = d
+	}
 	if raw, ok := lastLiteralValue(q["progress"]); ok {
 		v, err := ParseBool(raw, "progress")
 		if err != nil {
@@ -182,6 +231,29 @@ func ApplyWebQueryToOptions(def engine.Options, q url.Values) (engine.Options, e
 	if raw := q["tags"]; len(raw) > 0 {
 		out.Tags = SplitMulti(raw)
 	}
+	if raw, ok := lastLiteralValue(q["tag_word_boundary"]); ok {
+		v, err := ParseBool(raw, "tag_word_boundary")
+		if err != nil {
+			return out, err
+		}
+		out.TagRules.WordBoundary = v
+	}
+	if raw, ok := lastRawValue(q["tag_suffix"]); ok {
+		out.TagRules.Suffix = raw
+	}
+	if raw := q["tag_case_sensitive"]; len(raw) > 0 {
+		out.TagRules.CaseSensitive = SplitMulti(raw)
+	}
+	if raw := q["tag_pattern"]; len(raw) > 0 {
+		patterns, err := engine.ParseTagPatterns(raw)
+		if err != nil {
+			return out, err
+		}
+		out.TagRules.Patterns = patterns
+	}
+	if raw := q["owners"]; len(raw) > 0 {
+		out.Owners = SplitMulti(raw)
+	}
 	if raw, ok := lastLiteralValue(q["exclude_typical"]); ok {
 		v, err := ParseBool(raw, "exclude_typical")
 		if err != nil {
@@ -189,6 +261,13 @@ func ApplyWebQueryToOptions(def engine.Options, q url.Values) (engine.Options, e
 		}
 		out.ExcludeTypical = v
 	}
+	if raw, ok := lastLiteralValue(q["include_generated"]); ok {
+		v, err := ParseBool(raw, "include_generated")
+		if err != nil {
+			return out, err
+		}
+		out.IncludeGenerated = v
+	}
 	if raw, ok := lastRawValue(q["repo"]); ok {
 		out.RepoDir = raw
 	}
@@ -250,6 +329,15 @@ func NormalizeAndValidate(o *engine.Options) error {
 	if o.MaxFileBytes < 0 {
 		return fmt.Errorf("max_file_bytes must be >= 0")
 	}
+	if o.ContextLines < 0 || o.ContextLines > maxContext {
+		return fmt.Errorf("context must be between 0 and %d", maxContext)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout must be >= 0")
+	}
+	if o.GitTimeout < 0 {
+		return fmt.Errorf("git_timeout must be >= 0")
+	}
 
 	o.Paths = trimSlice(o.Paths)
 	o.Excludes = trimSlice(o.Excludes)
@@ -259,6 +347,22 @@ func NormalizeAndValidate(o *engine.Options) error {
 		o.DetectLangs = detect.CanonicalDetectLangs(o.DetectLangs)
 	}
 	o.Tags = trimSlice(o.Tags)
+	o.TagRules.Suffix = strings.TrimSpace(o.TagRules.Suffix)
+	o.TagRules.CaseSensitive = trimSlice(o.TagRules.CaseSensitive)
+	if err := o.TagRules.Validate(); err != nil {
+		return err
+	}
+	o.Owners = trimSlice(o.Owners)
+
+	o.SkipAuthors = strings.TrimSpace(o.SkipAuthors)
+	if o.SkipAuthors != "" {
+		if o.Mode == "first" {
+			return fmt.Errorf("--skip-authors cannot be combined with --mode first")
+		}
+		if _, err := regexp.Compile(o.SkipAuthors); err != nil {
+			return fmt.Errorf("invalid --skip-authors regex: %w", err)
+		}
+	}
 
 	compiled, err := engine.CompilePathRegex(o.PathRegex)
 	if err != nil {
@@ -304,7 +408,7 @@ func ParseIntInRange(raw, key string, min, max int) (int, error) {
 func NormalizeOutput(value string) (string, error) {
 	v := strings.ToLower(strings.TrimSpace(value))
 	switch v {
-	case "table", "tsv", "json", "csv", "ndjson", "md":
+	case "table", "tsv", "json", "csv", "ndjson", "md", "quickfix", "fzf":
 		return v, nil
 	case "markdown-table":
 		return "md", nil
@@ -339,6 +443,29 @@ func parseInt(raw, key string) (int, error) {
 	return n, nil
 }
 
+// ParseDuration parses a non-negative duration such as "90s", "5m" or "1h30m".
+// A bare integer is read as seconds; 0 disables the limit.
+func ParseDuration(raw, key string) (time.Duration, error) {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return 0, fmt.Errorf("invalid duration value for %s: %q", key, raw)
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("%s must be >= 0", key)
+		}
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration value for %s: %q", key, raw)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must be >= 0", key)
+	}
+	return d, nil
+}
+
 func lastLiteralValue(vals []string) (string, bool) {
 	flat := SplitMulti(vals)
 	if len(flat) == 0 {
@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/phyten/todox/internal/engine"
 )
@@ -78,6 +79,32 @@ func TestParseIntInRange(t *testing.T) {
 	})
 }
 
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	valid := map[string]time.Duration{
+		"90s":   90 * time.Second,
+		"5m":    5 * time.Minute,
+		"1h30m": 90 * time.Minute,
+		"15":    15 * time.Second,
+		" 0 ":   0,
+	}
+	for raw, want := range valid {
+		got, err := ParseDuration(raw, "timeout")
+		if err != nil {
+			t.Fatalf("ParseDuration(%q) returned error: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("ParseDuration(%q) = %v, want %v", raw, got, want)
+		}
+	}
+	for _, raw := range []string{"", "-5s", "-1", "soon", "5 minutes"} {
+		if _, err := ParseDuration(raw, "timeout"); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
 func TestNormalizeAndValidate(t *testing.T) {
 	t.Parallel()
 
@@ -170,11 +197,26 @@ func TestNormalizeAndValidate(t *testing.T) {
 		t.Fatal("expected error for invalid detect mode")
 	}
 
+	skipFirst := engine.Options{Type: "todo", Mode: "first", Jobs: 1, SkipAuthors: `\[bot\]`}
+	if err := NormalizeAndValidate(&skipFirst); err == nil {
+		t.Fatal("expected error for --skip-authors with --mode first")
+	}
+
+	skipInvalid := engine.Options{Type: "todo", Mode: "last", Jobs: 1, SkipAuthors: "("}
+	if err := NormalizeAndValidate(&skipInvalid); err == nil {
+		t.Fatal("expected error for invalid skip_authors regex")
+	}
+
 	negativeSize := engine.Options{Type: "todo", Mode: "last", Jobs: 1, MaxFileBytes: -1}
 	if err := NormalizeAndValidate(&negativeSize); err == nil {
 		t.Fatal("expected error for negative max_file_bytes")
 	}
 
+	wideContext := engine.Options{Type: "todo", Mode: "last", Jobs: 1, ContextLines: 51}
+	if err := NormalizeAndValidate(&wideContext); err == nil {
+		t.Fatal("expected error for context above the limit")
+	}
+
 	alias := engine.Options{Type: "todo", Mode: "last", Jobs: 1, DetectLangs: []string{"js", "JS", "py"}}
 	if err := NormalizeAndValidate(&alias); err != nil {
 		t.Fatalf("unexpected error for alias detect_langs: %v", err)
@@ -233,6 +275,8 @@ func TestApplyWebQueryToOptions(t *testing.T) {
 	q.Add("max_file_bytes", "4096")
 	q.Add("no_prefilter", "0")
 	q.Add("no_prefilter", "1")
+	q.Add("context", "1")
+	q.Add("context", "3")
 
 	got, err := ApplyWebQueryToOptions(base, q)
 	if err != nil {
@@ -283,6 +327,9 @@ func TestApplyWebQueryToOptions(t *testing.T) {
 	if !got.NoPrefilter {
 		t.Fatal("expected no_prefilter to be true")
 	}
+	if got.ContextLines != 3 {
+		t.Fatalf("expected last context literal, got %d", got.ContextLines)
+	}
 	if got.AuthorRegex != "Bob" {
 		t.Fatalf("expected author to use last raw value, got %q", got.AuthorRegex)
 	}
@@ -310,6 +357,45 @@ func TestApplyWebQueryToOptions(t *testing.T) {
 	}
 }
 
+func TestApplyWebQueryTagRules(t *testing.T) {
+	t.Parallel()
+
+	q := url.Values{}
+	q.Add("tag_word_boundary", "1")
+	q.Add("tag_suffix", ":(")
+	q.Add("tag_case_sensitive", "TODO,FIXME")
+	q.Add("tag_pattern", `NOTE=NOTE\(\w{1,20}\):`)
+	got, err := ApplyWebQueryToOptions(Defaults("/repo"), q)
+	if err != nil {
+		t.Fatalf("ApplyWebQueryToOptions failed: %v", err)
+	}
+	want := engine.TagRules{
+		WordBoundary:  true,
+		Suffix:        ":(",
+		CaseSensitive: []string{"TODO", "FIXME"},
+		Patterns:      map[string]string{"NOTE": `NOTE\(\w{1,20}\):`},
+	}
+	if !reflect.DeepEqual(got.TagRules, want) {
+		t.Fatalf("tag rules mismatch: got=%+v want=%+v", got.TagRules, want)
+	}
+	if err := NormalizeAndValidate(&got); err != nil {
+		t.Fatalf("NormalizeAndValidate failed: %v", err)
+	}
+
+	q.Set("tag_pattern", "TODO=(")
+	got, err = ApplyWebQueryToOptions(Defaults("/repo"), q)
+	if err != nil {
+		t.Fatalf("ApplyWebQueryToOptions failed: %v", err)
+	}
+	if err := NormalizeAndValidate(&got); err == nil {
+		t.Fatal("expected error for invalid tag_pattern regex")
+	}
+	q.Set("tag_pattern", "TODO")
+	if _, err := ApplyWebQueryToOptions(Defaults("/repo"), q); err == nil {
+		t.Fatal("expected error for tag_pattern without '='")
+	}
+}
+
 func TestApplyWebQueryIncludeStringsPriority(t *testing.T) {
 	t.Parallel()
 
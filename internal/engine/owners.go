@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phyten/todox/internal/codeowners"
+	"github.com/phyten/todox/internal/model"
+)
+
+// resolveOwners は CODEOWNERS を読み込み、各マッチの担当者を解決します。
+// --owners が指定されていれば担当者で絞り込み、blame の前に対象を減らします。
+// CODEOWNERS が無い場合は owners を nil で返します（--owners 指定時はエラー）。
+func resolveOwners(opts Options, matches []model.Match) ([]model.Match, [][]string, bool, error) {
+	file, err := codeowners.Load(opts.RepoDir)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	want := normalizedOwnerFilter(opts.Owners)
+	if file == nil {
+		if len(want) > 0 {
+			return nil, nil, false, fmt.Errorf("--owners: no CODEOWNERS file found (looked in %s)", strings.Join(codeowners.SearchPaths, ", "))
+		}
+		return matches, nil, false, nil
+	}
+
+	cache := make(map[string][]string)
+	filtered := matches[:0]
+	owners := make([][]string, 0, len(matches))
+	for _, m := range matches {
+		o, ok := cache[m.File]
+		if !ok {
+			o = file.Owners(archiveOuterPath(m.File))
+			cache[m.File] = o
+		}
+		if len(want) > 0 && !ownersMatch(o, want) {
+			continue
+		}
+		filtered = append(filtered, m)
+		owners = append(owners, o)
+	}
+	return filtered, owners, true, nil
+}
+
+func normalizedOwnerFilter(raw []string) map[string]struct{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	out := make(map[string]struct{}, len(raw))
+	for _, r := range raw {
+		if n := codeowners.NormalizeOwner(r); n != "" {
+			out[n] = struct{}{}
+		}
+	}
+	return out
+}
+
+func ownersMatch(owners []string, want map[string]struct{}) bool {
+	for _, o := range owners {
+		if _, ok := want[codeowners.NormalizeOwner(o)]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupOwnersRepo(t *testing.T, codeowners string) string {
+	t.Helper()
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "tester")
+	runGit(t, repoDir, "config", "user.email", "tester@example.com")
+	files := map[string]string{
+		"api/server.go": "package api\n// TODO api work\n",
+		"web/app.js":    "// FIXME web work\n",
+		"README.md":     "TODO docs\n",
+	}
+	if codeowners != "" {
+		files[".github/CODEOWNERS"] = codeowners
+	}
+	for rel, content := range files {
+		full := filepath.Join(repoDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("ディレクトリの作成に失敗しました: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("ファイルの作成に失敗しました: %v", err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	return repoDir
+}
+
+func TestRunはCODEOWNERSの担当者を付与し絞り込む(t *testing.T) {
+	repoDir := setupOwnersRepo(t, "/api/ @org/backend\n*.js @org/frontend @alice\n")
+
+	res, err := Run(Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1})
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if !res.HasOwners {
+		t.Fatalf("HasOwners が true になっていません")
+	}
+	got := map[string][]string{}
+	for _, it := range res.Items {
+		got[it.File] = it.Owners
+	}
+	want := map[string][]string{
+		"README.md":     nil,
+		"api/server.go": {"@org/backend"},
+		"web/app.js":    {"@org/frontend", "@alice"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("担当者が期待と異なります: got=%v want=%v", got, want)
+	}
+
+	res, err = Run(Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1, Owners: []string{"ALICE"}})
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].File != "web/app.js" {
+		t.Fatalf("--owners の絞り込みが期待と異なります: %+v", res.Items)
+	}
+}
+
+func TestRunはCODEOWNERSが無いとownersフィルタでエラーになる(t *testing.T) {
+	repoDir := setupOwnersRepo(t, "")
+
+	res, err := Run(Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1})
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if res.HasOwners || len(res.Items) != 3 {
+		t.Fatalf("CODEOWNERS が無い場合の結果が期待と異なります: %+v", res)
+	}
+
+	_, err = Run(Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1, Owners: []string{"@org/backend"}})
+	if err == nil || !strings.Contains(err.Error(), "no CODEOWNERS file found") {
+		t.Fatalf("CODEOWNERS 不在時のエラーが期待と異なります: %v", err)
+	}
+}
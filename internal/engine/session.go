@@ -0,0 +1,649 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phyten/todox/internal/codeowners"
+	"github.com/phyten/todox/internal/model"
+)
+
+// Session は一度走査した結果を保持し、変更のあったファイルだけを読み直して結果を更新します。
+// todox watch と serve --watch が使います。内容の変わらない行は前回の帰属を引き継ぐため、
+// blame し直すのは追加・変更された行と、HEAD の移動で帰属が変わりうるファイルの行だけです。
+//
+// Session は並行に使えません。Poll と Refresh は 1 つのゴルーチンから順に呼んでください。
+type Session struct {
+	opts       Options // 準備済みの設定。抑制した項目も保持し、--author の絞り込みは結果を作るときに行う
+	show       bool    // 呼び出し元の ShowSuppressed
+	authorRe   *regexp.Regexp
+	attr       attribution
+	tags       []string
+	specs      []tagSpec
+	files      map[string]*sessionFile
+	errs       []ItemError // ファイルに属さないエラー（サブモジュールの一覧の失敗など）
+	hasOwners  bool
+	head       string
+	stamps     map[string]fileStamp
+	searchTags []string
+}
+
+// sessionFile は 1 ファイル分の項目と、引き継ぎの照合に使う各項目の行の内容です。
+type sessionFile struct {
+	items []Item
+	lines []string
+	errs  []ItemError
+	used  []bool
+}
+
+// fileStamp は変更の検出に使うファイルの大きさと更新時刻です。
+type fileStamp struct {
+	size int64
+	mod  time.Time
+}
+
+// NewSession は opts で最初の走査を行い、その結果とともに Session を返します。
+// opts.OnItem を設定すると、最初の走査の項目は RunContext と同じく見つけ次第渡されます。
+func NewSession(ctx context.Context, opts Options) (*Session, *Result, error) {
+	prepared, tags, searchTags, err := prepareOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	specs, err := compileTagSpecs(searchTags, prepared.TagRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &Session{
+		opts:       prepared,
+		show:       prepared.ShowSuppressed,
+		attr:       attribution{aliases: newAuthorAliases(prepared.AuthorAliases)},
+		tags:       tags,
+		specs:      specs,
+		searchTags: searchTags,
+	}
+	if prepared.AuthorRegex != "" {
+		if s.authorRe, err = regexp.Compile(prepared.AuthorRegex); err != nil {
+			return nil, nil, fmt.Errorf("invalid --author regex: %w", err)
+		}
+	}
+	if prepared.SkipAuthors != "" {
+		if s.attr.skip, err = regexp.Compile(prepared.SkipAuthors); err != nil {
+			return nil, nil, fmt.Errorf("invalid --skip-authors regex: %w", err)
+		}
+	}
+	s.opts.ShowSuppressed = true
+	s.opts.AuthorRegex = ""
+	s.opts.OnItem = nil
+	s.opts.ProgressObserver = nil
+	s.opts.Progress = false
+
+	res, err := s.initialScan(ctx, prepared)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, res, nil
+}
+
+// initialScan は RunContext で全体を走査し、ファイルごとの状態を作り直します。
+func (s *Session) initialScan(ctx context.Context, opts Options) (*Result, error) {
+	start := time.Now()
+	run := s.opts
+	run.Progress = opts.Progress
+	run.ProgressObserver = opts.ProgressObserver
+	var items []Item
+	if opts.OnItem != nil {
+		run.StreamOrder = opts.StreamOrder
+		run.OnItem = func(it Item) error {
+			items = append(items, it)
+			if s.visible(it) {
+				return opts.OnItem(it)
+			}
+			return nil
+		}
+	}
+	res, err := RunContext(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	if run.OnItem == nil {
+		items = res.Items
+	}
+	head, err := gitHead(ctx, s.opts.RepoDir)
+	if err != nil {
+		return nil, err
+	}
+	stamps, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.head, s.stamps, s.hasOwners = head, stamps, res.HasOwners
+	s.files = make(map[string]*sessionFile)
+	s.errs = nil
+	for _, e := range res.Errors {
+		if e.File == "" {
+			s.errs = append(s.errs, e)
+			continue
+		}
+		s.file(e.File).errs = append(s.file(e.File).errs, e)
+	}
+	contents := make(map[string][]string)
+	for _, it := range items {
+		lines, ok := contents[it.File]
+		if !ok && it.Submodule == "" && it.MatchKind != string(model.MatchKindArchive) {
+			if data, readErr := os.ReadFile(filepath.Join(s.opts.RepoDir, filepath.FromSlash(it.File))); readErr == nil {
+				lines = sourceLines(data)
+			}
+			contents[it.File] = lines
+		}
+		s.file(it.File).add(it, lineAt(lines, it.Line))
+	}
+	return s.result(msSince(start)), nil
+}
+
+// Result は現在の結果を返します。
+func (s *Session) Result() *Result {
+	return s.result(0)
+}
+
+// Poll は前回から変わった追跡対象のファイルと HEAD の移動を調べ、変化があれば結果を更新します。
+// 変化がなければ nil を返します。changed は読み直したファイルです。
+//
+// 変更はファイルの大きさと更新時刻で検出します。HEAD が先へ進んだ場合は差分のあったファイルの行を
+// blame し直し、それ以外（リセット・リベース・別のブランチへの切り替え）は全体を走査し直します。
+func (s *Session) Poll(ctx context.Context) (res *Result, changed []string, err error) {
+	stamps, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for path, st := range stamps {
+		if old, ok := s.stamps[path]; !ok || old != st {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s.stamps {
+		if _, ok := stamps[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	s.stamps = stamps
+
+	head, err := gitHead(ctx, s.opts.RepoDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reblame map[string]bool
+	if head != s.head {
+		moved, ok, err := headChanges(ctx, s.opts.RepoDir, s.head, head)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			res, err := s.initialScan(ctx, s.opts)
+			return res, s.paths(), err
+		}
+		s.head = head
+		reblame = make(map[string]bool, len(moved))
+		for _, path := range moved {
+			reblame[path] = true
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil, nil
+	}
+	changed = uniqueStrings(changed)
+	res, err = s.refresh(ctx, changed, reblame)
+	return res, changed, err
+}
+
+// Refresh は files（リポジトリ相対のパス）を読み直して結果を更新します。
+// エディタの保存通知など、Poll 以外で変更を知った場合に使います。
+func (s *Session) Refresh(ctx context.Context, files []string) (*Result, error) {
+	return s.refresh(ctx, uniqueStrings(files), nil)
+}
+
+func (s *Session) refresh(parent context.Context, changed []string, reblame map[string]bool) (*Result, error) {
+	start := time.Now()
+	ctx, cancel := withTimeout(parent, s.opts.Timeout)
+	defer cancel()
+
+	tracked, err := gitListFiles(ctx, s.opts.RepoDir, s.opts.Paths, s.opts.Excludes, s.opts.ExcludeTypical)
+	if err != nil {
+		return nil, runError(ctx, s.opts, err)
+	}
+	trackedSet := make(map[string]bool, len(tracked))
+	for _, path := range tracked {
+		trackedSet[path] = true
+	}
+
+	// .todoxignore と CODEOWNERS はすべてのファイルの結果に関わるため、全体を読み直します。
+	targets := changed
+	if affectsAllFiles(changed) {
+		all := tracked
+		if pattern, ok := prefilterPattern(s.searchTags, s.opts.TagRules); ok && !s.opts.NoPrefilter {
+			all, err = prefilterFiles(ctx, s.opts, pattern)
+			if err != nil {
+				return nil, runError(ctx, s.opts, err)
+			}
+		}
+		targets = uniqueStrings(append(append(append([]string(nil), changed...), all...), s.paths()...))
+	}
+
+	prev := make(map[string]*sessionFile)
+	var parse, retained []string
+	for _, path := range targets {
+		if f := s.files[path]; f != nil && (archiveOuterPath(path) != path || f.inSubmodule()) {
+			// アーカイブ内やサブモジュール内の項目は読み直せないため残し、規則だけを当て直します。
+			retained = append(retained, path)
+			continue
+		}
+		if archiveFormatOf(path) != archiveNone {
+			// アーカイブの中身は読み直さず、アーカイブが消えたときだけ項目を捨てます。
+			if !trackedSet[path] {
+				for key := range s.files {
+					if strings.HasPrefix(key, path+archiveSeparator) {
+						delete(s.files, key)
+					}
+				}
+			}
+			continue
+		}
+		if old, ok := s.files[path]; ok {
+			prev[path] = old
+			delete(s.files, path)
+		}
+		if trackedSet[path] {
+			parse = append(parse, path)
+		}
+	}
+	parse = filterPathsByRegex(parse, s.opts.PathRegexCompiled)
+	if !s.opts.IncludeGenerated && len(parse) > 0 {
+		if parse, err = filterGeneratedPaths(ctx, s.opts.RepoDir, parse); err != nil {
+			return nil, runError(ctx, s.opts, err)
+		}
+	}
+
+	var matches []model.Match
+	contents := make(map[string]contextSource, len(parse))
+	for _, path := range parse {
+		data, readErr := os.ReadFile(filepath.Join(s.opts.RepoDir, filepath.FromSlash(path)))
+		if readErr != nil {
+			if !errors.Is(readErr, fs.ErrNotExist) {
+				s.file(path).errs = append(s.file(path).errs, newItemError(path, 0, "read", readErr))
+			}
+			continue
+		}
+		if isRegexMode(s.opts.DetectMode) && !hasUTF16BOM(data) && bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			continue // git grep -I と同じくバイナリは飛ばす
+		}
+		found, detectErr := detectContent(path, data, s.opts, s.specs)
+		if detectErr != nil {
+			return nil, detectErr
+		}
+		contents[path] = newContextSource(path, data)
+		matches = append(matches, found...)
+	}
+	if err := markTodoxIgnored(s.opts.RepoDir, matches); err != nil {
+		return nil, err
+	}
+	if err := s.reapplyPathRules(retained); err != nil {
+		return nil, err
+	}
+	matches = filterMatchesByType(matches, s.opts.Type, s.tags)
+	matches, owners, hasOwners, err := resolveOwners(s.opts, matches)
+	if err != nil {
+		return nil, err
+	}
+	s.hasOwners = hasOwners
+
+	items := make([]Item, len(matches))
+	lines := make([]string, len(matches))
+	var pending []int
+	for i, m := range matches {
+		it := newItem(m)
+		applyCommentFields(s.opts, m, &it)
+		if owners != nil {
+			it.Owners = owners[i]
+		}
+		lines[i] = lineAt(contents[m.File].lines, it.Line)
+		if old := prev[m.File]; old != nil && !reblame[m.File] && old.inherit(&it, lines[i]) {
+			items[i] = it
+			continue
+		}
+		items[i] = it
+		pending = append(pending, i)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs = make(map[string][]ItemError)
+		wg   sync.WaitGroup
+	)
+	jobs := make(chan int)
+	workers := min(s.opts.Jobs, len(pending))
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				it, itemErrs := processItem(ctx, s.opts, s.attr, nil, matches[i])
+				it.Owners = items[i].Owners
+				items[i] = it
+				if len(itemErrs) > 0 {
+					mu.Lock()
+					errs[matches[i].File] = append(errs[matches[i].File], itemErrs...)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, runError(ctx, s.opts, err)
+	}
+
+	for i := range items {
+		contents[items[i].File].attach(&items[i], s.opts.ContextLines)
+		if keepItem(items[i], nil) {
+			s.file(items[i].File).add(items[i], lines[i])
+		}
+	}
+	for path, fileErrs := range errs {
+		s.file(path).errs = append(s.file(path).errs, fileErrs...)
+	}
+	for path, f := range s.files {
+		if len(f.items) == 0 && len(f.errs) == 0 {
+			delete(s.files, path)
+			continue
+		}
+		sort.SliceStable(f.items, func(i, j int) bool { return f.items[i].Line < f.items[j].Line })
+	}
+	return s.result(msSince(start)), nil
+}
+
+// result は保持している項目から抑制と --author の絞り込みを反映した Result を作ります。
+func (s *Session) result(elapsed int64) *Result {
+	var items []Item
+	errs := append([]ItemError(nil), s.errs...)
+	suppressed := 0
+	for _, path := range s.paths() {
+		f := s.files[path]
+		errs = append(errs, f.errs...)
+		for _, it := range f.items {
+			if it.Suppressed != "" {
+				suppressed++
+			}
+			if s.visible(it) {
+				items = append(items, it)
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].File == items[j].File {
+			return items[i].Line < items[j].Line
+		}
+		return items[i].File < items[j].File
+	})
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].File == errs[j].File {
+			if errs[i].Line == errs[j].Line {
+				return errs[i].Stage < errs[j].Stage
+			}
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].File < errs[j].File
+	})
+	return &Result{
+		Items:      items,
+		HasComment: s.opts.WithComment,
+		HasMessage: s.opts.WithMessage,
+		HasOwners:  s.hasOwners,
+		Total:      len(items),
+		Suppressed: suppressed,
+		ElapsedMS:  elapsed,
+		Errors:     errs,
+		ErrorCount: len(errs),
+	}
+}
+
+// visible は呼び出し元の ShowSuppressed と --author に照らして it を結果に含めるかを返します。
+func (s *Session) visible(it Item) bool {
+	if it.Suppressed != "" && !s.show {
+		return false
+	}
+	if s.authorRe != nil && it.Commit != "" && !s.authorRe.MatchString(it.Author) && !s.authorRe.MatchString(it.Email) {
+		return false
+	}
+	return keepItem(it, s.authorRe)
+}
+
+func (s *Session) file(path string) *sessionFile {
+	f, ok := s.files[path]
+	if !ok {
+		f = &sessionFile{}
+		s.files[path] = f
+	}
+	return f
+}
+
+// paths は項目かエラーのあるファイルを名前順に返します。
+func (s *Session) paths() []string {
+	out := make([]string, 0, len(s.files))
+	for path := range s.files {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// snapshot は追跡対象のファイルと .todoxignore・CODEOWNERS の大きさと更新時刻を集めます。
+func (s *Session) snapshot(ctx context.Context) (map[string]fileStamp, error) {
+	files, err := gitListFiles(ctx, s.opts.RepoDir, s.opts.Paths, s.opts.Excludes, s.opts.ExcludeTypical)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, todoxIgnoreFile)
+	files = append(files, codeowners.SearchPaths...)
+	stamps := make(map[string]fileStamp, len(files))
+	for _, path := range files {
+		info, err := os.Lstat(filepath.Join(s.opts.RepoDir, filepath.FromSlash(path)))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		stamps[path] = fileStamp{size: info.Size(), mod: info.ModTime()}
+	}
+	return stamps, nil
+}
+
+// reapplyPathRules は読み直さないファイル（アーカイブ内・サブモジュール内）の項目に、
+// .todoxignore と CODEOWNERS をパスごとに当て直します。--owners に合わなくなった項目は捨てます。
+func (s *Session) reapplyPathRules(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	probes := make([]model.Match, len(paths))
+	for i, path := range paths {
+		probes[i] = model.Match{File: path}
+	}
+	if err := markTodoxIgnored(s.opts.RepoDir, probes); err != nil {
+		return err
+	}
+	ignored := make(map[string]bool, len(paths))
+	for _, m := range probes {
+		ignored[m.File] = m.Suppressed != ""
+	}
+	kept, owners, _, err := resolveOwners(s.opts, probes)
+	if err != nil {
+		return err
+	}
+	ownersOf := make(map[string][]string, len(kept))
+	for i, m := range kept {
+		var o []string
+		if owners != nil {
+			o = owners[i]
+		}
+		ownersOf[m.File] = o
+	}
+	for _, path := range paths {
+		f := s.files[path]
+		o, ok := ownersOf[path]
+		if !ok {
+			delete(s.files, path)
+			continue
+		}
+		for i := range f.items {
+			it := &f.items[i]
+			switch {
+			case ignored[path] && it.Suppressed == "":
+				it.Suppressed = suppressedTodoxIgnore
+			case !ignored[path] && it.Suppressed == suppressedTodoxIgnore:
+				it.Suppressed = ""
+			}
+			it.Owners = o
+		}
+	}
+	return nil
+}
+
+// inSubmodule はサブモジュール内のファイルの項目かを返します。
+func (f *sessionFile) inSubmodule() bool {
+	return len(f.items) > 0 && f.items[0].Submodule != ""
+}
+
+func (f *sessionFile) add(it Item, line string) {
+	f.items = append(f.items, it)
+	f.lines = append(f.lines, line)
+	f.used = append(f.used, false)
+}
+
+// inherit は同じタグで行の内容も同じ前回の項目（最も近い行の、まだ使っていないもの）から
+// 帰属の情報を it に写します。見つからなければ false を返します。
+func (f *sessionFile) inherit(it *Item, line string) bool {
+	best := -1
+	for i, old := range f.items {
+		if f.used[i] || old.Tag != it.Tag || f.lines[i] != line || old.Submodule != "" {
+			continue
+		}
+		if best < 0 || absInt(old.Line-it.Line) < absInt(f.items[best].Line-it.Line) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return false
+	}
+	f.used[best] = true
+	old := f.items[best]
+	it.Author, it.Email, it.Date, it.Commit = old.Author, old.Email, old.Date, old.Commit
+	it.Committer, it.CommitterEmail = old.Committer, old.CommitterEmail
+	it.AgeDays, it.Message, it.Skipped = old.AgeDays, old.Message, old.Skipped
+	return true
+}
+
+// affectsAllFiles は changed に .todoxignore か CODEOWNERS が含まれるかを返します。
+func affectsAllFiles(changed []string) bool {
+	for _, path := range changed {
+		if path == todoxIgnoreFile {
+			return true
+		}
+		for _, candidate := range codeowners.SearchPaths {
+			if path == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gitHead は HEAD のコミットを返します（コミットがまだなければ空文字列）。
+func gitHead(ctx context.Context, repo string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "-q", "--verify", "HEAD")
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && ee.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git rev-parse HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// headChanges は HEAD が from から to へ先に進んだだけなら、その間に変わったファイルを返します。
+// 履歴が書き換わった場合（from が to の祖先でない場合）は ok が false です。
+func headChanges(ctx context.Context, repo, from, to string) (files []string, ok bool, err error) {
+	if from == "" || to == "" {
+		return nil, false, nil
+	}
+	anc := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", from, to)
+	anc.Dir = repo
+	if err := anc.Run(); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && ee.ExitCode() == 1 {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("git merge-base: %w", err)
+	}
+	// --relative でパスを RepoDir 相対にそろえ、s.files のキーと突き合わせられるようにします。
+	cmd := exec.CommandContext(ctx, "git", "-c", "core.quotePath=false", "diff", "--name-only", "--no-renames", "--relative", "-z", from, to, "--")
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false, fmt.Errorf("git diff --name-only: %w", err)
+	}
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) > 0 {
+			files = append(files, filepath.ToSlash(string(p)))
+		}
+	}
+	return files, true, nil
+}
+
+func isRegexMode(mode string) bool {
+	return strings.EqualFold(strings.TrimSpace(mode), "regex")
+}
+
+func lineAt(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionは変更のあったファイルだけを読み直す(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	write("a.go", "package a\n// TODO: a1\n")
+	write("b.go", "package b\n// FIXME: b1\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	ctx := context.Background()
+	s, res, err := NewSession(ctx, Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 2})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if res.Total != 2 {
+		t.Fatalf("expected 2 items, got %+v", res.Items)
+	}
+	if res, _, err := s.Poll(ctx); err != nil || res != nil {
+		t.Fatalf("nothing changed yet, got %+v (err=%v)", res, err)
+	}
+
+	// 内容の変わらない行は blame し直さずに前回の帰属を引き継ぐ
+	s.files["a.go"].items[0].Author = "carried"
+	write("a.go", "package a\n\nimport \"fmt\"\n// TODO: a1\n// TODO: a2\n")
+	res, changed, err := s.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "a.go" {
+		t.Fatalf("expected only a.go to be read again, got %v", changed)
+	}
+	if res.Total != 3 || res.Items[0].Line != 4 || res.Items[0].Author != "carried" {
+		t.Fatalf("a1 should move to line 4 and keep its attribution: %+v", res.Items)
+	}
+	if res.Items[1].Line != 5 || res.Items[1].Author != "(working tree)" {
+		t.Fatalf("the new line should be blamed as uncommitted: %+v", res.Items[1])
+	}
+
+	// コミットで HEAD が進むと、差分のあったファイルの行は帰属を求め直す
+	runGit(t, repoDir, "commit", "-am", "add a2")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after commit: res=%v err=%v", res, err)
+	}
+	if res.Items[1].Author != "alice" || res.Items[1].Commit == "" || res.Items[0].Author != "alice" {
+		t.Fatalf("a.go should be blamed again after the commit: %+v", res.Items)
+	}
+
+	// .todoxignore の変更はすべてのファイルに効く
+	write(".todoxignore", "b.go\n")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after .todoxignore: res=%v err=%v", res, err)
+	}
+	if res.Total != 2 || res.Suppressed != 1 {
+		t.Fatalf("b.go should be suppressed: total=%d suppressed=%d items=%+v", res.Total, res.Suppressed, res.Items)
+	}
+
+	runGit(t, repoDir, "rm", "-q", "a.go")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after rm: res=%v err=%v", res, err)
+	}
+	if res.Total != 0 {
+		t.Fatalf("items of a removed file should disappear: %+v", res.Items)
+	}
+}
+
+func TestSessionはサブディレクトリでもコミット後に帰属を求め直す(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	sub := filepath.Join(repoDir, "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(sub, "a.go"), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	write("package a\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	ctx := context.Background()
+	s, _, err := NewSession(ctx, Options{Type: "both", Mode: "last", RepoDir: sub, Jobs: 1})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	write("package a\n// TODO: new\n")
+	if res, _, err := s.Poll(ctx); err != nil || res == nil || res.Items[0].Author != "(working tree)" {
+		t.Fatalf("the edit should show as uncommitted first: res=%v err=%v", res, err)
+	}
+	// コミットで変わるのは HEAD だけで、git diff のパスはリポジトリ直下からになる
+	runGit(t, repoDir, "commit", "-am", "add todo")
+	res, _, err := s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after commit: res=%v err=%v", res, err)
+	}
+	if len(res.Items) != 1 || res.Items[0].File != "a.go" || res.Items[0].Author != "alice" || res.Items[0].Commit == "" {
+		t.Fatalf("the committed TODO should be blamed again from a subdirectory: %+v", res.Items)
+	}
+}
+
+func TestSessionは規則の変更でアーカイブ内の項目を捨てない(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	writeZip(t, filepath.Join(repoDir, "b.zip"), map[string]string{"in.py": "# TODO: in zip\n"})
+	write("a.go", "package a\n// TODO: a1\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	ctx := context.Background()
+	s, res, err := NewSession(ctx, Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 1, ScanArchives: true})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if res.Total != 2 {
+		t.Fatalf("expected the file and archive items, got %+v", res.Items)
+	}
+
+	write(".todoxignore", "a.go\n")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after .todoxignore: res=%v err=%v", res, err)
+	}
+	if res.Total != 1 || res.Items[0].File != "b.zip!in.py" || res.Suppressed != 1 {
+		t.Fatalf("the archive item should survive a .todoxignore change: %+v", res.Items)
+	}
+
+	write("CODEOWNERS", "*.zip @team\n")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after CODEOWNERS: res=%v err=%v", res, err)
+	}
+	if res.Total != 1 || len(res.Items[0].Owners) != 1 || res.Items[0].Owners[0] != "@team" {
+		t.Fatalf("CODEOWNERS should apply to the archive item: %+v", res.Items)
+	}
+
+	write(".todoxignore", "b.zip\n")
+	res, _, err = s.Poll(ctx)
+	if err != nil || res == nil {
+		t.Fatalf("Poll after the second .todoxignore: res=%v err=%v", res, err)
+	}
+	if res.Total != 1 || res.Items[0].File != "a.go" || res.Suppressed != 1 {
+		t.Fatalf(".todoxignore should now suppress the archive item instead: %+v", res.Items)
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// maxSkipDepth は --skip-authors で遡るコミット数の上限です。
+const maxSkipDepth = 16
+
+// SkippedCommit は --skip-authors により帰属を飛ばしたコミットを表す
+type SkippedCommit struct {
+	Commit string `json:"commit"`
+	Author string `json:"author"`
+	Email  string `json:"email"`
+}
+
+// attribution は 1 件ごとの帰属処理で共有する、Run 単位で準備済みの設定です。
+type attribution struct {
+	aliases authorAliases
+	skip    *regexp.Regexp
+}
+
+func (a attribution) isSkipped(info commitInfo) bool {
+	if a.skip == nil {
+		return false
+	}
+	name, email := a.aliases.resolve(info.Author, info.Email)
+	return a.skip.MatchString(name) || a.skip.MatchString(email) ||
+		a.skip.MatchString(info.Author) || a.skip.MatchString(info.Email)
+}
+
+// walkPastSkipped は info の作者が --skip-authors に一致する間、
+// 一致したコミットを git blame --ignore-rev で無視しながら直前の変更へ遡ります。
+// 遡れない（その行を追加したのがボットだけ等）場合は最後に見つかったコミットのままにします。
+func walkPastSkipped(ctx context.Context, opts Options, attr attribution, file string, line int, sha string, info commitInfo) (string, commitInfo, []SkippedCommit, []ItemError) {
+	var skipped []SkippedCommit
+	var errs []ItemError
+	var ignored []string
+	for depth := 0; depth < maxSkipDepth && attr.isSkipped(info); depth++ {
+		ignored = append(ignored, sha)
+		next, err := blameSHA(ctx, opts.RepoDir, file, line, opts.IgnoreWS, ignored...)
+		if err != nil {
+			errs = append(errs, newItemError(file, line, "git blame --ignore-rev", err))
+			break
+		}
+		if next == "" || next == strings.Repeat("0", 40) || containsString(ignored, next) {
+			break
+		}
+		nextInfo, err := commitMeta(ctx, opts.RepoDir, next)
+		if err != nil {
+			errs = append(errs, newItemError(file, line, "git show", err))
+			break
+		}
+		skipped = append(skipped, SkippedCommit{Commit: sha, Author: info.Author, Email: info.Email})
+		sha, info = next, nextInfo
+	}
+	return sha, info, skipped, errs
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
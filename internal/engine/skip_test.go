@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunはskip_authorsに一致するコミットを飛ばして帰属させる(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "tester")
+	runGit(t, repoDir, "config", "user.email", "tester@example.com")
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte(content), 0o644); err != nil {
+			t.Fatalf("ファイルの作成に失敗しました: %v", err)
+		}
+	}
+	write("package main\n\n// TODO handle retries\nfunc main() {}\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "--author", "Alice <alice@example.com>", "-m", "add todo")
+	write("package main\n\n// TODO: handle retries\nfunc main() {}\n")
+	runGit(t, repoDir, "commit", "-am", "style: normalize", "--author", "renovate[bot] <bot@renovateapp.com>")
+	write("package main\n\n// TODO: handle retries!\nfunc main() {}\n")
+	runGit(t, repoDir, "commit", "-am", "fmt", "--author", "formatter-bot <fmt@example.com>")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Author != "formatter-bot" || len(res.Items[0].Skipped) != 0 {
+		t.Fatalf("--skip-authors なしでは最後のボットに帰属する想定です: %+v", res.Items)
+	}
+
+	opts.SkipAuthors = `\[bot\]$|-bot$`
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if res.ErrorCount != 0 {
+		t.Fatalf("エラーが発生しました: %+v", res.Errors)
+	}
+	if len(res.Items) != 1 {
+		t.Fatalf("件数が期待と異なります: %+v", res.Items)
+	}
+	it := res.Items[0]
+	if it.Author != "Alice" {
+		t.Fatalf("ボットを飛ばした帰属になっていません: %+v", it)
+	}
+	if len(it.Skipped) != 2 || it.Skipped[0].Author != "formatter-bot" || it.Skipped[1].Author != "renovate[bot]" {
+		t.Fatalf("飛ばしたコミットの記録が期待と異なります: %+v", it.Skipped)
+	}
+
+	opts.Mode = "first"
+	if _, err := Run(opts); err == nil || !strings.Contains(err.Error(), "--skip-authors") {
+		t.Fatalf("--mode first との併用はエラーになる想定です: %v", err)
+	}
+}
+
+func TestBuildBlameArgsはignore_revを付与する(t *testing.T) {
+	got := strings.Join(buildBlameArgs("a.go", 3, true, "abc", "def"), " ")
+	want := "blame -w --ignore-rev abc --ignore-rev def --line-porcelain -L 3,3 -- a.go"
+	if got != want {
+		t.Fatalf("引数が期待と異なります: got=%q want=%q", got, want)
+	}
+}
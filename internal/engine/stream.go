@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// Options.StreamOrder に指定できる値です。
+const (
+	StreamOrderFile     = "file"     // ファイル内の項目がそろった時点で行順に渡す
+	StreamOrderUnsorted = "unsorted" // 帰属を終えた順にすぐ渡す
+)
+
+// itemStream は帰属を終えた項目を opts.OnItem に渡します。
+// ワーカーから並行に呼ばれますが、OnItem の呼び出しは直列化されます。
+type itemStream struct {
+	mu        sync.Mutex
+	fn        func(Item) error
+	byFile    bool
+	remaining map[string]int
+	pending   map[string][]Item
+	emitted   int
+	err       error
+}
+
+func newItemStream(fn func(Item) error, order string, matches []model.Match) (*itemStream, error) {
+	s := &itemStream{fn: fn}
+	switch strings.ToLower(strings.TrimSpace(order)) {
+	case "", StreamOrderFile:
+		s.byFile = true
+		s.remaining = make(map[string]int)
+		s.pending = make(map[string][]Item)
+		for _, m := range matches {
+			s.remaining[m.File]++
+		}
+	case StreamOrderUnsorted:
+	default:
+		return nil, fmt.Errorf("invalid stream order: %s (must be file or unsorted)", order)
+	}
+	return s, nil
+}
+
+// done は file の 1 件の処理が終わったことを知らせます。keep が false の項目は渡しません。
+// OnItem がエラーを返すと、以降の呼び出しは何もせずそのエラーを返します。
+func (s *itemStream) done(file string, it Item, keep bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if !s.byFile {
+		if keep {
+			s.emit(it)
+		}
+		return s.err
+	}
+	if keep {
+		s.pending[file] = append(s.pending[file], it)
+	}
+	s.remaining[file]--
+	if s.remaining[file] > 0 {
+		return nil
+	}
+	items := s.pending[file]
+	delete(s.pending, file)
+	delete(s.remaining, file)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Line < items[j].Line })
+	for _, item := range items {
+		if s.emit(item); s.err != nil {
+			break
+		}
+	}
+	return s.err
+}
+
+func (s *itemStream) emit(it Item) {
+	if err := s.fn(it); err != nil {
+		s.err = err
+		return
+	}
+	s.emitted++
+}
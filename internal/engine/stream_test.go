@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunはOnItemへ項目を逐次渡す(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	files := map[string]string{
+		"a.go": "package a\n// TODO: a1\n// TODO: a2\n// FIXME: a3\n",
+		"b.go": "package b\n// FIXME: b1\n// TODO: b2\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	var got []Item
+	opts := Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 4}
+	opts.OnItem = func(it Item) error {
+		got = append(got, it)
+		return nil
+	}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Items != nil || res.Total != 5 || len(got) != 5 {
+		t.Fatalf("expected 5 streamed items and no buffered items, got total=%d streamed=%d items=%v", res.Total, len(got), res.Items)
+	}
+	// file 順では同じファイルの項目が連続し、行順に並ぶ
+	lastLine := map[string]int{}
+	for i, it := range got {
+		if i > 0 && got[i-1].File != it.File && lastLine[it.File] != 0 {
+			t.Fatalf("items of %s were interleaved with another file: %+v", it.File, got)
+		}
+		if it.Line <= lastLine[it.File] {
+			t.Fatalf("items of %s are not in line order: %+v", it.File, got)
+		}
+		lastLine[it.File] = it.Line
+	}
+
+	got = nil
+	opts.StreamOrder = StreamOrderUnsorted
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("unsorted stream lost items: %+v", got)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	opts.OnItem = func(Item) error {
+		calls++
+		return stop
+	}
+	if _, err := Run(opts); !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected the scan to stop after the first item, err=%v calls=%d", err, calls)
+	}
+
+	opts.StreamOrder = "random"
+	if _, err := Run(opts); err == nil {
+		t.Fatal("expected an error for an unknown stream order")
+	}
+}
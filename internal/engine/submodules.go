@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// maxSubmoduleDepth は --recurse-submodules で辿る入れ子の深さの上限です。
+const maxSubmoduleDepth = 8
+
+// submoduleSet はスーパープロジェクトからの相対パスで表したサブモジュールの一覧です。
+// 長いパスから順に並べ、入れ子のサブモジュールを優先して照合します。
+type submoduleSet []string
+
+// listSubmodules は repo 配下の初期化済みサブモジュールを再帰的に列挙します。
+// 未初期化のサブモジュールは走査できないため、ItemError として報告します。
+func listSubmodules(ctx context.Context, repo string) (submoduleSet, []ItemError, error) {
+	var subs submoduleSet
+	var errs []ItemError
+	var walk func(dir, prefix string, depth int) error
+	walk = func(dir, prefix string, depth int) error {
+		paths, err := gitSubmodulePaths(ctx, dir)
+		if err != nil {
+			return err
+		}
+		for _, rel := range paths {
+			full := prefix + rel
+			if _, statErr := os.Stat(filepath.Join(dir, filepath.FromSlash(rel), ".git")); statErr != nil {
+				errs = append(errs, ItemError{File: full, Stage: "submodule", Message: "submodule is not initialized (run git submodule update --init)"})
+				continue
+			}
+			subs = append(subs, full)
+			if depth+1 >= maxSubmoduleDepth {
+				continue
+			}
+			if err := walk(filepath.Join(dir, filepath.FromSlash(rel)), full+"/", depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(repo, "", 0); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if len(subs[i]) != len(subs[j]) {
+			return len(subs[i]) > len(subs[j])
+		}
+		return subs[i] < subs[j]
+	})
+	return subs, errs, nil
+}
+
+// gitSubmodulePaths は index 上で gitlink（mode 160000）になっているパスを返します。
+func gitSubmodulePaths(ctx context.Context, repo string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-c", "core.quotePath=false", "ls-files", "--stage", "-z")
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files --stage: %w", err)
+	}
+	var paths []string
+	for _, rec := range bytes.Split(out, []byte{0}) {
+		meta, path, ok := strings.Cut(string(rec), "\t")
+		if !ok || !strings.HasPrefix(meta, "160000 ") {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(path))
+	}
+	return paths, nil
+}
+
+// split は file を含むサブモジュールと、そのサブモジュール内での相対パスを返します。
+// スーパープロジェクトのファイルであれば sub は空文字列です。
+func (s submoduleSet) split(file string) (sub, rel string) {
+	for _, candidate := range s {
+		if strings.HasPrefix(file, candidate+"/") {
+			return candidate, file[len(candidate)+1:]
+		}
+	}
+	return "", file
+}
+
+// processInRepo は m を含むリポジトリ（スーパープロジェクトまたはサブモジュール）で blame を行い、
+// File と ItemError のパスをスーパープロジェクト基準に戻した Item を返します。
+func processInRepo(ctx context.Context, opts Options, attr attribution, subs submoduleSet, m model.Match) (Item, []ItemError) {
+	sub, rel := subs.split(m.File)
+	if sub == "" {
+		return processOne(ctx, opts, attr, m)
+	}
+	subOpts := opts
+	subOpts.RepoDir = filepath.Join(opts.RepoDir, filepath.FromSlash(sub))
+	m.File = rel
+	it, errs := processOne(ctx, subOpts, attr, m)
+	it.File = sub + "/" + rel
+	it.Submodule = sub
+	for i := range errs {
+		if errs[i].File != "" {
+			errs[i].File = sub + "/" + errs[i].File
+		}
+	}
+	return it, errs
+}
+
+// collectSubmoduleMatches は各サブモジュールをそれぞれの RepoDir で走査し、
+// File をスーパープロジェクトからの相対パスに書き換えたマッチを返します。
+func collectSubmoduleMatches(ctx context.Context, opts Options, searchTags []string, subs submoduleSet) ([]model.Match, []ItemError, error) {
+	var out []model.Match
+	var errs []ItemError
+	for _, sub := range subs {
+		subOpts, ok := submoduleOptions(opts, sub)
+		if !ok {
+			continue
+		}
+		matches, subErrs, err := collectMatches(ctx, subOpts, searchTags)
+		if err != nil {
+			errs = append(errs, newItemError(sub, 0, "submodule", err))
+			continue
+		}
+		for _, e := range subErrs {
+			if e.File != "" {
+				e.File = sub + "/" + e.File
+			}
+			errs = append(errs, e)
+		}
+		for _, m := range matches {
+			m.File = sub + "/" + m.File
+			out = append(out, m)
+		}
+	}
+	out = filterModelMatchesByPathRegex(out, opts.PathRegexCompiled)
+	if err := markTodoxIgnored(opts.RepoDir, out); err != nil {
+		return nil, nil, err
+	}
+	return out, errs, nil
+}
+
+// submoduleOptions は sub を走査するための Options を作ります。
+// --path / --exclude はスーパープロジェクト基準の指定をサブモジュール基準に読み替え、
+// サブモジュールが対象外になる場合は ok=false を返します。
+// --path-regex はスーパープロジェクト基準のパスで評価するため、ここでは外します。
+func submoduleOptions(opts Options, sub string) (Options, bool) {
+	if opts.ExcludeTypical && underTypicalExclude(sub) {
+		return opts, false
+	}
+	includes, ok := remapIncludes(sub, opts.Paths)
+	if !ok {
+		return opts, false
+	}
+	excludes, ok := remapExcludes(sub, opts.Excludes)
+	if !ok {
+		return opts, false
+	}
+	out := opts
+	out.RepoDir = filepath.Join(opts.RepoDir, filepath.FromSlash(sub))
+	out.Paths = includes
+	out.Excludes = excludes
+	out.PathRegex = nil
+	out.PathRegexCompiled = nil
+	return out, true
+}
+
+func normalizePathspec(raw string) string {
+	p := filepath.ToSlash(strings.TrimSpace(raw))
+	p = strings.TrimPrefix(p, "./")
+	return strings.TrimSuffix(p, "/")
+}
+
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// remapIncludes は --path の指定を sub 基準に変換します。指定が無ければ全体を対象にします。
+// マジック付きの pathspec（":" で始まるもの）は読み替えられないため無視します。
+func remapIncludes(sub string, includes []string) ([]string, bool) {
+	var out []string
+	seen := false
+	for _, raw := range includes {
+		p := normalizePathspec(raw)
+		if p == "" || strings.HasPrefix(p, ":") {
+			continue
+		}
+		seen = true
+		switch {
+		case p == "." || p == sub || strings.HasPrefix(sub, p+"/"):
+			return nil, true
+		case strings.HasPrefix(p, sub+"/"):
+			out = append(out, p[len(sub)+1:])
+		case !strings.Contains(p, "/") && hasGlobMeta(p):
+			out = append(out, p)
+		}
+	}
+	if !seen {
+		return nil, true
+	}
+	return out, len(out) > 0
+}
+
+// remapExcludes は --exclude の指定を sub 基準に変換します。
+// サブモジュール自体が除外される場合は ok=false を返します。
+func remapExcludes(sub string, excludes []string) ([]string, bool) {
+	var out []string
+	for _, raw := range excludes {
+		p := normalizePathspec(raw)
+		if p == "" || strings.HasPrefix(p, ":") {
+			continue
+		}
+		trimmed := strings.TrimSuffix(p, "/**")
+		switch {
+		case trimmed == sub || strings.HasPrefix(sub, trimmed+"/"):
+			return nil, false
+		case strings.HasPrefix(p, sub+"/"):
+			out = append(out, p[len(sub)+1:])
+		case !strings.Contains(p, "/"):
+			out = append(out, p)
+		}
+	}
+	return out, true
+}
+
+// underTypicalExclude は sub が --exclude-typical の対象ディレクトリ配下にあるかを返します。
+func underTypicalExclude(sub string) bool {
+	for _, pat := range typicalExcludePatterns {
+		dir, ok := strings.CutSuffix(strings.TrimPrefix(pat, ":(glob,exclude)"), "/**")
+		if ok && (sub == dir || strings.HasPrefix(sub, dir+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterModelMatchesByPathRegex(matches []model.Match, rx []*regexp.Regexp) []model.Match {
+	if len(rx) == 0 {
+		return matches
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		for _, r := range rx {
+			if r.MatchString(m.File) {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return out
+}
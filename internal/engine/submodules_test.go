@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func setupSubmoduleRepo(t *testing.T) string {
+	t.Helper()
+	subDir := t.TempDir()
+	runGit(t, subDir, "init", "-b", "main")
+	runGit(t, subDir, "config", "user.name", "bob")
+	runGit(t, subDir, "config", "user.email", "bob@example.com")
+	if err := os.MkdirAll(filepath.Join(subDir, "internal"), 0o755); err != nil {
+		t.Fatalf("ディレクトリの作成に失敗しました: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, "internal", "server.go"), []byte("package internal\n// TODO sub work\n"), 0o644); err != nil {
+		t.Fatalf("ファイルの作成に失敗しました: %v", err)
+	}
+	runGit(t, subDir, "add", ".")
+	runGit(t, subDir, "commit", "-m", "sub initial")
+
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n// FIXME top work\n"), 0o644); err != nil {
+		t.Fatalf("ファイルの作成に失敗しました: %v", err)
+	}
+	runGit(t, repoDir, "-c", "protocol.file.allow=always", "submodule", "--quiet", "add", subDir, "services/api")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	return repoDir
+}
+
+func TestRunはrecurse_submodulesでサブモジュール内も走査する(t *testing.T) {
+	repoDir := setupSubmoduleRepo(t)
+
+	for _, detect := range []string{"regex", "parse"} {
+		opts := Options{Type: "both", Mode: "last", DetectMode: detect, RepoDir: repoDir, Jobs: 1}
+		res, err := Run(opts)
+		if err != nil {
+			t.Fatalf("Run に失敗しました: %v", err)
+		}
+		if len(res.Items) != 1 || res.Items[0].File != "main.go" {
+			t.Fatalf("既定ではサブモジュールを走査しない想定です (%s): %+v", detect, res.Items)
+		}
+
+		opts.RecurseSubmodules = true
+		res, err = Run(opts)
+		if err != nil {
+			t.Fatalf("Run に失敗しました: %v", err)
+		}
+		if len(res.Items) != 2 || res.ErrorCount != 0 {
+			t.Fatalf("サブモジュールの項目が含まれていません (%s): %+v", detect, res)
+		}
+		sub := res.Items[1]
+		if sub.File != "services/api/internal/server.go" || sub.Submodule != "services/api" || sub.Author != "bob" || sub.Commit == "" {
+			t.Fatalf("サブモジュールの項目が期待と異なります (%s): %+v", detect, sub)
+		}
+		if res.Items[0].Submodule != "" || res.Items[0].Author != "alice" {
+			t.Fatalf("スーパープロジェクトの項目が期待と異なります (%s): %+v", detect, res.Items[0])
+		}
+	}
+}
+
+func TestRunはrecurse_submodulesでもpath指定を読み替える(t *testing.T) {
+	repoDir := setupSubmoduleRepo(t)
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "regex", RepoDir: repoDir, Jobs: 1, RecurseSubmodules: true}
+	opts.Paths = []string{"services/api/internal"}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].File != "services/api/internal/server.go" {
+		t.Fatalf("--path の読み替えが期待と異なります: %+v", res.Items)
+	}
+
+	opts.Paths = nil
+	opts.Excludes = []string{"services/**"}
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].File != "main.go" {
+		t.Fatalf("--exclude でサブモジュールが除外されていません: %+v", res.Items)
+	}
+
+	opts.Excludes = nil
+	opts.PathRegex = []string{`^services/api/`}
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run に失敗しました: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Submodule != "services/api" {
+		t.Fatalf("--path-regex はスーパープロジェクト基準で評価される想定です: %+v", res.Items)
+	}
+}
+
+func TestRemapIncludesはサブモジュール基準のpathspecに変換する(t *testing.T) {
+	cases := []struct {
+		in   []string
+		want []string
+		ok   bool
+	}{
+		{in: nil, want: nil, ok: true},
+		{in: []string{"services"}, want: nil, ok: true},
+		{in: []string{"services/api/"}, want: nil, ok: true},
+		{in: []string{"./services/api/cmd", "*.go"}, want: []string{"cmd", "*.go"}, ok: true},
+		{in: []string{"web"}, want: nil, ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := remapIncludes("services/api", tc.in)
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("remapIncludes(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// 抑制の理由（Match.Suppressed / Item.Suppressed の値）です。
+const (
+	suppressedLine        = "ignore"           // 同じ行の todox:ignore
+	suppressedNextLine    = "ignore-next-line" // 前の行の todox:ignore-next-line
+	suppressedFile        = "ignore-file"      // ファイル内の todox:ignore-file
+	suppressedTodoxIgnore = "todoxignore"      // .todoxignore に一致するパス
+)
+
+// todoxIgnoreFile はリポジトリ直下に置く、抑制するパスの一覧（gitignore 形式）です。
+const todoxIgnoreFile = ".todoxignore"
+
+var (
+	suppressMarker        = []byte("todox:ignore")
+	suppressMarkerPattern = regexp.MustCompile(`todox:ignore(?:-next-line|-file)?\b`)
+)
+
+// applyInlineSuppressions は data 中の todox:ignore / todox:ignore-next-line / todox:ignore-file に従って
+// matches に抑制の理由を記録します。マーカー自体の "todo" に一致した項目は取り除きます。
+// ノートブックのセル内の項目（Cell 付き）は scanNotebook がセルごとに処理済みなので変更しません。
+func applyInlineSuppressions(data []byte, matches []model.Match) []model.Match {
+	if len(matches) == 0 || !bytes.Contains(data, suppressMarker) {
+		return matches
+	}
+	lineOffsets := computeLineOffsets(data)
+	var markers [][]int
+	byLine := make(map[int]string)
+	fileWide := false
+	for _, loc := range suppressMarkerPattern.FindAllIndex(data, -1) {
+		markers = append(markers, loc)
+		line, _ := lineColFromOffset(loc[0], lineOffsets)
+		switch string(data[loc[0]:loc[1]]) {
+		case "todox:ignore-file":
+			fileWide = true
+		case "todox:ignore-next-line":
+			if _, ok := byLine[line+1]; !ok {
+				byLine[line+1] = suppressedNextLine
+			}
+		default:
+			byLine[line] = suppressedLine
+		}
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		if m.Cell != nil {
+			out = append(out, m)
+			continue
+		}
+		if insideSuppressMarker(markers, m.Span.ByteStart) {
+			continue
+		}
+		if reason, ok := byLine[m.Span.StartLine]; ok {
+			m.Suppressed = reason
+		} else if fileWide {
+			m.Suppressed = suppressedFile
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func insideSuppressMarker(markers [][]int, offset int) bool {
+	for _, loc := range markers {
+		if offset >= loc[0] && offset < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFileSuppressMarker は data に todox:ignore-file があるかを返します。
+func hasFileSuppressMarker(data []byte) bool {
+	for _, loc := range suppressMarkerPattern.FindAllIndex(data, -1) {
+		if string(data[loc[0]:loc[1]]) == "todox:ignore-file" {
+			return true
+		}
+	}
+	return false
+}
+
+// markSuppressed はまだ抑制されていない項目に reason を記録します。
+func markSuppressed(matches []model.Match, reason string) {
+	for i := range matches {
+		if matches[i].Suppressed == "" {
+			matches[i].Suppressed = reason
+		}
+	}
+}
+
+// splitSuppressed は抑制された項目を数え、show が false なら結果から取り除きます。
+func splitSuppressed(matches []model.Match, show bool) ([]model.Match, int) {
+	count := 0
+	out := matches[:0]
+	for _, m := range matches {
+		if m.Suppressed != "" {
+			count++
+			if !show {
+				continue
+			}
+		}
+		out = append(out, m)
+	}
+	return out, count
+}
+
+// markTodoxIgnored は repo 直下の .todoxignore に一致するファイルの項目を抑制します。
+// アーカイブ内の項目はアーカイブ自体のパスで判定します。
+func markTodoxIgnored(repo string, matches []model.Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	ignore, err := loadTodoxIgnore(repo)
+	if err != nil || ignore == nil {
+		return err
+	}
+	for i := range matches {
+		if matches[i].Suppressed == "" && ignore.ignored(archiveOuterPath(matches[i].File)) {
+			matches[i].Suppressed = suppressedTodoxIgnore
+		}
+	}
+	return nil
+}
+
+// ignoreRule は .todoxignore の 1 行分のパターンです。
+type ignoreRule struct {
+	rx      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher は gitignore と同じ規則でパスを判定します。後の行ほど優先され、
+// 除外されたディレクトリの中のファイルは ! で戻せません。
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadTodoxIgnore は repo 直下の .todoxignore を読みます。ファイルがなければ nil を返します。
+func loadTodoxIgnore(repo string) (*ignoreMatcher, error) {
+	data, err := os.ReadFile(filepath.Join(repo, todoxIgnoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTodoxIgnore(data), nil
+}
+
+// parseTodoxIgnore は gitignore 形式の内容を読みます。解釈できない行は git と同じく無視します。
+func parseTodoxIgnore(data []byte) *ignoreMatcher {
+	m := &ignoreMatcher{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		switch {
+		case strings.HasPrefix(line, "!"):
+			rule.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rx, err := regexp.Compile(ignoreGlobPattern(line, anchored))
+		if err != nil {
+			continue
+		}
+		rule.rx = rx
+		m.rules = append(m.rules, rule)
+	}
+	return m
+}
+
+// ignoreGlobPattern は gitignore のグロブを正規表現に変換します。
+// "/" を含まないパターンはどの階層の名前にも一致します。
+func ignoreGlobPattern(glob string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**":
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// ignored は p（リポジトリ相対・スラッシュ区切り）が除外されるかを返します。
+func (m *ignoreMatcher) ignored(p string) bool {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), i < len(parts)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ignoreMatcher) match(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.rx.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseContentは抑制マーカーに従う(t *testing.T) {
+	src := "package main\n" +
+		"// TODO: shown\n" +
+		"// TODO: known issue todox:ignore\n" +
+		"// todox:ignore-next-line\n" +
+		"// FIXME: silenced by the previous line\n" +
+		"// FIXME: shown again\n"
+	matches := parseContent("main.go", []byte(src), Options{}, normalizeTags(nil), false)
+	got := make(map[int]string)
+	for _, m := range matches {
+		got[m.Span.StartLine] = m.Suppressed
+	}
+	want := map[int]string{2: "", 3: suppressedLine, 5: suppressedNextLine, 6: ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("suppressed by line = %v, want %v", got, want)
+	}
+
+	whole := "# todox:ignore-file\n# TODO: a\nx = 1  # FIXME: b\n"
+	for _, m := range parseContent("tool.py", []byte(whole), Options{}, normalizeTags(nil), false) {
+		if m.Suppressed != suppressedFile {
+			t.Fatalf("line %d: expected ignore-file, got %q", m.Span.StartLine, m.Suppressed)
+		}
+	}
+}
+
+func TestTodoxIgnoreはgitignore形式で判定する(t *testing.T) {
+	ignore := parseTodoxIgnore([]byte("# comment\n*.log\n/build\ndocs/\nsrc/**/legacy_*.go\n!src/keep/legacy_ok.go\nthird_party/\n!third_party/ours.go\n"))
+	cases := map[string]bool{
+		"app.log":                   true,
+		"deep/nested/app.log":       true,
+		"build/out.go":              true,
+		"pkg/build/out.go":          false,
+		"docs/guide.md":             true,
+		"pkg/docs/guide.md":         true,
+		"docs":                      false,
+		"src/a/b/legacy_x.go":       true,
+		"src/legacy_x.go":           true,
+		"src/keep/legacy_ok.go":     false,
+		"third_party/ours.go":       true, // 除外したディレクトリの中は ! で戻せない
+		"main.go":                   false,
+		"vendor/sdk.zip!inner/a.go": false,
+	}
+	for p, want := range cases {
+		if got := ignore.ignored(p); got != want {
+			t.Fatalf("ignored(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestRunは抑制した項目を数えて既定で除外する(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	files := map[string]string{
+		".todoxignore":    "legacy/\n",
+		"main.go":         "package main\n// TODO: ours\n// TODO: accepted todox:ignore\n",
+		"legacy/old.go":   "package legacy\n// TODO: old\n",
+		"scripts/tool.py": "# todox:ignore-file\n# FIXME: tool\n",
+	}
+	for name, body := range files {
+		full := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(body), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "both", Mode: "last", DetectMode: "auto", RepoDir: repoDir, Jobs: 1}
+	res, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Total != 1 || res.Items[0].File != "main.go" || res.Items[0].Line != 2 {
+		t.Fatalf("expected only main.go:2, got %+v", res.Items)
+	}
+	if res.Suppressed != 3 {
+		t.Fatalf("expected 3 suppressed items, got %d", res.Suppressed)
+	}
+
+	opts.ShowSuppressed = true
+	res, err = Run(opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, it := range res.Items {
+		got[fmt.Sprintf("%s:%d", it.File, it.Line)] = it.Suppressed
+	}
+	want := map[string]string{
+		"main.go:2":         "",
+		"main.go:3":         suppressedLine,
+		"legacy/old.go:2":   suppressedTodoxIgnore,
+		"scripts/tool.py:2": suppressedFile,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if res.Suppressed != 3 {
+		t.Fatalf("expected 3 suppressed items, got %d", res.Suppressed)
+	}
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TagRules はタグを照合する際の追加規則です。ゼロ値は従来どおり
+// 大文字小文字を区別しない部分一致になります。
+type TagRules struct {
+	// WordBoundary が true の場合、識別子・パス・URL の一部として現れるタグ
+	// （todoList、TODOS、fixme-handler、/todo/ など）を無視します。
+	WordBoundary bool
+	// Suffix が空でなければ、タグの直後がこのいずれかの文字である場合だけ一致とします（例: ":("）。
+	Suffix string
+	// CaseSensitive に含まれるタグは大文字小文字を区別して照合します。"*" は全タグを表します。
+	CaseSensitive []string
+	// Patterns はタグごとの独自の正規表現です。最初のキャプチャグループがあればそれをタグの位置とします。
+	Patterns map[string]string
+}
+
+// Active は既定の照合から変更する規則が含まれているかを返します。
+func (r TagRules) Active() bool {
+	return r.WordBoundary || r.Suffix != "" || len(r.CaseSensitive) > 0 || len(r.Patterns) > 0
+}
+
+// Validate は Patterns の正規表現を検証します。
+func (r TagRules) Validate() error {
+	for tag, pattern := range r.Patterns {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("invalid --tag-pattern: empty tag")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid --tag-pattern for %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func (r TagRules) caseSensitive(tag string) bool {
+	for _, t := range r.CaseSensitive {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r TagRules) pattern(tag string) string {
+	for t, pattern := range r.Patterns {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// prefilterPattern は git grep で候補のファイルや行を絞るための正規表現を返します。
+// 独自の正規表現を持つタグは git grep -E では表せないため ok=false を返します。その場合、呼び出し側は
+// 追跡しているファイルをすべて一覧し、1 ファイルずつ読んで compileTagSpecs の結果で照合します。
+func prefilterPattern(tags []string, rules TagRules) (pattern string, ok bool) {
+	for _, tag := range effectiveTags(tags) {
+		if rules.pattern(tag) != "" {
+			return "", false
+		}
+	}
+	return patternForTags(tags), true
+}
+
+// compileTagSpecs は tags に rules を適用した照合用の tagSpec を作ります。
+func compileTagSpecs(tags []string, rules TagRules) ([]tagSpec, error) {
+	specs := normalizeTags(tags)
+	if !rules.Active() {
+		return specs, nil
+	}
+	for i := range specs {
+		spec := &specs[i]
+		spec.boundary = rules.WordBoundary
+		spec.suffix = rules.Suffix
+		spec.caseSensitive = rules.caseSensitive(spec.raw)
+		if pattern := rules.pattern(spec.raw); pattern != "" {
+			rx, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --tag-pattern for %s: %w", spec.raw, err)
+			}
+			spec.pattern = rx
+		}
+	}
+	return specs, nil
+}
+
+// findTagHits は text 中の tag の出現位置（バイトオフセットと長さ）を返します。
+func findTagHits(text, upper string, tag tagSpec) [][2]int {
+	var hits [][2]int
+	if tag.pattern != nil {
+		for _, loc := range tag.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if end > start {
+				hits = append(hits, [2]int{start, end - start})
+			}
+		}
+		return hits
+	}
+	haystack, needle := upper, tag.upper
+	if tag.caseSensitive {
+		haystack, needle = text, tag.raw
+	}
+	if needle == "" {
+		return nil
+	}
+	searchFrom := 0
+	for {
+		pos := strings.Index(haystack[searchFrom:], needle)
+		if pos < 0 {
+			break
+		}
+		abs := searchFrom + pos
+		if acceptTagAt(text, abs, len(needle), tag) {
+			hits = append(hits, [2]int{abs, len(needle)})
+		}
+		searchFrom = abs + len(needle)
+	}
+	return hits
+}
+
+// acceptTagAt は text[start:start+length] のタグが境界・後続文字の規則を満たすかを判定します。
+func acceptTagAt(text string, start, length int, tag tagSpec) bool {
+	end := start + length
+	if tag.boundary && (gluedBefore(text[:start]) || gluedAfter(text[end:])) {
+		return false
+	}
+	if tag.suffix != "" {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if end >= len(text) || !strings.ContainsRune(tag.suffix, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isConnectorRune(r rune) bool {
+	return r == '-' || r == '.' || r == '/' || r == '\\'
+}
+
+// gluedBefore はタグの直前が識別子、または "-./\" を挟んで識別子に続いているかを返します。
+func gluedBefore(prefix string) bool {
+	r, size := utf8.DecodeLastRuneInString(prefix)
+	if size == 0 {
+		return false
+	}
+	if isIdentRune(r) {
+		return true
+	}
+	if !isConnectorRune(r) {
+		return false
+	}
+	r, size = utf8.DecodeLastRuneInString(prefix[:len(prefix)-size])
+	return size > 0 && isIdentRune(r)
+}
+
+// gluedAfter はタグの直後が識別子、または "-./\" を挟んで識別子が続くかを返します。
+// 文末の "TODO." のように後ろに何も続かない区切り文字は許可します。
+func gluedAfter(suffix string) bool {
+	r, size := utf8.DecodeRuneInString(suffix)
+	if size == 0 {
+		return false
+	}
+	if isIdentRune(r) {
+		return true
+	}
+	if !isConnectorRune(r) {
+		return false
+	}
+	r, size = utf8.DecodeRuneInString(suffix[size:])
+	return size > 0 && isIdentRune(r)
+}
+
+// ParseTagPatterns は "TAG=REGEX" 形式の指定をタグごとの正規表現に変換します。
+// 空文字列の REGEX はそのタグの独自パターンを解除します。
+func ParseTagPatterns(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		tag, pattern, ok := strings.Cut(pair, "=")
+		tag = strings.TrimSpace(tag)
+		if !ok || tag == "" {
+			return nil, fmt.Errorf("invalid --tag-pattern: %q (expected TAG=REGEX)", pair)
+		}
+		if pattern == "" {
+			delete(out, tag)
+			continue
+		}
+		out[tag] = pattern
+	}
+	return out, nil
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+func mustTagSpecs(t *testing.T, tags []string, rules TagRules) []tagSpec {
+	t.Helper()
+	specs, err := compileTagSpecs(tags, rules)
+	if err != nil {
+		t.Fatalf("compileTagSpecs failed: %v", err)
+	}
+	return specs
+}
+
+func hitTexts(text string, matches []model.Match) []string {
+	var out []string
+	for _, m := range matches {
+		out = append(out, text[m.Span.ByteStart:m.Span.ByteEnd])
+	}
+	return out
+}
+
+func TestFindMatchesInTextWordBoundaryRejectsIdentifiers(t *testing.T) {
+	specs := mustTagSpecs(t, []string{"TODO", "FIXME"}, TagRules{WordBoundary: true})
+	cases := map[string]int{
+		"TODO: retry":                     1,
+		"todoList.push(x)":                0,
+		"see TODOS for details":           0,
+		"fixmeHandler()":                  0,
+		"fixme-handler and todo_items":    0,
+		"https://example.com/todo/list":   0,
+		"open todo.txt":                   0,
+		"FIXME.":                          1,
+		"(TODO) wire up; FIXME(alice): x": 2,
+		"--TODO sql style":                1,
+		"@todo phpdoc tag":                1,
+		"ＴＯＤＯ full-width is not a tag":    0,
+	}
+	for text, want := range cases {
+		got := findMatchesInText("a.go", text, specs, "go", model.MatchKindComment, 0, []int{0})
+		if len(got) != want {
+			t.Fatalf("%q: expected %d matches, got %d (%v)", text, want, len(got), hitTexts(text, got))
+		}
+	}
+}
+
+func TestFindMatchesInTextSuffixAndCaseSensitivity(t *testing.T) {
+	specs := mustTagSpecs(t, []string{"TODO", "FIXME"}, TagRules{Suffix: ":(", CaseSensitive: []string{"todo"}})
+	text := "TODO: a; todo: b; TODO(bob) c; TODO d; fixme: e"
+	got := hitTexts(text, findMatchesInText("a.go", text, specs, "go", model.MatchKindComment, 0, []int{0}))
+	want := []string{"TODO", "TODO", "fixme"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected hits: got %q want %q", got, want)
+	}
+}
+
+func TestFindMatchesInTextCustomPatternUsesFirstGroup(t *testing.T) {
+	specs := mustTagSpecs(t, []string{"TODO", "NOTE"}, TagRules{Patterns: map[string]string{"note": `(NOTE)\(\w+\):`}})
+	text := "NOTE: plain; NOTE(alice): scoped; todo later"
+	got := findMatchesInText("a.go", text, specs, "go", model.MatchKindComment, 0, []int{0})
+	if texts := hitTexts(text, got); !reflect.DeepEqual(texts, []string{"NOTE", "todo"}) {
+		t.Fatalf("unexpected hits: %q", texts)
+	}
+	if got[0].Tag != "NOTE" || got[0].Span.StartCol != 14 {
+		t.Fatalf("unexpected custom pattern match: %+v", got[0])
+	}
+}
+
+func TestScanWithStyleAppliesTagRules(t *testing.T) {
+	specs := mustTagSpecs(t, []string{"TODO"}, TagRules{WordBoundary: true})
+	data := []byte("// TODO: real\nvar todoList = []string{} // todoList helper\n")
+	matches := scanWithStyle("main.go", data, specs, "go", styleGo, true)
+	if len(matches) != 1 || matches[0].Span.StartLine != 1 {
+		t.Fatalf("expected only the real TODO, got %+v", matches)
+	}
+}
+
+func TestExpandLineSpecsDropsRejectedLinesWhenStrict(t *testing.T) {
+	lines := []match{
+		{file: "a.go", line: 1, text: "// TODO: real"},
+		{file: "a.go", line: 2, text: "todoList := nil"},
+	}
+	specs := mustTagSpecs(t, []string{"TODO"}, TagRules{WordBoundary: true})
+	out := expandLineSpecs(lines, specs, true)
+	if len(out) != 1 || out[0].Span.StartLine != 1 {
+		t.Fatalf("expected only line 1, got %+v", out)
+	}
+}
+
+func TestRunAppliesTagPatternsPastThePrefilter(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repoDir, "a.go"), []byte("package a\n// TBD: pick a name\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	// Without the git grep prefilter files are read one by one; binaries must still be skipped.
+	if err := os.WriteFile(filepath.Join(repoDir, "blob.bin"), []byte("\x00TBD: binary\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	// a.go contains no literal TODO, so a literal-tag git grep would drop it before the pattern runs.
+	rules := TagRules{Patterns: map[string]string{"TODO": `(TBD):`}}
+	for _, mode := range []string{"parse", "regex"} {
+		res, err := Run(Options{Type: "todo", Mode: "last", RepoDir: repoDir, Jobs: 1, DetectMode: mode, TagRules: rules})
+		if err != nil {
+			t.Fatalf("Run(%s) failed: %v", mode, err)
+		}
+		if len(res.Items) != 1 || res.Items[0].Line != 2 {
+			t.Fatalf("Run(%s) expected the TBD line, got %+v", mode, res.Items)
+		}
+
+		every, err := ParseInterval("1d")
+		if err != nil {
+			t.Fatalf("ParseInterval failed: %v", err)
+		}
+		now := time.Now()
+		trend, err := Trend(TrendOptions{
+			Options: Options{RepoDir: repoDir, Jobs: 1, DetectMode: mode, TagRules: rules},
+			Every:   every,
+			Since:   now,
+			Until:   now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Trend(%s) failed: %v", mode, err)
+		}
+		if len(trend.Points) == 0 || trend.Points[len(trend.Points)-1].Total != 1 {
+			t.Fatalf("Trend(%s) expected the TBD line, got %+v", mode, trend.Points)
+		}
+	}
+}
+
+func TestCompileTagSpecsRejectsInvalidPattern(t *testing.T) {
+	if _, err := compileTagSpecs([]string{"TODO"}, TagRules{Patterns: map[string]string{"TODO": "("}}); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+	if err := (TagRules{Patterns: map[string]string{"TODO": "("}}).Validate(); err == nil {
+		t.Fatal("expected Validate to reject invalid pattern")
+	}
+}
+
+func TestParseTagPatterns(t *testing.T) {
+	got, err := ParseTagPatterns([]string{`TODO=TODO\(\w+\)`, "NOTE=x{1,3}", "NOTE="})
+	if err != nil {
+		t.Fatalf("ParseTagPatterns failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"TODO": `TODO\(\w+\)`}) {
+		t.Fatalf("unexpected patterns: %v", got)
+	}
+	if _, err := ParseTagPatterns([]string{"missing-separator"}); err == nil {
+		t.Fatal("expected error for missing '='")
+	}
+}
@@ -0,0 +1,7 @@
+class Sample {
+    String url = "http://x/*"; // TODO after url
+    char c = '"'; // FIXME char quote
+    String block = """
+        TODO in text block "quoted"
+        """;
+}
@@ -0,0 +1,3 @@
+val raw = """C:\path\ // TODO raw"""
+/* outer /* nested */ TODO still comment */
+val c = '\'' // FIXME after char
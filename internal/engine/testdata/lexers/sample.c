@@ -0,0 +1,3 @@
+#include <stdio.h>
+char *s = "// TODO in string"; /* FIXME block */
+char c = '/'; // TODO after char
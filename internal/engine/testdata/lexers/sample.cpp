@@ -0,0 +1,3 @@
+auto raw = R"xyz(raw )" // TODO inside)xyz"; // FIXME after raw
+auto w = u8R"(TODO u8 raw)";
+int v = 1; // TODO plain
@@ -0,0 +1,10 @@
+package sample
+
+const url = "http://example.com/TODO" // TODO: real comment after url
+var r = '"' // FIXME rune literal with quote
+var q = '\'' // TODO escaped rune
+var raw = `raw // TODO in raw string
+still raw`
+/* TODO block
+   spanning lines */
+var s = "/* not a comment TODO */"
@@ -0,0 +1,6 @@
+const url = "http://example.com/TODO"; // TODO after url
+const re = /\/\/ TODO not a comment/g; // FIXME after regex
+const ratio = a / b; // TODO division
+const tpl = `template // TODO ${x} inside`;
+const s = 'it\'s // TODO in single';
+/* FIXME block */
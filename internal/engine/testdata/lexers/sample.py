@@ -0,0 +1,7 @@
+x = '#' # TODO after hash string
+y = "http://x" # FIXME after url
+z = f"{d['#']} TODO in fstring"
+doc = """
+TODO in docstring # still string
+"""
+w = r'\' # TODO raw single'
@@ -0,0 +1,8 @@
+fn main<'a>(s: &'a str) { // TODO lifetime then comment
+    let r = r#"raw "// TODO" raw"#; // FIXME after raw
+    let c = '"'; // TODO char quote
+    let b = br##"bytes "# TODO"##;
+    /* outer /* TODO nested */ still comment FIXME */
+    let u = "multi
+line TODO string";
+}
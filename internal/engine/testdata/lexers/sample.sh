@@ -0,0 +1,6 @@
+echo "http://x#y" # TODO after url
+n=${#arr[@]} # FIXME after length
+echo it\'s # TODO escaped quote
+echo 'single # TODO
+spanning' # FIXME after multiline
+url=a#b # TODO glued hash
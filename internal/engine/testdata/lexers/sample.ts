@@ -0,0 +1,4 @@
+function f(x: string): RegExp {
+  return /#TODO[/]x/; // TODO regex after return
+}
+const n = (a) / 2 // FIXME division after paren
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunContextは取り消しとタイムアウトを反映する(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "-b", "main")
+	runGit(t, repoDir, "config", "user.name", "alice")
+	runGit(t, repoDir, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n// TODO: one\n// FIXME: two\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	opts := Options{Type: "both", Mode: "last", RepoDir: repoDir, Jobs: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunContext(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled context should abort the scan, got %v", err)
+	}
+
+	withTimeout := opts
+	withTimeout.Timeout = time.Nanosecond
+	_, err := RunContext(context.Background(), withTimeout)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "scan timed out after 1ns") {
+		t.Fatalf("expected scan timeout error, got %v", err)
+	}
+
+	withGitTimeout := opts
+	withGitTimeout.GitTimeout = time.Nanosecond
+	res, err := RunContext(context.Background(), withGitTimeout)
+	if err != nil {
+		t.Fatalf("per-item timeouts should not abort the scan: %v", err)
+	}
+	if len(res.Items) != 0 || len(res.Errors) != 2 {
+		t.Fatalf("expected every item to time out, got items=%d errors=%+v", len(res.Items), res.Errors)
+	}
+	for _, e := range res.Errors {
+		if !strings.Contains(e.Message, "timed out after 1ns") {
+			t.Fatalf("unexpected error message: %+v", e)
+		}
+	}
+
+	res, err = RunContext(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("RunContext failed: %v", err)
+	}
+	if len(res.Items) != 2 || len(res.Errors) != 0 {
+		t.Fatalf("expected both items without errors, got %+v", res)
+	}
+}
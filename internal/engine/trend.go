@@ -0,0 +1,700 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phyten/todox/internal/model"
+)
+
+// trendMaxSamples はサンプリング点数の上限です（誤った --every で無限に近いループを避けるため）。
+const trendMaxSamples = 5000
+
+// Interval はサンプリング間隔を表します。暦単位（年・月・日）と固定長の時間を併用できます。
+type Interval struct {
+	Years    int
+	Months   int
+	Days     int
+	Duration time.Duration
+	raw      string
+}
+
+// ParseInterval は "1w", "2d", "1mo", "1y", "12h" のような間隔表記を解釈します。
+// 単位は h（時間）, d（日）, w（週）, m/mo（月）, y（年）です。
+func ParseInterval(raw string) (Interval, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(raw))
+	if trimmed == "" {
+		return Interval{}, fmt.Errorf("interval must not be empty")
+	}
+	idx := 0
+	for idx < len(trimmed) && trimmed[idx] >= '0' && trimmed[idx] <= '9' {
+		idx++
+	}
+	if idx == 0 {
+		return Interval{}, fmt.Errorf("invalid interval: %q (expected e.g. 1w, 30d, 1mo, 1y)", raw)
+	}
+	n, err := strconv.Atoi(trimmed[:idx])
+	if err != nil || n <= 0 {
+		return Interval{}, fmt.Errorf("invalid interval: %q (amount must be positive)", raw)
+	}
+	iv := Interval{raw: trimmed}
+	switch trimmed[idx:] {
+	case "h":
+		iv.Duration = time.Duration(n) * time.Hour
+	case "d":
+		iv.Days = n
+	case "w":
+		iv.Days = 7 * n
+	case "m", "mo":
+		iv.Months = n
+	case "y":
+		iv.Years = n
+	default:
+		return Interval{}, fmt.Errorf("invalid interval unit: %q (use h, d, w, mo or y)", raw)
+	}
+	return iv, nil
+}
+
+// String は入力された表記を返します。
+func (iv Interval) String() string {
+	return iv.raw
+}
+
+// IsZero は間隔が未指定かどうかを返します。
+func (iv Interval) IsZero() bool {
+	return iv.Years == 0 && iv.Months == 0 && iv.Days == 0 && iv.Duration == 0
+}
+
+// Back は t から k 回分さかのぼった時刻を返します。月末などのずれが累積しないよう常に t を基準に計算します。
+func (iv Interval) Back(t time.Time, k int) time.Time {
+	return t.AddDate(-k*iv.Years, -k*iv.Months, -k*iv.Days).Add(-time.Duration(k) * iv.Duration)
+}
+
+// ParseSince は "--since" の値を解釈します。間隔表記（1y など）は now からの相対、
+// それ以外は YYYY-MM-DD または RFC3339 として扱います。
+func ParseSince(raw string, now time.Time) (time.Time, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return time.Time{}, fmt.Errorf("since must not be empty")
+	}
+	if iv, err := ParseInterval(trimmed); err == nil {
+		return iv.Back(now, 1), nil
+	}
+	return parseTrendTime(trimmed, now.Location())
+}
+
+// ParseUntil は "--until" の値を解釈します。空文字列は now を返します。
+func ParseUntil(raw string, now time.Time) (time.Time, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return now, nil
+	}
+	return parseTrendTime(trimmed, now.Location())
+}
+
+func parseTrendTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q (expected interval like 1y, YYYY-MM-DD or RFC3339)", raw)
+}
+
+// TrendOptions は履歴をサンプリングして件数推移を集計するためのオプションです。
+// 検出方法・タグ・パス関連の設定は通常のスキャンと同じ Options を使います。
+type TrendOptions struct {
+	Options
+	Rev      string
+	Every    Interval
+	Since    time.Time
+	Until    time.Time
+	GroupBy  string
+	DirDepth int
+}
+
+// TrendGroup はグループ（ディレクトリまたは作者）ごとの件数です。
+type TrendGroup struct {
+	Key   string         `json:"key"`
+	Total int            `json:"total"`
+	Tags  map[string]int `json:"tags"`
+}
+
+// TrendPoint は1つのサンプリング時点の集計結果です。
+type TrendPoint struct {
+	Date   string         `json:"date"`
+	Commit string         `json:"commit"`
+	Total  int            `json:"total"`
+	Tags   map[string]int `json:"tags"`
+	Groups []TrendGroup   `json:"groups,omitempty"`
+}
+
+// TrendResult は件数推移の集計結果です。
+type TrendResult struct {
+	Rev        string       `json:"rev"`
+	Every      string       `json:"every"`
+	Since      string       `json:"since"`
+	Until      string       `json:"until"`
+	GroupBy    string       `json:"group_by,omitempty"`
+	Tags       []string     `json:"tags"`
+	Points     []TrendPoint `json:"points"`
+	ElapsedMS  int64        `json:"elapsed_ms"`
+	Errors     []ItemError  `json:"errors,omitempty"`
+	ErrorCount int          `json:"error_count"`
+}
+
+type trendCommit struct {
+	sha  string
+	time time.Time
+}
+
+type trendFileCounts struct {
+	tags   map[string]int
+	groups map[string]map[string]int
+	errs   []ItemError
+}
+
+// Trend は first-parent 履歴を一定間隔でサンプリングし、各時点のタグ件数を集計します。
+//
+// 各時点のツリーは checkout せずに git grep <rev> と git cat-file で読み出し、
+// 同じ blob の集計結果は時点をまたいで再利用します。
+func Trend(opts TrendOptions) (*TrendResult, error) {
+	return TrendContext(context.Background(), opts)
+}
+
+// TrendContext は ctx が取り消された時点で、実行中の git コマンドを止めてエラーを返します。
+func TrendContext(parent context.Context, opts TrendOptions) (*TrendResult, error) {
+	start := time.Now()
+	if opts.Every.IsZero() {
+		return nil, fmt.Errorf("--every must be specified")
+	}
+	if opts.Jobs <= 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	if strings.TrimSpace(opts.Rev) == "" {
+		opts.Rev = "HEAD"
+	}
+	if opts.Until.IsZero() {
+		opts.Until = time.Now()
+	}
+	if opts.Since.IsZero() {
+		opts.Since = opts.Until.AddDate(-1, 0, 0)
+	}
+	if opts.Since.After(opts.Until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+	groupBy, err := normalizeTrendGroupBy(opts.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	opts.GroupBy = groupBy
+	if opts.DirDepth <= 0 {
+		opts.DirDepth = 1
+	}
+	mode := strings.ToLower(strings.TrimSpace(opts.DetectMode))
+	switch mode {
+	case "", "auto", "parse", "regex":
+	default:
+		return nil, fmt.Errorf("invalid detect mode: %s", opts.DetectMode)
+	}
+
+	tags := effectiveTags(opts.Tags)
+	searchTags, err := resolveSearchTags(opts.Type, tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.PathRegexCompiled) == 0 && len(opts.PathRegex) > 0 {
+		compiled, compileErr := CompilePathRegex(opts.PathRegex)
+		if compileErr != nil {
+			return nil, fmt.Errorf("invalid --path-regex: %w", compileErr)
+		}
+		opts.PathRegexCompiled = compiled
+	}
+	if opts.LanguagesCompiled == nil && len(opts.Languages) > 0 {
+		langs, langErr := CompileLanguages(opts.Languages)
+		if langErr != nil {
+			return nil, langErr
+		}
+		opts.LanguagesCompiled = langs
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	commits, err := firstParentCommits(ctx, opts.RepoDir, opts.Rev)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := trendSampleTimes(opts.Every, opts.Since, opts.Until)
+	res := &TrendResult{
+		Rev:     opts.Rev,
+		Every:   opts.Every.String(),
+		Since:   formatTrendDate(opts.Since, opts.Every),
+		Until:   formatTrendDate(opts.Until, opts.Every),
+		GroupBy: opts.GroupBy,
+		Tags:    normalizedTags(searchTags),
+	}
+
+	specs, err := compileTagSpecs(searchTags, opts.TagRules)
+	if err != nil {
+		return nil, err
+	}
+	t := &trendCounter{
+		opts:    opts,
+		tags:    searchTags,
+		specs:   specs,
+		aliases: newAuthorAliases(opts.AuthorAliases),
+		cache:   make(map[string]*trendFileCounts),
+	}
+	pointsByCommit := make(map[string]TrendPoint)
+	for _, sample := range samples {
+		commit, ok := commitAtOrBefore(commits, sample)
+		if !ok {
+			continue
+		}
+		point, seen := pointsByCommit[commit.sha]
+		if !seen {
+			point, err = t.countAt(ctx, commit.sha)
+			if err != nil {
+				return nil, err
+			}
+			pointsByCommit[commit.sha] = point
+		}
+		point.Date = formatTrendDate(sample, opts.Every)
+		res.Points = append(res.Points, point)
+	}
+	res.Errors = t.errs
+	res.ErrorCount = len(t.errs)
+	res.ElapsedMS = msSince(start)
+	return res, nil
+}
+
+func normalizeTrendGroupBy(raw string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(raw)); v {
+	case "", "none":
+		return "", nil
+	case "dir", "author":
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid --group-by: %s (allowed: dir, author)", raw)
+	}
+}
+
+// trendSampleTimes は until から every 間隔でさかのぼった時刻を古い順に返します。
+func trendSampleTimes(every Interval, since, until time.Time) []time.Time {
+	var out []time.Time
+	for k := 0; k < trendMaxSamples; k++ {
+		t := every.Back(until, k)
+		if t.Before(since) {
+			break
+		}
+		out = append(out, t)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func formatTrendDate(t time.Time, every Interval) string {
+	if every.Duration != 0 {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format("2006-01-02")
+}
+
+// commitAtOrBefore は新しい順に並んだコミット列から、t 時点の先端だったコミットを返します。
+func commitAtOrBefore(commits []trendCommit, t time.Time) (trendCommit, bool) {
+	for _, c := range commits {
+		if !c.time.After(t) {
+			return c, true
+		}
+	}
+	return trendCommit{}, false
+}
+
+func firstParentCommits(ctx context.Context, repo, rev string) ([]trendCommit, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--first-parent", "--format=%H%x09%ct", rev, "--")
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w", rev, err)
+	}
+	var commits []trendCommit
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(sc.Text()), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		ts, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		commits = append(commits, trendCommit{sha: parts[0], time: time.Unix(ts, 0)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("git log scan: %w", err)
+	}
+	return commits, nil
+}
+
+type trendCounter struct {
+	opts    TrendOptions
+	tags    []string
+	specs   []tagSpec
+	aliases authorAliases
+
+	mu    sync.Mutex
+	cache map[string]*trendFileCounts
+	errs  []ItemError
+}
+
+func (t *trendCounter) countAt(ctx context.Context, rev string) (TrendPoint, error) {
+	point := TrendPoint{Commit: rev, Tags: make(map[string]int)}
+	for _, tag := range normalizedTags(t.tags) {
+		point.Tags[tag] = 0
+	}
+	var files []string
+	var err error
+	if pattern, ok := prefilterPattern(t.tags, t.opts.TagRules); ok {
+		files, err = gitGrepFilesAtRev(ctx, t.opts.RepoDir, rev, pattern, t.opts.Paths, t.opts.Excludes, t.opts.ExcludeTypical)
+	} else {
+		files, err = gitListFilesAtRev(ctx, t.opts.RepoDir, rev, t.opts.Paths, t.opts.Excludes, t.opts.ExcludeTypical)
+	}
+	if err != nil {
+		return TrendPoint{}, err
+	}
+	files = filterPathsByRegex(files, t.opts.PathRegexCompiled)
+	if len(files) == 0 {
+		return point, nil
+	}
+	blobs, err := gitTreeBlobs(ctx, t.opts.RepoDir, rev)
+	if err != nil {
+		return TrendPoint{}, err
+	}
+
+	workers := t.opts.Jobs
+	if workers > 64 {
+		workers = 64
+	}
+	jobs := make(chan string)
+	results := make(chan *trendFileCounts)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				results <- t.countFile(ctx, rev, file, blobs[file])
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, file := range files {
+			jobs <- file
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	groups := make(map[string]map[string]int)
+	for fc := range results {
+		for tag, n := range fc.tags {
+			point.Tags[tag] += n
+			point.Total += n
+		}
+		for key, counts := range fc.groups {
+			dst := groups[key]
+			if dst == nil {
+				dst = make(map[string]int)
+				groups[key] = dst
+			}
+			for tag, n := range counts {
+				dst[tag] += n
+			}
+		}
+	}
+	// 取り消された後の countFile は集計せずに戻るため、途中までの時点は返しません。
+	if err := ctx.Err(); err != nil {
+		return TrendPoint{}, err
+	}
+	if t.opts.GroupBy != "" {
+		point.Groups = trendGroupsFromMap(groups)
+	}
+	return point, nil
+}
+
+func trendGroupsFromMap(groups map[string]map[string]int) []TrendGroup {
+	out := make([]TrendGroup, 0, len(groups))
+	for key, counts := range groups {
+		g := TrendGroup{Key: key, Tags: counts}
+		for _, n := range counts {
+			g.Total += n
+		}
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// countFile は rev 時点の file を集計します。path と blob が同じなら結果を再利用します。
+// ctx が取り消されていれば何も数えず、エラーも記録せずに空の結果を返します（countAt がエラーにします）。
+func (t *trendCounter) countFile(ctx context.Context, rev, file, blob string) *trendFileCounts {
+	if ctx.Err() != nil {
+		return &trendFileCounts{tags: make(map[string]int)}
+	}
+	key := file + "\x00" + blob
+	if blob != "" {
+		t.mu.Lock()
+		cached, ok := t.cache[key]
+		t.mu.Unlock()
+		if ok {
+			return cached
+		}
+	}
+	fc := &trendFileCounts{tags: make(map[string]int)}
+	object := blob
+	if object == "" {
+		object = rev + ":" + file
+	}
+	data, err := gitCatBlob(ctx, t.opts.RepoDir, object)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.addErr(newItemError(file, 0, "read", err))
+		}
+		return fc
+	}
+	var matches []model.Match
+	switch strings.ToLower(strings.TrimSpace(t.opts.DetectMode)) {
+	case "regex":
+		matches = scanPlainContent(file, data, t.specs)
+	case "parse":
+		matches = parseContent(file, data, t.opts.Options, t.specs, false)
+	default:
+		matches = parseContent(file, data, t.opts.Options, t.specs, true)
+	}
+	// todox:ignore で抑制した項目は推移にも数えません。
+	matches, _ = splitSuppressed(matches, false)
+
+	var authors map[int]string
+	if t.opts.GroupBy == "author" && len(matches) > 0 {
+		authors, err = blameAuthorsAtRev(ctx, t.opts.RepoDir, rev, file, t.opts.IgnoreWS, t.aliases)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fc
+			}
+			t.addErr(newItemError(file, 0, "blame", err))
+		}
+	}
+	if t.opts.GroupBy != "" {
+		fc.groups = make(map[string]map[string]int)
+	}
+	for _, m := range matches {
+		if m.Tag == "" {
+			continue
+		}
+		fc.tags[m.Tag]++
+		if fc.groups == nil {
+			continue
+		}
+		var group string
+		switch t.opts.GroupBy {
+		case "dir":
+			group = dirGroupKey(file, t.opts.DirDepth)
+		case "author":
+			group = authors[m.Span.StartLine]
+			if group == "" {
+				group = "(unknown)"
+			}
+		}
+		counts := fc.groups[group]
+		if counts == nil {
+			counts = make(map[string]int)
+			fc.groups[group] = counts
+		}
+		counts[m.Tag]++
+	}
+	if blob != "" {
+		t.mu.Lock()
+		t.cache[key] = fc
+		t.mu.Unlock()
+	}
+	return fc
+}
+
+func (t *trendCounter) addErr(err ItemError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, existing := range t.errs {
+		if existing == err {
+			return
+		}
+	}
+	t.errs = append(t.errs, err)
+}
+
+// dirGroupKey はファイルパスを先頭 depth 階層のディレクトリにまとめます。ルート直下は "." です。
+func dirGroupKey(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." || dir == "/" {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if depth > 0 && len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+func gitGrepFilesAtRev(ctx context.Context, repo, rev, pattern string, includes, excludes []string, typical bool) ([]string, error) {
+	pathspecs := buildGrepPathspecs(includes, excludes, typical)
+	args := []string{"-c", "core.quotePath=false", "grep", "-Ilz", "-i", "-E", pattern, rev, "--"}
+	args = append(args, pathspecs...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && ee.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git grep %s: %w", rev, err)
+	}
+	prefix := rev + ":"
+	var files []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) == 0 {
+			continue
+		}
+		files = append(files, strings.TrimPrefix(string(p), prefix))
+	}
+	return files, nil
+}
+
+// gitListFilesAtRev は rev のツリーにあるファイルを、git grep と同じパス指定で絞って返します。
+// 空のツリーとの差分として一覧するため、除外のパス指定もそのまま使えます。
+func gitListFilesAtRev(ctx context.Context, repo, rev string, includes, excludes []string, typical bool) ([]string, error) {
+	args := []string{"-c", "core.quotePath=false", "diff", "--name-only", "--no-renames", "--relative", "--ignore-submodules", "-z", EmptyTree, rev, "--"}
+	args = append(args, buildGrepPathspecs(includes, excludes, typical)...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-only %s: %w", rev, err)
+	}
+	var files []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) > 0 {
+			files = append(files, string(p))
+		}
+	}
+	return files, nil
+}
+
+// gitTreeBlobs は rev のツリーに含まれるファイルのパスと blob ID の対応を返します。
+func gitTreeBlobs(ctx context.Context, repo, rev string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-c", "core.quotePath=false", "ls-tree", "-r", "-z", rev)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree %s: %w", rev, err)
+	}
+	blobs := make(map[string]string)
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <file>
+		tab := bytes.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(string(entry[:tab]))
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		blobs[string(entry[tab+1:])] = fields[2]
+	}
+	return blobs, nil
+}
+
+func gitCatBlob(ctx context.Context, repo, object string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "blob", object)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return out, nil
+}
+
+// blameAuthorsAtRev は rev 時点の file について行番号から作者名への対応を返します。
+// 作者名は git blame が適用する .mailmap と、authors: の別名を反映した値です。
+func blameAuthorsAtRev(ctx context.Context, repo, rev, file string, ignoreWS bool, aliases authorAliases) (map[int]string, error) {
+	args := []string{"blame"}
+	if ignoreWS {
+		args = append(args, "-w")
+	}
+	args = append(args, "--line-porcelain", rev, "--", file)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git blame: %w", err)
+	}
+	authors := make(map[int]string)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	buf := make([]byte, 0, 1024*1024)
+	sc.Buffer(buf, 1024*1024)
+	line := 0
+	name := ""
+	expectHeader := true
+	for sc.Scan() {
+		text := sc.Text()
+		if strings.HasPrefix(text, "\t") {
+			expectHeader = true
+			continue
+		}
+		if expectHeader {
+			// <sha> <orig-line> <final-line> [<count>]
+			fields := strings.Fields(text)
+			if len(fields) >= 3 {
+				line, _ = strconv.Atoi(fields[2])
+			}
+			expectHeader = false
+			continue
+		}
+		if v, ok := strings.CutPrefix(text, "author "); ok {
+			name = v
+			authors[line] = name
+			continue
+		}
+		if v, ok := strings.CutPrefix(text, "author-mail "); ok {
+			mail := strings.TrimSuffix(strings.TrimPrefix(v, "<"), ">")
+			authors[line], _ = aliases.resolve(name, mail)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("git blame scan: %w", err)
+	}
+	return authors, nil
+}
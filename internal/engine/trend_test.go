@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	base := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"1w", time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC)},
+		{"2d", time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)},
+		{"12h", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"1mo", time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{"1m", time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{"1y", time.Date(2023, 3, 31, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		iv, err := ParseInterval(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseInterval(%q) でエラー: %v", tc.raw, err)
+		}
+		if got := iv.Back(base, 1); !got.Equal(tc.want) {
+			t.Fatalf("ParseInterval(%q).Back = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+	for _, raw := range []string{"", "w", "0d", "3x", "-1w"} {
+		if _, err := ParseInterval(raw); err == nil {
+			t.Fatalf("ParseInterval(%q) はエラーになるべきです", raw)
+		}
+	}
+}
+
+func TestParseSinceは相対指定と日付を受け付ける(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	got, err := ParseSince("1y", now)
+	if err != nil || !got.Equal(time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ParseSince(1y) = %v, %v", got, err)
+	}
+	got, err = ParseSince("2024-01-02", now)
+	if err != nil || !got.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ParseSince(2024-01-02) = %v, %v", got, err)
+	}
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Fatal("不正な --since はエラーになるべきです")
+	}
+}
+
+func TestTrendは履歴をサンプリングしてタグ件数を集計する(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	runGit(t, repo, "config", "user.name", "Alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+
+	commitAt := func(date, author string, files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			full := filepath.Join(repo, name)
+			if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				t.Fatalf("ディレクトリ作成に失敗しました: %v", err)
+			}
+			if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+				t.Fatalf("ファイル作成に失敗しました: %v", err)
+			}
+		}
+		runGit(t, repo, "add", ".")
+		cmd := exec.Command("git", "commit", "-m", "snapshot "+date, "--author", author+" <"+strings.ToLower(author)+"@example.com>")
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date+"T12:00:00Z", "GIT_COMMITTER_DATE="+date+"T12:00:00Z")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git commit に失敗しました: %v\n%s", err, stderr.String())
+		}
+	}
+
+	commitAt("2024-01-03", "Alice", map[string]string{
+		"src/a.go": "package src\n// TODO: first\n",
+	})
+	commitAt("2024-01-10", "Bob", map[string]string{
+		"src/a.go":  "package src\n// TODO: first\n// FIXME: second\n",
+		"docs/b.md": "TODO: write docs\n",
+	})
+	commitAt("2024-01-17", "Alice", map[string]string{
+		"src/a.go": "package src\n// FIXME: second\n",
+	})
+
+	every, err := ParseInterval("1w")
+	if err != nil {
+		t.Fatalf("ParseInterval: %v", err)
+	}
+	opts := TrendOptions{
+		Options: Options{RepoDir: repo, Jobs: 2},
+		Every:   every,
+		Since:   time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		Until:   time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC),
+		GroupBy: "dir",
+	}
+	res, err := Trend(opts)
+	if err != nil {
+		t.Fatalf("Trend でエラー: %v", err)
+	}
+	// 2023-12-31 はコミット前なのでスキップされ、残り3時点が古い順に並ぶ。
+	if len(res.Points) != 3 {
+		t.Fatalf("サンプル数が一致しません: got=%d points=%+v", len(res.Points), res.Points)
+	}
+	wantDates := []string{"2024-01-07", "2024-01-14", "2024-01-21"}
+	wantTotals := []int{1, 3, 2}
+	for i, p := range res.Points {
+		if p.Date != wantDates[i] {
+			t.Fatalf("point[%d].Date = %q, want %q", i, p.Date, wantDates[i])
+		}
+		if p.Total != wantTotals[i] {
+			t.Fatalf("point[%d].Total = %d, want %d (%+v)", i, p.Total, wantTotals[i], p)
+		}
+	}
+	if got := res.Points[1].Tags; got["TODO"] != 2 || got["FIXME"] != 1 {
+		t.Fatalf("タグ別件数が一致しません: %+v", got)
+	}
+	if groups := res.Points[1].Groups; len(groups) != 2 || groups[0].Key != "src" || groups[0].Total != 2 || groups[1].Key != "docs" {
+		t.Fatalf("ディレクトリ別件数が一致しません: %+v", groups)
+	}
+
+	opts.GroupBy = "author"
+	res, err = Trend(opts)
+	if err != nil {
+		t.Fatalf("Trend(author) でエラー: %v", err)
+	}
+	last := res.Points[len(res.Points)-1]
+	// src/a.go の FIXME は Bob が追加した行なので、Alice の削除コミット後も Bob に帰属する。
+	if len(last.Groups) != 1 || last.Groups[0].Key != "Bob" || last.Groups[0].Total != 2 {
+		t.Fatalf("作者別件数が一致しません: %+v", last.Groups)
+	}
+
+	// 取り消し済みの ctx では git を呼ぶ前にエラーで止まる。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := TrendContext(ctx, opts); err == nil {
+		t.Fatal("取り消し済みの ctx でもエラーになりませんでした")
+	}
+}
+
+func TestDirGroupKey(t *testing.T) {
+	cases := map[string]string{
+		"main.go":          ".",
+		"src/a.go":         "src",
+		"src/pkg/deep/x.c": "src/pkg",
+	}
+	for in, want := range cases {
+		if got := dirGroupKey(in, 2); got != want {
+			t.Fatalf("dirGroupKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTrendCounterは取り消し後に読み込みエラーを記録しない(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.name", "alice")
+	runGit(t, repo, "config", "user.email", "alice@example.com")
+	if err := os.WriteFile(filepath.Join(repo, "a.go"), []byte("package a\n// TODO: one\n"), 0o644); err != nil {
+		t.Fatalf("書き込みに失敗: %v", err)
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "initial")
+	blobs, err := gitTreeBlobs(context.Background(), repo, "HEAD")
+	if err != nil {
+		t.Fatalf("gitTreeBlobs でエラー: %v", err)
+	}
+
+	counter := &trendCounter{
+		opts:  TrendOptions{Options: Options{RepoDir: repo, Jobs: 1}},
+		tags:  []string{"TODO"},
+		specs: normalizeTags([]string{"TODO"}),
+		cache: make(map[string]*trendFileCounts),
+	}
+	// サンプルの途中で取り消されても、残りのファイルを読み込みエラーとして数えず、空の結果も再利用しない。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if fc := counter.countFile(ctx, "HEAD", "a.go", blobs["a.go"]); len(fc.tags) != 0 {
+		t.Fatalf("取り消し後に集計されました: %+v", fc.tags)
+	}
+	if len(counter.errs) != 0 || len(counter.cache) != 0 {
+		t.Fatalf("取り消し後のエラーや結果が残りました: errs=%+v cache=%d", counter.errs, len(counter.cache))
+	}
+	if fc := counter.countFile(context.Background(), "HEAD", "a.go", blobs["a.go"]); fc.tags["TODO"] != 1 {
+		t.Fatalf("取り消していない集計が一致しません: %+v", fc.tags)
+	}
+}
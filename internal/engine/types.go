@@ -10,23 +10,33 @@ import (
 
 // Item は 1 件の TODO/FIXME を表す
 type Item struct {
-	Kind      string           `json:"kind"`
-	Tag       string           `json:"tag,omitempty"`
-	Lang      string           `json:"lang,omitempty"`
-	MatchKind string           `json:"match_kind,omitempty"`
-	Text      string           `json:"text,omitempty"`
-	Span      model.Span       `json:"span"`
-	Author    string           `json:"author"`
-	Email     string           `json:"email"`
-	Date      string           `json:"date"`
-	AgeDays   int              `json:"age_days"`
-	Commit    string           `json:"commit"`
-	File      string           `json:"file"`
-	Line      int              `json:"line"`
-	Comment   string           `json:"comment,omitempty"`
-	Message   string           `json:"message,omitempty"`
-	URL       string           `json:"url,omitempty"`
-	PRs       []PullRequestRef `json:"prs,omitempty"`
+	Kind           string           `json:"kind"`
+	Tag            string           `json:"tag,omitempty"`
+	Lang           string           `json:"lang,omitempty"`
+	MatchKind      string           `json:"match_kind,omitempty"`
+	Text           string           `json:"text,omitempty"`
+	Span           model.Span       `json:"span"`
+	Cell           *model.CellRef   `json:"cell,omitempty"`
+	Author         string           `json:"author"`
+	Email          string           `json:"email"`
+	Committer      string           `json:"committer,omitempty"`
+	CommitterEmail string           `json:"committer_email,omitempty"`
+	Date           string           `json:"date"`
+	AgeDays        int              `json:"age_days"`
+	Commit         string           `json:"commit"`
+	File           string           `json:"file"`
+	Submodule      string           `json:"submodule,omitempty"`
+	Line           int              `json:"line"`
+	Comment        string           `json:"comment,omitempty"`
+	Body           string           `json:"body,omitempty"`
+	ContextBefore  []string         `json:"context_before,omitempty"`
+	ContextAfter   []string         `json:"context_after,omitempty"`
+	Message        string           `json:"message,omitempty"`
+	URL            string           `json:"url,omitempty"`
+	PRs            []PullRequestRef `json:"prs,omitempty"`
+	Owners         []string         `json:"owners,omitempty"`
+	Skipped        []SkippedCommit  `json:"skipped,omitempty"`
+	Suppressed     string           `json:"suppressed,omitempty"`
 }
 
 // PullRequestRef はコミットに紐づく PR の参照情報を表す
@@ -52,10 +62,13 @@ type Options struct {
 	Mode              string // last|first
 	DetectMode        string
 	AuthorRegex       string
+	AuthorAliases     map[string][]string // 正規名（"Name" / "Name <email>"）→ 別名（名前・メール）
+	SkipAuthors       string              // 一致する作者のコミットを飛ばして帰属させる（正規表現）
 	WithComment       bool
 	WithMessage       bool
 	IncludeStrings    bool
 	Tags              []string
+	TagRules          TagRules // 単語境界・後続文字・大文字小文字・独自正規表現による照合規則
 	TruncAll          int
 	TruncComment      int
 	TruncMessage      int
@@ -69,10 +82,28 @@ type Options struct {
 	Excludes          []string
 	PathRegex         []string
 	PathRegexCompiled []*regexp.Regexp
+	Languages         map[string]LanguageDef // 設定ファイルの languages: による言語定義の追加・上書き
+	LanguagesCompiled *LanguageSet
 	MaxFileBytes      int
 	ExcludeTypical    bool
+	IncludeGenerated  bool // 生成ファイル・vendored（.gitattributes やヘッダの目印）も走査する
 	NoPrefilter       bool
+	RecurseSubmodules bool              // 初期化済みのサブモジュールもそれぞれのリポジトリとして走査する
+	ScanArchives      bool              // 追跡されている .zip / .jar / .tar.gz / .gz の中身も走査する（blame なし）
+	ContextLines      int               // 各項目の前後に付けるソースの行数（0 = 付けない）
+	ShowSuppressed    bool              // todox:ignore や .todoxignore で抑制した項目も Suppressed 付きで出力する
+	Timeout           time.Duration     // 走査全体の上限（0 = 無制限）
+	GitTimeout        time.Duration     // 1 件ごとの blame / git log -L / git show の上限（0 = 無制限）
+	Owners            []string          // CODEOWNERS の担当者で絞り込み（先頭の @ と大文字小文字は無視）
 	ProgressObserver  progress.Observer `json:"-"`
+	OnItem            func(Item) error  `json:"-"` // 設定すると項目を見つけ次第渡し、Result.Items には保持しない
+	StreamOrder       string            // OnItem に渡す順序: file（既定: ファイルごとに行順）/ unsorted（帰属した順）
+}
+
+// Group は --group-by 指定時のグループごとの件数を表す
+type Group struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
 }
 
 // Result は出力
@@ -83,7 +114,11 @@ type Result struct {
 	HasAge     bool        `json:"has_age"`
 	HasURL     bool        `json:"has_url"`
 	HasPRs     bool        `json:"has_prs"`
+	HasOwners  bool        `json:"has_owners,omitempty"`
+	GroupBy    string      `json:"group_by,omitempty"`
+	Groups     []Group     `json:"groups,omitempty"`
 	Total      int         `json:"total"`
+	Suppressed int         `json:"suppressed,omitempty"`
 	ElapsedMS  int64       `json:"elapsed_ms"`
 	Errors     []ItemError `json:"errors,omitempty"`
 	ErrorCount int         `json:"error_count"`
@@ -0,0 +1,140 @@
+// Package lsp は Language Server Protocol の基本プロトコル（Content-Length で区切った
+// JSON-RPC 2.0）と、todox が使うメッセージ型を提供します。
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC のエラーコードです。
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message は要求・通知・応答のいずれかです。ID がなければ通知、Method がなければ応答です。
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// IsRequest は応答を必要とする要求かを返します。
+func (m *Message) IsRequest() bool { return m.Method != "" && len(m.ID) > 0 }
+
+// IsNotification は応答を返さない通知かを返します。
+func (m *Message) IsNotification() bool { return m.Method != "" && len(m.ID) == 0 }
+
+// ResponseError は応答に含めるエラーです。
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// Conn は 1 本のストリーム上の JSON-RPC 接続です。Read は 1 つのゴルーチンから呼び、
+// 書き込み系のメソッドは複数のゴルーチンから同時に呼べます。
+type Conn struct {
+	r *bufio.Reader
+
+	mu     sync.Mutex
+	w      io.Writer
+	nextID int64
+}
+
+// NewConn は r から読み w へ書く接続を返します。
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read は次のメッセージを読みます。ストリームが終わると io.EOF を返します。
+func (c *Conn) Read() (*Message, error) {
+	tp := textproto.NewReader(c.r)
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (len(header) == 0 && err == io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, &ResponseError{Code: CodeParseError, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+// Write はメッセージを 1 件書き出します。
+func (c *Conn) Write(msg *Message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// Reply は id の要求に応答します。rerr が nil でなければエラー応答になります。
+func (c *Conn) Reply(id json.RawMessage, result any, rerr *ResponseError) error {
+	msg := &Message{ID: id, Error: rerr}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		msg.Result = data
+	}
+	return c.Write(msg)
+}
+
+// Notify は通知を送ります。
+func (c *Conn) Notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(&Message{Method: method, Params: data})
+}
+
+// Request は相手に要求を送ります。応答は Read で受け取ったときに呼び出し側が扱います。
+func (c *Conn) Request(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+	return c.Write(&Message{ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: data})
+}
@@ -0,0 +1,64 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConnRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewConn(strings.NewReader(""), &buf)
+	if err := w.Reply(json.RawMessage("1"), nil, nil); err != nil {
+		t.Fatalf("Reply failed: %v", err)
+	}
+	if err := w.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: "file:///a.go", Diagnostics: []Diagnostic{}}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Content-Length: 38\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":null}") {
+		t.Fatalf("unexpected framing: %q", buf.String())
+	}
+
+	r := NewConn(&buf, io.Discard)
+	reply, err := r.Read()
+	if err != nil || reply.IsRequest() || reply.IsNotification() || string(reply.Result) != "null" {
+		t.Fatalf("unexpected reply: %+v err=%v", reply, err)
+	}
+	note, err := r.Read()
+	if err != nil || !note.IsNotification() || note.Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("unexpected notification: %+v err=%v", note, err)
+	}
+	if _, err := r.Read(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestColumnsCountUTF16Units(t *testing.T) {
+	line := "a😀é // TODO"
+	byteCol := strings.Index(line, "TODO")
+	if got := UTF16Column(line, byteCol); got != 8 {
+		t.Fatalf("UTF16Column = %d, want 8", got)
+	}
+	if got := ByteColumn(line, 8); got != byteCol {
+		t.Fatalf("ByteColumn = %d, want %d", got, byteCol)
+	}
+	if got := ByteColumn(line, 100); got != len(line) {
+		t.Fatalf("ByteColumn past the end = %d, want %d", got, len(line))
+	}
+}
+
+func TestURIConversion(t *testing.T) {
+	uri := PathToURI("/tmp/my repo/main.go")
+	if uri != "file:///tmp/my%20repo/main.go" {
+		t.Fatalf("PathToURI = %q", uri)
+	}
+	if p, ok := URIToPath(uri); !ok || p != "/tmp/my repo/main.go" {
+		t.Fatalf("URIToPath = %q, %v", p, ok)
+	}
+	if _, ok := URIToPath("untitled:Untitled-1"); ok {
+		t.Fatal("non-file URIs should be rejected")
+	}
+}
@@ -8,6 +8,7 @@ const (
 	MatchKindComment MatchKind = "comment"
 	MatchKindString  MatchKind = "string"
 	MatchKindHeredoc MatchKind = "heredoc"
+	MatchKindArchive MatchKind = "archive" // --scan-archives で見つかったアーカイブ内のファイル（blame なし）
 )
 
 // Span は 1 件の検出範囲を行・桁・バイトオフセットで表します。
@@ -20,6 +21,12 @@ type Span struct {
 	ByteEnd   int
 }
 
+// CellRef は Jupyter ノートブック内の位置（0 始まりのセル番号と、セル内の 1 始まりの行）です。
+type CellRef struct {
+	Index int `json:"index"`
+	Line  int `json:"line"`
+}
+
 // Match は構文解析またはフォールバック検出による 1 件の TODO/FIXME を表します。
 type Match struct {
 	File string
@@ -27,5 +34,9 @@ type Match struct {
 	Kind MatchKind
 	Tag  string
 	Text string
+	Body string // タグから続くコメント行までの本文（改行区切り）
 	Span Span
+	Cell *CellRef // .ipynb のセル内で見つかった場合のみ設定
+
+	Suppressed string // todox:ignore や .todoxignore で抑制された場合の理由
 }
@@ -0,0 +1,16 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+// WriteJSON renders the whole result as an indented JSON document.
+func WriteJSON(w io.Writer, res *engine.Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(res)
+}
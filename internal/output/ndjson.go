@@ -9,12 +9,21 @@ import (
 
 // WriteNDJSON streams items as newline-delimited JSON objects.
 func WriteNDJSON(w io.Writer, items []engine.Item) error {
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
+	write := NewNDJSONWriter(w)
 	for _, it := range items {
-		if err := enc.Encode(it); err != nil {
+		if err := write(it); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// NewNDJSONWriter returns a function that writes one item per line, suitable
+// for engine.Options.OnItem when items should be written as they are found.
+func NewNDJSONWriter(w io.Writer) func(engine.Item) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return func(it engine.Item) error {
+		return enc.Encode(it)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/model"
 )
 
 var sampleItems = []engine.Item{
@@ -97,6 +98,30 @@ func TestWriteMarkdownTable(t *testing.T) {
 	assertGolden(t, "want-md.md", output)
 }
 
+func TestWriteQuickfix(t *testing.T) {
+	items := append([]engine.Item{}, sampleItems...)
+	items = append(items, engine.Item{Kind: "TODO", Tag: "HACK", File: "web/app.js", Line: 3, Span: model.Span{StartCol: 8}, Text: "  x(); // hack: retry\ttwice"})
+	var buf bytes.Buffer
+	if err := WriteQuickfix(&buf, items); err != nil {
+		t.Fatalf("WriteQuickfix failed: %v", err)
+	}
+	assertGolden(t, "want-quickfix.txt", buf.String())
+}
+
+func TestWriteFZF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFZF(&buf, sampleItems); err != nil {
+		t.Fatalf("WriteFZF failed: %v", err)
+	}
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\x00"), "\x00")
+	if len(records) != len(sampleItems) || strings.Contains(buf.String(), "\n") {
+		t.Fatalf("expected %d NUL-terminated single-line records, got %q", len(sampleItems), buf.String())
+	}
+	if records[1] != "pkg/util/helpers.go:7:1: FIXME escape pipes | for markdown" {
+		t.Fatalf("unexpected record: %q", records[1])
+	}
+}
+
 func assertGolden(t *testing.T, name, got string) {
 	t.Helper()
 	path := filepath.Join("testdata", name)
@@ -123,3 +148,42 @@ func diffStrings(want, got string) string {
 	buf.WriteString(got)
 	return buf.String()
 }
+
+func TestWriteTrendCSV(t *testing.T) {
+	res := &engine.TrendResult{
+		Tags: []string{"TODO", "FIXME"},
+		Points: []engine.TrendPoint{
+			{Date: "2024-01-07", Commit: "aaa", Total: 1, Tags: map[string]int{"TODO": 1, "FIXME": 0}},
+			{Date: "2024-01-14", Commit: "bbb", Total: 3, Tags: map[string]int{"TODO": 2, "FIXME": 1}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteTrendCSV(&buf, res); err != nil {
+		t.Fatalf("WriteTrendCSV failed: %v", err)
+	}
+	want := "date,commit,tag,count\r\n" +
+		"2024-01-07,aaa,TODO,1\r\n2024-01-07,aaa,FIXME,0\r\n" +
+		"2024-01-14,bbb,TODO,2\r\n2024-01-14,bbb,FIXME,1\r\n"
+	if diff := diffStrings(want, buf.String()); diff != "" {
+		t.Fatalf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	res.GroupBy = "dir"
+	res.Points = []engine.TrendPoint{{
+		Date: "2024-01-14", Commit: "bbb", Total: 3,
+		Groups: []engine.TrendGroup{
+			{Key: "src", Total: 2, Tags: map[string]int{"TODO": 1, "FIXME": 1}},
+			{Key: "docs", Total: 1, Tags: map[string]int{"TODO": 1}},
+		},
+	}}
+	buf.Reset()
+	if err := WriteTrendCSV(&buf, res); err != nil {
+		t.Fatalf("WriteTrendCSV failed: %v", err)
+	}
+	want = "date,commit,dir,tag,count\r\n" +
+		"2024-01-14,bbb,src,TODO,1\r\n2024-01-14,bbb,src,FIXME,1\r\n" +
+		"2024-01-14,bbb,docs,TODO,1\r\n"
+	if diff := diffStrings(want, buf.String()); diff != "" {
+		t.Fatalf("output mismatch (-want +got):\n%s", diff)
+	}
+}
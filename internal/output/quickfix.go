@@ -0,0 +1,84 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+// WriteQuickfix writes one "file:line:col: TAG text" line per item. Vim's
+// quickfix list (:cfile / -q), Emacs compilation-mode and tools that read
+// `grep -n` output all understand this form.
+func WriteQuickfix(w io.Writer, items []engine.Item) error {
+	for _, it := range items {
+		if _, err := io.WriteString(w, QuickfixLine(it)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFZF writes the same records as WriteQuickfix terminated by NUL instead
+// of a newline, for `fzf --read0`. With `--delimiter :` the file is {1} and the
+// line is {2}, which is what preview and bind commands need.
+func WriteFZF(w io.Writer, items []engine.Item) error {
+	for _, it := range items {
+		if _, err := io.WriteString(w, QuickfixLine(it)+"\x00"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuickfixLine formats a single item as "file:line:col: TAG text". The column
+// is the 1-based byte column of the tag (1 when unknown) and the text is kept
+// on one line.
+func QuickfixLine(it engine.Item) string {
+	col := it.Span.StartCol
+	if col < 1 {
+		col = 1
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", it.File, it.Line, col, quickfixText(it))
+}
+
+// quickfixText prefers the extracted comment and otherwise cuts the source
+// line at the tag, so the message always starts with the tag.
+func quickfixText(it engine.Item) string {
+	tag := it.Tag
+	if tag == "" {
+		tag = it.Kind
+	}
+	text := strings.TrimSpace(it.Comment)
+	if text == "" {
+		text = strings.TrimSpace(it.Text)
+		if i := indexFold(text, tag); i > 0 {
+			text = text[i:]
+		}
+	}
+	text = strings.Join(strings.Fields(strings.ReplaceAll(text, "\x00", "")), " ")
+	switch {
+	case tag == "":
+		return text
+	case text == "":
+		return tag
+	case indexFold(text, tag) == 0:
+		return text
+	}
+	return tag + " " + text
+}
+
+// indexFold returns the byte offset of the first case-insensitive occurrence
+// of sub in s, or -1.
+func indexFold(s, sub string) int {
+	if sub == "" {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
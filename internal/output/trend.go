@@ -0,0 +1,46 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/phyten/todox/internal/engine"
+)
+
+// WriteTrendCSV renders trend samples in long format (one row per date and tag,
+// plus the group column when grouping is enabled) so spreadsheets can pivot them.
+func WriteTrendCSV(w io.Writer, res *engine.TrendResult) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	header := []string{"date", "commit", "tag", "count"}
+	if res.GroupBy != "" {
+		header = []string{"date", "commit", res.GroupBy, "tag", "count"}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, p := range res.Points {
+		if res.GroupBy == "" {
+			for _, tag := range res.Tags {
+				if err := writer.Write([]string{p.Date, p.Commit, tag, strconv.Itoa(p.Tags[tag])}); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for _, g := range p.Groups {
+			for _, tag := range res.Tags {
+				n, ok := g.Tags[tag]
+				if !ok {
+					continue
+				}
+				if err := writer.Write([]string{p.Date, p.Commit, g.Key, tag, strconv.Itoa(n)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
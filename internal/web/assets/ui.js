@@ -36,6 +36,10 @@
   const resetBtn = document.getElementById('reset-form');
   const fieldAutoNote = document.getElementById('field-auto-note');
   const prOptions = document.getElementById('pr-options');
+  const previewPane = document.getElementById('preview');
+  const previewLocation = document.getElementById('preview-location');
+  const previewBody = document.getElementById('preview-body');
+  const previewClose = document.getElementById('preview-close');
 
   const LOCAL_STORAGE_KEY = 'todox:lastParams';
 
@@ -43,10 +47,13 @@
   let fetchAbort = null;
   let latestResult = null;
   let tableRows = [];
+  let renderedRows = [];
+  let previewKey = null;
   let sortKey = null;
   let sortDesc = false;
   let lastSnap = null;
   let rafId = 0;
+  let itemRenderTimer = 0;
 
   function showError(message) {
     if (!errorBanner || !errorMessage) {
@@ -200,6 +207,9 @@
     }
     meta.push({ key: 'commit', label: 'COMMIT' });
     meta.push({ key: 'location', label: 'LOCATION' });
+    if (info && info.has_owners) {
+      meta.push({ key: 'owners', label: 'OWNER' });
+    }
     if (info && info.has_url) {
       meta.push({ key: 'url', label: 'URL' });
     }
@@ -215,6 +225,22 @@
     return meta;
   }
 
+  function formatSkipped(value) {
+    const list = Array.isArray(value) ? value : [];
+    return list.map((sc) => {
+      const info = sc || {};
+      const commit = String(info.commit ?? '').slice(0, 8);
+      return `${commit}(${info.author ?? ''})`;
+    }).join('; ');
+  }
+
+  function formatCell(value) {
+    if (!value || typeof value !== 'object') {
+      return '';
+    }
+    return `${value.index ?? ''}:${value.line ?? ''}`;
+  }
+
   function renderPRCell(value) {
     const list = Array.isArray(value) ? value : [];
     if (!list.length) {
@@ -306,6 +332,12 @@
       }
       case 'prs':
         return renderPRCell(r.prs);
+      case 'owners':
+        return escText(Array.isArray(r.owners) ? r.owners.join(' ') : '');
+      case 'skipped':
+        return escText(formatSkipped(r.skipped));
+      case 'cell':
+        return escText(formatCell(r.cell));
       case 'comment':
         return escText(r.comment);
       case 'message':
@@ -350,13 +382,13 @@
       return `<th aria-sort="${ariaSort}"><button type="button" class="${classes.join(' ')}" data-key="${escAttr(meta.key)}">${escText(meta.label)}</button></th>`;
     }).join('');
     let tableHTML = `<table><thead><tr>${head}</tr></thead><tbody>`;
-    for (const r of rows) {
+    rows.forEach((r, index) => {
       const cells = [];
       for (const meta of headerMeta) {
         cells.push(`<td>${renderTableCell(meta.key, r, info)}</td>`);
       }
-      tableHTML += `<tr>${cells.join('')}</tr>`;
-    }
+      tableHTML += `<tr data-index="${index}">${cells.join('')}</tr>`;
+    });
     tableHTML += '</tbody></table>';
     parts.push(tableHTML);
     return parts.join('');
@@ -457,6 +489,7 @@
       case 'email':
       case 'kind':
       case 'comment':
+      case 'body':
       case 'message':
       case 'url': {
         const val = r[key];
@@ -469,6 +502,8 @@
       }
       case 'prs':
         return !(Array.isArray(r.prs) && r.prs.length > 0);
+      case 'cell':
+        return !(r.cell && typeof r.cell === 'object');
       default: {
         const val = r[key];
         return val == null || String(val).trim() === '';
@@ -548,10 +583,89 @@
   function renderTableWithSort() {
     const base = (latestResult && typeof latestResult === 'object') ? latestResult : {};
     const sorted = sortRows(tableRows, sortKey, sortDesc);
+    renderedRows = sorted;
     if (resultTable) {
       resultTable.innerHTML = renderResultTable(base, { rows: sorted, sortKey, sortDesc });
       attachSortHandlers();
+      attachRowHandlers();
     }
+    refreshPreview();
+  }
+
+  function rowKey(row) {
+    return row ? `${row.file || ''}:${row.line || 0}` : '';
+  }
+
+  function attachRowHandlers() {
+    if (!resultTable) {
+      return;
+    }
+    for (const tr of resultTable.querySelectorAll('tbody tr[data-index]')) {
+      tr.addEventListener('click', (ev) => {
+        // リンクのクリックはそのまま開く
+        if (ev.target instanceof Element && ev.target.closest('a')) {
+          return;
+        }
+        const row = renderedRows[Number(tr.getAttribute('data-index'))];
+        previewKey = rowKey(row);
+        refreshPreview();
+      });
+    }
+  }
+
+  // refreshPreview は選んだ行の context_before／context_after を前後に並べて表示します。
+  // 再スキャンで行が消えたときはプレビューを閉じます。
+  function refreshPreview() {
+    if (!previewPane || !previewBody) {
+      return;
+    }
+    let index = -1;
+    if (previewKey) {
+      index = renderedRows.findIndex((r) => rowKey(r) === previewKey);
+    }
+    if (resultTable) {
+      for (const tr of resultTable.querySelectorAll('tbody tr.selected')) {
+        tr.classList.remove('selected');
+      }
+      const tr = index >= 0 ? resultTable.querySelector(`tbody tr[data-index="${index}"]`) : null;
+      if (tr) {
+        tr.classList.add('selected');
+      }
+    }
+    if (index < 0) {
+      previewKey = null;
+      previewPane.hidden = true;
+      return;
+    }
+    const row = renderedRows[index];
+    const before = Array.isArray(row.context_before) ? row.context_before : [];
+    const after = Array.isArray(row.context_after) ? row.context_after : [];
+    const line = Number(row.line) || 0;
+    const span = row.span || {};
+    const start = Number(span.StartLine) || line;
+    const end = Math.max(Number(span.EndLine) || start, start);
+    const numbered = (n, text, cls) => `<span class="${cls}"><span class="ln">${n > 0 ? n : ''}</span>${escText(text)}</span>`;
+    const parts = [];
+    before.forEach((text, i) => {
+      parts.push(numbered(start - before.length + i, text, 'src'));
+    });
+    parts.push(numbered(start, row.comment || row.text || '', 'src hit'));
+    after.forEach((text, i) => {
+      parts.push(numbered(end + 1 + i, text, 'src'));
+    });
+    previewBody.innerHTML = parts.join('');
+    if (previewLocation) {
+      const note = before.length || after.length ? '' : '（前後の行を表示するには context を 1 以上にしてください）';
+      previewLocation.textContent = `${row.file || ''}:${line}${note}`;
+    }
+    previewPane.hidden = false;
+  }
+
+  if (previewClose) {
+    previewClose.addEventListener('click', () => {
+      previewKey = null;
+      refreshPreview();
+    });
   }
 
   function updateResultData(data) {
@@ -738,7 +852,29 @@
     rafId = requestAnimationFrame(renderProgressOnce);
   }
 
+  // item イベントは件数が多いので、表の再描画はまとめて行う
+  function scheduleItemsRender() {
+    if (itemRenderTimer) {
+      return;
+    }
+    itemRenderTimer = setTimeout(() => {
+      itemRenderTimer = 0;
+      renderTableWithSort();
+      if (resultCount) {
+        resultCount.textContent = `結果: ${tableRows.length} 件（走査中）`;
+      }
+    }, 200);
+  }
+
+  function clearItemsRender() {
+    if (itemRenderTimer) {
+      clearTimeout(itemRenderTimer);
+      itemRenderTimer = 0;
+    }
+  }
+
   function closeStream() {
+    clearItemsRender();
     if (es) {
       try { es.close(); } catch (_err) {}
       es = null;
@@ -824,6 +960,16 @@
       return;
     }
 
+    // serve --watch では最初の result の後も接続を保ち、変更のたびに result が届く
+    let watching = false;
+    es.addEventListener('watch', () => {
+      if (watching) {
+        // 再接続するとサーバーは走査をやり直し、item イベントを最初から送り直す
+        tableRows = [];
+      }
+      watching = true;
+    });
+
     es.addEventListener('progress', (ev) => {
       try {
         lastSnap = JSON.parse(ev.data);
@@ -833,16 +979,38 @@
       }
     });
 
+    es.addEventListener('item', (ev) => {
+      try {
+        tableRows.push(JSON.parse(ev.data));
+        scheduleItemsRender();
+      } catch (parseErr) {
+        console.warn('item parse failed', parseErr);
+      }
+    });
+
     es.addEventListener('result', (ev) => {
       try {
         const res = JSON.parse(ev.data);
+        const keepSort = watching && latestResult ? { key: sortKey, desc: sortDesc } : null;
+        clearItemsRender();
         hideProgressUI();
+        // 項目を item イベントで送り終えたとき、result は集計だけで items を含まない
+        if (res && typeof res === 'object' && !Array.isArray(res.items)) {
+          res.items = tableRows;
+        }
         updateResultData(res);
+        if (keepSort && keepSort.key) {
+          sortKey = keepSort.key;
+          sortDesc = keepSort.desc;
+          renderTableWithSort();
+        }
       } catch (parseErr) {
         console.error(parseErr);
         showError(parseErr instanceof Error ? parseErr.message : String(parseErr));
       } finally {
-        closeStream();
+        if (!watching) {
+          closeStream();
+        }
       }
     });
 
@@ -940,6 +1108,16 @@
         }
         continue;
       }
+      if (el.dataset.lines != null) {
+        const lines = String(el.value || '')
+          .split('\n')
+          .map((s) => s.trim())
+          .filter((s) => s !== '');
+        for (const line of lines) {
+          params.append(name, line);
+        }
+        continue;
+      }
       if (el.dataset.multi != null) {
         const values = toCSVList(el.value || '');
         for (const value of values) {
@@ -1065,6 +1243,20 @@
     if (tags.length) {
       args.push('--tags', tags.join(','));
     }
+    if (params.get('tag_word_boundary') === '1') {
+      args.push('--tag-word-boundary');
+    }
+    const tagSuffix = params.get('tag_suffix');
+    if (tagSuffix) {
+      args.push('--tag-suffix', tagSuffix);
+    }
+    const tagCase = getAll('tag_case_sensitive');
+    if (tagCase.length) {
+      args.push('--tag-case-sensitive', tagCase.join(','));
+    }
+    for (const pattern of getAll('tag_pattern')) {
+      args.push('--tag-pattern', pattern);
+    }
     if (params.get('comments_only') === '1') {
       args.push('--comments-only');
     } else if (params.get('include_strings') === '1') {
@@ -1079,6 +1271,15 @@
     if (params.get('no_prefilter') === '1') {
       args.push('--no-prefilter');
     }
+    if (params.get('recurse_submodules') === '1') {
+      args.push('--recurse-submodules');
+    }
+    if (params.get('scan_archives') === '1') {
+      args.push('--scan-archives');
+    }
+    if (params.get('show_suppressed') === '1') {
+      args.push('--show-suppressed');
+    }
     const pathValues = getAll('path');
     for (const value of pathValues) {
       args.push('--path', value);
@@ -1094,6 +1295,9 @@
     if (params.get('exclude_typical') === '1') {
       args.push('--exclude-typical');
     }
+    if (params.get('include_generated') === '1') {
+      args.push('--include-generated');
+    }
     const fields = getAll('fields');
     if (fields.length) {
       args.push('--fields', fields.join(','));
@@ -1110,6 +1314,10 @@
     if (params.get('with_commit_link') === '1') {
       args.push('--with-commit-link');
     }
+    const contextLines = params.get('context');
+    if (contextLines && contextLines !== '0') {
+      args.push('--context', contextLines);
+    }
     if (params.get('with_pr_links') === '1') {
       args.push('--with-pr-links');
       const prState = params.get('pr_state');
@@ -1141,6 +1349,18 @@
     if (sort) {
       args.push('--sort', sort);
     }
+    const owners = params.get('owners');
+    if (owners) {
+      args.push('--owners', owners);
+    }
+    const skipAuthors = params.get('skip_authors');
+    if (skipAuthors) {
+      args.push('--skip-authors', skipAuthors);
+    }
+    const groupBy = params.get('group_by');
+    if (groupBy) {
+      args.push('--group-by', groupBy);
+    }
     const ignoreWS = params.get('ignore_ws');
     if (ignoreWS === '0') {
       args.push('--no-ignore-ws');
@@ -1308,9 +1528,9 @@
     };
 
     for (const [key, values] of grouped.entries()) {
-      if (['path', 'exclude', 'path_regex'].includes(key)) {
+      if (['path', 'exclude', 'path_regex', 'tag_pattern'].includes(key)) {
         setTextareaValues(key, values);
-      } else if (['detect_langs', 'tags'].includes(key)) {
+      } else if (['detect_langs', 'tags', 'tag_case_sensitive'].includes(key)) {
         const el = form.elements.namedItem(key);
         if (el instanceof HTMLInputElement) {
           el.value = values.join(',');
@@ -1415,6 +1635,12 @@
         return `${r.file ?? ''}:${r.line ?? ''}`;
       case 'url':
         return String(r.url ?? '');
+      case 'owners':
+        return Array.isArray(r.owners) ? r.owners.join(' ') : '';
+      case 'skipped':
+        return formatSkipped(r.skipped);
+      case 'cell':
+        return formatCell(r.cell);
       case 'prs': {
         const list = Array.isArray(r.prs) ? r.prs : [];
         return list.map((pr) => {
@@ -1478,6 +1704,140 @@
     }
   }
 
+  const TREND_MAX_SERIES = 8;
+
+  function trendSeries(data) {
+    const points = (data && Array.isArray(data.points)) ? data.points : [];
+    if (data && data.group_by) {
+      const totals = new Map();
+      for (const point of points) {
+        for (const group of point.groups || []) {
+          totals.set(group.key, (totals.get(group.key) || 0) + (group.total || 0));
+        }
+      }
+      const keys = Array.from(totals.keys())
+        .sort((a, b) => (totals.get(b) - totals.get(a)) || (a < b ? -1 : a > b ? 1 : 0))
+        .slice(0, TREND_MAX_SERIES);
+      return keys.map((key) => ({
+        name: key,
+        values: points.map((point) => {
+          const found = (point.groups || []).find((group) => group.key === key);
+          return found ? (found.total || 0) : 0;
+        }),
+      }));
+    }
+    const tags = (data && Array.isArray(data.tags)) ? data.tags : [];
+    return tags.slice(0, TREND_MAX_SERIES).map((tag) => ({
+      name: tag,
+      values: points.map((point) => (point.tags && point.tags[tag]) || 0),
+    }));
+  }
+
+  function buildTrendChart(data) {
+    const points = (data && Array.isArray(data.points)) ? data.points : [];
+    if (points.length === 0) {
+      return '<p class="muted">サンプル対象のコミットがありません。</p>';
+    }
+    const series = trendSeries(data);
+    const width = 720;
+    const height = 240;
+    const padL = 40;
+    const padR = 12;
+    const padT = 12;
+    const padB = 28;
+    let max = 0;
+    for (const s of series) {
+      for (const v of s.values) {
+        if (v > max) {
+          max = v;
+        }
+      }
+    }
+    if (max === 0) {
+      max = 1;
+    }
+    const plotW = width - padL - padR;
+    const plotH = height - padT - padB;
+    const xAt = (i) => padL + (points.length === 1 ? plotW / 2 : (plotW * i) / (points.length - 1));
+    const yAt = (v) => padT + plotH - (plotH * v) / max;
+    const parts = [];
+    parts.push(`<svg viewBox="0 0 ${width} ${height}" role="img" aria-label="件数の推移">`);
+    parts.push(`<line class="trend-axis" x1="${padL}" y1="${padT + plotH}" x2="${width - padR}" y2="${padT + plotH}"></line>`);
+    parts.push(`<line class="trend-axis" x1="${padL}" y1="${padT}" x2="${padL}" y2="${padT + plotH}"></line>`);
+    parts.push(`<text class="trend-label" x="${padL - 6}" y="${padT + 4}" text-anchor="end">${escText(max)}</text>`);
+    parts.push(`<text class="trend-label" x="${padL - 6}" y="${padT + plotH}" text-anchor="end">0</text>`);
+    const first = points[0].date || '';
+    const last = points[points.length - 1].date || '';
+    parts.push(`<text class="trend-label" x="${padL}" y="${height - 8}">${escText(first)}</text>`);
+    parts.push(`<text class="trend-label" x="${width - padR}" y="${height - 8}" text-anchor="end">${escText(last)}</text>`);
+    series.forEach((s, idx) => {
+      const coords = s.values.map((v, i) => `${xAt(i).toFixed(1)},${yAt(v).toFixed(1)}`).join(' ');
+      parts.push(`<polyline class="trend-line trend-series-${idx}" points="${coords}"><title>${escText(s.name)}</title></polyline>`);
+    });
+    parts.push('</svg>');
+    parts.push('<ul class="trend-legend">');
+    series.forEach((s, idx) => {
+      const latest = s.values.length > 0 ? s.values[s.values.length - 1] : 0;
+      parts.push(`<li><span class="trend-swatch trend-series-${idx}"></span>${escText(s.name)} (${escText(latest)})</li>`);
+    });
+    parts.push('</ul>');
+    return parts.join('');
+  }
+
+  const trendForm = document.getElementById('trend-form');
+  const trendChart = document.getElementById('trend-chart');
+  const trendStatus = document.getElementById('trend-status');
+  let trendAbort = null;
+
+  function startTrend(ev) {
+    ev.preventDefault();
+    hideError();
+    if (trendAbort) {
+      trendAbort.abort();
+    }
+    const params = buildParamsFromForm();
+    for (const [key, value] of new FormData(trendForm).entries()) {
+      if (String(value).trim() !== '') {
+        params.set(key, String(value));
+      }
+    }
+    if (trendStatus) {
+      trendStatus.hidden = false;
+      trendStatus.textContent = '履歴をサンプリングしています…';
+    }
+    trendAbort = new AbortController();
+    fetch(`/api/trend?${params.toString()}`, { signal: trendAbort.signal })
+      .then((res) => res.text().then((raw) => ({ res, raw })))
+      .then(({ res, raw }) => {
+        if (!res.ok) {
+          throw new Error(`HTTP ${res.status}: ${raw.trim()}`);
+        }
+        const data = JSON.parse(raw);
+        if (trendChart) {
+          trendChart.innerHTML = buildTrendChart(data);
+        }
+        if (trendStatus) {
+          trendStatus.textContent = `${(data.points || []).length} 時点 / ${data.elapsed_ms} ms`;
+        }
+      })
+      .catch((err) => {
+        if (err && err.name === 'AbortError') {
+          return;
+        }
+        if (trendStatus) {
+          trendStatus.hidden = true;
+        }
+        showError(err instanceof Error ? err.message : String(err));
+      })
+      .finally(() => {
+        trendAbort = null;
+      });
+  }
+
+  if (trendForm) {
+    trendForm.addEventListener('submit', startTrend);
+  }
+
   function applyPreset(name) {
     switch (name) {
       case 'precise':
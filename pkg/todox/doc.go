@@ -0,0 +1,26 @@
+// Package todox is the public Go API of the todox scanner.
+//
+// It finds TODO/FIXME comments in a git repository and attributes each one to
+// the commit that introduced (or last touched) it, the same way the todox
+// command does:
+//
+//	res, err := todox.Scan(ctx, todox.Options{RepoDir: ".", WithComment: true})
+//	if err != nil {
+//		return err
+//	}
+//	for _, it := range res.Items {
+//		fmt.Printf("%s:%d %s %s\n", it.File, it.Line, it.Author, it.Comment)
+//	}
+//
+// ScanEach hands items to a callback as soon as they are attributed, and the
+// Write* functions render results in the formats supported by the command
+// (JSON, NDJSON, CSV and Markdown).
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are not removed or changed incompatibly, and the JSON encoding of
+// Item (as written by WriteJSON and WriteNDJSON) only gains new optional keys:
+// existing keys keep their names, types and meaning. Everything under
+// internal/ may change at any time.
+package todox
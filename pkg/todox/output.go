@@ -0,0 +1,85 @@
+package todox
+
+import (
+	"io"
+
+	"github.com/phyten/todox/internal/output"
+)
+
+// Fields is a column selection for the tabular writers (CSV and Markdown).
+type Fields struct {
+	sel output.FieldSelection
+}
+
+// DefaultFields returns the columns the command prints when --fields is not
+// given: type, author, email, date, commit and location, plus comment and
+// message when requested.
+func DefaultFields(withComment, withMessage bool) Fields {
+	sel, _ := output.ResolveFields("", withComment, withMessage, false, false, false)
+	return Fields{sel: sel}
+}
+
+// ParseFields parses a comma-separated column list in the --fields syntax
+// ("type,author,location,comment"). Unknown columns are an error.
+func ParseFields(spec string) (Fields, error) {
+	if spec == "" {
+		return DefaultFields(false, false), nil
+	}
+	sel, err := output.ResolveFields(spec, false, false, false, false, false)
+	if err != nil {
+		return Fields{}, err
+	}
+	return Fields{sel: sel}, nil
+}
+
+// Keys returns the selected column names in order.
+func (f Fields) Keys() []string {
+	keys := make([]string, len(f.sel.Fields))
+	for i, field := range f.sel.Fields {
+		keys[i] = field.Key
+	}
+	return keys
+}
+
+// Headers returns the column headers in order.
+func (f Fields) Headers() []string {
+	return output.Headers(f.sel.Fields)
+}
+
+// Values returns the column values of it in order, formatted as in the
+// command's tabular outputs.
+func (f Fields) Values(it Item) []string {
+	return output.RowValues(it, f.sel.Fields)
+}
+
+// WriteJSON writes res as an indented JSON document (the --output json format).
+func WriteJSON(w io.Writer, res *Result) error {
+	return output.WriteJSON(w, res)
+}
+
+// WriteNDJSON writes one JSON object per item (the --output ndjson format).
+func WriteNDJSON(w io.Writer, items []Item) error {
+	return output.WriteNDJSON(w, items)
+}
+
+// WriteQuickfix writes one "file:line:col: TAG text" line per item (the
+// --output quickfix format read by Vim, Emacs and grep -n consumers).
+func WriteQuickfix(w io.Writer, items []Item) error {
+	return output.WriteQuickfix(w, items)
+}
+
+// WriteFZF writes the quickfix records NUL-terminated for fzf --read0 (the
+// --output fzf format).
+func WriteFZF(w io.Writer, items []Item) error {
+	return output.WriteFZF(w, items)
+}
+
+// WriteCSV writes items as RFC 4180 CSV with a header row (the --output csv format).
+func WriteCSV(w io.Writer, items []Item, fields Fields) error {
+	return output.WriteCSV(w, items, fields.sel)
+}
+
+// WriteMarkdown writes items as a GitHub Flavored Markdown table (the --output md format).
+func WriteMarkdown(w io.Writer, items []Item, fields Fields) error {
+	return output.WriteMarkdownTable(w, items, fields.sel)
+}
@@ -0,0 +1,177 @@
+package todox
+
+import (
+	"context"
+	"time"
+
+	"github.com/phyten/todox/internal/engine"
+	"github.com/phyten/todox/internal/model"
+)
+
+type (
+	// Result is the outcome of a scan: the items plus totals and per-item errors.
+	Result = engine.Result
+	// Item is one TODO/FIXME with its location and attribution.
+	Item = engine.Item
+	// ItemError describes a file or line that could not be read or attributed.
+	ItemError = engine.ItemError
+	// Group is the per-key count reported when results are grouped.
+	Group = engine.Group
+	// PullRequestRef is a pull request associated with an item's commit.
+	PullRequestRef = engine.PullRequestRef
+	// SkippedCommit is a commit passed over by Options.SkipAuthors.
+	SkippedCommit = engine.SkippedCommit
+	// Span is the 1-based line/column and 0-based byte range of a match.
+	Span = model.Span
+	// CellRef locates a match inside a Jupyter notebook cell.
+	CellRef = model.CellRef
+	// TagRules tightens how tags are matched (word boundaries, suffixes, case, custom patterns).
+	TagRules = engine.TagRules
+	// LanguageDef adds or overrides the comment syntax of a language.
+	LanguageDef = engine.LanguageDef
+)
+
+// Options configures a scan. The zero value scans the current directory for
+// TODO and FIXME in auto-detect mode, attributing each line to its last commit.
+type Options struct {
+	// RepoDir is the repository (or a directory inside it) to scan. Empty means the current directory.
+	RepoDir string
+	// Type is "todo", "fixme" or "both" (default).
+	Type string
+	// Mode is "last" (default: the commit that last touched the line) or "first" (the commit that introduced it).
+	Mode string
+	// Detect is "auto" (default), "parse" or "regex".
+	Detect string
+	// DetectLangs restricts parser-based detection to these languages.
+	DetectLangs []string
+	// Tags replaces the default tag set (TODO, FIXME).
+	Tags []string
+	// TagRules tightens tag matching.
+	TagRules TagRules
+	// Languages adds or overrides language definitions, keyed by language name.
+	Languages map[string]LanguageDef
+	// IncludeStrings also reports tags inside string literals.
+	IncludeStrings bool
+
+	// Author keeps only items whose author name or email matches this regular expression.
+	Author string
+	// AuthorAliases maps a canonical identity ("Name" or "Name <email>") to its aliases.
+	AuthorAliases map[string][]string
+	// SkipAuthors re-attributes items whose commit author matches this regular expression (Mode "last" only).
+	SkipAuthors string
+	// IgnoreWhitespace ignores whitespace-only changes when blaming.
+	IgnoreWhitespace bool
+	// Owners keeps only items owned by one of these CODEOWNERS owners.
+	Owners []string
+
+	// Paths limits the scan to these pathspecs.
+	Paths []string
+	// Excludes drops these pathspecs or globs.
+	Excludes []string
+	// PathRegex keeps only files whose path matches any of these regular expressions.
+	PathRegex []string
+	// ExcludeTypical drops vendor/, node_modules/, dist/, build/, target/ and *.min.*.
+	ExcludeTypical bool
+	// IncludeGenerated also scans generated and vendored files.
+	IncludeGenerated bool
+	// RecurseSubmodules also scans initialized submodules.
+	RecurseSubmodules bool
+	// ScanArchives also scans files inside tracked zip, jar and gzip archives.
+	ScanArchives bool
+	// ShowSuppressed keeps items silenced by todox:ignore markers or .todoxignore.
+	ShowSuppressed bool
+	// NoPrefilter disables the git grep prefilter before parsing.
+	NoPrefilter bool
+	// MaxFileBytes falls back to the plain-text scanner above this size (0 = unlimited).
+	MaxFileBytes int
+
+	// WithComment fills Item.Comment and Item.Body.
+	WithComment bool
+	// WithMessage fills Item.Message with the commit subject.
+	WithMessage bool
+	// Truncate, TruncateComment and TruncateMessage shorten comments and messages to
+	// this many display columns (0 = no limit).
+	Truncate        int
+	TruncateComment int
+	TruncateMessage int
+	// Context fills Item.ContextBefore and Item.ContextAfter with this many
+	// source lines around each item (0 = none).
+	Context int
+
+	// Timeout aborts the whole scan after this duration (0 = no limit).
+	Timeout time.Duration
+	// GitTimeout limits each blame or git log -L call; items that exceed it
+	// are reported in Result.Errors and the scan continues (0 = no limit).
+	GitTimeout time.Duration
+	// StreamOrder is the order in which ScanEach delivers items: "file" (default)
+	// hands over each file's items in line order once the whole file is
+	// attributed, "unsorted" hands over every item as soon as it is attributed.
+	StreamOrder string
+	// Jobs is the number of parallel workers (0 = number of CPUs).
+	Jobs int
+	// Now is the reference time for Item.AgeDays (zero = time.Now()).
+	Now time.Time
+}
+
+func (o Options) engineOptions() engine.Options {
+	return engine.Options{
+		Type:              o.Type,
+		Mode:              o.Mode,
+		DetectMode:        o.Detect,
+		AuthorRegex:       o.Author,
+		AuthorAliases:     o.AuthorAliases,
+		SkipAuthors:       o.SkipAuthors,
+		WithComment:       o.WithComment,
+		WithMessage:       o.WithMessage,
+		IncludeStrings:    o.IncludeStrings,
+		Tags:              o.Tags,
+		TagRules:          o.TagRules,
+		TruncAll:          o.Truncate,
+		TruncComment:      o.TruncateComment,
+		TruncMessage:      o.TruncateMessage,
+		IgnoreWS:          o.IgnoreWhitespace,
+		Jobs:              o.Jobs,
+		RepoDir:           o.RepoDir,
+		Now:               o.Now,
+		DetectLangs:       o.DetectLangs,
+		Paths:             o.Paths,
+		Excludes:          o.Excludes,
+		PathRegex:         o.PathRegex,
+		Languages:         o.Languages,
+		MaxFileBytes:      o.MaxFileBytes,
+		ExcludeTypical:    o.ExcludeTypical,
+		IncludeGenerated:  o.IncludeGenerated,
+		NoPrefilter:       o.NoPrefilter,
+		RecurseSubmodules: o.RecurseSubmodules,
+		ScanArchives:      o.ScanArchives,
+		ContextLines:      o.Context,
+		ShowSuppressed:    o.ShowSuppressed,
+		Timeout:           o.Timeout,
+		GitTimeout:        o.GitTimeout,
+		Owners:            o.Owners,
+		StreamOrder:       o.StreamOrder,
+	}
+}
+
+// Scan scans the repository and returns every item sorted by file and line.
+// Invalid options (an unknown Type, a bad regular expression, ...) are
+// reported as an error; failures on individual files or lines end up in
+// Result.Errors instead. When ctx is done or Options.Timeout passes, the
+// running git commands are stopped and Scan returns an error wrapping
+// ctx.Err().
+func Scan(ctx context.Context, opts Options) (*Result, error) {
+	return engine.RunContext(ctx, opts.engineOptions())
+}
+
+// ScanEach scans the repository and calls fn for each item while the scan is
+// still running, so the first items arrive early and memory stays bounded.
+// Calls to fn are never concurrent. Files arrive in the order they finish;
+// see Options.StreamOrder for the order within them. If fn returns an error,
+// ScanEach stops the scan and returns that error. The returned Result carries
+// the totals and errors of the scan; its Items are nil because they were
+// handed to fn.
+func ScanEach(ctx context.Context, opts Options, fn func(Item) error) (*Result, error) {
+	eopts := opts.engineOptions()
+	eopts.OnItem = fn
+	return engine.RunContext(ctx, eopts)
+}
@@ -0,0 +1,130 @@
+package todox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/phyten/todox/pkg/todox"
+)
+
+// fullItem sets every field so the golden file pins the complete JSON encoding of Item.
+var fullItem = todox.Item{
+	Kind:           "TODO",
+	Tag:            "TODO",
+	Lang:           "python",
+	MatchKind:      "comment",
+	Text:           "# TODO: split <parser>",
+	Span:           todox.Span{StartLine: 12, StartCol: 3, EndLine: 12, EndCol: 7, ByteStart: 140, ByteEnd: 144},
+	Cell:           &todox.CellRef{Index: 2, Line: 4},
+	Author:         "Alice",
+	Email:          "alice@example.com",
+	Committer:      "GitHub",
+	CommitterEmail: "noreply@github.com",
+	Date:           "2024-05-01",
+	AgeDays:        12,
+	Commit:         "abcdef1234567890abcdef1234567890abcdef12",
+	File:           "notebooks/etl.ipynb",
+	Submodule:      "notebooks",
+	Line:           12,
+	Comment:        "TODO: split <parser>",
+	Body:           "TODO: split <parser>\nonce the schema settles",
+	Message:        "Add ETL notebook",
+	URL:            "https://github.com/acme/app/blob/abcdef1/notebooks/etl.ipynb#L12",
+	PRs:            []todox.PullRequestRef{{Number: 7, State: "merged", URL: "https://github.com/acme/app/pull/7", Title: "ETL", Body: "Adds the notebook"}},
+	Owners:         []string{"@acme/data"},
+	Skipped:        []todox.SkippedCommit{{Commit: "1234567890abcdef1234567890abcdef12345678", Author: "renovate[bot]", Email: "bot@renovateapp.com"}},
+	Suppressed:     "ignore",
+}
+
+func TestItemJSONIsStable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := todox.WriteNDJSON(&buf, []todox.Item{fullItem, {Kind: "FIXME", File: "main.go", Line: 1}}); err != nil {
+		t.Fatalf("WriteNDJSON failed: %v", err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "items.ndjson"))
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Fatalf("Item JSON changed; keys may only be added.\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestScanEach(t *testing.T) {
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-b", "main")
+	git("config", "user.name", "alice")
+	git("config", "user.email", "alice@example.com")
+	src := "package main\n// TODO: first\nfunc main() {}\n// FIXME: second\n"
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	git("add", ".")
+	git("commit", "-m", "initial")
+
+	opts := todox.Options{RepoDir: repo, WithComment: true, Jobs: 1}
+	var lines []int
+	res, err := todox.ScanEach(context.Background(), opts, func(it todox.Item) error {
+		if it.Author != "alice" {
+			t.Fatalf("unexpected author: %+v", it)
+		}
+		lines = append(lines, it.Line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanEach failed: %v", err)
+	}
+	if len(lines) != 2 || lines[0] != 2 || lines[1] != 4 || res.Total != 2 || res.Items != nil {
+		t.Fatalf("unexpected scan: lines=%v res=%+v", lines, res)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if _, err := todox.ScanEach(context.Background(), opts, func(todox.Item) error {
+		calls++
+		return stop
+	}); !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected ScanEach to stop after the first item, err=%v calls=%d", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := todox.Scan(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	fields, err := todox.ParseFields("type,location,comment")
+	if err != nil {
+		t.Fatalf("ParseFields failed: %v", err)
+	}
+	var md bytes.Buffer
+	if err := todox.WriteMarkdown(&md, []todox.Item{{Kind: "TODO", File: "main.go", Line: 2, Comment: "TODO: first"}}, fields); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if want := "| TYPE | LOCATION | COMMENT |\n| --- | --- | --- |\n| TODO | main.go:2 | TODO: first |\n"; md.String() != want {
+		t.Fatalf("unexpected markdown:\n%s", md.String())
+	}
+	var qf bytes.Buffer
+	if err := todox.WriteQuickfix(&qf, []todox.Item{{Kind: "TODO", Tag: "TODO", File: "main.go", Line: 2, Comment: "TODO: first"}}); err != nil {
+		t.Fatalf("WriteQuickfix failed: %v", err)
+	}
+	if want := "main.go:2:1: TODO: first\n"; qf.String() != want {
+		t.Fatalf("unexpected quickfix output: %q", qf.String())
+	}
+	if _, err := todox.ParseFields("type,nope"); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+}